@@ -0,0 +1,79 @@
+package gocoverdir
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// setupArtifacts points every output that was not set explicitly into the -artifacts directory, and tees the log
+// into it
+func (m *gocoverdir) setupArtifacts() error {
+	if m.args.artifacts == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Join(m.args.artifacts, "packages"), 0755); err != nil {
+		return err
+	}
+	defaults := []struct {
+		flag string
+		file string
+	}{
+		{"coverprofile", "coverage.out"},
+		{"json", "report.json"},
+		{"junit", "junit.xml"},
+		{"cobertura", "cobertura.xml"},
+		{"markdown", "summary.md"},
+		{"html", "coverage.html"},
+		{"htmlreport", "report.html"},
+		{"violations", "violations.json"},
+	}
+	for _, d := range defaults {
+		m.setDefaultFlag(d.flag, filepath.Join(m.args.artifacts, d.file))
+	}
+	logfile, err := os.Create(filepath.Join(m.args.artifacts, "gocoverdir.log"))
+	if err != nil {
+		return err
+	}
+	m.artifactLog = logfile
+	m.log.SetOutput(io.MultiWriter(m.log.Writer(), logfile))
+	return nil
+}
+
+// packageArtifactDir mirrors the package's directory under the artifacts directory
+func (m *gocoverdir) packageArtifactDir(dirpath string) (string, error) {
+	dir := filepath.Join(m.args.artifacts, "packages", dirpath)
+	return dir, os.MkdirAll(dir, 0755)
+}
+
+// packageOutput returns a writer for the package's test output, or nil if artifacts are not kept
+func (m *gocoverdir) packageOutput(dirpath string) (io.WriteCloser, error) {
+	if m.args.artifacts == "" {
+		return nil, nil
+	}
+	dir, err := m.packageArtifactDir(dirpath)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filepath.Join(dir, "test.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// keepPackageProfile copies a package's profile from the store directory into the artifacts directory
+func (m *gocoverdir) keepPackageProfile(dirpath string, profileName string) error {
+	if m.args.artifacts == "" {
+		return nil
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(m.storeDir, profileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	dir, err := m.packageArtifactDir(dirpath)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "coverage.out"), contents, 0644)
+}
@@ -0,0 +1,34 @@
+package gocoverdir
+
+import (
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetupArtifacts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdirtest")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	m := gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	fs := flag.NewFlagSet("testsetup", flag.PanicOnError)
+	m.setupFlags(fs)
+	noError(t, fs.Parse([]string{"-artifacts", dir, "-json", "elsewhere.json"}))
+	noError(t, m.setupArtifacts())
+	defer m.artifactLog.Close()
+	if m.args.coverprofile != filepath.Join(dir, "coverage.out") {
+		t.Errorf("Unexpected coverprofile %s", m.args.coverprofile)
+	}
+	if m.args.json != "elsewhere.json" {
+		t.Errorf("Explicit -json should be kept, saw %s", m.args.json)
+	}
+	m.log.Printf("hello")
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "gocoverdir.log"))
+	noError(t, err)
+	if string(contents) != "hello\n" {
+		t.Errorf("Unexpected log %q", contents)
+	}
+}
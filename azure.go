@@ -0,0 +1,67 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+var azureEscaper = strings.NewReplacer("%", "%AZP25", ";", "%3B", "]", "%5D", "\r", "%0D", "\n", "%0A")
+
+// azureOutputDir is where Azure DevOps tasks look for test and coverage results by default
+func azureOutputDir() string {
+	for _, env := range []string{"COMMON_TESTRESULTSDIRECTORY", "BUILD_ARTIFACTSTAGINGDIRECTORY"} {
+		if dir := os.Getenv(env); dir != "" {
+			return dir
+		}
+	}
+	return os.TempDir()
+}
+
+// azureCommand prints a logging command.  keyValues are alternating property names and values
+func (m *gocoverdir) azureCommand(command string, message string, keyValues ...string) {
+	props := make([]string, 0, len(keyValues)/2)
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		props = append(props, fmt.Sprintf("%s=%s;", keyValues[i], azureEscaper.Replace(keyValues[i+1])))
+	}
+	fmt.Printf("##vso[%s %s]%s\n", command, strings.Join(props, ""), azureEscaper.Replace(message))
+}
+
+func (m *gocoverdir) publishAzureTestResults() error {
+	junitFile := filepath.Join(azureOutputDir(), "gocoverdir-junit.xml")
+	if err := writeFileWith(junitFile, func(w io.Writer) error {
+		return writeJUnit(w, m.emittedResults(), m.args.runid)
+	}); err != nil {
+		return err
+	}
+	m.azureCommand("results.publish", "", "type", "JUnit", "mergeResults", "true", "runTitle", "gocoverdir", "resultFiles", junitFile)
+	return nil
+}
+
+func (m *gocoverdir) publishAzure(profiles []*cover.Profile, coverage float64) error {
+	if err := m.publishAzureTestResults(); err != nil {
+		return err
+	}
+	summaryFile := filepath.Join(azureOutputDir(), "coverage.cobertura.xml")
+	if err := writeFileWith(summaryFile, func(w io.Writer) error {
+		return writeCobertura(w, profiles, m.locator(profiles))
+	}); err != nil {
+		return err
+	}
+	m.log.Printf("Wrote cobertura summary to %s", summaryFile)
+	m.azureCommand("codecoverage.publish", "", "codecoveragetool", "Cobertura", "summaryfile", summaryFile)
+	m.azureCommand("task.setvariable", fmt.Sprintf("%.1f", coverage), "variable", "GOCOVERDIR_COVERAGE")
+	return nil
+}
+
+func (m *gocoverdir) publishAzureFailure(err error) {
+	if publishErr := m.publishAzureTestResults(); publishErr != nil {
+		m.log.Printf("Unable to publish azure test results: %s", publishErr)
+	}
+	m.azureCommand("task.logissue", err.Error(), "type", "error")
+	m.azureCommand("task.complete", "", "result", "Failed")
+}
@@ -0,0 +1,124 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// shieldsEndpoint is the JSON shields.io renders with its endpoint badge, https://shields.io/badges/endpoint-badge
+type shieldsEndpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+func badgeColor(coverage float64) string {
+	switch {
+	case coverage >= 90:
+		return "brightgreen"
+	case coverage >= 75:
+		return "green"
+	case coverage >= 60:
+		return "yellow"
+	case coverage >= 40:
+		return "orange"
+	}
+	return "red"
+}
+
+// badgeHexColors are the shields.io colors used by badgeColor
+var badgeHexColors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+}
+
+var badgeTemplate = template.Must(template.New("badge").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="20" role="img" aria-label="{{.Label}}: {{.Message}}">
+<title>{{.Label}}: {{.Message}}</title>
+<rect width="{{.LabelWidth}}" height="20" fill="#555"/>
+<rect x="{{.LabelWidth}}" width="{{.MessageWidth}}" height="20" fill="{{.Color}}"/>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">
+<text x="{{.LabelX}}" y="14">{{.Label}}</text>
+<text x="{{.MessageX}}" y="14">{{.Message}}</text>
+</g>
+</svg>
+`))
+
+// writeBadge renders a flat badge.  Text width is estimated, since the font is not available to measure
+func writeBadge(rw http.ResponseWriter, label string, coverage float64) error {
+	textWidth := func(s string) int {
+		return len(s)*7 + 10
+	}
+	message := fmt.Sprintf("%.1f%%", coverage)
+	labelWidth := textWidth(label)
+	messageWidth := textWidth(message)
+	rw.Header().Set("Content-Type", "image/svg+xml")
+	rw.Header().Set("Cache-Control", "no-cache")
+	return badgeTemplate.Execute(rw, map[string]interface{}{
+		"Label":        label,
+		"Message":      message,
+		"Color":        badgeHexColors[badgeColor(coverage)],
+		"Width":        labelWidth + messageWidth,
+		"LabelWidth":   labelWidth,
+		"MessageWidth": messageWidth,
+		"LabelX":       labelWidth / 2,
+		"MessageX":     labelWidth + messageWidth/2,
+	})
+}
+
+// findPackage finds a package of r by import path, or by a suffix of it like services/auth
+func findPackage(r *report, name string) (reportPackage, bool) {
+	for _, pkg := range r.Packages {
+		if pkg.Path == name || strings.HasSuffix(pkg.Path, "/"+name) {
+			return pkg, true
+		}
+	}
+	return reportPackage{}, false
+}
+
+// latestPackage finds the package named by the request path, after prefix and without suffix, in the latest run
+func (d *daemon) latestPackage(rw http.ResponseWriter, req *http.Request, prefix string, suffix string) (reportPackage, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, prefix), suffix)
+	d.mu.Lock()
+	latest := d.latest
+	d.mu.Unlock()
+	if latest == nil {
+		http.Error(rw, "no completed run yet", http.StatusNotFound)
+		return reportPackage{}, false
+	}
+	pkg, exists := findPackage(latest, name)
+	if !exists {
+		http.Error(rw, fmt.Sprintf("no coverage for package %s", name), http.StatusNotFound)
+	}
+	return pkg, exists
+}
+
+// handleBadge serves /badge/{package}.svg
+func (d *daemon) handleBadge(rw http.ResponseWriter, req *http.Request) {
+	pkg, exists := d.latestPackage(rw, req, "/badge/", ".svg")
+	if !exists {
+		return
+	}
+	if err := writeBadge(rw, "coverage", pkg.Coverage); err != nil {
+		d.log.Printf("Unable to write badge: %s", err)
+	}
+}
+
+// handleCoverage serves /coverage/{package}.json, in the format of a shields.io endpoint badge
+func (d *daemon) handleCoverage(rw http.ResponseWriter, req *http.Request) {
+	pkg, exists := d.latestPackage(rw, req, "/coverage/", ".json")
+	if !exists {
+		return
+	}
+	d.writeJSON(rw, http.StatusOK, shieldsEndpoint{
+		SchemaVersion: 1,
+		Label:         "coverage",
+		Message:       fmt.Sprintf("%.1f%%", pkg.Coverage),
+		Color:         badgeColor(pkg.Coverage),
+	})
+}
@@ -0,0 +1,39 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestDaemonBadges(t *testing.T) {
+	d := newDaemon(nil, log.New(ioutil.Discard, "", 0))
+	get := func(url string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		d.handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, url, nil))
+		return rw
+	}
+	if rw := get("/badge/a.svg"); rw.Code != http.StatusNotFound {
+		t.Errorf("Expected no badge before a run, saw %d", rw.Code)
+	}
+	profiles := []*cover.Profile{
+		{FileName: "example.com/services/auth/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 3, Count: 1}, {NumStmt: 1}}},
+	}
+	d.latest = newReport(profiles, "statements", nil, nil, nil, nil)
+	rw := get("/badge/services/auth.svg")
+	if rw.Code != http.StatusOK || rw.Header().Get("Content-Type") != "image/svg+xml" || !strings.Contains(rw.Body.String(), "coverage: 75.0%") || !strings.Contains(rw.Body.String(), "#97ca00") {
+		t.Errorf("Unexpected badge %d %s", rw.Code, rw.Body.String())
+	}
+	rw = get("/coverage/example.com/services/auth.json")
+	if expected := `{"schemaVersion":1,"label":"coverage","message":"75.0%","color":"green"}`; rw.Code != http.StatusOK || strings.TrimSpace(rw.Body.String()) != expected {
+		t.Errorf("Unexpected endpoint %d %s", rw.Code, rw.Body.String())
+	}
+	if rw := get("/coverage/services/missing.json"); rw.Code != http.StatusNotFound {
+		t.Errorf("Expected a missing package, saw %d", rw.Code)
+	}
+}
@@ -0,0 +1,189 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// lcovEndCol is the end column used for blocks converted from lcov, which only knows about whole lines
+const lcovEndCol = 1 << 16
+
+// modulePath returns the module path declared by go.mod in dir, or "" if there is none
+func modulePath(dir string) string {
+	contents, err := ioutil.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// lcovToProfiles converts lcov line data into cover profiles with one single statement block per line.  Source
+// files are named prefix/SF
+func lcovToProfiles(r io.Reader, prefix string, mode string) ([]*cover.Profile, error) {
+	files := make(map[string]*cover.Profile)
+	var current *cover.Profile
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "SF:"):
+			name := filepath.ToSlash(strings.TrimPrefix(line, "SF:"))
+			if prefix != "" {
+				name = path.Join(prefix, name)
+			}
+			if current = files[name]; current == nil {
+				current = &cover.Profile{FileName: name, Mode: mode}
+				files[name] = current
+			}
+		case strings.HasPrefix(line, "DA:"):
+			if current == nil {
+				return nil, fmt.Errorf("lcov line data before a source file: %s", line)
+			}
+			parts := strings.Split(strings.TrimPrefix(line, "DA:"), ",")
+			if len(parts) < 2 {
+				return nil, fmt.Errorf("invalid lcov line data: %s", line)
+			}
+			lineNumber, err := strconv.Atoi(parts[0])
+			if err != nil {
+				return nil, err
+			}
+			hits, err := strconv.Atoi(parts[1])
+			if err != nil {
+				return nil, err
+			}
+			if mode == "set" && hits > 0 {
+				hits = 1
+			}
+			current.Blocks = append(current.Blocks, cover.ProfileBlock{
+				StartLine: lineNumber,
+				StartCol:  1,
+				EndLine:   lineNumber,
+				EndCol:    lcovEndCol,
+				NumStmt:   1,
+				Count:     hits,
+			})
+		case line == "end_of_record":
+			current = nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	set := newProfileSet()
+	for _, p := range files {
+		if err := set.add([]*cover.Profile{p}); err != nil {
+			return nil, err
+		}
+	}
+	return set.profiles(), nil
+}
+
+func (m *gocoverdir) bazelCommand(stdout io.Writer, args ...string) error {
+	cmd := exec.Command("bazel", args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = m.testOutputStderr
+	m.log.Printf("Executing %s", strings.Join(cmd.Args, " "))
+	return cmd.Run()
+}
+
+// goTestFiles are the files the go test profiles in storeDir cover
+func goTestFiles(storeDir string) (map[string]bool, error) {
+	entries, err := ioutil.ReadDir(storeDir)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		profiles, err := readProfiles(filepath.Join(storeDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range profiles {
+			ret[p.FileName] = true
+		}
+	}
+	return ret, nil
+}
+
+// withoutFiles drops the profiles of files already covered.  Merging whole line lcov blocks with go test's blocks for
+// the same file would count its statements twice
+func withoutFiles(profiles []*cover.Profile, covered map[string]bool) []*cover.Profile {
+	ret := profiles[:0]
+	for _, p := range profiles {
+		if !covered[p.FileName] {
+			ret = append(ret, p)
+		}
+	}
+	return ret
+}
+
+// coverBazel runs bazel coverage on every go_test target and stores the combined report as another profile
+func (m *gocoverdir) coverBazel() error {
+	var targets bytes.Buffer
+	if err := m.bazelCommand(&targets, "query", m.args.bazelquery); err != nil {
+		return err
+	}
+	targetList := strings.Fields(targets.String())
+	if len(targetList) == 0 {
+		m.log.Printf("No bazel go_test targets found")
+		return nil
+	}
+	args := append([]string{"coverage", "--combined_report=lcov"}, targetList...)
+	if err := m.bazelCommand(m.testOutputStdout, args...); err != nil {
+		return err
+	}
+	var outputPath bytes.Buffer
+	if err := m.bazelCommand(&outputPath, "info", "output_path"); err != nil {
+		return err
+	}
+	report := filepath.Join(strings.TrimSpace(outputPath.String()), "_coverage", "_coverage_report.dat")
+	f, err := os.Open(report)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	mode := m.args.covermode
+	if mode == "" && m.args.race {
+		mode = "atomic"
+	} else if mode == "" {
+		mode = "set"
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	profiles, err := lcovToProfiles(f, modulePath(cwd), mode)
+	if err != nil {
+		return err
+	}
+	covered, err := goTestFiles(m.storeDir)
+	if err != nil {
+		return err
+	}
+	converted := len(profiles)
+	profiles = withoutFiles(profiles, covered)
+	m.log.Printf("Converted %d files from bazel coverage report %s, %d of them only built by bazel", converted, report, len(profiles))
+	return writeFileWith(filepath.Join(m.storeDir, "_bazel.cover"), func(w io.Writer) error {
+		return writeProfiles(w, profiles)
+	})
+}
@@ -0,0 +1,26 @@
+package gocoverdir
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLcovToProfiles(t *testing.T) {
+	lcov := "TN:\nSF:pkg/a/a.go\nDA:3,0\nDA:1,2\nend_of_record\nSF:pkg/b/b.go\nDA:5,1\nend_of_record\n"
+	profiles, err := lcovToProfiles(strings.NewReader(lcov), "example.com/m", "set")
+	noError(t, err)
+	if len(profiles) != 2 || profiles[0].FileName != "example.com/m/pkg/a/a.go" {
+		t.Fatalf("Unexpected profiles %+v", profiles)
+	}
+	blocks := profiles[0].Blocks
+	if len(blocks) != 2 || blocks[0].StartLine != 1 || blocks[0].Count != 1 || blocks[1].Count != 0 {
+		t.Errorf("Unexpected blocks %+v", blocks)
+	}
+	if _, err := lcovToProfiles(strings.NewReader("DA:1,1\n"), "", "set"); err == nil {
+		t.Errorf("Expected an error for line data without a source file")
+	}
+	bazelOnly := withoutFiles(profiles, map[string]bool{"example.com/m/pkg/a/a.go": true})
+	if len(bazelOnly) != 1 || bazelOnly[0].FileName != "example.com/m/pkg/b/b.go" {
+		t.Errorf("Expected only the file go test did not cover, saw %+v", bazelOnly)
+	}
+}
@@ -0,0 +1,102 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// maxLogSection is how much of a package's buffered output a failure prints.  The end is kept, since that is where
+// go test says what failed
+const maxLogSection = 64 << 10
+
+// packageLogs keeps each package's test output apart while the log is buffered, so a failure can print just the
+// packages that failed instead of the whole run
+type packageLogs struct {
+	mu   sync.Mutex
+	logs map[string]*bytes.Buffer
+}
+
+func newPackageLogs() *packageLogs {
+	return &packageLogs{logs: make(map[string]*bytes.Buffer)}
+}
+
+type packageLogWriter struct {
+	p   *packageLogs
+	dir string
+}
+
+func (w packageLogWriter) Write(b []byte) (int, error) {
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+	if w.p.logs[w.dir] == nil {
+		w.p.logs[w.dir] = &bytes.Buffer{}
+	}
+	return w.p.logs[w.dir].Write(b)
+}
+
+// writer collects the output of dir's tests
+func (p *packageLogs) writer(dir string) io.Writer {
+	return packageLogWriter{p: p, dir: dir}
+}
+
+func (p *packageLogs) output(dir string) []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.logs[dir] == nil {
+		return nil
+	}
+	return p.logs[dir].Bytes()
+}
+
+// writeLogSection writes output under a header naming it, keeping only its last maxLogSection bytes from the start of
+// a line
+func writeLogSection(w io.Writer, name string, output []byte) {
+	header := name
+	if len(output) > maxLogSection {
+		header = fmt.Sprintf("%s, last %d of %d bytes", name, maxLogSection, len(output))
+		output = output[len(output)-maxLogSection:]
+		if i := bytes.IndexByte(output, '\n'); i >= 0 {
+			output = output[i+1:]
+		}
+	}
+	fmt.Fprintf(w, "==== %s ====\n", header)
+	w.Write(output)
+	if len(output) > 0 && output[len(output)-1] != '\n' {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "==== end of %s ====\n", name)
+}
+
+// failedDirs lists the packages whose tests failed, in the order they ran
+func (m *gocoverdir) failedDirs() []string {
+	var ret []string
+	seen := make(map[string]struct{})
+	for _, result := range m.results {
+		if _, exists := seen[result.dir]; exists || result.err == nil {
+			continue
+		}
+		seen[result.dir] = struct{}{}
+		ret = append(ret, result.dir)
+	}
+	return ret
+}
+
+// flushBufferedLog writes what a silent -logfile="" or -q run kept back to w once it fails: the output of each package
+// that failed, or all of it when the failure was not a package's
+func (m *gocoverdir) flushBufferedLog(w io.Writer) {
+	if m.packageLogs == nil {
+		return
+	}
+	failed := m.failedDirs()
+	if len(failed) == 0 {
+		if m.panicPrintBuffer.Len() > 0 {
+			writeLogSection(w, "gocoverdir output", m.panicPrintBuffer.Bytes())
+		}
+		return
+	}
+	for _, dir := range failed {
+		writeLogSection(w, "test output of "+m.emittedPath(dir), m.packageLogs.output(dir))
+	}
+}
@@ -0,0 +1,47 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFlushBufferedLog(t *testing.T) {
+	m := gocoverdir{packageLogs: newPackageLogs()}
+	m.panicPrintBuffer.WriteString("Executing go test ./a\nExecuting go test ./b\n")
+	m.packageLogs.writer("a").Write([]byte("ok a\n"))
+	m.packageLogs.writer("b").Write([]byte("--- FAIL: TestB\nFAIL b"))
+
+	var out bytes.Buffer
+	m.flushBufferedLog(&out)
+	if out.String() != "==== gocoverdir output ====\nExecuting go test ./a\nExecuting go test ./b\n==== end of gocoverdir output ====\n" {
+		t.Errorf("Unexpected output without failed packages:\n%s", out.String())
+	}
+
+	m.results = []packageResult{{dir: "a"}, {dir: "b", err: errors.New("exit status 1")}}
+	out.Reset()
+	m.flushBufferedLog(&out)
+	if out.String() != "==== test output of b ====\n--- FAIL: TestB\nFAIL b\n==== end of test output of b ====\n" {
+		t.Errorf("Unexpected output of failed packages:\n%s", out.String())
+	}
+
+	out.Reset()
+	(&gocoverdir{}).flushBufferedLog(&out)
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing from an unbuffered log, got %s", out.String())
+	}
+}
+
+func TestWriteLogSectionTruncates(t *testing.T) {
+	output := strings.Repeat("early line\n", maxLogSection/10) + "--- FAIL: TestLast\n"
+	var out bytes.Buffer
+	writeLogSection(&out, "test output of a", []byte(output))
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !strings.HasPrefix(lines[0], "==== test output of a, last ") || lines[1] != "early line" || lines[len(lines)-2] != "--- FAIL: TestLast" {
+		t.Errorf("Unexpected truncated section %s ... %s", lines[0], lines[len(lines)-2])
+	}
+	if out.Len() > maxLogSection+200 {
+		t.Errorf("Section not truncated: %d bytes", out.Len())
+	}
+}
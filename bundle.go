@@ -0,0 +1,408 @@
+package gocoverdir
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+// bundleManifestName is the file in every bundle that says what made it and what each file is
+const bundleManifestName = "bundle.json"
+
+// bundleFile is one file of a bundle.  Flag is the output it came from, or "artifacts" for the other files of the
+// -artifacts directory
+type bundleFile struct {
+	Name string `json:"name"`
+	Flag string `json:"flag"`
+	// path is where the file is on disk
+	path string
+}
+
+type bundleManifest struct {
+	Created time.Time    `json:"created"`
+	Meta    profileMeta  `json:"meta"`
+	Files   []bundleFile `json:"files"`
+}
+
+// bundleName names the output p of flagName in a bundle: relative to the working directory if it is under it, like
+// the flag gave it, and otherwise by its base name under external/<flag>, so outputs with the same base name in
+// different directories outside the tree do not collide
+func bundleName(flagName string, p string) string {
+	if !filepath.IsAbs(p) {
+		if rel := filepath.ToSlash(filepath.Clean(p)); rel != ".." && !strings.HasPrefix(rel, "../") {
+			return rel
+		}
+	}
+	return path.Join("external", flagName, filepath.Base(p))
+}
+
+// bundleFiles lists every output of the run that exists: the -artifacts directory, and each file output with its
+// -compress variant.  Outputs inside -artifacts are only included once, with the flag of the output
+func (m *gocoverdir) bundleFiles() ([]bundleFile, error) {
+	var ret []bundleFile
+	seen := make(map[string]int)
+	names := make(map[string]bool)
+	add := func(flagName string, name string, p string) {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return
+		}
+		// The run still holds its lock on the profile
+		if sameFile(p, m.args.bundle) || (m.lockfile != "" && sameFile(p, m.lockfile)) {
+			return
+		}
+		if i, exists := seen[abs]; exists {
+			ret[i].Flag = flagName
+			return
+		}
+		// Two names collide only if an output in the tree is under artifacts/ or external/
+		unique := name
+		for i := 2; names[unique]; i++ {
+			unique = path.Join(path.Dir(name), strconv.Itoa(i), path.Base(name))
+		}
+		seen[abs] = len(ret)
+		names[unique] = true
+		ret = append(ret, bundleFile{Name: unique, Flag: flagName, path: p})
+	}
+	if m.args.artifacts != "" {
+		err := filepath.Walk(m.args.artifacts, func(p string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(m.args.artifacts, p)
+			if err != nil {
+				return err
+			}
+			add("artifacts", path.Join("artifacts", filepath.ToSlash(rel)), p)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	outputs := []struct {
+		flag string
+		file string
+	}{
+		{"coverprofile", m.args.coverprofile},
+		{"coverprofile", profileMetaFile(m.args.coverprofile)},
+		{"json", m.args.json},
+		{"sign", signatureFile(m.args.json)},
+		{"redact", m.args.redact},
+		{"pb", m.args.pb},
+		{"jenkins", m.args.jenkins},
+		{"cobertura", m.args.cobertura},
+		{"lcov", m.args.lcov},
+		{"junit", m.args.junit},
+		{"markdown", m.args.markdown},
+		{"csv", m.args.csv},
+		{"html", m.args.html},
+		{"htmlreport", m.args.htmlreport},
+		{"violations", m.args.violations},
+		{"explain", m.args.explain},
+		{"contribution", m.args.contribution},
+		{"logfile", m.args.logfile},
+	}
+	for _, output := range outputs {
+		if output.file == "" || output.file == stdio {
+			continue
+		}
+		for _, p := range []string{output.file, output.file + ".gz"} {
+			if info, err := os.Stat(p); err == nil && !info.IsDir() {
+				add(output.flag, bundleName(output.flag, p), p)
+			}
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Name < ret[j].Name
+	})
+	return ret, nil
+}
+
+func addTarFile(tw *tar.Writer, name string, p string) error {
+	f, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// writeBundle writes manifest, and its files, as a gzipped tarball
+func writeBundle(w io.Writer, manifest bundleManifest) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	contents, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: bundleManifestName, Mode: 0644, Size: int64(len(contents)), ModTime: manifest.Created}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(contents); err != nil {
+		return err
+	}
+	for _, file := range manifest.Files {
+		if err := addTarFile(tw, file.Name, file.path); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// writeRunBundle archives every output of the run into -bundle, for audits that keep single files
+func (m *gocoverdir) writeRunBundle() error {
+	if m.args.bundle == "" {
+		return nil
+	}
+	files, err := m.bundleFiles()
+	if err != nil {
+		return err
+	}
+	m.log.Printf("Bundling %d files into %s", len(files), m.args.bundle)
+	return writeFileWith(m.args.bundle, func(w io.Writer) error {
+		return writeBundle(w, bundleManifest{Created: time.Now(), Meta: m.profileMeta(), Files: files})
+	})
+}
+
+// unbundle extracts the bundle r into dir, returning its manifest
+func unbundle(r io.Reader, dir string) (*bundleManifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+	var manifest bundleManifest
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return &manifest, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		name := path.Clean(header.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("refusing to extract %s outside of %s", header.Name, dir)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		if err := writeFileWith(target, func(w io.Writer) error {
+			_, err := io.Copy(w, tr)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		if name == bundleManifestName {
+			contents, err := ioutil.ReadFile(target)
+			if err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal(contents, &manifest); err != nil {
+				return nil, fmt.Errorf("cannot parse %s: %s", bundleManifestName, err)
+			}
+		}
+	}
+}
+
+// runUnbundle extracts a -bundle, so its profile and reports can be fed to other commands offline
+func runUnbundle(args []string) error {
+	fs := flag.NewFlagSet("unbundle", flag.ContinueOnError)
+	dir := fs.String("d", ".", "Directory to extract the bundle into")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return fmt.Errorf("usage: gocoverdir unbundle [-d dir] coverage-bundle.tar.gz")
+	}
+	f, err := openInput(files[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	manifest, err := unbundle(f, *dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Bundle of commit %s, made %s by gocoverdir %s\n", manifest.Meta.Commit, manifest.Created.Format(time.RFC3339), manifest.Meta.ToolVersion)
+	for _, file := range manifest.Files {
+		fmt.Printf("%s\t%s\n", file.Flag, filepath.Join(*dir, filepath.FromSlash(file.Name)))
+	}
+	return nil
+}
+
+// bundledReport is the profile and JSON report a bundle holds, extracted to disk
+type bundledReport struct {
+	profile string
+	report  string
+}
+
+// findBundledReport finds the -coverprofile, and the -json report if there is one, of manifest, extracted into dir
+func findBundledReport(manifest *bundleManifest, dir string) (bundledReport, error) {
+	var ret bundledReport
+	for _, file := range manifest.Files {
+		p := filepath.Join(dir, filepath.FromSlash(file.Name))
+		switch {
+		case file.Flag == "coverprofile" && ret.profile == "" && !strings.HasSuffix(file.Name, ".meta.json"):
+			ret.profile = p
+		case file.Flag == "json" && ret.report == "":
+			ret.report = p
+		}
+	}
+	if ret.profile == "" {
+		return ret, fmt.Errorf("the bundle has no -coverprofile")
+	}
+	return ret, nil
+}
+
+// results are the go test results of r, as much of them as JUnit needs
+func (r *report) results() []packageResult {
+	ret := make([]packageResult, 0, len(r.Tests))
+	for _, test := range r.Tests {
+		result := packageResult{
+			dir:         test.Dir,
+			command:     test.Command,
+			duration:    time.Duration(test.Seconds * float64(time.Second)),
+			skipped:     test.SkippedTests,
+			flaky:       test.Flaky,
+			quarantined: test.Quarantined,
+		}
+		if !test.Passed {
+			result.err = errors.New(test.Error)
+		}
+		ret = append(ret, result)
+	}
+	return ret
+}
+
+// rebuildReport is the report of profiles with the run's tests, skips, findings and CI from bundled, the bundle's
+// JSON report, if it has one
+func rebuildReport(profiles []*cover.Profile, metric string, bundled *report) *report {
+	ret := newReport(profiles, metric, nil, nil, nil, nil)
+	if bundled != nil {
+		ret.RunID = bundled.RunID
+		ret.Timestamp = bundled.Timestamp
+		ret.CI = bundled.CI
+		ret.Tests = bundled.Tests
+		ret.Skipped = bundled.Skipped
+		ret.Findings = bundled.Findings
+	}
+	return ret
+}
+
+// runReport rebuilds the HTML, JSON and JUnit reports of a -bundle offline.  Coverage comes from the bundle's
+// profile, and test results from its JSON report
+func runReport(args []string) error {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fromBundle := fs.String("from-bundle", "", "Bundle written by -bundle to rebuild reports from")
+	html := fs.String("html", "", "If set, generate coverage HTML at this file.  The source files are read from the working directory")
+	jsonReport := fs.String("json", "", "If set, write a JSON coverage report to this file")
+	junit := fs.String("junit", "", "If set, write JUnit XML with one test suite per package to this file.  Needs a bundle with a -json report")
+	metric := fs.String("metric", "", "What coverage counts: statements, blocks or lines.  Empty means the metric of the bundled report")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *fromBundle == "" || fs.NArg() != 0 {
+		return fmt.Errorf("usage: gocoverdir report -from-bundle coverage-bundle.tar.gz [-html file] [-json file] [-junit file]")
+	}
+	dir, err := ioutil.TempDir("", "gocoverdir-report")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	f, err := openInput(*fromBundle)
+	if err != nil {
+		return err
+	}
+	manifest, err := unbundle(f, dir)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	bundled, err := findBundledReport(manifest, dir)
+	if err != nil {
+		return err
+	}
+	profiles, err := readProfiles(bundled.profile)
+	if err != nil {
+		return err
+	}
+	var bundledReport *report
+	if bundled.report != "" {
+		if bundledReport, err = readReport(bundled.report); err != nil {
+			return err
+		}
+	}
+	if *metric == "" {
+		*metric = "statements"
+		if bundledReport != nil && bundledReport.Metric != "" {
+			*metric = bundledReport.Metric
+		}
+	}
+	if err := verifyMetric(*metric); err != nil {
+		return err
+	}
+	rebuilt := rebuildReport(profiles, *metric, bundledReport)
+	if *html != "" {
+		// go tool cover cannot read gzipped profiles
+		textProfile := filepath.Join(dir, "coverage.out")
+		if err := writeFileWith(textProfile, func(w io.Writer) error {
+			return writeProfiles(w, profiles)
+		}); err != nil {
+			return err
+		}
+		cmd := exec.Command("go", "tool", "cover", "-html", textProfile, "-o", *html)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return err
+		}
+	}
+	if *jsonReport != "" {
+		if err := writeFileWith(*jsonReport, func(w io.Writer) error {
+			return writeReport(w, rebuilt)
+		}); err != nil {
+			return err
+		}
+	}
+	if *junit != "" {
+		if bundledReport == nil {
+			return fmt.Errorf("-junit needs test results, and the bundle has no -json report")
+		}
+		if err := writeFileWith(*junit, func(w io.Writer) error {
+			return writeJUnit(w, bundledReport.results(), bundledReport.RunID)
+		}); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("coverage: %.1f%% of %s\n", rebuilt.Coverage, *metric)
+	return nil
+}
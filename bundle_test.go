@@ -0,0 +1,135 @@
+package gocoverdir
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBundleRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestBundleRoundTrip")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	write := func(name string, contents string) string {
+		filename := filepath.Join(dir, name)
+		noError(t, os.MkdirAll(filepath.Dir(filename), 0755))
+		noError(t, ioutil.WriteFile(filename, []byte(contents), 0644))
+		return filename
+	}
+	m := &gocoverdir{}
+	m.args.artifacts = filepath.Join(dir, "artifacts")
+	m.args.coverprofile = write("artifacts/coverage.out", "mode: set\n")
+	write("artifacts/packages/a/test.log", "ok\n")
+	m.args.json = write("out/report.json", "{}\n")
+	m.args.junit = write("out/junit.xml", "<testsuites/>\n")
+	noError(t, gzipFile(m.args.junit))
+	m.args.html = filepath.Join(dir, "missing.html")
+	m.args.bundle = filepath.Join(dir, "artifacts", "bundle.tar.gz")
+	write("artifacts/bundle.tar.gz", "old bundle")
+	m.lockfile = write("artifacts/coverage.out.lock", "1\n")
+	files, err := m.bundleFiles()
+	noError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Flag+" "+f.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"coverprofile artifacts/coverage.out", "artifacts artifacts/packages/a/test.log", "json external/json/report.json", "junit external/junit/junit.xml.gz"}) {
+		t.Errorf("Unexpected bundle files %v", names)
+	}
+
+	var buf bytes.Buffer
+	noError(t, writeBundle(&buf, bundleManifest{Created: time.Now(), Meta: profileMeta{Commit: "abc"}, Files: files}))
+	out := filepath.Join(dir, "extracted")
+	manifest, err := unbundle(&buf, out)
+	noError(t, err)
+	if manifest.Meta.Commit != "abc" || len(manifest.Files) != 4 {
+		t.Errorf("Unexpected manifest %+v", manifest)
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(out, "artifacts", "packages", "a", "test.log"))
+	noError(t, err)
+	if string(contents) != "ok\n" {
+		t.Errorf("Unexpected extracted contents %q", contents)
+	}
+}
+
+func TestUnbundleOutsideDir(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	noError(t, tw.WriteHeader(&tar.Header{Name: "../escape", Mode: 0644}))
+	noError(t, tw.Close())
+	noError(t, gz.Close())
+	if _, err := unbundle(&buf, os.TempDir()); err == nil || !strings.Contains(err.Error(), "refusing") {
+		t.Errorf("Expected an error extracting outside the directory, saw %v", err)
+	}
+}
+
+func TestBundleNamesAreUnique(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	write := func(name string) string {
+		noError(t, os.MkdirAll(filepath.Dir(name), 0755))
+		noError(t, ioutil.WriteFile(name, []byte("{}\n"), 0644))
+		return name
+	}
+	outside := t.TempDir()
+	m := &gocoverdir{}
+	m.args.json = write(filepath.Join(outside, "a", "out.json"))
+	m.args.redact = write(filepath.Join(outside, "b", "out.json"))
+	m.args.markdown = write(filepath.Join("external", "json", "out.json"))
+	files, err := m.bundleFiles()
+	noError(t, err)
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Flag+" "+f.Name)
+	}
+	expected := []string{"markdown external/json/2/out.json", "json external/json/out.json", "redact external/redact/out.json"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Unexpected bundle files %v", names)
+	}
+}
+
+func TestReportFromBundle(t *testing.T) {
+	dir := t.TempDir()
+	profile := filepath.Join(dir, "coverage.out")
+	noError(t, ioutil.WriteFile(profile, []byte("mode: set\nexample.com/a/a.go:1.1,2.1 3 1\nexample.com/a/a.go:3.1,4.1 1 0\n"), 0644))
+	bundledJSON := filepath.Join(dir, "report.json")
+	noError(t, ioutil.WriteFile(bundledJSON, []byte(`{"run_id":"r1","coverage":10,"tests":[{"dir":"./a","command":"go test","seconds":2,"passed":false,"error":"exit status 1"}]}`), 0644))
+	bundle := filepath.Join(dir, "bundle.tar.gz")
+	f, err := os.Create(bundle)
+	noError(t, err)
+	noError(t, writeBundle(f, bundleManifest{Created: time.Now(), Files: []bundleFile{
+		{Name: "coverage.out", Flag: "coverprofile", path: profile},
+		{Name: "external/json/report.json", Flag: "json", path: bundledJSON},
+	}}))
+	noError(t, f.Close())
+
+	out := filepath.Join(dir, "out.json")
+	junit := filepath.Join(dir, "junit.xml")
+	noError(t, runReport([]string{"-from-bundle", bundle, "-json", out, "-junit", junit}))
+	rebuilt, err := readReport(out)
+	noError(t, err)
+	if rebuilt.Coverage != 75 || rebuilt.RunID != "r1" || len(rebuilt.Tests) != 1 || rebuilt.Metric != "statements" {
+		t.Errorf("Unexpected rebuilt report %+v", rebuilt)
+	}
+	contents, err := ioutil.ReadFile(junit)
+	noError(t, err)
+	if !strings.Contains(string(contents), `<failure message="exit status 1">`) || !strings.Contains(string(contents), `value="r1"`) {
+		t.Errorf("Unexpected JUnit %s", contents)
+	}
+
+	f, err = os.Create(bundle)
+	noError(t, err)
+	noError(t, writeBundle(f, bundleManifest{Created: time.Now(), Files: []bundleFile{{Name: "coverage.out", Flag: "coverprofile", path: profile}}}))
+	noError(t, f.Close())
+	if err := runReport([]string{"-from-bundle", bundle, "-junit", junit}); err == nil || !strings.Contains(err.Error(), "no -json report") {
+		t.Errorf("Expected -junit to need a bundled report, saw %v", err)
+	}
+}
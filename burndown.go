@@ -0,0 +1,185 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const week = 7 * 24 * time.Hour
+
+// burndownRow is the plan for one package to reach its goal
+type burndownRow struct {
+	Package string  `json:"package"`
+	Goal    string  `json:"goal"`
+	Current float64 `json:"current"`
+	Target  float64 `json:"target"`
+	By      string  `json:"by"`
+	// WeeksLeft is the time until the deadline, in weeks
+	WeeksLeft float64 `json:"weeks_left"`
+	// Needed is how many percentage points a week the package must gain to make it
+	Needed float64 `json:"needed_per_week"`
+	// Trend is how many points a week it gained over the history window, and HasTrend is false without enough runs
+	Trend    float64 `json:"trend_per_week"`
+	HasTrend bool    `json:"has_trend"`
+	// Status is met, on track, behind, no trend or overdue
+	Status string `json:"status"`
+}
+
+// burndownOrder puts the rows that need action first
+var burndownOrder = map[string]int{"overdue": 0, "behind": 1, "no trend": 2, "on track": 3, "met": 4}
+
+// trendPerWeek is the least squares slope of pkg's coverage, in points a week, over the entries since since
+func trendPerWeek(entries []historyEntry, pkg string, since time.Time) (float64, bool) {
+	var xs, ys []float64
+	for _, entry := range entries {
+		coverage, exists := entry.Packages[pkg]
+		if !exists || entry.Timestamp.Before(since) {
+			continue
+		}
+		xs = append(xs, float64(entry.Timestamp.Sub(since))/float64(week))
+		ys = append(ys, coverage)
+	}
+	if len(xs) < 2 {
+		return 0, false
+	}
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(len(xs))
+	meanY /= float64(len(ys))
+	var num, den float64
+	for i := range xs {
+		num += (xs[i] - meanX) * (ys[i] - meanY)
+		den += (xs[i] - meanX) * (xs[i] - meanX)
+	}
+	if den == 0 {
+		return 0, false
+	}
+	return num / den, true
+}
+
+// computeBurndown plans, for each package of the latest history entry with a goal, the weekly improvement that reaches
+// it, and whether the trend over the last window is enough
+func computeBurndown(entries []historyEntry, goals map[string]goal, now time.Time, window time.Duration) []burndownRow {
+	if len(entries) == 0 {
+		return nil
+	}
+	latest := entries[len(entries)-1]
+	patterns := make([]string, 0, len(goals))
+	for pattern := range goals {
+		patterns = append(patterns, pattern)
+	}
+	sortBySpecificity(patterns)
+	var ret []burndownRow
+	for pkg, current := range latest.Packages {
+		for _, pattern := range patterns {
+			if !matchPackage(pattern, pkg) {
+				continue
+			}
+			g := goals[pattern]
+			deadline, err := g.deadline()
+			if err != nil {
+				break
+			}
+			row := burndownRow{Package: pkg, Goal: pattern, Current: current, Target: g.Target, By: g.By, WeeksLeft: math.Max(0, float64(deadline.Sub(now))/float64(week))}
+			row.Trend, row.HasTrend = trendPerWeek(entries, pkg, latest.Timestamp.Add(-window))
+			switch {
+			case !belowThreshold(current, g.Target):
+				row.Status = "met"
+			case row.WeeksLeft == 0:
+				row.Status = "overdue"
+				row.Needed = g.Target - current
+			default:
+				row.Needed = (g.Target - current) / row.WeeksLeft
+				if !row.HasTrend {
+					row.Status = "no trend"
+				} else if row.Trend >= row.Needed {
+					row.Status = "on track"
+				} else {
+					row.Status = "behind"
+				}
+			}
+			ret = append(ret, row)
+			break
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if burndownOrder[ret[i].Status] != burndownOrder[ret[j].Status] {
+			return burndownOrder[ret[i].Status] < burndownOrder[ret[j].Status]
+		}
+		if ret[i].Needed != ret[j].Needed {
+			return ret[i].Needed > ret[j].Needed
+		}
+		return ret[i].Package < ret[j].Package
+	})
+	return ret
+}
+
+func writeBurndownText(w io.Writer, rows []burndownRow) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "PACKAGE\tCURRENT\tTARGET\tBY\tWEEKS LEFT\tNEEDED/WEEK\tTREND/WEEK\tSTATUS\n")
+	for _, row := range rows {
+		trend := "-"
+		if row.HasTrend {
+			trend = fmt.Sprintf("%+.2f", row.Trend)
+		}
+		needed := "-"
+		if row.Status != "met" {
+			needed = fmt.Sprintf("%.2f", row.Needed)
+		}
+		fmt.Fprintf(tw, "%s\t%.1f%%\t%.1f%%\t%s\t%.1f\t%s\t%s\t%s\n", row.Package, row.Current, row.Target, row.By, row.WeeksLeft, needed, trend, row.Status)
+	}
+	return tw.Flush()
+}
+
+func writeBurndownJSON(w io.Writer, rows []burndownRow) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rows)
+}
+
+// runBurndown turns the config's goals into a weekly plan per package, using the -history store's trends
+func runBurndown(args []string) error {
+	fs := flag.NewFlagSet("burndown", flag.ContinueOnError)
+	configFile := fs.String("config", "", "Config file with the goals to plan for")
+	history := fs.String("history", "gocoverdir-history.jsonl", "History store written by -history")
+	window := fs.Duration("window", 8*week, "How far back from the latest run the trend is measured")
+	output := fs.String("o", stdio, "File to write the plan to, as JSON if it ends in .json and as a table otherwise")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *configFile == "" {
+		return fmt.Errorf("usage: gocoverdir burndown -config coverage.json [-history gocoverdir-history.jsonl] [-window 1344h] [-o plan.json]")
+	}
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Goals) == 0 {
+		return fmt.Errorf("%s has no goals to plan for", *configFile)
+	}
+	entries, err := readHistory(*history)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("%s has no runs yet", *history)
+	}
+	rows := computeBurndown(entries, cfg.Goals, time.Now(), *window)
+	write := writeBurndownText
+	if strings.HasSuffix(*output, ".json") {
+		write = writeBurndownJSON
+	}
+	return writeFileWith(*output, func(w io.Writer) error {
+		return write(w, rows)
+	})
+}
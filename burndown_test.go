@@ -0,0 +1,64 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrendPerWeek(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	entries := []historyEntry{
+		{Timestamp: start, Packages: map[string]float64{"a": 10}},
+		{Timestamp: start.Add(week), Packages: map[string]float64{"a": 12}},
+		{Timestamp: start.Add(2 * week), Packages: map[string]float64{"a": 14, "b": 50}},
+	}
+	trend, ok := trendPerWeek(entries, "a", start)
+	if !ok || math.Abs(trend-2) > .0001 {
+		t.Errorf("Expected 2 points a week, got %f %v", trend, ok)
+	}
+	if _, ok := trendPerWeek(entries, "b", start); ok {
+		t.Errorf("Expected no trend from one run")
+	}
+	if _, ok := trendPerWeek(entries, "a", start.Add(week+time.Hour)); ok {
+		t.Errorf("Expected runs before the window to be left out")
+	}
+}
+
+func TestComputeBurndown(t *testing.T) {
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)
+	entries := []historyEntry{
+		{Timestamp: now.Add(-2 * week), Packages: map[string]float64{"example.com/fast": 50, "example.com/slow": 50}},
+		{Timestamp: now.Add(-week), Packages: map[string]float64{"example.com/fast": 55, "example.com/slow": 50.5}},
+		{Timestamp: now, Packages: map[string]float64{"example.com/fast": 60, "example.com/slow": 51, "example.com/done": 90, "example.com/late": 40, "example.com/new": 30, "example.com/other": 10}},
+	}
+	goals := map[string]goal{
+		"example.com/fast": {Target: 70, By: now.AddDate(0, 0, 27).Format(goalDateLayout)},
+		"example.com/slow": {Target: 70, By: now.AddDate(0, 0, 27).Format(goalDateLayout)},
+		"example.com/done": {Target: 80, By: "2025-06-01"},
+		"example.com/late": {Target: 80, By: "2025-01-01"},
+		"example.com/new":  {Target: 60, By: now.AddDate(0, 0, 13).Format(goalDateLayout)},
+	}
+	rows := computeBurndown(entries, goals, now, 8*week)
+	var statuses []string
+	for _, row := range rows {
+		statuses = append(statuses, row.Package+" "+row.Status)
+	}
+	expected := "example.com/late overdue,example.com/slow behind,example.com/new no trend,example.com/fast on track,example.com/done met"
+	if strings.Join(statuses, ",") != expected {
+		t.Errorf("Unexpected plan %s", strings.Join(statuses, ","))
+	}
+	// 19 points to gain over the 4 weeks to the end of the deadline day
+	if slow := rows[1]; math.Abs(slow.WeeksLeft-4) > .0001 || math.Abs(slow.Needed-4.75) > .0001 {
+		t.Errorf("Unexpected plan for slow %+v", slow)
+	}
+
+	var buf bytes.Buffer
+	noError(t, writeBurndownText(&buf, rows))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 6 || !strings.HasPrefix(lines[0], "PACKAGE") || !strings.Contains(lines[2], "4.75") || !strings.HasSuffix(lines[2], "behind") {
+		t.Errorf("Unexpected table:\n%s", buf.String())
+	}
+}
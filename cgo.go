@@ -0,0 +1,80 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"fmt"
+	"go/build"
+	"os/exec"
+	"strings"
+)
+
+// setupCgo resolves -cgo into the CGO_ENABLED value used for test runs, or "" to inherit the environment
+func (m *gocoverdir) setupCgo() error {
+	switch m.args.cgo {
+	case "":
+		m.cgoEnabled = ""
+	case "on":
+		m.cgoEnabled = "1"
+	case "off":
+		m.cgoEnabled = "0"
+	case "auto":
+		m.cgoEnabled = "0"
+		if cCompilerAvailable() {
+			m.cgoEnabled = "1"
+		}
+		m.log.Printf("Auto detected CGO_ENABLED=%s", m.cgoEnabled)
+	default:
+		return fmt.Errorf("-cgo must be on, off or auto, not %s", m.args.cgo)
+	}
+	return nil
+}
+
+func cCompilerAvailable() bool {
+	var stdout bytes.Buffer
+	cmd := exec.Command("go", "env", "CC")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return false
+	}
+	cc := strings.Fields(stdout.String())
+	if len(cc) == 0 {
+		return false
+	}
+	_, err := exec.LookPath(cc[0])
+	return err == nil
+}
+
+func (m *gocoverdir) buildContext() *build.Context {
+	ctx := build.Default
+	if m.cgoEnabled != "" {
+		ctx.CgoEnabled = m.cgoEnabled == "1"
+	}
+	if m.args.tags != "" {
+		ctx.BuildTags = strings.FieldsFunc(m.args.tags, func(r rune) bool {
+			return r == ',' || r == ' '
+		})
+	}
+	return &ctx
+}
+
+// testableReason returns "" if dir can be tested, or why it cannot
+func (m *gocoverdir) testableReason(dir string) string {
+	ctx := m.buildContext()
+	reason := buildableIn(ctx, dir)
+	if reason == "" || ctx.CgoEnabled {
+		return reason
+	}
+	withCgo := *ctx
+	withCgo.CgoEnabled = true
+	if buildableIn(&withCgo, dir) == "" {
+		return "requires cgo, which is disabled"
+	}
+	return reason
+}
+
+func (m *gocoverdir) cgoEnv() []string {
+	if m.cgoEnabled == "" {
+		return nil
+	}
+	return []string{"CGO_ENABLED=" + m.cgoEnabled}
+}
@@ -0,0 +1,31 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTestableReasonCgo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdirtest")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nimport \"C\"\n"), 0644))
+	m := gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	m.args.cgo = "off"
+	noError(t, m.setupCgo())
+	if reason := m.testableReason(dir); reason != "requires cgo, which is disabled" {
+		t.Errorf("Unexpected reason %q", reason)
+	}
+	m.args.cgo = "on"
+	noError(t, m.setupCgo())
+	if reason := m.testableReason(dir); reason != "" {
+		t.Errorf("Expected directory to be testable, saw %q", reason)
+	}
+	m.args.cgo = "sometimes"
+	if err := m.setupCgo(); err == nil {
+		t.Errorf("Expected an error for an invalid -cgo")
+	}
+}
@@ -0,0 +1,144 @@
+package gocoverdir
+
+import (
+	"flag"
+	"html/template"
+	"io"
+	"sort"
+)
+
+type chartSeries struct {
+	Name   string       `json:"name"`
+	Points [][2]float64 `json:"points"`
+}
+
+// historySeries turns history into a total series followed by one series per package, with points of
+// [unix milliseconds, coverage]
+func historySeries(entries []historyEntry) []chartSeries {
+	total := chartSeries{Name: "total"}
+	byPackage := make(map[string]*chartSeries)
+	for _, entry := range entries {
+		ts := float64(entry.Timestamp.UnixNano() / 1e6)
+		total.Points = append(total.Points, [2]float64{ts, entry.Coverage})
+		for pkg, coverage := range entry.Packages {
+			series, exists := byPackage[pkg]
+			if !exists {
+				series = &chartSeries{Name: pkg}
+				byPackage[pkg] = series
+			}
+			series.Points = append(series.Points, [2]float64{ts, coverage})
+		}
+	}
+	names := make([]string, 0, len(byPackage))
+	for name := range byPackage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	ret := []chartSeries{total}
+	for _, name := range names {
+		ret = append(ret, *byPackage[name])
+	}
+	return ret
+}
+
+var chartTemplate = template.Must(template.New("chart").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+#chart { border: 1px solid #ccc; }
+#series { min-width: 20em; }
+.axis { stroke: #888; }
+.label { font-size: 11px; fill: #444; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div>
+<svg id="chart" width="900" height="400"></svg>
+</div>
+<p><label for="series">Series (ctrl/cmd click to select several)</label></p>
+<select id="series" multiple size="12"></select>
+<script>
+var data = {{.Series}};
+var colors = ["#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd", "#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf"];
+var svgNS = "http://www.w3.org/2000/svg";
+var select = document.getElementById("series");
+data.forEach(function(series, i) {
+	var option = document.createElement("option");
+	option.value = i;
+	option.text = series.name;
+	option.selected = i === 0;
+	select.appendChild(option);
+});
+function el(name, attrs, parent) {
+	var e = document.createElementNS(svgNS, name);
+	for (var k in attrs) { e.setAttribute(k, attrs[k]); }
+	parent.appendChild(e);
+	return e;
+}
+function draw() {
+	var svg = document.getElementById("chart");
+	while (svg.firstChild) { svg.removeChild(svg.firstChild); }
+	var w = 900, h = 400, pad = 50;
+	var selected = Array.prototype.filter.call(select.options, function(o) { return o.selected; }).map(function(o) { return data[o.value]; });
+	var minT = Infinity, maxT = -Infinity;
+	selected.forEach(function(s) { s.points.forEach(function(p) { minT = Math.min(minT, p[0]); maxT = Math.max(maxT, p[0]); }); });
+	if (!isFinite(minT)) { return; }
+	if (maxT === minT) { maxT = minT + 1; }
+	var x = function(t) { return pad + (t - minT) / (maxT - minT) * (w - 2 * pad); };
+	var y = function(c) { return h - pad - c / 100 * (h - 2 * pad); };
+	el("line", {x1: pad, y1: y(0), x2: w - pad, y2: y(0), "class": "axis"}, svg);
+	el("line", {x1: pad, y1: y(0), x2: pad, y2: y(100), "class": "axis"}, svg);
+	[0, 25, 50, 75, 100].forEach(function(c) {
+		el("text", {x: 5, y: y(c) + 4, "class": "label"}, svg).textContent = c + "%";
+	});
+	el("text", {x: pad, y: h - 20, "class": "label"}, svg).textContent = new Date(minT).toISOString().slice(0, 10);
+	el("text", {x: w - pad - 60, y: h - 20, "class": "label"}, svg).textContent = new Date(maxT).toISOString().slice(0, 10);
+	selected.forEach(function(s, i) {
+		var color = colors[i % colors.length];
+		var points = s.points.map(function(p) { return x(p[0]) + "," + y(p[1]); }).join(" ");
+		el("polyline", {points: points, fill: "none", stroke: color, "stroke-width": 2}, svg);
+		s.points.forEach(function(p) {
+			var dot = el("circle", {cx: x(p[0]), cy: y(p[1]), r: 3, fill: color}, svg);
+			el("title", {}, dot).textContent = s.name + " " + new Date(p[0]).toISOString() + ": " + p[1].toFixed(1) + "%";
+		});
+		el("text", {x: w - pad + 5, y: pad + i * 14, fill: color, "class": "label"}, svg).textContent = s.name;
+	});
+}
+select.addEventListener("change", draw);
+draw();
+</script>
+</body>
+</html>
+`))
+
+func writeHistoryChart(w io.Writer, title string, entries []historyEntry) error {
+	return chartTemplate.Execute(w, struct {
+		Title  string
+		Series []chartSeries
+	}{
+		Title:  title,
+		Series: historySeries(entries),
+	})
+}
+
+// runHistoryChart renders the history store as an interactive HTML chart of total and per package coverage
+func runHistoryChart(args []string) error {
+	fs := flag.NewFlagSet("history chart", flag.ContinueOnError)
+	history := fs.String("history", "gocoverdir-history.jsonl", "History store written by -history")
+	output := fs.String("o", "trend.html", "Where to write the chart")
+	title := fs.String("title", "Coverage trend", "Title of the chart page")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	entries, err := readHistory(*history)
+	if err != nil {
+		return err
+	}
+	return writeFileWith(*output, func(w io.Writer) error {
+		return writeHistoryChart(w, *title, entries)
+	})
+}
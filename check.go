@@ -0,0 +1,57 @@
+package gocoverdir
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// runCheck evaluates coverage gates against an existing profile, without running any tests.  It shares the gates,
+// config and exclude rules of a run, so a separate gate job agrees with the run that wrote the profile
+func runCheck(args []string) error {
+	m := &gocoverdir{log: log.New(os.Stderr, "", 0)}
+	fs := flag.NewFlagSet("check", flag.ContinueOnError)
+	profile := fs.String("profile", "coverage.out", "Cover profile to check")
+	policyFile := fs.String("policy", "", "File of policies, one CEL expression per line over report and pkg, that fail the check when true.  For example: pkg.path.startsWith(\"internal/payments\") && pkg.coverage < 90")
+	fs.StringVar(&m.args.vcs, "vcs", "auto", "Version control system -diffbase compares against: git, hg, or auto to detect it")
+	m.gateFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	m.flags = fs
+	if err := verifyMetric(m.args.metric); err != nil {
+		return err
+	}
+	if err := m.applyConfig(); err != nil {
+		return err
+	}
+	var err error
+	if m.repo, err = selectVCS(m.args.vcs); err != nil {
+		return err
+	}
+	profiles, err := readProfiles(*profile)
+	if err != nil {
+		return err
+	}
+	if len(m.config.Exclude) > 0 {
+		m.excludeMatching(profiles)
+	}
+	fmt.Printf("coverage: %.1f%% of %s\n", calculateCoverage(profiles, m.args.metric), m.args.metric)
+	violations, err := m.evaluateViolations(profiles)
+	if err != nil {
+		return err
+	}
+	if *policyFile != "" {
+		policies, err := loadPolicies(*policyFile)
+		if err != nil {
+			return err
+		}
+		fromPolicies, err := policyViolations(policies, newReport(profiles, m.args.metric, nil, nil, nil, m.config.Internal), modulePath(moduleRoot()))
+		if err != nil {
+			return err
+		}
+		violations = append(violations, fromPolicies...)
+	}
+	return violationsError(violations)
+}
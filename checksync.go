@@ -0,0 +1,68 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+// blockFits is true if every block of p starts and ends inside lines, which are a source file split on newlines.
+// Columns are 1 based bytes, and a block may end just past the last byte of a line
+func blockFits(p *cover.Profile, lines []string) (cover.ProfileBlock, bool) {
+	inside := func(line int, col int) bool {
+		return line >= 1 && line <= len(lines) && col >= 1 && col <= len(lines[line-1])+1
+	}
+	for _, b := range p.Blocks {
+		if !inside(b.StartLine, b.StartCol) || !inside(b.EndLine, b.EndCol) {
+			return b, false
+		}
+	}
+	return cover.ProfileBlock{}, true
+}
+
+// syncProblems lists the profiles that no longer describe the source on disk: the file is gone, was changed after
+// since, or has blocks outside of it.  Files that cannot be found in pkgDirs are not checked
+func syncProblems(profiles []*cover.Profile, pkgDirs map[string]string, since time.Time) []string {
+	var ret []string
+	for _, p := range profiles {
+		dir, exists := pkgDirs[path.Dir(p.FileName)]
+		if !exists {
+			continue
+		}
+		filename := filepath.Join(dir, path.Base(p.FileName))
+		stat, err := os.Stat(filename)
+		if err != nil {
+			ret = append(ret, fmt.Sprintf("%s: %s", p.FileName, err))
+			continue
+		}
+		if !since.IsZero() && stat.ModTime().After(since) {
+			ret = append(ret, fmt.Sprintf("%s: changed at %s, after the tests started", p.FileName, stat.ModTime().Format(time.RFC3339)))
+			continue
+		}
+		contents, err := ioutil.ReadFile(filename)
+		if err != nil {
+			ret = append(ret, fmt.Sprintf("%s: %s", p.FileName, err))
+			continue
+		}
+		if b, fits := blockFits(p, strings.Split(string(contents), "\n")); !fits {
+			ret = append(ret, fmt.Sprintf("%s: block %d.%d,%d.%d is outside the current source", p.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol))
+		}
+	}
+	return ret
+}
+
+// checkSync fails if the merged profile does not match the current source, which happens when stale profiles are
+// merged or files are edited during a run
+func (m *gocoverdir) checkSync(profiles []*cover.Profile) error {
+	problems := syncProblems(profiles, resolvePackageDirs(profiles), m.startTime)
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the cover profile does not match the current source, so its coverage cannot be trusted:\n%s", strings.Join(problems, "\n"))
+}
@@ -0,0 +1,34 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestSyncProblems(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestSyncProblems")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {\n\treturn\n}\n"), 0644))
+	pkgDirs := map[string]string{"example.com/a": dir}
+	current := &cover.Profile{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{StartLine: 3, StartCol: 10, EndLine: 5, EndCol: 2, NumStmt: 1}}}
+	stale := &cover.Profile{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{StartLine: 3, StartCol: 10, EndLine: 9, EndCol: 2, NumStmt: 1}}}
+	gone := &cover.Profile{FileName: "example.com/a/gone.go"}
+	unknown := &cover.Profile{FileName: "example.com/b/b.go"}
+	if problems := syncProblems([]*cover.Profile{current, unknown}, pkgDirs, time.Time{}); len(problems) != 0 {
+		t.Errorf("Unexpected problems %v", problems)
+	}
+	problems := syncProblems([]*cover.Profile{stale, gone}, pkgDirs, time.Time{})
+	if len(problems) != 2 || !strings.Contains(problems[0], "3.10,9.2 is outside") || !strings.Contains(problems[1], "gone.go") {
+		t.Errorf("Unexpected problems %v", problems)
+	}
+	if problems := syncProblems([]*cover.Profile{current}, pkgDirs, time.Now().Add(-time.Hour)); len(problems) != 1 {
+		t.Errorf("Expected a file changed during the run to be a problem, saw %v", problems)
+	}
+}
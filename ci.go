@@ -0,0 +1,206 @@
+package gocoverdir
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type ciEnvironment struct {
+	Name     string `json:"name"`
+	Commit   string `json:"commit,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+	BuildURL string `json:"build_url,omitempty"`
+}
+
+type ciProvider struct {
+	name     string
+	detect   func(getenv func(string) string) bool
+	commit   []string
+	branch   []string
+	buildURL func(getenv func(string) string) string
+}
+
+func envEquals(key string, value string) func(getenv func(string) string) bool {
+	return func(getenv func(string) string) bool {
+		return strings.EqualFold(getenv(key), value)
+	}
+}
+
+func envSet(key string) func(getenv func(string) string) bool {
+	return func(getenv func(string) string) bool {
+		return getenv(key) != ""
+	}
+}
+
+func envValue(key string) func(getenv func(string) string) string {
+	return func(getenv func(string) string) string {
+		return getenv(key)
+	}
+}
+
+// Order matters: woodpecker also sets drone's variables for compatibility
+var ciProviders = []ciProvider{
+	{
+		name:   "github",
+		detect: envEquals("GITHUB_ACTIONS", "true"),
+		commit: []string{"GITHUB_SHA"},
+		branch: []string{"GITHUB_HEAD_REF", "GITHUB_REF_NAME"},
+		buildURL: func(getenv func(string) string) string {
+			if getenv("GITHUB_RUN_ID") == "" {
+				return ""
+			}
+			return fmt.Sprintf("%s/%s/actions/runs/%s", getenv("GITHUB_SERVER_URL"), getenv("GITHUB_REPOSITORY"), getenv("GITHUB_RUN_ID"))
+		},
+	},
+	{
+		name:     "gitlab",
+		detect:   envSet("GITLAB_CI"),
+		commit:   []string{"CI_COMMIT_SHA"},
+		branch:   []string{"CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_REF_NAME"},
+		buildURL: envValue("CI_JOB_URL"),
+	},
+	{
+		name:     "woodpecker",
+		detect:   envEquals("CI", "woodpecker"),
+		commit:   []string{"CI_COMMIT_SHA"},
+		branch:   []string{"CI_COMMIT_SOURCE_BRANCH", "CI_COMMIT_BRANCH"},
+		buildURL: envValue("CI_PIPELINE_URL"),
+	},
+	{
+		name:     "drone",
+		detect:   envEquals("DRONE", "true"),
+		commit:   []string{"DRONE_COMMIT_SHA"},
+		branch:   []string{"DRONE_SOURCE_BRANCH", "DRONE_BRANCH"},
+		buildURL: envValue("DRONE_BUILD_LINK"),
+	},
+	{
+		name:     "circle",
+		detect:   envEquals("CIRCLECI", "true"),
+		commit:   []string{"CIRCLE_SHA1"},
+		branch:   []string{"CIRCLE_BRANCH"},
+		buildURL: envValue("CIRCLE_BUILD_URL"),
+	},
+	{
+		name:     "buildkite",
+		detect:   envEquals("BUILDKITE", "true"),
+		commit:   []string{"BUILDKITE_COMMIT"},
+		branch:   []string{"BUILDKITE_BRANCH"},
+		buildURL: envValue("BUILDKITE_BUILD_URL"),
+	},
+	{
+		name:   "azure",
+		detect: envEquals("TF_BUILD", "true"),
+		commit: []string{"BUILD_SOURCEVERSION"},
+		branch: []string{"SYSTEM_PULLREQUEST_SOURCEBRANCH", "BUILD_SOURCEBRANCHNAME"},
+		buildURL: func(getenv func(string) string) string {
+			if getenv("BUILD_BUILDID") == "" {
+				return ""
+			}
+			return fmt.Sprintf("%s%s/_build/results?buildId=%s", getenv("SYSTEM_COLLECTIONURI"), getenv("SYSTEM_TEAMPROJECT"), getenv("BUILD_BUILDID"))
+		},
+	},
+	{
+		name:     "jenkins",
+		detect:   envSet("JENKINS_URL"),
+		commit:   []string{"GIT_COMMIT"},
+		branch:   []string{"CHANGE_BRANCH", "BRANCH_NAME", "GIT_BRANCH"},
+		buildURL: envValue("BUILD_URL"),
+	},
+	{
+		name:     "travis",
+		detect:   envEquals("TRAVIS", "true"),
+		commit:   []string{"TRAVIS_COMMIT"},
+		branch:   []string{"TRAVIS_PULL_REQUEST_BRANCH", "TRAVIS_BRANCH"},
+		buildURL: envValue("TRAVIS_BUILD_WEB_URL"),
+	},
+}
+
+func firstEnv(getenv func(string) string, keys []string) string {
+	for _, key := range keys {
+		if val := getenv(key); val != "" {
+			return val
+		}
+	}
+	return ""
+}
+
+// detectCI finds the CI system named by mode, or the first one whose environment is present if mode is "auto"
+func detectCI(mode string, getenv func(string) string) (*ciEnvironment, error) {
+	for _, provider := range ciProviders {
+		if (mode == "auto" && provider.detect(getenv)) || mode == provider.name {
+			return &ciEnvironment{
+				Name:     provider.name,
+				Commit:   firstEnv(getenv, provider.commit),
+				Branch:   firstEnv(getenv, provider.branch),
+				BuildURL: provider.buildURL(getenv),
+			}, nil
+		}
+	}
+	if mode == "auto" {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown ci %s", mode)
+}
+
+func (m *gocoverdir) isFlagSet(name string) bool {
+	if m.flags == nil {
+		return false
+	}
+	found := false
+	m.flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			found = true
+		}
+	})
+	return found
+}
+
+func (m *gocoverdir) setDefaultFlag(name string, value string) {
+	if m.flags == nil || m.isFlagSet(name) {
+		return
+	}
+	if err := m.flags.Set(name, value); err != nil {
+		m.log.Printf("Unable to default %s to %s: %s", name, value, err)
+	}
+}
+
+// setupCI detects the CI system and enables the output formats it understands, unless they were set explicitly
+func (m *gocoverdir) setupCI() error {
+	if m.args.ci == "" {
+		return nil
+	}
+	var err error
+	m.ci, err = detectCI(m.args.ci, os.Getenv)
+	if err != nil || m.ci == nil {
+		return err
+	}
+	m.log.Printf("Detected ci %s commit=%s branch=%s url=%s", m.ci.Name, m.ci.Commit, m.ci.Branch, m.ci.BuildURL)
+	m.setDefaultFlag("printcoverage", "true")
+	switch m.ci.Name {
+	case "azure":
+		m.setDefaultFlag("azure", "true")
+	case "jenkins":
+		m.setDefaultFlag("jenkins", "coverage-summary.json")
+	case "gitlab":
+		m.setDefaultFlag("cobertura", "coverage.cobertura.xml")
+	case "github":
+		if summary := os.Getenv("GITHUB_STEP_SUMMARY"); summary != "" {
+			m.setDefaultFlag("markdown", summary)
+		}
+	}
+	return nil
+}
+
+var githubEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+
+// annotateFailure surfaces a failed run in whatever format the CI system renders
+func (m *gocoverdir) annotateFailure(err error) {
+	if m.args.azure {
+		m.publishAzureFailure(err)
+	}
+	if m.ci != nil && m.ci.Name == "github" {
+		fmt.Printf("::error title=gocoverdir::%s\n", githubEscaper.Replace(err.Error()))
+	}
+}
@@ -0,0 +1,27 @@
+package gocoverdir
+
+import "testing"
+
+func TestDetectCI(t *testing.T) {
+	env := map[string]string{
+		"CI":               "woodpecker",
+		"DRONE":            "true",
+		"CI_COMMIT_SHA":    "abc123",
+		"CI_COMMIT_BRANCH": "master",
+	}
+	ci, err := detectCI("auto", func(key string) string { return env[key] })
+	noError(t, err)
+	if ci == nil || ci.Name != "woodpecker" || ci.Commit != "abc123" || ci.Branch != "master" {
+		t.Errorf("Unexpected ci %+v", ci)
+	}
+
+	ci, err = detectCI("auto", func(string) string { return "" })
+	noError(t, err)
+	if ci != nil {
+		t.Errorf("Expected no ci, saw %+v", ci)
+	}
+
+	if _, err := detectCI("unknown", func(string) string { return "" }); err == nil {
+		t.Errorf("Expected an error for an unknown ci")
+	}
+}
@@ -0,0 +1,123 @@
+package gocoverdir
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tempDirPrefix starts the name of every temp dir and file gocoverdir makes: its store, and the workdirs of
+// integration, prune-advise and compare-branches
+const tempDirPrefix = "gocoverdir"
+
+// newestModTime is the latest modification time of root or anything under it, so a temp dir a long run is still
+// writing profiles into is never stale
+func newestModTime(root string) (time.Time, error) {
+	var ret time.Time
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(ret) {
+			ret = info.ModTime()
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// staleTempDirs lists the gocoverdir temp dirs and files in tmpdir untouched since before cutoff, which crashed or
+// killed runs left behind
+func staleTempDirs(tmpdir string, cutoff time.Time) ([]string, error) {
+	entries, err := ioutil.ReadDir(tmpdir)
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), tempDirPrefix) {
+			continue
+		}
+		dir := filepath.Join(tmpdir, entry.Name())
+		newest, err := newestModTime(dir)
+		if err != nil {
+			// Another run, or another user's, that cannot be read is not ours to remove
+			continue
+		}
+		if newest.Before(cutoff) {
+			ret = append(ret, dir)
+		}
+	}
+	return ret, nil
+}
+
+// isCachedProfile is true for the files a cache of profiles holds: profiles, compressed or not, and their sidecars
+func isCachedProfile(name string) bool {
+	for _, suffix := range []string{".out", ".cover", ".out.gz", ".cover.gz", ".meta.json"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// staleProfiles lists the profiles under dir modified before cutoff
+func staleProfiles(dir string, cutoff time.Time) ([]string, error) {
+	var ret []string
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && isCachedProfile(info.Name()) && info.ModTime().Before(cutoff) {
+			ret = append(ret, p)
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// runClean removes what gocoverdir leaves behind: temp dirs of crashed runs and old profiles in caches
+func runClean(args []string) error {
+	fs := flag.NewFlagSet("clean", flag.ContinueOnError)
+	tmpdir := fs.String("tmpdir", os.TempDir(), "Directory gocoverdir makes its temp dirs in")
+	age := fs.Duration("age", time.Hour*24, "Remove gocoverdir temp dirs untouched for this long.  Runs still going write to theirs, so keep it above the longest run")
+	profiles := fs.String("profiles", "", "Comma separated directories of cached profiles, like a CI cache, to evict old profiles from")
+	days := fs.Int("days", 30, "Remove cached profiles older than this many days")
+	dryRun := fs.Bool("n", false, "If true, print what would be removed without removing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: gocoverdir clean [-n] [-tmpdir dir] [-age 24h] [-profiles dir,dir] [-days 30]")
+	}
+	now := time.Now()
+	remove, err := staleTempDirs(*tmpdir, now.Add(-*age))
+	if err != nil {
+		return err
+	}
+	for _, dir := range strings.Split(*profiles, ",") {
+		if dir = strings.TrimSpace(dir); dir == "" {
+			continue
+		}
+		stale, err := staleProfiles(dir, now.AddDate(0, 0, -*days))
+		if err != nil {
+			return err
+		}
+		remove = append(remove, stale...)
+	}
+	for _, p := range remove {
+		if *dryRun {
+			fmt.Printf("Would remove %s\n", p)
+			continue
+		}
+		if err := os.RemoveAll(p); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", p)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStaleTempDirs(t *testing.T) {
+	tmpdir, err := ioutil.TempDir("", "TestStaleTempDirs")
+	noError(t, err)
+	defer os.RemoveAll(tmpdir)
+	old := time.Now().Add(-time.Hour * 48)
+	for _, name := range []string{"gocoverdir123", "gocoverdir-prune456", "gocoverdir789", "other"} {
+		noError(t, os.Mkdir(filepath.Join(tmpdir, name), 0755))
+	}
+	// A run still writing profiles into an old dir keeps it
+	noError(t, ioutil.WriteFile(filepath.Join(tmpdir, "gocoverdir789", "a.cover"), []byte("mode: set\n"), 0644))
+	for _, name := range []string{"gocoverdir123", "gocoverdir-prune456", "gocoverdir789", "other"} {
+		noError(t, os.Chtimes(filepath.Join(tmpdir, name), old, old))
+	}
+	noError(t, ioutil.WriteFile(filepath.Join(tmpdir, "gocoverdir-integration1"), nil, 0644))
+	noError(t, os.Chtimes(filepath.Join(tmpdir, "gocoverdir-integration1"), old, old))
+	stale, err := staleTempDirs(tmpdir, time.Now().Add(-time.Hour*24))
+	noError(t, err)
+	if !reflect.DeepEqual(stale, []string{filepath.Join(tmpdir, "gocoverdir-integration1"), filepath.Join(tmpdir, "gocoverdir-prune456"), filepath.Join(tmpdir, "gocoverdir123")}) {
+		t.Errorf("Unexpected stale dirs %v", stale)
+	}
+}
+
+func TestStaleProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestStaleProfiles")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	old := time.Now().AddDate(0, 0, -40)
+	for _, name := range []string{"old.out", "old.out.meta.json", "new.out", "notes.txt"} {
+		noError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("mode: set\n"), 0644))
+	}
+	for _, name := range []string{"old.out", "old.out.meta.json", "notes.txt"} {
+		noError(t, os.Chtimes(filepath.Join(dir, name), old, old))
+	}
+	stale, err := staleProfiles(dir, time.Now().AddDate(0, 0, -30))
+	noError(t, err)
+	if !reflect.DeepEqual(stale, []string{filepath.Join(dir, "old.out"), filepath.Join(dir, "old.out.meta.json")}) {
+		t.Errorf("Unexpected stale profiles %v", stale)
+	}
+}
@@ -0,0 +1,86 @@
+package gocoverdir
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// removeStore deletes the directory of per package profiles, or with -keepwork says where it is
+func (m *gocoverdir) removeStore() error {
+	if m.storeDir == "" {
+		return nil
+	}
+	if m.args.keepwork {
+		fmt.Fprintf(os.Stderr, "Keeping work directory %s\n", m.storeDir)
+		return nil
+	}
+	return os.RemoveAll(m.storeDir)
+}
+
+// partialProfile is where a run that is interrupted or panics leaves the coverage it collected
+func (m *gocoverdir) partialProfile() string {
+	return m.args.coverprofile + ".partial"
+}
+
+// flushPartial merges the profiles of the packages tested so far into partialProfile, so an interrupted run is not a
+// total loss.  It never touches -coverprofile, which gates and later stages trust to be complete
+func (m *gocoverdir) flushPartial() {
+	if m.storeDir == "" || m.stdoutProfile {
+		return
+	}
+	files, err := ioutil.ReadDir(m.storeDir)
+	if err != nil {
+		return
+	}
+	var profiles []string
+	for _, file := range files {
+		if !file.IsDir() {
+			profiles = append(profiles, filepath.Join(m.storeDir, file.Name()))
+		}
+	}
+	if len(profiles) == 0 {
+		return
+	}
+	set, err := mergeFiles(profiles, m.args.coerce)
+	if err == nil {
+		err = writeFileWith(m.partialProfile(), func(w io.Writer) error {
+			return writeProfiles(w, set.profiles())
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to write the partial profile %s: %s\n", m.partialProfile(), err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Wrote the %d profiles collected so far to %s\n", len(profiles), m.partialProfile())
+}
+
+// closeOnCancel flushes the partial profile and closes the run as soon as ctx is done, without waiting for its package
+// tests to be killed.  The returned func stops watching ctx
+func (m *gocoverdir) closeOnCancel(ctx context.Context) func() bool {
+	return context.AfterFunc(ctx, func() {
+		fmt.Fprintf(os.Stderr, "gocoverdir: %s, cleaning up\n", context.Cause(ctx))
+		m.flushPartial()
+		if err := m.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to clean up: %s\n", err)
+		}
+	})
+}
+
+// runCommand runs cmd, killing it if the run is cancelled
+func (m *gocoverdir) runCommand(cmd *exec.Cmd) error {
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	if m.ctx != nil {
+		stop := context.AfterFunc(m.ctx, func() {
+			cmd.Process.Kill()
+		})
+		defer stop()
+	}
+	return cmd.Wait()
+}
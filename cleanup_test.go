@@ -0,0 +1,71 @@
+package gocoverdir
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCloseBeforeSetup(t *testing.T) {
+	m := &gocoverdir{}
+	noError(t, m.Close())
+	noError(t, m.Close())
+}
+
+func TestCloseKeepwork(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestCloseKeepwork")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	m := &gocoverdir{storeDir: dir}
+	m.args.keepwork = true
+	noError(t, m.Close())
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("Expected -keepwork to keep the store: %s", err)
+	}
+	m = &gocoverdir{storeDir: dir}
+	noError(t, m.Close())
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("Expected Close to remove the store, saw %v", err)
+	}
+}
+
+func TestFlushPartial(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestFlushPartial")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	m := &gocoverdir{storeDir: filepath.Join(dir, "store")}
+	m.args.coverprofile = filepath.Join(dir, "coverage.out")
+	noError(t, os.Mkdir(m.storeDir, 0755))
+	m.flushPartial()
+	if _, err := os.Stat(m.partialProfile()); !os.IsNotExist(err) {
+		t.Errorf("Expected no partial profile without any tested packages")
+	}
+	noError(t, ioutil.WriteFile(filepath.Join(m.storeDir, "a.cover"), []byte("mode: set\nexample.com/a/a.go:1.1,2.1 1 1\n"), 0644))
+	noError(t, ioutil.WriteFile(filepath.Join(m.storeDir, "b.cover"), []byte("mode: set\nexample.com/b/b.go:1.1,2.1 1 0\n"), 0644))
+	m.flushPartial()
+	profiles, err := readProfiles(m.partialProfile())
+	noError(t, err)
+	if len(profiles) != 2 || calculateCoverage(profiles, "statements") != 50 {
+		t.Errorf("Unexpected partial profiles %v", profiles)
+	}
+	if _, err := os.Stat(m.args.coverprofile); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial flush to leave -coverprofile alone")
+	}
+}
+
+func TestRunCommandCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &gocoverdir{ctx: ctx}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	if err := m.runCommand(exec.Command("sleep", "10")); err == nil {
+		t.Errorf("Expected the cancelled command to fail")
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Errorf("Expected the command to be killed on cancel")
+	}
+}
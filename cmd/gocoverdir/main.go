@@ -0,0 +1,22 @@
+// Command gocoverdir runs go test with coverage on every package of a tree and merges their profiles.  Install it
+// with
+//
+//	go install github.com/cep21/gocoverdir/cmd/gocoverdir@latest
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/cep21/gocoverdir"
+)
+
+func main() {
+	// The command owns its process, so it is the one to handle signals and change directory
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := gocoverdir.RunWith(os.Args[1:], gocoverdir.Options{Context: ctx, Chdir: os.Chdir})
+	stop()
+	os.Exit(code)
+}
@@ -0,0 +1,114 @@
+package gocoverdir
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+type coberturaCoverage struct {
+	XMLName         xml.Name           `xml:"coverage"`
+	LineRate        float64            `xml:"line-rate,attr"`
+	BranchRate      float64            `xml:"branch-rate,attr"`
+	LinesCovered    int                `xml:"lines-covered,attr"`
+	LinesValid      int                `xml:"lines-valid,attr"`
+	BranchesCovered int                `xml:"branches-covered,attr"`
+	BranchesValid   int                `xml:"branches-valid,attr"`
+	Complexity      float64            `xml:"complexity,attr"`
+	Version         string             `xml:"version,attr"`
+	Timestamp       int64              `xml:"timestamp,attr"`
+	Sources         []string           `xml:"sources>source"`
+	Packages        []coberturaPackage `xml:"packages>package"`
+}
+
+type coberturaPackage struct {
+	Name       string           `xml:"name,attr"`
+	LineRate   float64          `xml:"line-rate,attr"`
+	BranchRate float64          `xml:"branch-rate,attr"`
+	Complexity float64          `xml:"complexity,attr"`
+	Classes    []coberturaClass `xml:"classes>class"`
+}
+
+type coberturaClass struct {
+	Name       string          `xml:"name,attr"`
+	Filename   string          `xml:"filename,attr"`
+	LineRate   float64         `xml:"line-rate,attr"`
+	BranchRate float64         `xml:"branch-rate,attr"`
+	Complexity float64         `xml:"complexity,attr"`
+	Methods    struct{}        `xml:"methods"`
+	Lines      []coberturaLine `xml:"lines>line"`
+}
+
+type coberturaLine struct {
+	Number int `xml:"number,attr"`
+	Hits   int `xml:"hits,attr"`
+}
+
+func lineRate(covered int, valid int) float64 {
+	if valid == 0 {
+		return 0.0
+	}
+	return float64(covered) / float64(valid)
+}
+
+func writeCobertura(w io.Writer, profiles []*cover.Profile, loc *fileLocator) error {
+	report := coberturaCoverage{
+		Version:   toolVersion(),
+		Timestamp: time.Now().Unix(),
+		Sources:   []string{loc.source()},
+	}
+	packages := make(map[string]*coberturaPackage)
+	packageLines := make(map[string][2]int)
+	for _, p := range profiles {
+		pkgName := path.Dir(p.FileName)
+		pkg, exists := packages[pkgName]
+		if !exists {
+			pkg = &coberturaPackage{Name: pkgName}
+			packages[pkgName] = pkg
+		}
+		class := coberturaClass{
+			Name:     path.Base(p.FileName),
+			Filename: loc.name(p),
+		}
+		covered := 0
+		for _, line := range lineHits(p) {
+			class.Lines = append(class.Lines, coberturaLine{Number: line.number, Hits: line.hits})
+			if line.hits > 0 {
+				covered++
+			}
+		}
+		class.LineRate = lineRate(covered, len(class.Lines))
+		pkg.Classes = append(pkg.Classes, class)
+		counts := packageLines[pkgName]
+		packageLines[pkgName] = [2]int{counts[0] + covered, counts[1] + len(class.Lines)}
+		report.LinesCovered += covered
+		report.LinesValid += len(class.Lines)
+	}
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		pkg := packages[name]
+		pkg.LineRate = lineRate(packageLines[name][0], packageLines[name][1])
+		report.Packages = append(report.Packages, *pkg)
+	}
+	report.LineRate = lineRate(report.LinesCovered, report.LinesValid)
+
+	if _, err := fmt.Fprintf(w, "%s<!DOCTYPE coverage SYSTEM \"http://cobertura.sourceforge.net/xml/coverage-04.dtd\">\n", xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,93 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+type codeownersRule struct {
+	pattern string
+	owners  []string
+}
+
+// codeowners maps files to their owners, using the last matching rule like GitHub does
+type codeowners struct {
+	rules []codeownersRule
+}
+
+var codeownersLocations = []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
+// loadCodeowners reads the first CODEOWNERS file found in the repository.  No file means nobody owns anything
+func loadCodeowners() (*codeowners, error) {
+	for _, location := range codeownersLocations {
+		f, err := os.Open(location)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return parseCodeowners(f)
+	}
+	return &codeowners{}, nil
+}
+
+func parseCodeowners(r io.Reader) (*codeowners, error) {
+	ret := &codeowners{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		fields := strings.Fields(line)
+		// GitLab sections look like [Section]
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "[") {
+			continue
+		}
+		ret.rules = append(ret.rules, codeownersRule{pattern: fields[0], owners: fields[1:]})
+	}
+	return ret, scanner.Err()
+}
+
+// matchCodeownersPattern matches the gitignore style patterns CODEOWNERS uses against a slash separated path
+func matchCodeownersPattern(pattern string, file string) bool {
+	anchored := strings.HasPrefix(pattern, "/") || strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/**")
+	if pattern == "*" || pattern == "**" || pattern == "" {
+		return !dirOnly || strings.Contains(file, "/")
+	}
+	elements := strings.Split(file, "/")
+	patternLen := len(strings.Split(pattern, "/"))
+	for start := 0; start+patternLen <= len(elements); start++ {
+		if anchored && start > 0 {
+			break
+		}
+		candidate := strings.Join(elements[start:start+patternLen], "/")
+		if matched, err := path.Match(pattern, candidate); err != nil || !matched {
+			continue
+		}
+		// A match on a directory owns everything under it.  A dir only pattern must not match the file itself
+		if !dirOnly || start+patternLen < len(elements) {
+			return true
+		}
+	}
+	return false
+}
+
+// owners of file, which is relative to the repository root
+func (c *codeowners) owners(file string) []string {
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if matchCodeownersPattern(c.rules[i].pattern, file) {
+			return c.rules[i].owners
+		}
+	}
+	return nil
+}
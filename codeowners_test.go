@@ -0,0 +1,51 @@
+package gocoverdir
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchCodeownersPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		file    string
+		match   bool
+	}{
+		{"*", "a/b.go", true},
+		{"*.go", "a/b.go", true},
+		{"*.go", "a/b.md", false},
+		{"/a/", "a/b.go", true},
+		{"/a/", "x/a/b.go", false},
+		{"a/", "x/a/b.go", true},
+		{"b.go/", "a/b.go", false},
+		{"a/b", "a/b/c.go", true},
+		{"a/b", "x/a/b/c.go", false},
+		{"/docs/**", "docs/x/y.md", true},
+		{"internal", "pkg/internal/x.go", true},
+	}
+	for _, c := range cases {
+		if got := matchCodeownersPattern(c.pattern, c.file); got != c.match {
+			t.Errorf("matchCodeownersPattern(%q, %q) = %v, expected %v", c.pattern, c.file, got, c.match)
+		}
+	}
+}
+
+func TestCodeownersLastMatchWins(t *testing.T) {
+	owners, err := parseCodeowners(strings.NewReader(`# comment
+*       @org/everyone
+[Section]
+/api/   @org/api @bob # trailing comment
+/api/internal/
+`))
+	noError(t, err)
+	if got := owners.owners("main.go"); !reflect.DeepEqual(got, []string{"@org/everyone"}) {
+		t.Errorf("Unexpected owners %v", got)
+	}
+	if got := owners.owners("api/handler.go"); !reflect.DeepEqual(got, []string{"@org/api", "@bob"}) {
+		t.Errorf("Unexpected owners %v", got)
+	}
+	if got := owners.owners("api/internal/x.go"); len(got) != 0 {
+		t.Errorf("Expected a rule without owners to clear ownership, got %v", got)
+	}
+}
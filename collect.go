@@ -0,0 +1,121 @@
+package gocoverdir
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// defaultMaxUpload is the largest profile a shard can upload unless -maxupload says otherwise
+const defaultMaxUpload = 256 << 20
+
+// collector merges profiles POSTed by distributed test shards
+type collector struct {
+	expectedShards int
+	log            *log.Logger
+	// maxUpload is the largest profile, in bytes, a shard can upload
+	maxUpload int64
+
+	mu       sync.Mutex
+	merged   *profileSet
+	received map[string]struct{}
+	done     chan struct{}
+}
+
+func newCollector(expectedShards int, logger *log.Logger) *collector {
+	return &collector{
+		expectedShards: expectedShards,
+		log:            logger,
+		maxUpload:      defaultMaxUpload,
+		merged:         newProfileSet(),
+		received:       make(map[string]struct{}),
+		done:           make(chan struct{}),
+	}
+}
+
+func (c *collector) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(rw, "POST a cover profile", http.StatusMethodNotAllowed)
+		return
+	}
+	profiles, err := parseProfiles(http.MaxBytesReader(rw, req.Body, c.maxUpload))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(rw, fmt.Sprintf("profile is over the limit of %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	shard := req.URL.Query().Get("shard")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if shard == "" {
+		shard = fmt.Sprintf("anonymous-%d", len(c.received))
+	}
+	if _, exists := c.received[shard]; exists {
+		http.Error(rw, fmt.Sprintf("shard %s already reported", shard), http.StatusConflict)
+		return
+	}
+	if err := c.merged.add(profiles); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.received[shard] = struct{}{}
+	c.log.Printf("Received shard %s from %s (%d/%d)", shard, req.RemoteAddr, len(c.received), c.expectedShards)
+	rw.WriteHeader(http.StatusAccepted)
+	if c.expectedShards > 0 && len(c.received) == c.expectedShards {
+		close(c.done)
+	}
+}
+
+func (c *collector) write(filename string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return writeFileWith(filename, func(w io.Writer) error {
+		return writeProfiles(w, c.merged.profiles())
+	})
+}
+
+// runCollect listens for profile uploads, writing the merged profile once every shard reports in or on timeout
+func runCollect(args []string) error {
+	fs := flag.NewFlagSet("collect", flag.ContinueOnError)
+	listen := fs.String("listen", "localhost:9090", "Address to accept profile uploads on.  Shards POST their profile to /?shard=name.  Anyone who can reach it can upload, so listen on other interfaces only on a trusted network")
+	maxUpload := fs.Int64("maxupload", defaultMaxUpload, "Largest profile, in bytes, a shard can upload")
+	shards := fs.Int("shards", 0, "Number of shards to wait for.  Zero means wait until the timeout")
+	timeout := fs.Duration("timeout", time.Minute*30, "Write whatever was collected after this long")
+	output := fs.String("o", "coverage.out", "Where to write the merged cover profile")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	c := newCollector(*shards, logger)
+	c.maxUpload = *maxUpload
+	server := &http.Server{Addr: *listen, Handler: c}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-serveErr:
+		return err
+	case <-c.done:
+		logger.Printf("All %d shards reported", *shards)
+	case <-time.After(*timeout):
+		c.mu.Lock()
+		logger.Printf("Timed out with %d of %d shards reported", len(c.received), *shards)
+		c.mu.Unlock()
+	}
+	if err := server.Close(); err != nil {
+		return err
+	}
+	logger.Printf("Writing merged profile to %s", *output)
+	return c.write(*output)
+}
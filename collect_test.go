@@ -0,0 +1,48 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCollector(t *testing.T) {
+	c := newCollector(2, log.New(ioutil.Discard, "", 0))
+	post := func(shard string, body string) int {
+		rw := httptest.NewRecorder()
+		c.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/?shard="+shard, strings.NewReader(body)))
+		return rw.Code
+	}
+	if code := post("a", "mode: count\nexample.com/a/a.go:1.1,2.2 1 1\n"); code != http.StatusAccepted {
+		t.Fatalf("Unexpected code %d", code)
+	}
+	if code := post("a", "mode: count\nexample.com/a/a.go:1.1,2.2 1 1\n"); code != http.StatusConflict {
+		t.Errorf("Expected duplicate shard to conflict, saw %d", code)
+	}
+	if code := post("b", "mode: set\nexample.com/a/a.go:1.1,2.2 1 1\n"); code != http.StatusBadRequest {
+		t.Errorf("Expected mode mismatch to fail, saw %d", code)
+	}
+	c.maxUpload = 10
+	if code := post("d", "mode: count\nexample.com/a/a.go:1.1,2.2 1 1\n"); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected an upload over -maxupload to be refused, saw %d", code)
+	}
+	c.maxUpload = defaultMaxUpload
+	if code := post("c", "mode: count\nexample.com/a/a.go:1.1,2.2 1 2\nexample.com/a/a.go:3.1,4.2 1 0\n"); code != http.StatusAccepted {
+		t.Fatalf("Unexpected code %d", code)
+	}
+	select {
+	case <-c.done:
+	default:
+		t.Fatalf("Expected collection to be done")
+	}
+	buf := bytes.Buffer{}
+	noError(t, writeProfiles(&buf, c.merged.profiles()))
+	expected := "mode: count\nexample.com/a/a.go:1.1,2.2 1 3\nexample.com/a/a.go:3.1,4.2 1 0\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected merged profile %q", buf.String())
+	}
+}
@@ -0,0 +1,107 @@
+package gocoverdir
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/tools/cover"
+)
+
+// mergeBlocks merges blocks, in order, into one block spanning them, with the highest of their hit counts
+func mergeBlocks(blocks []cover.ProfileBlock) cover.ProfileBlock {
+	ret := blocks[0]
+	for _, block := range blocks[1:] {
+		ret.EndLine, ret.EndCol = block.EndLine, block.EndCol
+		ret.NumStmt += block.NumStmt
+		if block.Count > ret.Count {
+			ret.Count = block.Count
+		}
+	}
+	return ret
+}
+
+// compactProfile merges each run of blocks with the same coverage status into one block, and drops blocks without
+// statements.  Hit counts become the highest of the run, so percentages stay the same but counts are approximate
+func compactProfile(p *cover.Profile) *cover.Profile {
+	ret := &cover.Profile{FileName: p.FileName, Mode: p.Mode}
+	var run []cover.ProfileBlock
+	for _, block := range p.Blocks {
+		if block.NumStmt == 0 {
+			continue
+		}
+		if len(run) > 0 && (run[0].Count > 0) != (block.Count > 0) {
+			ret.Blocks = append(ret.Blocks, mergeBlocks(run))
+			run = nil
+		}
+		run = append(run, block)
+	}
+	if len(run) > 0 {
+		ret.Blocks = append(ret.Blocks, mergeBlocks(run))
+	}
+	return ret
+}
+
+// aggregateProfile reduces p to at most two blocks, one of its covered statements and one of its uncovered ones.  Only
+// the file's percentage survives: each block spans from the first to the last block of its status
+func aggregateProfile(p *cover.Profile) *cover.Profile {
+	ret := &cover.Profile{FileName: p.FileName, Mode: p.Mode}
+	var covered, uncovered []cover.ProfileBlock
+	for _, block := range compactProfile(p).Blocks {
+		if block.Count > 0 {
+			covered = append(covered, block)
+		} else {
+			uncovered = append(uncovered, block)
+		}
+	}
+	for _, blocks := range [][]cover.ProfileBlock{covered, uncovered} {
+		if len(blocks) > 0 {
+			ret.Blocks = append(ret.Blocks, mergeBlocks(blocks))
+		}
+	}
+	return ret
+}
+
+func countBlocks(profiles []*cover.Profile) int {
+	ret := 0
+	for _, p := range profiles {
+		ret += len(p.Blocks)
+	}
+	return ret
+}
+
+// runCompact shrinks a profile for storage, keeping its coverage percentages
+func runCompact(args []string) error {
+	fs := flag.NewFlagSet("compact", flag.ContinueOnError)
+	output := fs.String("o", stdio, "File to write the compacted profile to.  - means stdout")
+	perFile := fs.Bool("perfile", false, "If true, keep only each file's covered and uncovered statement counts, losing line information")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return fmt.Errorf("usage: gocoverdir compact [-perfile] [-o small.out] profile.out")
+	}
+	profiles, err := readProfiles(files[0])
+	if err != nil {
+		return err
+	}
+	// Merging first means blocks repeated across packages' profiles are counted once
+	set := newProfileSet()
+	if err := set.add(profiles); err != nil {
+		return err
+	}
+	compacted := make([]*cover.Profile, 0, len(profiles))
+	for _, p := range set.profiles() {
+		if *perFile {
+			compacted = append(compacted, aggregateProfile(p))
+		} else {
+			compacted = append(compacted, compactProfile(p))
+		}
+	}
+	fmt.Fprintf(os.Stderr, "Compacted %d blocks to %d\n", countBlocks(profiles), countBlocks(compacted))
+	return writeFileWith(*output, func(w io.Writer) error {
+		return writeProfiles(w, compacted)
+	})
+}
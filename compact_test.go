@@ -0,0 +1,42 @@
+package gocoverdir
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestCompactProfile(t *testing.T) {
+	p := &cover.Profile{FileName: "example.com/a/a.go", Mode: "count", Blocks: []cover.ProfileBlock{
+		{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 5, NumStmt: 1, Count: 3},
+		{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 9, NumStmt: 2, Count: 7},
+		{StartLine: 5, StartCol: 1, EndLine: 5, EndCol: 2, NumStmt: 0, Count: 0},
+		{StartLine: 6, StartCol: 1, EndLine: 7, EndCol: 3, NumStmt: 2, Count: 0},
+		{StartLine: 8, StartCol: 1, EndLine: 9, EndCol: 4, NumStmt: 1, Count: 1},
+		{StartLine: 10, StartCol: 1, EndLine: 11, EndCol: 4, NumStmt: 4, Count: 0},
+	}}
+	compacted := compactProfile(p)
+	expected := []cover.ProfileBlock{
+		{StartLine: 1, StartCol: 1, EndLine: 4, EndCol: 9, NumStmt: 3, Count: 7},
+		{StartLine: 6, StartCol: 1, EndLine: 7, EndCol: 3, NumStmt: 2, Count: 0},
+		{StartLine: 8, StartCol: 1, EndLine: 9, EndCol: 4, NumStmt: 1, Count: 1},
+		{StartLine: 10, StartCol: 1, EndLine: 11, EndCol: 4, NumStmt: 4, Count: 0},
+	}
+	if !reflect.DeepEqual(compacted.Blocks, expected) {
+		t.Errorf("Unexpected blocks %+v", compacted.Blocks)
+	}
+	aggregated := aggregateProfile(p)
+	expected = []cover.ProfileBlock{
+		{StartLine: 1, StartCol: 1, EndLine: 9, EndCol: 4, NumStmt: 4, Count: 7},
+		{StartLine: 6, StartCol: 1, EndLine: 11, EndCol: 4, NumStmt: 6, Count: 0},
+	}
+	if !reflect.DeepEqual(aggregated.Blocks, expected) {
+		t.Errorf("Unexpected aggregated blocks %+v", aggregated.Blocks)
+	}
+	for _, shrunk := range []*cover.Profile{compacted, aggregated} {
+		if coverage := calculateCoverage([]*cover.Profile{shrunk}, "statements"); coverage != calculateCoverage([]*cover.Profile{p}, "statements") {
+			t.Errorf("Coverage changed to %.1f", coverage)
+		}
+	}
+}
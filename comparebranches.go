@@ -0,0 +1,163 @@
+package gocoverdir
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// splitPassthrough splits args at "--" into compare-branches' own arguments and the flags given to each gocoverdir run
+func splitPassthrough(args []string) ([]string, []string) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], args[i+1:]
+		}
+	}
+	return args, nil
+}
+
+// affectedDirs is the sorted directories of the changed .go files
+func affectedDirs(changed []string) []string {
+	seen := make(map[string]struct{})
+	ret := make([]string, 0, len(changed))
+	for _, file := range changed {
+		if !strings.HasSuffix(file, ".go") {
+			continue
+		}
+		dir := path.Dir(filepath.ToSlash(file))
+		if _, exists := seen[dir]; exists {
+			continue
+		}
+		seen[dir] = struct{}{}
+		ret = append(ret, dir)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// repoPrefix is the current directory relative to root, so each checkout can be tested from the same place
+func repoPrefix(root string) (string, error) {
+	if root == "" {
+		return "", fmt.Errorf("cannot find the root of the repository")
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	// Temp dirs and home dirs are often symlinks, and git reports the resolved root
+	if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
+		cwd = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	return filepath.Rel(root, cwd)
+}
+
+// refCoverage checks ref out into workdir and runs gocoverdir with flags in it, returning its report.  dirs, if not
+// nil, limits the run to those packages.  Progress goes to out
+func refCoverage(repo vcs, ref string, workdir string, prefix string, dirs []string, flags []string, out io.Writer) (*report, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	checkout := filepath.Join(workdir, "checkout")
+	if err := repo.checkout(ref, checkout, out); err != nil {
+		return nil, fmt.Errorf("cannot check out %s: %s", ref, err)
+	}
+	defer func() {
+		if err := repo.removeCheckout(checkout); err != nil {
+			fmt.Fprintf(out, "Warning: cannot remove checkout of %s: %s\n", ref, err)
+		}
+	}()
+	reportFile := filepath.Join(workdir, "coverage.json")
+	args := []string{"-chdir", filepath.Join(checkout, prefix), "-json", reportFile, "-coverprofile", filepath.Join(workdir, "coverage.out")}
+	if dirs != nil {
+		args = append(args, "-stdin")
+	}
+	cmd := exec.Command(executable, append(args, flags...)...)
+	cmd.Stdin = strings.NewReader(strings.Join(dirs, "\n"))
+	cmd.Stdout = out
+	cmd.Stderr = out
+	fmt.Fprintf(out, "Running coverage for %s\n", ref)
+	runErr := cmd.Run()
+	ret, err := readReport(reportFile)
+	if err != nil {
+		if runErr != nil {
+			return nil, fmt.Errorf("coverage for %s failed: %s", ref, runErr)
+		}
+		return nil, err
+	}
+	// Failing tests or gates still leave a report worth comparing
+	if runErr != nil {
+		fmt.Fprintf(out, "Warning: coverage for %s failed: %s\n", ref, runErr)
+	}
+	return ret, nil
+}
+
+// runCompareBranches runs coverage for two refs in temporary checkouts and prints how it changed
+func runCompareBranches(args []string) error {
+	fs := flag.NewFlagSet("compare-branches", flag.ContinueOnError)
+	format := fs.String("format", "markdown", "Output format: markdown, text or html")
+	output := fs.String("o", stdio, "File to write the comparison to.  - means stdout")
+	affected := fs.Bool("affected", false, "If true, only test packages with Go files changed between the refs")
+	own, flags := splitPassthrough(args)
+	refs, err := parseInterspersed(fs, own)
+	if err != nil {
+		return err
+	}
+	if len(refs) != 2 {
+		return fmt.Errorf("usage: gocoverdir compare-branches [-format markdown|text|html] [-affected] [-o out] base head [-- gocoverdir flags]")
+	}
+	render, exists := diffRenderers[*format]
+	if !exists {
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	repo := detectVCS(cwd)
+	prefix, err := repoPrefix(repo.root())
+	if err != nil {
+		return err
+	}
+	var dirs []string
+	if *affected {
+		changed, err := repo.changedFiles(refs[0], refs[1])
+		if err != nil {
+			return err
+		}
+		if dirs = affectedDirs(changed); len(dirs) == 0 {
+			return fmt.Errorf("no Go files changed between %s and %s", refs[0], refs[1])
+		}
+	}
+	workdir, err := ioutil.TempDir("", "gocoverdir-compare")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workdir)
+	reports := make([]*report, 0, len(refs))
+	for i, ref := range refs {
+		refDir := filepath.Join(workdir, fmt.Sprintf("ref%d", i))
+		if err := os.Mkdir(refDir, 0755); err != nil {
+			return err
+		}
+		r, err := refCoverage(repo, ref, refDir, prefix, dirs, flags, os.Stderr)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, r)
+	}
+	diff := diffReports(reports[0], reports[1])
+	return writeFileWith(*output, func(w io.Writer) error {
+		return render(w, diff)
+	})
+}
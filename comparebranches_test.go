@@ -0,0 +1,30 @@
+package gocoverdir
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitPassthrough(t *testing.T) {
+	own, flags := splitPassthrough([]string{"-affected", "main", "feature", "--", "-race", "-v"})
+	if !reflect.DeepEqual(own, []string{"-affected", "main", "feature"}) || !reflect.DeepEqual(flags, []string{"-race", "-v"}) {
+		t.Errorf("Unexpected split %v %v", own, flags)
+	}
+	own, flags = splitPassthrough([]string{"main", "feature"})
+	if len(own) != 2 || flags != nil {
+		t.Errorf("Unexpected split without -- %v %v", own, flags)
+	}
+}
+
+func TestAffectedDirs(t *testing.T) {
+	dirs := affectedDirs([]string{"b/b.go", "README.md", "a/a.go", "a/a_test.go", "main.go", "a/testdata/x.txt"})
+	if !reflect.DeepEqual(dirs, []string{".", "a", "b"}) {
+		t.Errorf("Unexpected affected dirs %v", dirs)
+	}
+}
+
+func TestRepoPrefix(t *testing.T) {
+	if _, err := repoPrefix(""); err == nil {
+		t.Errorf("Expected an error without a repository root")
+	}
+}
@@ -0,0 +1,74 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+
+	"golang.org/x/tools/cover"
+)
+
+// maybeGunzip transparently decompresses r if it starts with the gzip magic number
+func maybeGunzip(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return br, nil
+	}
+	return gzip.NewReader(br)
+}
+
+func parseProfiles(r io.Reader) ([]*cover.Profile, error) {
+	r, err := maybeGunzip(r)
+	if err != nil {
+		return nil, err
+	}
+	return cover.ParseProfilesFromReader(r)
+}
+
+// readProfiles is cover.ParseProfiles that also reads gzipped profiles, and stdin for -
+func readProfiles(filename string) ([]*cover.Profile, error) {
+	f, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseProfiles(f)
+}
+
+// gzipFile replaces filename with filename.gz
+func gzipFile(filename string) error {
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := writeFileWith(filename+".gz", func(w io.Writer) error {
+		gz := gzip.NewWriter(w)
+		if _, err := io.Copy(gz, in); err != nil {
+			return err
+		}
+		return gz.Close()
+	}); err != nil {
+		return err
+	}
+	return os.Remove(filename)
+}
+
+// compressOutputs gzips the merged profile and reports once nothing else in the run needs to read them
+func (m *gocoverdir) compressOutputs() error {
+	for _, filename := range []string{m.args.coverprofile, m.args.json, m.args.pb, m.args.jenkins, m.args.cobertura, m.args.lcov, m.args.junit} {
+		if filename == "" {
+			continue
+		}
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			continue
+		}
+		m.log.Printf("Compressing %s", filename)
+		if err := gzipFile(filename); err != nil {
+			return err
+		}
+	}
+	return nil
+}
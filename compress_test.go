@@ -0,0 +1,25 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGzipFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdirtest")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "coverage.out")
+	noError(t, ioutil.WriteFile(filename, []byte("mode: set\nexample.com/a/a.go:1.1,2.2 1 1\n"), 0644))
+	noError(t, gzipFile(filename))
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("Expected the uncompressed profile to be removed")
+	}
+	profiles, err := readProfiles(filename + ".gz")
+	noError(t, err)
+	if len(profiles) != 1 || profiles[0].FileName != "example.com/a/a.go" {
+		t.Errorf("Unexpected profiles %+v", profiles)
+	}
+}
@@ -0,0 +1,95 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// config is read from the -config file, which is JSON or YAML
+type config struct {
+	RequiredCoverage float64            `json:"requiredcoverage"`
+	Packages         map[string]float64 `json:"packages"`
+	// MinStatements excludes packages with fewer statements from the packages thresholds
+	MinStatements int `json:"minstmts"`
+	// Internal lists package globs that are not public API, in addition to packages under an internal directory
+	Internal []string `json:"internal"`
+	// RequiredPublic and RequiredInternal are thresholds for the total coverage of public API and internal packages
+	RequiredPublic   float64 `json:"requiredpublic"`
+	RequiredInternal float64 `json:"requiredinternal"`
+	// Serial lists package directory globs whose tests must not run at the same time as any other package
+	Serial []string `json:"serial"`
+	// Goals are package thresholds that only fail after a deadline.  Until then, packages short of them are warned about
+	Goals map[string]goal `json:"goals"`
+	// Slowlist lists package directory globs that -fast skips, in addition to packages slower than -slowcutoff
+	Slowlist []string `json:"slowlist"`
+	// TestFlags maps package directory globs to extra go test flags, like "-count=3 -timeout=10m", for those packages
+	TestFlags map[string]string `json:"testflags"`
+	// FailOutput are regular expressions that fail a package if a line of its test output matches one, even if its
+	// tests pass, like "WARNING: DATA RACE" or "goroutine leak"
+	FailOutput []string `json:"failoutput"`
+	failOutput []*regexp.Regexp
+	// Exclude names constructs to leave out of coverage: return-err, panic and stringer
+	Exclude []string `json:"exclude"`
+	// SourceMap attributes the coverage of generated files to the templates or specs they are generated from, in
+	// addition to .gocovermap files
+	SourceMap []sourceMapRule `json:"sourcemap"`
+}
+
+func loadConfig(filename string) (*config, error) {
+	ret := &config{}
+	if filename == "" {
+		return ret, nil
+	}
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(contents), []byte("{")) {
+		if contents, err = yamlToJSON(contents); err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %s", filename, err)
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(contents))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(ret); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %s", filename, err)
+	}
+	if err := verifyGoals(ret.Goals); err != nil {
+		return nil, err
+	}
+	if err := verifyTestFlags(ret.TestFlags); err != nil {
+		return nil, err
+	}
+	if ret.failOutput, err = compileFailOutput(ret.FailOutput); err != nil {
+		return nil, err
+	}
+	if err := verifyExclude(ret.Exclude); err != nil {
+		return nil, err
+	}
+	if err := compileSourceMap(ret.SourceMap); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// matchPackage matches an import path against a glob, where a trailing /... matches the package and all its children
+func matchPackage(pattern string, pkg string) bool {
+	pattern = strings.TrimPrefix(pattern, "./")
+	if pattern == "..." {
+		return true
+	}
+	if prefix := strings.TrimSuffix(pattern, "/..."); prefix != pattern {
+		if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") || strings.HasSuffix(pkg, "/"+prefix) || strings.Contains(pkg, "/"+prefix+"/") {
+			return true
+		}
+	}
+	if matched, err := path.Match(pattern, pkg); err == nil && matched {
+		return true
+	}
+	return pkg == pattern || strings.HasSuffix(pkg, "/"+pattern)
+}
@@ -0,0 +1,72 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// contentionProfile is a profile go test writes for a package next to its artifacts
+type contentionProfile struct {
+	kind string
+	flag string
+	file string
+}
+
+func (m *gocoverdir) verifyContention() error {
+	if (m.args.mutexprofile || m.args.blockprofile) && m.args.artifacts == "" {
+		return fmt.Errorf("-mutexprofile and -blockprofile need -artifacts to write profiles into")
+	}
+	if (m.args.mutexprofile || m.args.blockprofile) && m.args.remote != "" {
+		m.log.Printf("-mutexprofile and -blockprofile are not collected from -remote hosts")
+	}
+	return nil
+}
+
+// contentionProfiles lists the profiles to write for dirpath, or nil if none were asked for
+func (m *gocoverdir) contentionProfiles(dirpath string) []contentionProfile {
+	if (!m.args.mutexprofile && !m.args.blockprofile) || m.args.artifacts == "" || m.args.remote != "" {
+		return nil
+	}
+	dir, err := filepath.Abs(filepath.Join(m.args.artifacts, "packages", dirpath))
+	if err != nil {
+		return nil
+	}
+	var ret []contentionProfile
+	if m.args.mutexprofile {
+		ret = append(ret, contentionProfile{kind: "mutex", flag: "-mutexprofile", file: filepath.Join(dir, "mutex.pprof")})
+	}
+	if m.args.blockprofile {
+		ret = append(ret, contentionProfile{kind: "block", flag: "-blockprofile", file: filepath.Join(dir, "block.pprof")})
+	}
+	return ret
+}
+
+// contentionArgs are the go test flags writing dirpath's profiles.  Profiling makes go test keep the test binary,
+// so it is written next to the profiles, where pprof can find it
+func (m *gocoverdir) contentionArgs(dirpath string) []string {
+	profiles := m.contentionProfiles(dirpath)
+	if len(profiles) == 0 {
+		return nil
+	}
+	args := []string{"-o", filepath.Join(filepath.Dir(profiles[0].file), "pkg.test")}
+	for _, profile := range profiles {
+		args = append(args, profile.flag, profile.file)
+	}
+	return args
+}
+
+// writtenProfiles maps the kind of each profile go test wrote for dirpath to its file
+func (m *gocoverdir) writtenProfiles(dirpath string) map[string]string {
+	var ret map[string]string
+	for _, profile := range m.contentionProfiles(dirpath) {
+		if _, err := os.Stat(profile.file); err != nil {
+			continue
+		}
+		if ret == nil {
+			ret = make(map[string]string)
+		}
+		ret[profile.kind] = profile.file
+	}
+	return ret
+}
@@ -0,0 +1,29 @@
+package gocoverdir
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContentionArgs(t *testing.T) {
+	m := &gocoverdir{}
+	if args := m.contentionArgs("a"); args != nil {
+		t.Errorf("Expected no profiling by default, saw %v", args)
+	}
+	if err := m.verifyContention(); err != nil {
+		t.Errorf("Unexpected error %s", err)
+	}
+	m.args.mutexprofile = true
+	if err := m.verifyContention(); err == nil {
+		t.Errorf("Expected -mutexprofile without -artifacts to fail")
+	}
+	m.args.artifacts = "/tmp/artifacts"
+	m.args.blockprofile = true
+	args := strings.Join(m.contentionArgs("a/b"), " ")
+	dir := filepath.Join("/tmp/artifacts", "packages", "a", "b")
+	expected := "-o " + filepath.Join(dir, "pkg.test") + " -mutexprofile " + filepath.Join(dir, "mutex.pprof") + " -blockprofile " + filepath.Join(dir, "block.pprof")
+	if args != expected {
+		t.Errorf("Unexpected args %s", args)
+	}
+}
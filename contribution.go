@@ -0,0 +1,145 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/tools/cover"
+)
+
+// contribution is how much of the coverage one test package provides, for -contribution
+type contribution struct {
+	Dir string `json:"dir"`
+	// Covered counts the statements the package's tests run
+	Covered int `json:"covered"`
+	// Unique counts the statements no other package's tests run.  A package with none could be dropped without
+	// losing coverage
+	Unique int `json:"unique"`
+}
+
+// storeProfileDir is the package directory of a profile named by storeProfileName
+func storeProfileDir(profileName string) string {
+	name := strings.SplitN(profileName, ".", 2)[0]
+	if name == "_root" {
+		return "."
+	}
+	dir, err := url.PathUnescape(name)
+	if err != nil {
+		return name
+	}
+	return filepath.FromSlash(dir)
+}
+
+// contributions counts, for each test package, the statements its profiles cover and how many of those only it covers
+func contributions(perPackage map[string][]*cover.Profile) []contribution {
+	coveredBy := make(map[blockKey]map[string]struct{})
+	statements := make(map[blockKey]int)
+	for dir, profiles := range perPackage {
+		for _, p := range profiles {
+			for _, block := range p.Blocks {
+				if block.Count == 0 {
+					continue
+				}
+				key := blockKey{file: p.FileName, startLine: block.StartLine, startCol: block.StartCol, endLine: block.EndLine, endCol: block.EndCol}
+				if coveredBy[key] == nil {
+					coveredBy[key] = make(map[string]struct{})
+				}
+				coveredBy[key][dir] = struct{}{}
+				statements[key] = block.NumStmt
+			}
+		}
+	}
+	byDir := make(map[string]*contribution, len(perPackage))
+	for dir := range perPackage {
+		byDir[dir] = &contribution{Dir: dir}
+	}
+	for key, dirs := range coveredBy {
+		for dir := range dirs {
+			byDir[dir].Covered += statements[key]
+			if len(dirs) == 1 {
+				byDir[dir].Unique += statements[key]
+			}
+		}
+	}
+	ret := make([]contribution, 0, len(byDir))
+	for _, c := range byDir {
+		ret = append(ret, *c)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Unique != ret[j].Unique {
+			return ret[i].Unique > ret[j].Unique
+		}
+		return ret[i].Dir < ret[j].Dir
+	})
+	return ret
+}
+
+// storedProfiles reads the profiles in the store, before finish merges them, by the package directory that wrote them
+func (m *gocoverdir) storedProfiles() (map[string][]*cover.Profile, error) {
+	files, err := ioutil.ReadDir(m.storeDir)
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string][]*cover.Profile)
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".cover") {
+			continue
+		}
+		profiles, err := cover.ParseProfiles(filepath.Join(m.storeDir, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		dir := storeProfileDir(file.Name())
+		ret[dir] = append(ret[dir], profiles...)
+	}
+	return ret, nil
+}
+
+func writeContributionsText(w io.Writer, contributions []contribution) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "PACKAGE\tCOVERED\tUNIQUE\n")
+	for _, c := range contributions {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", c.Dir, c.Covered, c.Unique)
+	}
+	return tw.Flush()
+}
+
+func writeContributionsJSON(w io.Writer, contributions []contribution) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(contributions)
+}
+
+// writeContribution writes how much each test package contributes to -contribution, as JSON if the file ends in .json
+// and as a table otherwise.  - writes to stderr
+func (m *gocoverdir) writeContribution() error {
+	if m.args.contribution == "" {
+		return nil
+	}
+	perPackage, err := m.storedProfiles()
+	if err != nil {
+		return err
+	}
+	ret := contributions(perPackage)
+	for i := range ret {
+		ret[i].Dir = m.emittedPath(ret[i].Dir)
+	}
+	write := writeContributionsText
+	if strings.HasSuffix(m.args.contribution, ".json") {
+		write = writeContributionsJSON
+	}
+	if m.args.contribution == "-" {
+		return write(os.Stderr, ret)
+	}
+	return writeFileWith(m.args.contribution, func(w io.Writer) error {
+		return write(w, ret)
+	})
+}
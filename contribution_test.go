@@ -0,0 +1,62 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestStoreProfileDir(t *testing.T) {
+	for _, dir := range []string{".", "a", filepath.Join("a", "b.c"), filepath.Join("x y", "z")} {
+		for _, name := range []string{storeProfileName(dir, "", 0), storeProfileName(dir, "quarantined", 2)} {
+			if got := storeProfileDir(name); got != dir {
+				t.Errorf("Expected %s from %s, saw %s", dir, name, got)
+			}
+		}
+	}
+}
+
+func TestContributions(t *testing.T) {
+	profile := func(blocks ...cover.ProfileBlock) []*cover.Profile {
+		return []*cover.Profile{{FileName: "example.com/lib/lib.go", Mode: "set", Blocks: blocks}}
+	}
+	shared := cover.ProfileBlock{StartLine: 1, EndLine: 2, NumStmt: 3, Count: 1}
+	onlyA := cover.ProfileBlock{StartLine: 3, EndLine: 4, NumStmt: 2, Count: 1}
+	onlyB := cover.ProfileBlock{StartLine: 5, EndLine: 6, NumStmt: 1, Count: 1}
+	uncovered := cover.ProfileBlock{StartLine: 7, EndLine: 8, NumStmt: 5}
+	got := contributions(map[string][]*cover.Profile{
+		"a": profile(shared, onlyA, uncovered),
+		"b": profile(shared, onlyB),
+		"c": profile(uncovered),
+	})
+	want := []contribution{{Dir: "a", Covered: 5, Unique: 2}, {Dir: "b", Covered: 4, Unique: 1}, {Dir: "c"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected contributions %+v", got)
+	}
+	var buf bytes.Buffer
+	noError(t, writeContributionsText(&buf, got))
+	if !strings.HasPrefix(buf.String(), "PACKAGE  COVERED  UNIQUE\na        5        2\n") {
+		t.Errorf("Unexpected table:\n%s", buf.String())
+	}
+}
+
+func TestStoredProfiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestStoredProfiles")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	m := &gocoverdir{storeDir: dir}
+	noError(t, ioutil.WriteFile(filepath.Join(dir, storeProfileName("a", "", 0)), []byte("mode: set\nexample.com/a/a.go:1.1,2.1 1 1\n"), 0644))
+	noError(t, ioutil.WriteFile(filepath.Join(dir, storeProfileName("a", "", 1)), []byte("mode: set\nexample.com/a/a.go:3.1,4.1 1 1\n"), 0644))
+	noError(t, os.Mkdir(filepath.Join(dir, "stdout"), 0755))
+	stored, err := m.storedProfiles()
+	noError(t, err)
+	if len(stored) != 1 || len(stored["a"]) != 2 {
+		t.Errorf("Unexpected stored profiles %v", stored)
+	}
+}
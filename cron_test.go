@@ -0,0 +1,38 @@
+package gocoverdir
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	start := time.Date(2020, 1, 1, 3, 30, 0, 0, time.UTC)
+	for _, c := range []struct {
+		expr string
+		next time.Time
+	}{
+		{"0 2 * * *", time.Date(2020, 1, 2, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2020, 1, 1, 3, 45, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2020, 1, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 0 1 3 *", time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 4 15 * 0", time.Date(2020, 1, 5, 4, 30, 0, 0, time.UTC)},
+	} {
+		s, err := parseCron(c.expr)
+		noError(t, err)
+		if next := s.next(start); !next.Equal(c.next) {
+			t.Errorf("%s: expected %s, saw %s", c.expr, c.next, next)
+		}
+	}
+	kolkata := time.FixedZone("IST", 5*60*60+30*60)
+	s, err := parseCron("0 2 * * *")
+	noError(t, err)
+	expected := time.Date(2020, 1, 2, 2, 0, 0, 0, kolkata)
+	if next := s.next(time.Date(2020, 1, 1, 3, 30, 0, 0, kolkata)); !next.Equal(expected) {
+		t.Errorf("Expected %s in a half hour zone, saw %s", expected, next)
+	}
+	for _, invalid := range []string{"* * * *", "60 * * * *", "a * * * *", "*/0 * * * *"} {
+		if _, err := parseCron(invalid); err == nil {
+			t.Errorf("Expected %q to be invalid", invalid)
+		}
+	}
+}
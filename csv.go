@@ -0,0 +1,76 @@
+package gocoverdir
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+
+	"golang.org/x/tools/cover"
+)
+
+func formatPercent(percent float64) string {
+	return fmt.Sprintf("%.2f", percent)
+}
+
+// formatDelta is the change in coverage from the baseline, or "" if the path is not in the baseline
+func formatDelta(percent float64, baseline map[string]float64, key string) string {
+	base, exists := baseline[key]
+	if !exists {
+		return ""
+	}
+	return fmt.Sprintf("%+.2f", percent-base)
+}
+
+func coverageByPath(profiles []*cover.Profile, metric string) (packages map[string]float64, files map[string]float64) {
+	packages = make(map[string]float64)
+	files = make(map[string]float64)
+	for _, pkg := range packageCoverages(profiles, metric) {
+		packages[pkg.name] = pkg.percent()
+	}
+	for _, p := range profiles {
+		total, covered := countStatements(p, metric)
+		files[p.FileName] = lineRate(covered, total) * 100
+	}
+	return packages, files
+}
+
+// writeCSV writes one row per package, and optionally per file named by loc, with the coverage delta against
+// baseline if given
+func writeCSV(w io.Writer, profiles []*cover.Profile, metric string, baseline []*cover.Profile, perFile bool, loc *fileLocator) error {
+	basePackages, baseFiles := coverageByPath(baseline, metric)
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"type", "path", "statements", "covered", "percent", "delta"}); err != nil {
+		return err
+	}
+	for _, pkg := range packageCoverages(profiles, metric) {
+		row := []string{"package", pkg.name, fmt.Sprintf("%d", pkg.statements), fmt.Sprintf("%d", pkg.covered), formatPercent(pkg.percent()), formatDelta(pkg.percent(), basePackages, pkg.name)}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	if perFile {
+		for _, p := range profiles {
+			total, covered := countStatements(p, metric)
+			percent := lineRate(covered, total) * 100
+			row := []string{"file", loc.name(p), fmt.Sprintf("%d", total), fmt.Sprintf("%d", covered), formatPercent(percent), formatDelta(percent, baseFiles, p.FileName)}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (m *gocoverdir) writeCSV(profiles []*cover.Profile) error {
+	var baseline []*cover.Profile
+	if m.args.baseprofile != "" {
+		var err error
+		if baseline, err = m.baseProfiles(); err != nil {
+			return err
+		}
+	}
+	return writeFileWith(m.args.csv, func(w io.Writer) error {
+		return writeCSV(w, profiles, m.args.metric, baseline, m.args.csvfiles, m.locator(profiles))
+	})
+}
@@ -0,0 +1,23 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestWriteCSV(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 1, Count: 1}, {NumStmt: 1}}},
+	}
+	baseline := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 4, Count: 1}}},
+	}
+	buf := bytes.Buffer{}
+	noError(t, writeCSV(&buf, profiles, "statements", baseline, true, newFileLocator(profiles, false)))
+	expected := "type,path,statements,covered,percent,delta\npackage,example.com/a,2,1,50.00,-50.00\nfile,example.com/a/a.go,2,1,50.00,-50.00\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected csv %q", buf.String())
+	}
+}
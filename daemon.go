@@ -0,0 +1,482 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/cover"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+)
+
+// runEvent describes progress of a run, streamed to daemon clients
+type runEvent struct {
+	Type     string  `json:"type"`
+	Run      int     `json:"run,omitempty"`
+	Package  string  `json:"package,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Coverage float64 `json:"coverage,omitempty"`
+}
+
+func (m *gocoverdir) emit(event runEvent) {
+	if m.events != nil {
+		m.events(event)
+	}
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// daemon runs coverage on request and serves the results over HTTP
+type daemon struct {
+	runArgs  []string
+	log      *log.Logger
+	branch   string
+	repo     vcs
+	webhooks []string
+	// grpcServer serves -grpc, if it is set, and grpcHealth is its health service
+	grpcServer *grpc.Server
+	grpcHealth *health.Server
+
+	mu             sync.Mutex
+	running        bool
+	runID          int
+	lastError      string
+	latest         *report
+	latestProfiles []*cover.Profile
+	// finished is the run_done event of the last run to finish
+	finished    runEvent
+	subscribers map[chan runEvent]struct{}
+	// draining is set once the daemon is shutting down: it is no longer ready, and starts no runs
+	draining bool
+	// runs tracks the run in progress, so shutdown can wait for it
+	runs sync.WaitGroup
+	// done is closed on shutdown, to end event streams
+	done chan struct{}
+}
+
+func newDaemon(runArgs []string, logger *log.Logger) *daemon {
+	return &daemon{
+		runArgs:     runArgs,
+		log:         logger,
+		subscribers: make(map[chan runEvent]struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+func (d *daemon) publish(event runEvent) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if event.Run == 0 {
+		event.Run = d.runID
+	}
+	for sub := range d.subscribers {
+		select {
+		case sub <- event:
+		default:
+			// Slow subscribers miss events rather than block the run
+		}
+	}
+}
+
+// startRun begins a run in the background, returning false if one is already in progress or the daemon is shutting
+// down
+func (d *daemon) startRun() (int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.running || d.draining {
+		return d.runID, false
+	}
+	d.running = true
+	d.runID++
+	d.runs.Add(1)
+	go d.run(d.runID)
+	return d.runID, true
+}
+
+func (d *daemon) run(runID int) {
+	defer d.runs.Done()
+	d.publish(runEvent{Type: "run_start", Run: runID})
+	err := d.checkoutBranch()
+	var rep *report
+	var profiles []*cover.Profile
+	if err == nil {
+		rep, profiles, err = d.runOnce()
+	}
+	defer d.notifyWebhooks(runID, rep, err)
+	done := runEvent{Type: "run_done", Run: runID, Error: errorString(err)}
+	if err == nil {
+		done.Coverage = rep.Coverage
+	}
+	d.mu.Lock()
+	d.running = false
+	d.lastError = errorString(err)
+	d.finished = done
+	if err == nil {
+		d.latest = rep
+		d.latestProfiles = profiles
+	}
+	d.mu.Unlock()
+	if err != nil {
+		d.log.Printf("Run %d failed: %s", runID, err)
+	} else {
+		d.log.Printf("Run %d done with coverage %.1f%%", runID, rep.Coverage)
+	}
+	d.publish(done)
+}
+
+// subscribe returns a channel of the events published from now on, and a func to stop them
+func (d *daemon) subscribe() (<-chan runEvent, func()) {
+	events := make(chan runEvent, 64)
+	d.mu.Lock()
+	d.subscribers[events] = struct{}{}
+	d.mu.Unlock()
+	return events, func() {
+		d.mu.Lock()
+		delete(d.subscribers, events)
+		d.mu.Unlock()
+	}
+}
+
+func (d *daemon) runOnce() (rep *report, profiles []*cover.Profile, err error) {
+	m := &gocoverdir{}
+	defer func() {
+		if panicCondition := recover(); panicCondition != nil {
+			err = fmt.Errorf("run panicked: %v", panicCondition)
+		}
+		if m.storeDir != "" {
+			if closeErr := m.Close(); closeErr != nil && err == nil {
+				err = closeErr
+			}
+		}
+	}()
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	m.setupFlags(fs)
+	if err = fs.Parse(d.runArgs); err != nil {
+		return nil, nil, err
+	}
+	m.args.packages = fs.Args()
+	if m.args.chdir != "" {
+		return nil, nil, fmt.Errorf("-chdir would change the daemon's directory; start the daemon in %s instead", m.args.chdir)
+	}
+	m.events = d.publish
+	if err = m.finish(m.Main()); err != nil {
+		return nil, nil, err
+	}
+	return m.report, m.profiles, nil
+}
+
+func (d *daemon) writeJSON(rw http.ResponseWriter, code int, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(code)
+	if err := json.NewEncoder(rw).Encode(v); err != nil {
+		d.log.Printf("Unable to write response: %s", err)
+	}
+}
+
+func (d *daemon) handleRuns(rw http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodPost:
+		if d.isDraining() {
+			http.Error(rw, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		runID, started := d.startRun()
+		if !started {
+			d.writeJSON(rw, http.StatusConflict, map[string]interface{}{"run": runID, "error": "a run is already in progress"})
+			return
+		}
+		d.writeJSON(rw, http.StatusAccepted, map[string]interface{}{"run": runID})
+	case http.MethodGet:
+		d.mu.Lock()
+		status := map[string]interface{}{"run": d.runID, "running": d.running, "error": d.lastError}
+		d.mu.Unlock()
+		d.writeJSON(rw, http.StatusOK, status)
+	default:
+		http.Error(rw, "GET or POST", http.StatusMethodNotAllowed)
+	}
+}
+
+func (d *daemon) handleReport(rw http.ResponseWriter, req *http.Request) {
+	d.mu.Lock()
+	latest := d.latest
+	d.mu.Unlock()
+	if latest == nil {
+		http.Error(rw, "no completed run yet", http.StatusNotFound)
+		return
+	}
+	d.writeJSON(rw, http.StatusOK, latest)
+}
+
+type fileCoverage struct {
+	reportFile
+	Lines []jenkinsLine `json:"lines"`
+}
+
+func (d *daemon) handleFile(rw http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, "/files/")
+	d.mu.Lock()
+	profiles := d.latestProfiles
+	metric := "statements"
+	if d.latest != nil {
+		metric = d.latest.Metric
+	}
+	d.mu.Unlock()
+	for _, p := range profiles {
+		if p.FileName != name && !strings.HasSuffix(p.FileName, "/"+name) {
+			continue
+		}
+		total, covered := countStatements(p, metric)
+		ret := fileCoverage{
+			reportFile: reportFile{Path: p.FileName, Statements: total, Covered: covered, Coverage: lineRate(covered, total) * 100},
+			Lines:      make([]jenkinsLine, 0),
+		}
+		for _, line := range lineHits(p) {
+			ret.Lines = append(ret.Lines, jenkinsLine{Number: line.number, Hits: line.hits})
+		}
+		d.writeJSON(rw, http.StatusOK, ret)
+		return
+	}
+	http.Error(rw, fmt.Sprintf("no coverage for file %s", name), http.StatusNotFound)
+}
+
+// handleEvents streams run events as server sent events
+func (d *daemon) handleEvents(rw http.ResponseWriter, req *http.Request) {
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		http.Error(rw, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	events, unsubscribe := d.subscribe()
+	defer unsubscribe()
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case <-d.done:
+			return
+		case event := <-events:
+			data, err := json.Marshal(event)
+			if err != nil {
+				d.log.Printf("Unable to encode event: %s", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(rw, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (d *daemon) isDraining() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.draining
+}
+
+// handleHealth is the liveness probe: the daemon is serving requests
+func (d *daemon) handleHealth(rw http.ResponseWriter, req *http.Request) {
+	d.writeJSON(rw, http.StatusOK, map[string]interface{}{"status": "ok"})
+}
+
+// handleReady is the readiness probe.  It fails once the daemon is shutting down, so load balancers stop sending it
+// runs while the one in progress finishes
+func (d *daemon) handleReady(rw http.ResponseWriter, req *http.Request) {
+	d.mu.Lock()
+	status := map[string]interface{}{"status": "ready", "run": d.runID, "running": d.running, "has_report": d.latest != nil}
+	draining := d.draining
+	d.mu.Unlock()
+	if draining {
+		status["status"] = "shutting down"
+		d.writeJSON(rw, http.StatusServiceUnavailable, status)
+		return
+	}
+	d.writeJSON(rw, http.StatusOK, status)
+}
+
+// shutdown stops taking runs, waits up to timeout for the run in progress, then stops the servers.  Waiting keeps a run
+// from being killed half way, which would leave its store of profiles behind
+func (d *daemon) shutdown(timeout time.Duration, servers ...*http.Server) error {
+	// gRPC health checks fail before /readyz does, so both say the same once draining is set
+	if d.grpcHealth != nil {
+		d.grpcHealth.Shutdown()
+	}
+	d.mu.Lock()
+	d.draining = true
+	runID := d.runID
+	d.mu.Unlock()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	finished := make(chan struct{})
+	go func() {
+		d.runs.Wait()
+		close(finished)
+	}()
+	var err error
+	select {
+	case <-finished:
+	case <-ctx.Done():
+		err = fmt.Errorf("run %d was still going after %s", runID, timeout)
+	}
+	close(d.done)
+	if d.grpcServer != nil {
+		stopGRPC(ctx, d.grpcServer)
+	}
+	for _, server := range servers {
+		if shutdownErr := server.Shutdown(ctx); shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
+	return err
+}
+
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", d.handleHealth)
+	mux.HandleFunc("/readyz", d.handleReady)
+	mux.HandleFunc("/runs", d.handleRuns)
+	mux.HandleFunc("/report", d.handleReport)
+	mux.HandleFunc("/files/", d.handleFile)
+	mux.HandleFunc("/events", d.handleEvents)
+	mux.HandleFunc("/badge/", d.handleBadge)
+	mux.HandleFunc("/coverage/", d.handleCoverage)
+	return mux
+}
+
+// checkoutBranch updates the working tree to the latest -branch before a run
+func (d *daemon) checkoutBranch() error {
+	if d.branch == "" {
+		return nil
+	}
+	d.log.Printf("Updating to the latest %s", d.branch)
+	return d.repo.update(d.branch, os.Stderr)
+}
+
+type webhookPayload struct {
+	Run    int     `json:"run"`
+	Error  string  `json:"error,omitempty"`
+	Report *report `json:"report,omitempty"`
+}
+
+// notifyWebhooks POSTs the outcome of a run to every -webhook
+func (d *daemon) notifyWebhooks(runID int, rep *report, err error) {
+	if len(d.webhooks) == 0 {
+		return
+	}
+	body, marshalErr := json.Marshal(webhookPayload{Run: runID, Error: errorString(err), Report: rep})
+	if marshalErr != nil {
+		d.log.Printf("Unable to encode webhook payload: %s", marshalErr)
+		return
+	}
+	client := http.Client{Timeout: time.Second * 30}
+	for _, webhook := range d.webhooks {
+		resp, postErr := client.Post(webhook, "application/json", bytes.NewReader(body))
+		if postErr != nil {
+			d.log.Printf("Webhook %s failed: %s", webhook, postErr)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 300 {
+			d.log.Printf("Webhook %s returned %s", webhook, resp.Status)
+		}
+	}
+}
+
+// schedule starts a run every time the cron schedule fires
+func (d *daemon) schedule(s *cronSchedule) {
+	for {
+		next := s.next(time.Now())
+		if next.IsZero() {
+			d.log.Printf("Schedule never fires again")
+			return
+		}
+		d.log.Printf("Next scheduled run at %s", next)
+		time.Sleep(time.Until(next))
+		if runID, started := d.startRun(); !started {
+			d.log.Printf("Skipping scheduled run: run %d is still in progress", runID)
+		}
+	}
+}
+
+// runDaemon serves a REST API to trigger runs and query their results.  Arguments after the daemon's own flags are
+// the flags used for every run.  It shuts down once ctx is done
+func runDaemon(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	listen := fs.String("listen", "localhost:8080", "Address to serve the API on.  Anyone who can reach it can start runs, so listen on other interfaces only behind a firewall or proxy")
+	grpcListen := fs.String("grpc", "", "If set, also serve the gRPC API of gocoverdir.proto on this address, like :9090")
+	runOnStart := fs.Bool("runonstart", false, "If true, start a run as soon as the daemon starts")
+	schedule := fs.String("schedule", "", "Cron expression, like \"0 2 * * *\", to start runs on")
+	branch := fs.String("branch", "", "If set, fetch and check out this branch from origin before every run")
+	vcsName := fs.String("vcs", "auto", "Version control system to check out -branch with: git, hg, or auto to detect it")
+	webhooks := fs.String("webhook", "", "Comma separated URLs to POST each run's result to")
+	shutdownTimeout := fs.Duration("shutdowntimeout", 5*time.Minute, "On SIGTERM or interrupt, how long to wait for the run in progress before exiting.  Keep it under the pod's termination grace period")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	d := newDaemon(fs.Args(), logger)
+	d.branch = *branch
+	repo, err := selectVCS(*vcsName)
+	if err != nil {
+		return err
+	}
+	d.repo = repo
+	if *webhooks != "" {
+		d.webhooks = strings.Split(*webhooks, ",")
+	}
+	if *schedule != "" {
+		s, err := parseCron(*schedule)
+		if err != nil {
+			return err
+		}
+		go d.schedule(s)
+	}
+	if *runOnStart {
+		d.startRun()
+	}
+	server := &http.Server{Addr: *listen, Handler: d.handler()}
+	if *grpcListen != "" {
+		if err := d.serveGRPC(*grpcListen); err != nil {
+			return err
+		}
+	}
+	shutdownErr := make(chan error, 1)
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+			return
+		}
+		logger.Printf("%s: shutting down", context.Cause(ctx))
+		shutdownErr <- d.shutdown(*shutdownTimeout, server)
+	}()
+	logger.Printf("Serving on %s", *listen)
+	if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	if err := <-shutdownErr; err != nil {
+		return err
+	}
+	logger.Printf("Shut down")
+	return nil
+}
@@ -0,0 +1,108 @@
+package gocoverdir
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestDaemonHandler(t *testing.T) {
+	d := newDaemon(nil, log.New(ioutil.Discard, "", 0))
+	get := func(url string) *httptest.ResponseRecorder {
+		rw := httptest.NewRecorder()
+		d.handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, url, nil))
+		return rw
+	}
+	if rw := get("/report"); rw.Code != http.StatusNotFound {
+		t.Errorf("Expected no report yet, saw %d", rw.Code)
+	}
+	profiles := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{StartLine: 1, EndLine: 1, NumStmt: 1, Count: 1}}},
+	}
+	d.latest = newReport(profiles, "statements", nil, nil, nil, nil)
+	d.latestProfiles = profiles
+	if rw := get("/report"); rw.Code != http.StatusOK || !strings.Contains(rw.Body.String(), `"coverage":100`) {
+		t.Errorf("Unexpected report %d %s", rw.Code, rw.Body.String())
+	}
+	if rw := get("/files/a/a.go"); rw.Code != http.StatusOK || !strings.Contains(rw.Body.String(), `"lines":[{"number":1,"hits":1}]`) {
+		t.Errorf("Unexpected file coverage %d %s", rw.Code, rw.Body.String())
+	}
+	if rw := get("/files/b.go"); rw.Code != http.StatusNotFound {
+		t.Errorf("Expected missing file, saw %d", rw.Code)
+	}
+	if rw := get("/runs"); rw.Code != http.StatusOK || !strings.Contains(rw.Body.String(), `"running":false`) {
+		t.Errorf("Unexpected run status %d %s", rw.Code, rw.Body.String())
+	}
+}
+
+func TestDaemonWebhooks(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		contents, err := ioutil.ReadAll(req.Body)
+		noError(t, err)
+		body = string(contents)
+	}))
+	defer srv.Close()
+	d := newDaemon(nil, log.New(ioutil.Discard, "", 0))
+	d.webhooks = []string{srv.URL}
+	d.notifyWebhooks(3, nil, errors.New("tests failed"))
+	if body != `{"run":3,"error":"tests failed"}` {
+		t.Errorf("Unexpected webhook body %s", body)
+	}
+}
+
+func TestDaemonRejectsChdir(t *testing.T) {
+	d := newDaemon([]string{"-chdir", "elsewhere"}, log.New(ioutil.Discard, "", 0))
+	if _, _, err := d.runOnce(); err == nil || !strings.Contains(err.Error(), "elsewhere") {
+		t.Errorf("Expected -chdir to be rejected, saw %v", err)
+	}
+}
+
+func TestDaemonRunPackages(t *testing.T) {
+	d := newDaemon([]string{"-coverprofile", filepath.Join(t.TempDir(), "coverage.out"), "./does-not-exist"}, log.New(ioutil.Discard, "", 0))
+	if _, _, err := d.runOnce(); err == nil || !strings.Contains(err.Error(), "does-not-exist") {
+		t.Errorf("Expected the run to test the daemon's package arguments, saw %v", err)
+	}
+}
+
+func TestDaemonShutdown(t *testing.T) {
+	d := newDaemon(nil, log.New(ioutil.Discard, "", 0))
+	srv := httptest.NewServer(d.handler())
+	defer srv.Close()
+	for _, path := range []string{"/healthz", "/readyz"} {
+		resp, err := http.Get(srv.URL + path)
+		noError(t, err)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected %s to be OK, saw %s", path, resp.Status)
+		}
+	}
+
+	// A run still going holds up shutdown until the timeout
+	d.runs.Add(1)
+	if err := d.shutdown(10*time.Millisecond, srv.Config); err == nil {
+		t.Errorf("Expected shutdown to time out waiting for the run")
+	}
+	d.runs.Done()
+	if _, started := d.startRun(); started {
+		t.Errorf("Expected no runs to start while shutting down")
+	}
+	rw := httptest.NewRecorder()
+	d.handler().ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if rw.Code != http.StatusServiceUnavailable || !strings.Contains(rw.Body.String(), "shutting down") {
+		t.Errorf("Expected not to be ready while shutting down, saw %d %s", rw.Code, rw.Body.String())
+	}
+	rw = httptest.NewRecorder()
+	d.handler().ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/runs", nil))
+	if rw.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected runs to be refused while shutting down, saw %d", rw.Code)
+	}
+}
@@ -0,0 +1,77 @@
+package gocoverdir
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// depToolMarkers are the files that say which tool manages a module's dependencies, in the order they win when a
+// directory has more than one.  A repository part way through a migration to modules keeps its old manifest
+var depToolMarkers = []struct {
+	tool   string
+	marker string
+}{
+	{"modules", "go.mod"},
+	{"godep", "Godeps"},
+	{"dep", "Gopkg.toml"},
+	{"glide", "glide.yaml"},
+}
+
+// detectDepTool is the tool managing the dependencies of the module rooted at dir, or "" if dir is not a module root
+func detectDepTool(dir string) string {
+	for _, m := range depToolMarkers {
+		if _, err := os.Stat(filepath.Join(dir, m.marker)); err == nil {
+			return m.tool
+		}
+	}
+	return ""
+}
+
+// depStrategy is how to test the packages of one module: the tool managing its dependencies, and the directory,
+// relative to the working directory, the test command runs in
+type depStrategy struct {
+	tool string
+	root string
+}
+
+// depStrategy finds the module dirpath belongs to by walking up to the nearest directory with a dependency manifest.
+// Packages outside any module are tested from the working directory like before
+func (m *gocoverdir) depStrategy(dirpath string) depStrategy {
+	dir := filepath.Clean(dirpath)
+	for {
+		if tool := detectDepTool(dir); tool != "" {
+			return depStrategy{tool: tool, root: dir}
+		}
+		if dir == "." || filepath.IsAbs(dir) && filepath.Dir(dir) == dir {
+			return depStrategy{root: "."}
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// command is the executable, and its leading arguments, that runs go for the module
+func (s depStrategy) command() (string, []string) {
+	if s.tool == "godep" {
+		return "godep", []string{"go"}
+	}
+	return "go", nil
+}
+
+// packageArg is the argument naming dirpath to a go command run in the module's root
+func (s depStrategy) packageArg(dirpath string) string {
+	rel, err := filepath.Rel(s.root, filepath.Clean(dirpath))
+	if err != nil {
+		return "./" + dirpath
+	}
+	return "./" + filepath.ToSlash(rel)
+}
+
+// depToolEnv turns modules off for the tools that predate them, which only find their vendored dependencies in GOPATH
+// mode
+func depToolEnv(tool string) []string {
+	switch tool {
+	case "godep", "dep", "glide":
+		return []string{"GO111MODULE=off"}
+	}
+	return nil
+}
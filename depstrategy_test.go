@@ -0,0 +1,64 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDepStrategy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdir")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	cwd, err := os.Getwd()
+	noError(t, err)
+	noError(t, os.Chdir(dir))
+	defer os.Chdir(cwd)
+	for _, d := range []string{"plain/pkg", "legacy/Godeps", "legacy/sub", "mod/internal/x", "deptool/pkg", "glidetool"} {
+		noError(t, os.MkdirAll(d, 0755))
+	}
+	noError(t, ioutil.WriteFile(filepath.Join("mod", "go.mod"), []byte("module example.com/mod\n"), 0644))
+	noError(t, ioutil.WriteFile(filepath.Join("deptool", "Gopkg.toml"), nil, 0644))
+	noError(t, ioutil.WriteFile(filepath.Join("glidetool", "glide.yaml"), nil, 0644))
+
+	m := gocoverdir{}
+	tests := []struct {
+		dir  string
+		tool string
+		root string
+		arg  string
+	}{
+		{"plain/pkg", "", ".", "./plain/pkg"},
+		{"legacy/sub", "godep", "legacy", "./sub"},
+		{"mod/internal/x", "modules", "mod", "./internal/x"},
+		{"mod", "modules", "mod", "./."},
+		{"deptool/pkg", "dep", "deptool", "./pkg"},
+		{"glidetool", "glide", "glidetool", "./."},
+	}
+	for _, test := range tests {
+		s := m.depStrategy(test.dir)
+		if s.tool != test.tool || s.root != test.root {
+			t.Errorf("%s: expected %s in %s, got %s in %s", test.dir, test.tool, test.root, s.tool, s.root)
+		}
+		if arg := s.packageArg(test.dir); arg != test.arg {
+			t.Errorf("%s: expected package %s, got %s", test.dir, test.arg, arg)
+		}
+	}
+
+	executable, args := m.testArgs("legacy/sub", "/store", "a.cover", nil)
+	if executable != "godep" || args[0] != "go" || args[len(args)-1] != "./sub" {
+		t.Errorf("Unexpected godep command %s %s", executable, strings.Join(args, " "))
+	}
+	cmd, err := m.testCommand("legacy", executable, args...)
+	noError(t, err)
+	if cmd.Dir != "legacy" || cmd.Env[len(cmd.Env)-1] != "GO111MODULE=off" {
+		t.Errorf("Unexpected godep command environment %s in %s", cmd.Env, cmd.Dir)
+	}
+	cmd, err = m.testCommand("mod", "go", "test", "./internal/x")
+	noError(t, err)
+	if cmd.Dir != "mod" || cmd.Env != nil {
+		t.Errorf("Unexpected module command environment %s in %s", cmd.Env, cmd.Dir)
+	}
+}
@@ -0,0 +1,178 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// diffCoverage is the coverage of the code lines changed since -diffbase
+type diffCoverage struct {
+	Lines   int
+	Covered int
+	// Files lists the files with uncovered changed lines
+	Files []string
+}
+
+func (d diffCoverage) percent() float64 {
+	if d.Lines == 0 {
+		return 100
+	}
+	return float64(d.Covered) * 100 / float64(d.Lines)
+}
+
+// computeDiffCoverage counts the changed lines of profiles' files that are code, and how many of those tests ran.
+// changed is keyed by paths relative to root.  keep, if set, picks which changed lines count
+func computeDiffCoverage(profiles []*cover.Profile, loc *fileLocator, root string, changed map[string][]int, keep func(file string, line int) bool) diffCoverage {
+	var ret diffCoverage
+	for _, p := range profiles {
+		fullPath := loc.path(p)
+		if fullPath == "" {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(fullPath); err == nil {
+			fullPath = resolved
+		}
+		rel, err := filepath.Rel(root, fullPath)
+		if err != nil {
+			continue
+		}
+		file := filepath.ToSlash(rel)
+		lines := changed[file]
+		if len(lines) == 0 {
+			continue
+		}
+		hits := make(map[int]int)
+		for _, hit := range lineHits(p) {
+			hits[hit.number] = hit.hits
+		}
+		uncovered := false
+		for _, line := range lines {
+			count, isCode := hits[line]
+			if !isCode || (keep != nil && !keep(file, line)) {
+				continue
+			}
+			ret.Lines++
+			if count > 0 {
+				ret.Covered++
+			} else {
+				uncovered = true
+			}
+		}
+		if uncovered {
+			ret.Files = append(ret.Files, file)
+		}
+	}
+	sort.Strings(ret.Files)
+	return ret
+}
+
+// authorMatches is true if author, "Name <email>" like blame reports it, is one of authors, given by name or email
+func authorMatches(author string, authors []string) bool {
+	name := strings.TrimSpace(author)
+	email := ""
+	if i := strings.LastIndex(author, " <"); i >= 0 {
+		name, email = author[:i], strings.Trim(author[i+1:], "<>")
+	}
+	for _, want := range authors {
+		want = strings.TrimSpace(want)
+		if strings.EqualFold(want, name) || strings.EqualFold(strings.Trim(want, "<>"), email) {
+			return true
+		}
+	}
+	return false
+}
+
+// diffFilter keeps the changed lines written by -diffauthor, in files CODEOWNERS gives -diffteam, so teams sharing a
+// pipeline can gate only their own changes.  It is nil without either
+func (m *gocoverdir) diffFilter(changed map[string][]int) (func(file string, line int) bool, error) {
+	if m.args.diffauthor == "" && m.args.diffteam == "" {
+		return nil, nil
+	}
+	owners := &codeowners{}
+	if m.args.diffteam != "" {
+		var err error
+		if owners, err = loadCodeowners(); err != nil {
+			return nil, err
+		}
+	}
+	owned := func(file string) bool {
+		return m.args.diffteam == "" || containsString(owners.owners(file), m.args.diffteam)
+	}
+	blamed := make(map[string][]string)
+	if m.args.diffauthor != "" {
+		for file := range changed {
+			if !strings.HasSuffix(file, ".go") || !owned(file) {
+				continue
+			}
+			lineAuthors, err := m.repo.lineAuthors(file)
+			if err != nil {
+				return nil, fmt.Errorf("cannot find who changed %s: %s", file, err)
+			}
+			blamed[file] = lineAuthors
+		}
+	}
+	authors := strings.Split(m.args.diffauthor, ",")
+	return func(file string, line int) bool {
+		if !owned(file) {
+			return false
+		}
+		if m.args.diffauthor == "" {
+			return true
+		}
+		lineAuthors := blamed[file]
+		return line <= len(lineAuthors) && authorMatches(lineAuthors[line-1], authors)
+	}, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// diffScope describes the lines -diffcoverage gates
+func (m *gocoverdir) diffScope() string {
+	ret := "lines changed since " + m.args.diffbase
+	if m.args.diffauthor != "" {
+		ret += " by " + m.args.diffauthor
+	}
+	if m.args.diffteam != "" {
+		ret += " in files owned by " + m.args.diffteam
+	}
+	return ret
+}
+
+// evaluateDiffCoverage checks the coverage of lines changed since -diffbase against -diffcoverage
+func (m *gocoverdir) evaluateDiffCoverage(profiles []*cover.Profile) ([]violation, error) {
+	if m.args.diffbase == "" {
+		return nil, nil
+	}
+	root := m.repo.root()
+	if root == "" {
+		return nil, fmt.Errorf("-diffbase needs a git or hg repository")
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+	changed, err := m.repo.changedLines(m.args.diffbase)
+	if err != nil {
+		return nil, err
+	}
+	keep, err := m.diffFilter(changed)
+	if err != nil {
+		return nil, err
+	}
+	diff := computeDiffCoverage(profiles, m.locator(profiles), root, changed, keep)
+	m.log.Printf("Coverage of %s: %.1f%% of %d lines", m.diffScope(), diff.percent(), diff.Lines)
+	if !belowThreshold(diff.percent(), m.args.diffcoverage) {
+		return nil, nil
+	}
+	return []violation{{Rule: "diffcoverage", Scope: m.diffScope(), Required: m.args.diffcoverage, Actual: diff.percent(), Files: diff.Files}}, nil
+}
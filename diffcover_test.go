@@ -0,0 +1,84 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+// diffVCS is a repository with fixed changes and authors
+type diffVCS struct {
+	nullVCS
+	dir     string
+	changed map[string][]int
+	authors map[string][]string
+}
+
+func (d diffVCS) root() string {
+	return d.dir
+}
+
+func (d diffVCS) changedLines(base string) (map[string][]int, error) {
+	return d.changed, nil
+}
+
+func (d diffVCS) lineAuthors(file string) ([]string, error) {
+	return d.authors[file], nil
+}
+
+func TestAuthorMatches(t *testing.T) {
+	for _, want := range []string{"Ann Lee", "ann@example.com", "<ANN@example.com>"} {
+		if !authorMatches("Ann Lee <ann@example.com>", []string{"bob", want}) {
+			t.Errorf("Expected %s to match", want)
+		}
+	}
+	if authorMatches("Ann Lee <ann@example.com>", []string{"Ann"}) {
+		t.Errorf("Expected part of a name not to match")
+	}
+}
+
+func TestEvaluateDiffCoverage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestEvaluateDiffCoverage")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	profiles := []*cover.Profile{{FileName: "example.com/a/a.go", Mode: "set", Blocks: []cover.ProfileBlock{
+		{StartLine: 3, EndLine: 4, NumStmt: 2, Count: 1},
+		{StartLine: 6, EndLine: 7, NumStmt: 2, Count: 0},
+	}}}
+	m := &gocoverdir{
+		log:   log.New(ioutil.Discard, "", 0),
+		files: &fileLocator{pkgDirs: map[string]string{"example.com/a": filepath.Join(dir, "a")}, root: dir},
+		repo: diffVCS{
+			dir:     dir,
+			changed: map[string][]int{"a/a.go": {1, 3, 6, 7}, "README.md": {1}},
+			authors: map[string][]string{"a/a.go": {"Ann <ann@example.com>", "", "Ann <ann@example.com>", "", "", "Bob <bob@example.com>", "Bob <bob@example.com>"}},
+		},
+	}
+	m.args.diffbase = "main"
+	m.args.diffcoverage = 50
+	violations, err := m.evaluateDiffCoverage(profiles)
+	noError(t, err)
+	if len(violations) != 1 || violations[0].Actual != float64(1)*100/3 || !reflect.DeepEqual(violations[0].Files, []string{"a/a.go"}) {
+		t.Errorf("Expected one of three changed code lines to be covered, saw %+v", violations)
+	}
+	m.args.diffauthor = "ann@example.com"
+	violations, err = m.evaluateDiffCoverage(profiles)
+	noError(t, err)
+	if len(violations) != 0 {
+		t.Errorf("Expected Ann's changed line to be covered, saw %+v", violations)
+	}
+	m.args.diffauthor = "Bob"
+	violations, err = m.evaluateDiffCoverage(profiles)
+	noError(t, err)
+	if len(violations) != 1 || violations[0].Actual != 0 || violations[0].Scope != "lines changed since main by Bob" {
+		t.Errorf("Expected none of Bob's changed lines to be covered, saw %+v", violations)
+	}
+}
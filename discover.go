@@ -0,0 +1,290 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Package is a directory a Discoverer thinks may need testing, with what it knows about it
+type Package struct {
+	// Dir is relative to the current directory
+	Dir string
+	// ImportPath is empty if the Discoverer does not know it
+	ImportPath string
+}
+
+// Discoverer finds the package directories to test.  Programs that already know the package set, like a build graph
+// service, pass their own in Options to RunWith, or use -discover cmd:, instead of walking the tree.  Unless they
+// were walked, directories that are gone or have no Go files are ignored
+type Discoverer interface {
+	// Source names the Discoverer in logs and -explain
+	Source() string
+	Discover() ([]Package, error)
+}
+
+// WalkDiscoverer searches every directory under the current one, with the -depth and -ignoredirs of the run it is
+// used in.  It is the default
+func WalkDiscoverer() Discoverer {
+	return walkDiscoverer{}
+}
+
+// GoListDiscoverer asks 'go list' for the packages matching patterns, or ./... without any
+func GoListDiscoverer(patterns ...string) Discoverer {
+	return goListDiscoverer{patterns: patterns}
+}
+
+// GitDiscoverer uses the directories of the Go files git tracks, except those under a directory named in ignoreDirs
+func GitDiscoverer(ignoreDirs ...string) Discoverer {
+	ignoreDirSet := make(map[string]struct{}, len(ignoreDirs))
+	for _, dir := range ignoreDirs {
+		ignoreDirSet[dir] = struct{}{}
+	}
+	return gitDiscoverer{ignoreDirSet: ignoreDirSet}
+}
+
+// CommandDiscoverer runs a shell command that prints one directory per line, optionally followed by a tab and its
+// import path
+func CommandDiscoverer(command string) Discoverer {
+	return commandDiscoverer{command: command}
+}
+
+// walkDiscoverer searches every directory under the current one, up to -depth and skipping -ignoredirs.  Unlike the
+// others, what it finds is already checked for Go files.  Without m, it walks for the run it is injected into
+type walkDiscoverer struct {
+	m *gocoverdir
+}
+
+func (w walkDiscoverer) Source() string {
+	return "walk"
+}
+
+func (w walkDiscoverer) Discover() ([]Package, error) {
+	dirs, err := w.m.findTestDirs(".", 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]Package, 0, len(dirs))
+	for _, dir := range dirs {
+		ret = append(ret, Package{Dir: dir})
+	}
+	return ret, nil
+}
+
+// goListDiscoverer asks 'go list' for the packages matching patterns
+type goListDiscoverer struct {
+	patterns []string
+}
+
+func (g goListDiscoverer) Source() string {
+	return "go list"
+}
+
+func (g goListDiscoverer) Discover() ([]Package, error) {
+	patterns := g.patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", append([]string{"list", "-e", "-f", "{{.Dir}}\t{{.ImportPath}}"}, patterns...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("cannot list packages %s: %s\n%s", strings.Join(patterns, " "), err, stderr.String())
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := parseDiscovered(&stdout)
+	if err != nil {
+		return nil, err
+	}
+	for i, pkg := range pkgs {
+		rel, err := filepath.Rel(cwd, pkg.Dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("package directory %s is outside the current directory", pkg.Dir)
+		}
+		pkgs[i].Dir = rel
+	}
+	return pkgs, nil
+}
+
+// gitDiscoverer uses the directories of the Go files git tracks, which is much faster than a walk in trees with
+// large untracked or generated directories
+type gitDiscoverer struct {
+	ignoreDirSet map[string]struct{}
+}
+
+func (g gitDiscoverer) Source() string {
+	return "git ls-files"
+}
+
+func (g gitDiscoverer) Discover() ([]Package, error) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", "ls-files", "--", "*.go")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("cannot list files with git: %s\n%s", err, stderr.String())
+	}
+	return gitPackages(strings.Split(stdout.String(), "\n"), g.ignoreDirSet), nil
+}
+
+// gitPackages are the directories of files, without the ones under a directory named in ignoreDirSet
+func gitPackages(files []string, ignoreDirSet map[string]struct{}) []Package {
+	var ret []Package
+	seen := make(map[string]struct{})
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		dir := filepath.Dir(filepath.FromSlash(file))
+		if _, exists := seen[dir]; exists {
+			continue
+		}
+		seen[dir] = struct{}{}
+		if ignoredPath(dir, ignoreDirSet) {
+			continue
+		}
+		ret = append(ret, Package{Dir: dir})
+	}
+	return ret
+}
+
+// ignoredPath is true if any element of dir is in ignoreDirSet
+func ignoredPath(dir string, ignoreDirSet map[string]struct{}) bool {
+	for _, element := range strings.Split(filepath.ToSlash(dir), "/") {
+		if _, ignored := ignoreDirSet[element]; ignored {
+			return true
+		}
+	}
+	return false
+}
+
+// commandDiscoverer runs a shell command that prints one directory per line, optionally followed by a tab and its
+// import path
+type commandDiscoverer struct {
+	command string
+}
+
+func (c commandDiscoverer) Source() string {
+	return "discover command"
+}
+
+func (c commandDiscoverer) Discover() ([]Package, error) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd := exec.Command("sh", "-c", c.command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("discover command %q failed: %s\n%s", c.command, err, stderr.String())
+	}
+	return parseDiscovered(&stdout)
+}
+
+// parseDiscovered reads lines of a directory, optionally followed by a tab and an import path, without duplicates
+func parseDiscovered(r io.Reader) ([]Package, error) {
+	dirs, err := readPackageList(r)
+	if err != nil {
+		return nil, err
+	}
+	var ret []Package
+	seen := make(map[string]struct{}, len(dirs))
+	for _, line := range dirs {
+		pkg := Package{Dir: line}
+		if idx := strings.Index(line, "\t"); idx >= 0 {
+			pkg = Package{Dir: filepath.Clean(strings.TrimSpace(line[:idx])), ImportPath: strings.TrimSpace(line[idx+1:])}
+		}
+		if _, exists := seen[pkg.Dir]; exists {
+			continue
+		}
+		seen[pkg.Dir] = struct{}{}
+		ret = append(ret, pkg)
+	}
+	return ret, nil
+}
+
+// selectDiscoverer picks how to find packages: an injected Discoverer, -stdin, the package arguments, or -discover
+func (m *gocoverdir) selectDiscoverer() (Discoverer, error) {
+	if m.discoverer != nil {
+		if _, walks := m.discoverer.(walkDiscoverer); walks {
+			return walkDiscoverer{m: m}, nil
+		}
+		return m.discoverer, nil
+	}
+	if m.args.stdin && len(m.args.packages) > 0 {
+		return nil, fmt.Errorf("-stdin cannot be used with package arguments")
+	}
+	if (m.args.stdin || len(m.args.packages) > 0) && m.isFlagSet("discover") {
+		return nil, fmt.Errorf("-discover cannot be used with -stdin or package arguments")
+	}
+	if m.args.stdin {
+		return readerDiscoverer{r: os.Stdin}, nil
+	}
+	if len(m.args.packages) > 0 {
+		return goListDiscoverer{patterns: m.args.packages}, nil
+	}
+	switch {
+	case m.args.discover == "walk":
+		return walkDiscoverer{m: m}, nil
+	case m.args.discover == "golist":
+		return goListDiscoverer{}, nil
+	case m.args.discover == "git":
+		return gitDiscoverer{ignoreDirSet: m.ignoreDirSet}, nil
+	case strings.HasPrefix(m.args.discover, "cmd:") && strings.TrimPrefix(m.args.discover, "cmd:") != "":
+		return commandDiscoverer{command: strings.TrimPrefix(m.args.discover, "cmd:")}, nil
+	}
+	return nil, fmt.Errorf("unknown -discover %q: expected walk, golist, git or cmd:<command>", m.args.discover)
+}
+
+// readerDiscoverer reads the package list from r, for -stdin
+type readerDiscoverer struct {
+	r io.Reader
+}
+
+func (s readerDiscoverer) Source() string {
+	return "stdin"
+}
+
+func (s readerDiscoverer) Discover() ([]Package, error) {
+	return parseDiscovered(s.r)
+}
+
+// discoverDirs finds the directories to test with the selected Discoverer.  Unless they were walked, directories that
+// are gone or have no Go files, which is common when the list comes from a diff, are logged and ignored
+func (m *gocoverdir) discoverDirs() ([]string, error) {
+	d, err := m.selectDiscoverer()
+	if err != nil {
+		return nil, err
+	}
+	pkgs, err := d.Discover()
+	if err != nil {
+		return nil, err
+	}
+	listed := make([]string, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		listed = append(listed, pkg.Dir)
+		if pkg.ImportPath != "" {
+			if m.importPaths == nil {
+				m.importPaths = make(map[string]string)
+			}
+			m.importPaths[pkg.Dir] = pkg.ImportPath
+		}
+	}
+	if _, walked := d.(walkDiscoverer); walked {
+		return listed, nil
+	}
+	dirs, err := m.listedDirs(listed, d.Source())
+	if err != nil {
+		return nil, err
+	}
+	return dirs, m.checkMaxPackages(dirs)
+}
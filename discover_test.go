@@ -0,0 +1,103 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDiscovered(t *testing.T) {
+	pkgs, err := parseDiscovered(strings.NewReader("a\texample.com/m/a\n./b\n\na/\texample.com/m/a\n"))
+	noError(t, err)
+	expected := []Package{{Dir: "a", ImportPath: "example.com/m/a"}, {Dir: "b"}}
+	if !reflect.DeepEqual(pkgs, expected) {
+		t.Errorf("Unexpected packages %v", pkgs)
+	}
+}
+
+func TestGitPackages(t *testing.T) {
+	files := []string{"main.go", "a/a.go", "a/a_test.go", "vendor/v/v.go", "b/testdata/x.go", ""}
+	pkgs := gitPackages(files, map[string]struct{}{"vendor": {}, "testdata": {}})
+	expected := []Package{{Dir: "."}, {Dir: "a"}}
+	if !reflect.DeepEqual(pkgs, expected) {
+		t.Errorf("Unexpected packages %v", pkgs)
+	}
+}
+
+func TestDiscoverDirsInjected(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDiscoverDirsInjected")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	noError(t, os.Mkdir(filepath.Join(dir, "a"), 0755))
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a", "a.go"), []byte("package a\n"), 0644))
+	a := filepath.Join(dir, "a")
+	m := &gocoverdir{
+		log:        log.New(ioutil.Discard, "", 0),
+		discoverer: CommandDiscoverer("printf '" + a + "\\texample.com/m/a\\n" + filepath.Join(dir, "gone") + "\\n'"),
+	}
+	dirs, err := m.discoverDirs()
+	noError(t, err)
+	if !reflect.DeepEqual(dirs, []string{a}) {
+		t.Errorf("Unexpected dirs %v", dirs)
+	}
+	if !reflect.DeepEqual(m.dirImportPaths(dirs), map[string]string{a: "example.com/m/a"}) {
+		t.Errorf("Unexpected import paths %v", m.importPaths)
+	}
+	m.discoverer = staticDiscoverer{{Dir: a}, {Dir: filepath.Join(dir, "gone")}}
+	if dirs, err := m.discoverDirs(); err != nil || !reflect.DeepEqual(dirs, []string{a}) {
+		t.Errorf("Unexpected dirs from a custom Discoverer %v %v", dirs, err)
+	}
+	m.discoverer = CommandDiscoverer("exit 3")
+	if _, err := m.discoverDirs(); err == nil {
+		t.Errorf("Expected an error from a failing discover command")
+	}
+}
+
+func TestSelectDiscoverer(t *testing.T) {
+	m := &gocoverdir{}
+	for name, expected := range map[string]Discoverer{
+		"walk":         walkDiscoverer{m: m},
+		"golist":       goListDiscoverer{},
+		"git":          gitDiscoverer{},
+		"cmd:ls -d */": commandDiscoverer{command: "ls -d */"},
+	} {
+		m.args.discover = name
+		d, err := m.selectDiscoverer()
+		noError(t, err)
+		if !reflect.DeepEqual(d, expected) {
+			t.Errorf("-discover %s selected %v", name, d)
+		}
+	}
+	for _, name := range []string{"find", "cmd:"} {
+		m.args.discover = name
+		if _, err := m.selectDiscoverer(); err == nil {
+			t.Errorf("Expected an error for -discover %s", name)
+		}
+	}
+	m.args.discover = "walk"
+	m.args.packages = []string{"./a/..."}
+	d, err := m.selectDiscoverer()
+	noError(t, err)
+	if !reflect.DeepEqual(d, goListDiscoverer{patterns: []string{"./a/..."}}) {
+		t.Errorf("Package arguments selected %v", d)
+	}
+	m.discoverer = WalkDiscoverer()
+	if d, err := m.selectDiscoverer(); err != nil || !reflect.DeepEqual(d, walkDiscoverer{m: m}) {
+		t.Errorf("An injected walk should walk for the run, saw %v %v", d, err)
+	}
+}
+
+// staticDiscoverer is a Discoverer like an embedding program would write
+type staticDiscoverer []Package
+
+func (s staticDiscoverer) Source() string {
+	return "static"
+}
+
+func (s staticDiscoverer) Discover() ([]Package, error) {
+	return s, nil
+}
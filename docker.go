@@ -0,0 +1,39 @@
+package gocoverdir
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// testCommand builds the command to run executable in dir, relative to the working directory, inside a docker
+// container when -docker is set.  The working directory and store directory are mounted at the same paths inside the
+// container so relative package paths and -outputdir work unchanged.
+func (m *gocoverdir) testCommand(dir string, executable string, args ...string) (*exec.Cmd, error) {
+	env := append(m.testEnv(), depToolEnv(detectDepTool(dir))...)
+	if m.args.docker == "" {
+		cmd := exec.Command(executable, args...)
+		if dir != "." {
+			cmd.Dir = dir
+		}
+		if len(env) > 0 {
+			cmd.Env = append(cmd.Environ(), env...)
+		}
+		return cmd, nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	dockerArgs := []string{"run", "--rm", "-v", cwd + ":" + cwd, "-v", m.storeDir + ":" + m.storeDir, "-w", filepath.Join(cwd, dir)}
+	for _, env := range filepath.SplitList(m.args.dockerenv) {
+		if _, exists := os.LookupEnv(env); exists {
+			dockerArgs = append(dockerArgs, "-e", env)
+		}
+	}
+	for _, kv := range env {
+		dockerArgs = append(dockerArgs, "-e", kv)
+	}
+	dockerArgs = append(dockerArgs, m.args.docker, executable)
+	return exec.Command("docker", append(dockerArgs, args...)...), nil
+}
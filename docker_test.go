@@ -0,0 +1,23 @@
+package gocoverdir
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTestCommandDocker(t *testing.T) {
+	m := gocoverdir{storeDir: "/tmp/store"}
+	m.args.docker = "golang:latest"
+	m.args.dockerenv = "GOCOVERDIR_TEST_ENV:GOCOVERDIR_UNSET_ENV"
+	noError(t, os.Setenv("GOCOVERDIR_TEST_ENV", "1"))
+	defer os.Unsetenv("GOCOVERDIR_TEST_ENV")
+	cmd, err := m.testCommand(".", "go", "test", "./a")
+	noError(t, err)
+	cwd, err := os.Getwd()
+	noError(t, err)
+	expected := "docker run --rm -v " + cwd + ":" + cwd + " -v /tmp/store:/tmp/store -w " + cwd + " -e GOCOVERDIR_TEST_ENV golang:latest go test ./a"
+	if strings.Join(cmd.Args, " ") != expected {
+		t.Errorf("Unexpected command %s", strings.Join(cmd.Args, " "))
+	}
+}
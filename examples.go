@@ -0,0 +1,43 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"strings"
+)
+
+// setExamples records which of the tests that ran were examples, and whether nothing but examples ran.  Packages
+// covered only by examples are easy to miss: an example without an output comment compiles but never runs.
+func (m *gocoverdir) setExamples(result *packageResult, ran []string) {
+	if m.args.examples == "" {
+		return
+	}
+	for _, test := range ran {
+		if strings.HasPrefix(test, "Example") {
+			result.examples = append(result.examples, test)
+		}
+	}
+	result.examplesOnly = m.args.examples != "only" && len(ran) > 0 && len(result.examples) == len(ran)
+}
+
+// verifyExamples checks the -examples mode
+func (m *gocoverdir) verifyExamples() error {
+	switch m.args.examples {
+	case "", "on", "only":
+		return nil
+	}
+	return fmt.Errorf("-examples must be on or only, not %s", m.args.examples)
+}
+
+// examplesOnlySummary lists the packages whose only coverage came from examples, or "" if there are none
+func examplesOnlySummary(results []packageResult) string {
+	var dirs []string
+	for _, result := range results {
+		if result.examplesOnly {
+			dirs = append(dirs, result.dir)
+		}
+	}
+	if len(dirs) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("Only examples cover %d packages: %s", len(dirs), strings.Join(dirs, " "))
+}
@@ -0,0 +1,32 @@
+package gocoverdir
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetExamples(t *testing.T) {
+	m := &gocoverdir{}
+	result := packageResult{dir: "a"}
+	m.setExamples(&result, []string{"ExampleA"})
+	if result.examples != nil || result.examplesOnly {
+		t.Errorf("Expected examples to be ignored without -examples %+v", result)
+	}
+	m.args.examples = "on"
+	m.setExamples(&result, []string{"ExampleA", "ExampleB"})
+	if strings.Join(result.examples, ",") != "ExampleA,ExampleB" || !result.examplesOnly {
+		t.Errorf("Expected an examples only package %+v", result)
+	}
+	other := packageResult{dir: "b"}
+	m.setExamples(&other, []string{"TestB", "ExampleB"})
+	if other.examplesOnly {
+		t.Errorf("Did not expect b to be examples only")
+	}
+	if summary := examplesOnlySummary([]packageResult{result, other}); summary != "Only examples cover 1 packages: a" {
+		t.Errorf("Unexpected summary %q", summary)
+	}
+	m.args.examples = "always"
+	if err := m.verifyExamples(); err == nil {
+		t.Errorf("Expected an invalid -examples mode to fail")
+	}
+}
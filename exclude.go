@@ -0,0 +1,205 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// excludeRules are the constructs the config's exclude can leave out of coverage.  Each returns the source ranges,
+// start to end, that the constructs it finds in f span.  Only blocks entirely inside one are left out
+var excludeRules = map[string]func(f *ast.File) [][2]token.Pos{
+	// return-err is an if body that only returns err, like if err != nil { return nil, err }, or wraps it
+	"return-err": func(f *ast.File) [][2]token.Pos {
+		var ret [][2]token.Pos
+		ast.Inspect(f, func(n ast.Node) bool {
+			if ifStmt, ok := n.(*ast.IfStmt); ok && len(ifStmt.Body.List) == 1 && returnsErr(ifStmt.Body.List[0]) {
+				ret = append(ret, [2]token.Pos{ifStmt.Body.Lbrace, ifStmt.Body.End()})
+			}
+			return true
+		})
+		return ret
+	},
+	// panic is a block or case whose only statement is a call to panic
+	"panic": func(f *ast.File) [][2]token.Pos {
+		var ret [][2]token.Pos
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.BlockStmt:
+				if len(n.List) == 1 && isPanic(n.List[0]) {
+					ret = append(ret, [2]token.Pos{n.Lbrace, n.End()})
+				}
+			case *ast.CaseClause:
+				if len(n.Body) == 1 && isPanic(n.Body[0]) {
+					ret = append(ret, [2]token.Pos{n.Colon, n.End()})
+				}
+			case *ast.CommClause:
+				if len(n.Body) == 1 && isPanic(n.Body[0]) {
+					ret = append(ret, [2]token.Pos{n.Colon, n.End()})
+				}
+			}
+			return true
+		})
+		return ret
+	},
+	// stringer is the body of a String() string method
+	"stringer": func(f *ast.File) [][2]token.Pos {
+		var ret [][2]token.Pos
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Body == nil || fn.Name.Name != "String" || fn.Type.Params.NumFields() != 0 {
+				continue
+			}
+			if results := fn.Type.Results; results.NumFields() == 1 {
+				if ident, ok := results.List[0].Type.(*ast.Ident); ok && ident.Name == "string" {
+					ret = append(ret, [2]token.Pos{fn.Body.Lbrace, fn.Body.End()})
+				}
+			}
+		}
+		return ret
+	},
+}
+
+// returnsErr is true for return statements whose last result is err, or a call wrapping it
+func returnsErr(stmt ast.Stmt) bool {
+	ret, ok := stmt.(*ast.ReturnStmt)
+	if !ok || len(ret.Results) == 0 {
+		return false
+	}
+	last := ret.Results[len(ret.Results)-1]
+	if call, ok := last.(*ast.CallExpr); ok {
+		for _, arg := range call.Args {
+			if isErrIdent(arg) {
+				return true
+			}
+		}
+		return false
+	}
+	return isErrIdent(last)
+}
+
+func isErrIdent(expr ast.Expr) bool {
+	ident, ok := expr.(*ast.Ident)
+	return ok && ident.Name == "err"
+}
+
+func isPanic(stmt ast.Stmt) bool {
+	expr, ok := stmt.(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	call, ok := expr.X.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := call.Fun.(*ast.Ident)
+	return ok && ident.Name == "panic"
+}
+
+func verifyExclude(rules []string) error {
+	for _, rule := range rules {
+		if _, exists := excludeRules[rule]; !exists {
+			names := make([]string, 0, len(excludeRules))
+			for name := range excludeRules {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return fmt.Errorf("unknown exclude rule %q: use %s", rule, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// excludedRanges parses the file at filename and returns the ranges rules match, as line and column positions
+func excludedRanges(filename string, rules []string) ([][2]token.Position, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var ret [][2]token.Position
+	for _, rule := range rules {
+		for _, r := range excludeRules[rule](f) {
+			ret = append(ret, [2]token.Position{fset.Position(r[0]), fset.Position(r[1])})
+		}
+	}
+	return ret, nil
+}
+
+// notAfter is true if line and col come no later than pos
+func notAfter(line int, col int, pos token.Position) bool {
+	return line < pos.Line || (line == pos.Line && col <= pos.Column)
+}
+
+// notBefore is true if line and col come no earlier than pos
+func notBefore(line int, col int, pos token.Position) bool {
+	return line > pos.Line || (line == pos.Line && col >= pos.Column)
+}
+
+// excludeBlocks drops the blocks of p that lie entirely inside one of ranges, returning how many statements it dropped
+func excludeBlocks(p *cover.Profile, ranges [][2]token.Position) int {
+	dropped := 0
+	blocks := p.Blocks[:0]
+	for _, block := range p.Blocks {
+		excluded := false
+		for _, r := range ranges {
+			if notBefore(block.StartLine, block.StartCol, r[0]) && notAfter(block.EndLine, block.EndCol, r[1]) {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			dropped += block.NumStmt
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	p.Blocks = blocks
+	return dropped
+}
+
+// excludeConstructs rewrites -coverprofile without the blocks the config's exclude rules match, so every report and
+// gate leaves them out of coverage accounting
+func (m *gocoverdir) excludeConstructs() error {
+	profiles, err := readProfiles(m.args.coverprofile)
+	if err != nil {
+		return err
+	}
+	m.excludeMatching(profiles)
+	return writeFileWith(m.args.coverprofile, func(w io.Writer) error {
+		return writeProfiles(w, profiles)
+	})
+}
+
+// excludeMatching drops the blocks of profiles the config's exclude rules match.  Files whose source cannot be found
+// or parsed keep all their blocks
+func (m *gocoverdir) excludeMatching(profiles []*cover.Profile) {
+	loc := m.locator(profiles)
+	dropped := 0
+	var skipped []string
+	for _, p := range profiles {
+		filename := loc.path(p)
+		if filename == "" {
+			skipped = append(skipped, p.FileName)
+			continue
+		}
+		ranges, err := excludedRanges(filename, m.config.Exclude)
+		if err != nil {
+			m.log.Printf("Unable to apply exclude rules to %s: %s", filename, err)
+			skipped = append(skipped, p.FileName)
+			continue
+		}
+		dropped += excludeBlocks(p, ranges)
+	}
+	m.log.Printf("Excluded %d statements matching %s from %d of %d files", dropped, strings.Join(m.config.Exclude, ", "), len(profiles)-len(skipped), len(profiles))
+	if len(skipped) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: exclude rules were not applied to %d files whose source could not be read: %s\n", len(skipped), strings.Join(skipped, ", "))
+	}
+}
@@ -0,0 +1,83 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestExcludeBlocks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdir")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	source := `package c
+
+type T int
+
+func (t T) String() string {
+	return "t"
+}
+
+func F(x int) (int, error) {
+	err := check(x)
+	if err != nil {
+		return 0, fmt.Errorf("checking: %s", err)
+	}
+	switch x {
+	case 1:
+		return 1, nil
+	default:
+		panic("bad")
+	}
+}
+`
+	filename := filepath.Join(dir, "c.go")
+	noError(t, ioutil.WriteFile(filename, []byte(source), 0644))
+	blocks := []cover.ProfileBlock{
+		{StartLine: 5, StartCol: 28, EndLine: 7, EndCol: 2, NumStmt: 1},
+		{StartLine: 9, StartCol: 28, EndLine: 11, EndCol: 16, NumStmt: 2, Count: 1},
+		{StartLine: 11, StartCol: 16, EndLine: 13, EndCol: 3, NumStmt: 1},
+		{StartLine: 14, StartCol: 2, EndLine: 14, EndCol: 11, NumStmt: 1, Count: 1},
+		{StartLine: 15, StartCol: 9, EndLine: 16, EndCol: 16, NumStmt: 1, Count: 1},
+		{StartLine: 17, StartCol: 10, EndLine: 18, EndCol: 15, NumStmt: 1},
+	}
+	for _, tc := range []struct {
+		rules     []string
+		remaining int
+	}{
+		{rules: []string{"stringer"}, remaining: 5},
+		{rules: []string{"return-err"}, remaining: 5},
+		{rules: []string{"panic"}, remaining: 5},
+		{rules: []string{"return-err", "panic", "stringer"}, remaining: 3},
+	} {
+		ranges, err := excludedRanges(filename, tc.rules)
+		noError(t, err)
+		p := &cover.Profile{FileName: "example.com/c/c.go", Blocks: append([]cover.ProfileBlock(nil), blocks...)}
+		dropped := excludeBlocks(p, ranges)
+		if len(p.Blocks) != tc.remaining || dropped != len(blocks)-tc.remaining {
+			t.Errorf("%v: expected %d blocks left, got %v", tc.rules, tc.remaining, p.Blocks)
+		}
+	}
+	if err := verifyExclude([]string{"return-nil"}); err == nil {
+		t.Errorf("Expected an unknown rule to be refused")
+	}
+
+	m := gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	m.args.coverprofile = filepath.Join(dir, "coverage.out")
+	m.config = &config{Exclude: []string{"stringer"}}
+	m.files = &fileLocator{pkgDirs: map[string]string{"example.com/c": dir}}
+	profile := "mode: set\nexample.com/c/c.go:5.28,7.2 1 0\nexample.com/c/c.go:9.28,11.16 2 1\nexample.com/missing/m.go:1.1,2.2 1 0\n"
+	noError(t, ioutil.WriteFile(m.args.coverprofile, []byte(profile), 0644))
+	noError(t, gzipFile(m.args.coverprofile))
+	noError(t, os.Rename(m.args.coverprofile+".gz", m.args.coverprofile))
+	noError(t, m.excludeConstructs())
+	profiles, err := readProfiles(m.args.coverprofile)
+	noError(t, err)
+	if len(profiles) != 2 || len(profiles[0].Blocks) != 1 || len(profiles[1].Blocks) != 1 {
+		t.Errorf("Expected the stringer dropped from a gzipped profile and the missing file kept, saw %v", profiles)
+	}
+}
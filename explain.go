@@ -0,0 +1,88 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// explanation is the decision discovery made about one directory, for -explain
+type explanation struct {
+	Dir      string `json:"dir"`
+	Decision string `json:"decision"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// explainer keeps the latest decision about each directory, in the order directories were first seen
+type explainer struct {
+	order     []string
+	decisions map[string]explanation
+}
+
+func (e *explainer) record(dir string, decision string, reason string) {
+	if e.decisions == nil {
+		e.decisions = make(map[string]explanation)
+	}
+	if _, exists := e.decisions[dir]; !exists {
+		e.order = append(e.order, dir)
+	}
+	e.decisions[dir] = explanation{Dir: dir, Decision: decision, Reason: reason}
+}
+
+func (e *explainer) explanations() []explanation {
+	ret := make([]explanation, 0, len(e.order))
+	for _, dir := range e.order {
+		ret = append(ret, e.decisions[dir])
+	}
+	return ret
+}
+
+func writeExplanationsText(w io.Writer, explanations []explanation) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "DIRECTORY\tDECISION\tREASON\n")
+	for _, e := range explanations {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Dir, e.Decision, e.Reason)
+	}
+	return tw.Flush()
+}
+
+func writeExplanationsJSON(w io.Writer, explanations []explanation) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(explanations)
+}
+
+// hasTestFiles is true if any of files is a Go test file
+func hasTestFiles(files []os.FileInfo) bool {
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), "_test.go") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeExplain writes every discovery decision to -explain, as JSON if the file ends in .json and as a table
+// otherwise.  - writes to stderr
+func (m *gocoverdir) writeExplain() error {
+	if m.args.explain == "" {
+		return nil
+	}
+	explanations := m.explained.explanations()
+	for i := range explanations {
+		explanations[i].Dir = m.emittedPath(explanations[i].Dir)
+	}
+	write := writeExplanationsText
+	if strings.HasSuffix(m.args.explain, ".json") {
+		write = writeExplanationsJSON
+	}
+	if m.args.explain == "-" {
+		return write(os.Stderr, explanations)
+	}
+	return writeFileWith(m.args.explain, func(w io.Writer) error {
+		return write(w, explanations)
+	})
+}
@@ -0,0 +1,77 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExplainDiscovery(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestExplainDiscovery")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	write := func(name string, contents string) {
+		noError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		noError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+	write("a/a.go", "package a\n")
+	write("a/a_test.go", "package a\n")
+	write("b/b.go", "package b\n")
+	write("docs/README", "docs\n")
+	write("vendor/v/v.go", "package v\n")
+	write("a/deep/deeper/d.go", "package d\n")
+	m := &gocoverdir{
+		log:          log.New(ioutil.Discard, "", 0),
+		ignoreDirSet: map[string]struct{}{"vendor": {}},
+	}
+	m.args.depth = 2
+	dirs, err := m.findTestDirs(dir, 0, nil)
+	noError(t, err)
+	if len(dirs) != 2 {
+		t.Errorf("Unexpected dirs %v", dirs)
+	}
+	decisions := make(map[string]string)
+	for _, e := range m.explained.explanations() {
+		rel, err := filepath.Rel(dir, e.Dir)
+		noError(t, err)
+		decisions[filepath.ToSlash(rel)] = e.Decision
+	}
+	expected := map[string]string{
+		".":             "no Go files",
+		"a":             "tested",
+		"a/deep":        "no Go files",
+		"a/deep/deeper": "too deep",
+		"b":             "tested",
+		"docs":          "no Go files",
+		"vendor":        "ignored",
+	}
+	if !reflect.DeepEqual(decisions, expected) {
+		t.Errorf("Unexpected decisions %v", decisions)
+	}
+}
+
+func TestExplainerRecord(t *testing.T) {
+	e := explainer{}
+	e.record("a", "tested", "")
+	e.record("b", "no Go files", "")
+	e.record("a", "skipped", "does not fit in -maxruntime")
+	expected := []explanation{{Dir: "a", Decision: "skipped", Reason: "does not fit in -maxruntime"}, {Dir: "b", Decision: "no Go files"}}
+	if !reflect.DeepEqual(e.explanations(), expected) {
+		t.Errorf("Unexpected explanations %v", e.explanations())
+	}
+	buf := bytes.Buffer{}
+	noError(t, writeExplanationsText(&buf, expected))
+	if !strings.Contains(buf.String(), "skipped") || strings.Count(buf.String(), "\n") != 3 {
+		t.Errorf("Unexpected text %q", buf.String())
+	}
+	buf.Reset()
+	noError(t, writeExplanationsJSON(&buf, expected))
+	if !strings.Contains(buf.String(), `"decision": "no Go files"`) {
+		t.Errorf("Unexpected JSON %s", buf.String())
+	}
+}
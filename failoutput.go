@@ -0,0 +1,46 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// compileFailOutput compiles the failoutput config rules
+func compileFailOutput(patterns []string) ([]*regexp.Regexp, error) {
+	ret := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid failoutput rule %q: %s", pattern, err)
+		}
+		ret = append(ret, re)
+	}
+	return ret, nil
+}
+
+// outputMatcher collects the lines of test output any of its rules match
+type outputMatcher struct {
+	rules   []*regexp.Regexp
+	matched []string
+}
+
+func (o *outputMatcher) line(line string) {
+	trimmed := strings.TrimRight(line, "\n")
+	for _, rule := range o.rules {
+		if rule.MatchString(trimmed) {
+			o.matched = append(o.matched, trimmed)
+			return
+		}
+	}
+}
+
+// outputMatchError fails a package whose tests passed, but printed lines a failoutput rule matches, like a goroutine
+// leak detector that only warns
+type outputMatchError struct {
+	matched []string
+}
+
+func (o *outputMatchError) Error() string {
+	return fmt.Sprintf("test output matched a failoutput rule:\n  %s", strings.Join(o.matched, "\n  "))
+}
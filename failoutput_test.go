@@ -0,0 +1,44 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFailOutputRules(t *testing.T) {
+	f, err := ioutil.TempFile("", "gocoverdir")
+	noError(t, err)
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(`{"failoutput": ["WARNING: DATA RACE", "goroutine leak"]}`)
+	noError(t, err)
+	noError(t, f.Close())
+	conf, err := loadConfig(f.Name())
+	noError(t, err)
+
+	var out bytes.Buffer
+	w := newTestEventWriter(&out)
+	w.matches.rules = conf.failOutput
+	_, err = w.Write([]byte(`{"Action":"output","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"output","Test":"TestA","Output":"found unexpected goroutine leak: 2 goroutines\n"}
+{"Action":"pass","Test":"TestA"}
+{"Action":"output","Output":"ok\texample.com/a\t0.1s\n"}
+`))
+	noError(t, err)
+	noError(t, w.Close())
+	if len(w.matches.matched) != 1 || w.matches.matched[0] != "found unexpected goroutine leak: 2 goroutines" {
+		t.Errorf("Unexpected matches %v", w.matches.matched)
+	}
+	matchErr := &outputMatchError{matched: w.matches.matched}
+	if !strings.Contains(matchErr.Error(), "goroutine leak: 2 goroutines") {
+		t.Errorf("Unexpected error %s", matchErr)
+	}
+}
+
+func TestCompileFailOutputInvalid(t *testing.T) {
+	if _, err := compileFailOutput([]string{"leak(("}); err == nil {
+		t.Errorf("Expected an invalid rule to fail")
+	}
+}
@@ -0,0 +1,125 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// failureSummary is failure.json in a failed package's bundle
+type failureSummary struct {
+	Dir        string   `json:"dir"`
+	Command    []string `json:"command"`
+	ExitStatus int      `json:"exit_status"`
+	Error      string   `json:"error"`
+	Failed     []string `json:"failed,omitempty"`
+}
+
+func newFailureSummary(result packageResult) failureSummary {
+	ret := failureSummary{
+		Dir:        result.dir,
+		Command:    result.commandLine,
+		ExitStatus: -1,
+		Error:      errorString(result.err),
+		Failed:     result.failed,
+	}
+	if exitErr, isExit := result.err.(*exec.ExitError); isExit {
+		ret.ExitStatus = exitErr.ExitCode()
+	}
+	return ret
+}
+
+// goEnvOutput is 'go env' with the test environment, read once per run
+func (m *gocoverdir) goEnvOutput() []byte {
+	if m.goEnv == nil {
+		var stdout bytes.Buffer
+		cmd := exec.Command("go", "env")
+		cmd.Env = append(cmd.Environ(), m.testEnv()...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stdout
+		if err := cmd.Run(); err != nil {
+			stdout.WriteString(err.Error() + "\n")
+		}
+		m.goEnv = stdout.Bytes()
+	}
+	return m.goEnv
+}
+
+// copyIfExists copies src to dst, doing nothing if there is no src
+func copyIfExists(src string, dst string) error {
+	in, err := os.Open(src)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFileWith(dst, func(w io.Writer) error {
+		_, err := io.Copy(w, in)
+		return err
+	})
+}
+
+// saveFailureBundle keeps everything needed to debug a failed package in artifacts/failures/<dir>: failure.json with
+// the command and exit status, the test output, go env, and the package's profile if it wrote one.  It returns the
+// bundle's directory
+func (m *gocoverdir) saveFailureBundle(result packageResult, profileName string) (string, error) {
+	dir := filepath.Join(m.args.artifacts, "failures", result.dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := writeFileWith(filepath.Join(dir, "failure.json"), func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(newFailureSummary(result))
+	}); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "go-env.txt"), m.goEnvOutput(), 0644); err != nil {
+		return "", err
+	}
+	if err := copyIfExists(filepath.Join(m.args.artifacts, "packages", result.dir, "test.log"), filepath.Join(dir, "test.log")); err != nil {
+		return "", err
+	}
+	if err := copyIfExists(filepath.Join(m.storeDir, profileName), filepath.Join(dir, "coverage.out")); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// writeFailedReport writes the -json and -pb reports of a run whose tests failed.  There is no coverage to report,
+// but the tests point at their failure bundles
+func (m *gocoverdir) writeFailedReport() error {
+	if len(m.results) == 0 || m.config == nil {
+		return nil
+	}
+	r := newReport(nil, m.args.metric, m.emittedResults(), m.emittedSkipped(), m.ci, m.config.Internal)
+	r.RunID = m.args.runid
+	if m.args.json != "" {
+		if err := m.writeJSONReport(r); err != nil {
+			return err
+		}
+	}
+	if m.args.markdown != "" && hasLeaks(m.results) {
+		// Leaked goroutines fail their package, so without this the markdown would never show them
+		if err := writeFileWith(m.args.markdown, func(w io.Writer) error {
+			if _, err := io.WriteString(w, "## Coverage not measured: tests failed\n"); err != nil {
+				return err
+			}
+			return writeMarkdownLeaks(w, m.emittedResults())
+		}); err != nil {
+			return err
+		}
+	}
+	if m.args.pb != "" {
+		return writeFileWith(m.args.pb, func(w io.Writer) error {
+			return writePBReport(w, r)
+		})
+	}
+	return nil
+}
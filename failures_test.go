@@ -0,0 +1,49 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveFailureBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestSaveFailureBundle")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	m := &gocoverdir{log: log.New(ioutil.Discard, "", 0), storeDir: filepath.Join(dir, "store"), goEnv: []byte("GOOS='linux'\n")}
+	m.args.artifacts = filepath.Join(dir, "artifacts")
+	noError(t, os.MkdirAll(filepath.Join(m.args.artifacts, "packages", "a"), 0755))
+	noError(t, os.MkdirAll(m.storeDir, 0755))
+	noError(t, ioutil.WriteFile(filepath.Join(m.args.artifacts, "packages", "a", "test.log"), []byte("--- FAIL: TestA\n"), 0644))
+	noError(t, ioutil.WriteFile(filepath.Join(m.storeDir, "1.out"), []byte("mode: set\n"), 0644))
+
+	result := packageResult{dir: "a", commandLine: []string{"go", "test", "./a"}, err: errors.New("timed out"), failed: []string{"TestA"}}
+	bundle, err := m.saveFailureBundle(result, "1.out")
+	noError(t, err)
+	for _, name := range []string{"failure.json", "go-env.txt", "test.log", "coverage.out"} {
+		if _, err := os.Stat(filepath.Join(bundle, name)); err != nil {
+			t.Errorf("Missing %s: %s", name, err)
+		}
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(bundle, "failure.json"))
+	noError(t, err)
+	var summary failureSummary
+	noError(t, json.Unmarshal(contents, &summary))
+	expected := failureSummary{Dir: "a", Command: []string{"go", "test", "./a"}, ExitStatus: -1, Error: "timed out", Failed: []string{"TestA"}}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+}
+
+func TestFailureExitStatus(t *testing.T) {
+	err := exec.Command("go", "tool", "no-such-tool").Run()
+	if summary := newFailureSummary(packageResult{err: err}); summary.ExitStatus <= 0 {
+		t.Errorf("Expected the exit status of the failed command, saw %d", summary.ExitStatus)
+	}
+}
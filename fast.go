@@ -0,0 +1,38 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"time"
+)
+
+// slowReason is why -fast skips dir, or "" if dir is not slow.  Directories are slow if the config's slowlist names
+// them, or if their last recorded test time is over cutoff
+func slowReason(dir string, slowlist []string, seconds map[string]float64, cutoff time.Duration) string {
+	for _, pattern := range slowlist {
+		if matchPackage(pattern, dir) {
+			return "slow: in the config's slowlist as " + pattern
+		}
+	}
+	if last, exists := seconds[dir]; exists && cutoff > 0 && last > cutoff.Seconds() {
+		return fmt.Sprintf("slow: took %.1fs last run, over -slowcutoff %s", last, cutoff)
+	}
+	return ""
+}
+
+// skipSlow removes slow directories from dirs for -fast, reporting them as skipped so the partial coverage says
+// what it leaves out
+func (m *gocoverdir) skipSlow(dirs []string) []string {
+	if !m.args.fast {
+		return dirs
+	}
+	seconds := m.lastTimings()
+	ret := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		if reason := slowReason(dir, m.config.Slowlist, seconds, m.args.slowcutoff); reason != "" {
+			m.skip(dir, reason)
+			continue
+		}
+		ret = append(ret, dir)
+	}
+	return ret
+}
@@ -0,0 +1,55 @@
+package gocoverdir
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowReason(t *testing.T) {
+	seconds := map[string]float64{"a": 45, "b": 2}
+	slowlist := []string{"./integration/..."}
+	if reason := slowReason("integration/db", slowlist, seconds, 30*time.Second); !strings.Contains(reason, "slowlist") {
+		t.Errorf("Unexpected reason %q", reason)
+	}
+	if reason := slowReason("a", slowlist, seconds, 30*time.Second); reason != "slow: took 45.0s last run, over -slowcutoff 30s" {
+		t.Errorf("Unexpected reason %q", reason)
+	}
+	for _, dir := range []string{"b", "c"} {
+		if reason := slowReason(dir, slowlist, seconds, 30*time.Second); reason != "" {
+			t.Errorf("%s should not be slow: %s", dir, reason)
+		}
+	}
+	if reason := slowReason("a", nil, seconds, 0); reason != "" {
+		t.Errorf("A zero cutoff should not make anything slow: %s", reason)
+	}
+}
+
+func TestSkipSlow(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestSkipSlow")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	m := &gocoverdir{log: log.New(ioutil.Discard, "", 0), config: &config{Slowlist: []string{"./e2e"}}}
+	m.args.timings = filepath.Join(dir, "timings.json")
+	m.args.slowcutoff = time.Second
+	noError(t, writeFileWith(m.args.timings, func(w io.Writer) error {
+		return writeTimings(w, map[string]float64{"a": 5, "b": 0.5})
+	}))
+	dirs := []string{"a", "b", "e2e"}
+	if kept := m.skipSlow(dirs); !reflect.DeepEqual(kept, dirs) {
+		t.Errorf("Without -fast nothing should be skipped: %v", kept)
+	}
+	m.args.fast = true
+	if kept := m.skipSlow(dirs); !reflect.DeepEqual(kept, []string{"b"}) {
+		t.Errorf("Unexpected kept dirs %v", kept)
+	}
+	if len(m.skipped) != 2 || m.skipped[0].dir != "a" || !strings.HasPrefix(m.skipped[1].reason, "slow:") {
+		t.Errorf("Unexpected skipped %v", m.skipped)
+	}
+}
@@ -0,0 +1,107 @@
+package gocoverdir
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// flakyTest is a test that failed, then passed when retried
+type flakyTest struct {
+	Package string `json:"package"`
+	Test    string `json:"test"`
+}
+
+// retryFailures reruns the failed tests of result, up to -retries times, using run.  Tests that pass on a retry are
+// recorded as flaky, and the result passes if every failed test eventually passed
+func (m *gocoverdir) retryFailures(result packageResult, run func(only []string) packageResult) packageResult {
+	if m.args.retries <= 0 || result.err == nil || len(result.failed) == 0 {
+		return result
+	}
+	failing := result.failed
+	for attempt := 1; attempt <= m.args.retries && len(failing) > 0; attempt++ {
+		m.log.Printf("Retrying %s in %s, attempt %d of %d", strings.Join(failing, " "), result.dir, attempt, m.args.retries)
+		retry := run(failing)
+		result.duration += retry.duration
+		if retry.err != nil && len(retry.failed) == 0 {
+			// Failed outside of any test, like a build error or a panic in TestMain
+			return result
+		}
+		stillFailing := make(map[string]struct{}, len(retry.failed))
+		for _, test := range retry.failed {
+			stillFailing[test] = struct{}{}
+		}
+		for _, test := range failing {
+			if _, exists := stillFailing[test]; !exists {
+				result.flaky = append(result.flaky, test)
+			}
+		}
+		failing = retry.failed
+	}
+	result.failed = failing
+	if len(failing) == 0 {
+		m.log.Printf("Flaky tests in %s passed on retry: %s", result.dir, strings.Join(result.flaky, " "))
+		result.err = nil
+	}
+	return result
+}
+
+type flakeCount struct {
+	flakyTest
+	count int
+}
+
+// countFlakes totals how often each test was flaky across history entries, most frequent first
+func countFlakes(entries []historyEntry) []flakeCount {
+	counts := make(map[flakyTest]int)
+	for _, entry := range entries {
+		for _, flaky := range entry.Flaky {
+			counts[flaky]++
+		}
+	}
+	ret := make([]flakeCount, 0, len(counts))
+	for test, count := range counts {
+		ret = append(ret, flakeCount{flakyTest: test, count: count})
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].count != ret[j].count {
+			return ret[i].count > ret[j].count
+		}
+		if ret[i].Package != ret[j].Package {
+			return ret[i].Package < ret[j].Package
+		}
+		return ret[i].Test < ret[j].Test
+	})
+	return ret
+}
+
+// runFlakes prints the tests that were most often flaky in the history store
+func runFlakes(args []string) error {
+	fs := flag.NewFlagSet("flakes", flag.ContinueOnError)
+	history := fs.String("history", "gocoverdir-history.jsonl", "History store written by -history")
+	limit := fs.Int("n", 20, "Number of tests to print.  0 prints all of them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	entries, err := readHistory(*history)
+	if err != nil {
+		return err
+	}
+	flakes := countFlakes(entries)
+	if len(flakes) == 0 {
+		fmt.Printf("No flaky tests in %d runs\n", len(entries))
+		return nil
+	}
+	if *limit > 0 && len(flakes) > *limit {
+		flakes = flakes[:*limit]
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "PACKAGE\tTEST\tFLAKY RUNS\tRATE\n")
+	for _, flake := range flakes {
+		fmt.Fprintf(tw, "%s\t%s\t%d/%d\t%.1f%%\n", flake.Package, flake.Test, flake.count, len(entries), lineRate(flake.count, len(entries))*100)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,48 @@
+package gocoverdir
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestRetryFailures(t *testing.T) {
+	m := &gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	m.args.retries = 2
+	first := packageResult{dir: "a", err: errors.New("exit status 1"), failed: []string{"TestA", "TestB"}}
+	var runs []string
+	retried := m.retryFailures(first, func(only []string) packageResult {
+		runs = append(runs, strings.Join(only, ","))
+		if len(runs) == 1 {
+			return packageResult{err: errors.New("exit status 1"), failed: []string{"TestB"}}
+		}
+		return packageResult{}
+	})
+	if strings.Join(runs, " ") != "TestA,TestB TestB" {
+		t.Errorf("Unexpected retries %v", runs)
+	}
+	if retried.err != nil || strings.Join(retried.flaky, ",") != "TestA,TestB" {
+		t.Errorf("Unexpected result %+v", retried)
+	}
+
+	retried = m.retryFailures(first, func(only []string) packageResult {
+		return packageResult{err: errors.New("exit status 1"), failed: []string{"TestB"}}
+	})
+	if retried.err == nil || strings.Join(retried.flaky, ",") != "TestA" || strings.Join(retried.failed, ",") != "TestB" {
+		t.Errorf("Expected TestB to keep failing %+v", retried)
+	}
+}
+
+func TestCountFlakes(t *testing.T) {
+	entries := []historyEntry{
+		{Flaky: []flakyTest{{Package: "a", Test: "TestA"}, {Package: "b", Test: "TestB"}}},
+		{Flaky: []flakyTest{{Package: "b", Test: "TestB"}}},
+		{},
+	}
+	flakes := countFlakes(entries)
+	if len(flakes) != 2 || flakes[0].Test != "TestB" || flakes[0].count != 2 || flakes[1].Test != "TestA" {
+		t.Errorf("Unexpected flakes %+v", flakes)
+	}
+}
@@ -0,0 +1,85 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"sort"
+	"text/tabwriter"
+
+	"golang.org/x/tools/cover"
+)
+
+// thresholdError is a coverage gate failure.  Its message is a complete summary meant for people, so it is printed
+// as is rather than panicked with
+type thresholdError struct {
+	summary string
+}
+
+func (t *thresholdError) Error() string {
+	return t.summary
+}
+
+// improvement is how much total coverage would rise if every statement of a file were covered
+type improvement struct {
+	file      string
+	uncovered int
+	gain      float64
+}
+
+// topImprovements returns the n files whose full coverage would raise total coverage the most
+func topImprovements(profiles []*cover.Profile, metric string, n int, loc *fileLocator) []improvement {
+	total := 0
+	ret := make([]improvement, 0, len(profiles))
+	for _, p := range profiles {
+		statements, covered := countStatements(p, metric)
+		total += statements
+		if statements > covered {
+			ret = append(ret, improvement{file: loc.name(p), uncovered: statements - covered})
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].uncovered > ret[j].uncovered
+	})
+	if len(ret) > n {
+		ret = ret[:n]
+	}
+	for i := range ret {
+		ret[i].gain = float64(ret[i].uncovered) / float64(total) * 100
+	}
+	return ret
+}
+
+// closingSummary explains a failed coverage gate: where coverage is, where it needs to be, and where to start
+func closingSummary(profiles []*cover.Profile, metric string, violations []violation, required float64, htmlCommand string, loc *fileLocator) string {
+	buf := bytes.Buffer{}
+	fmt.Fprintf(&buf, "\nCoverage check failed\n\n")
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "  Achieved:\t%.1f%% of %s\n", calculateCoverage(profiles, metric), metric)
+	if required > 0 {
+		fmt.Fprintf(w, "  Required:\t%.1f%%\n", required)
+	}
+	w.Flush()
+	fmt.Fprintf(&buf, "\nFailed gates:\n")
+	for _, v := range violations {
+		fmt.Fprintf(&buf, "  %s\n", v)
+	}
+	if improvements := topImprovements(profiles, metric, 5, loc); len(improvements) > 0 {
+		fmt.Fprintf(&buf, "\nBiggest opportunities (total coverage gain if fully covered):\n")
+		w = tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+		for i, imp := range improvements {
+			fmt.Fprintf(w, "  %d.\t%s\t+%.1f%%\t(%d uncovered %s)\n", i+1, imp.file, imp.gain, imp.uncovered, metric)
+		}
+		w.Flush()
+	}
+	fmt.Fprintf(&buf, "\nTo see what is uncovered, run:\n  %s\n", htmlCommand)
+	return buf.String()
+}
+
+// htmlCommand is how to open the coverage HTML of this run
+func (m *gocoverdir) htmlCommand() string {
+	if m.args.html != "" {
+		return openCommand(runtime.GOOS) + " " + fileURL(m.args.html)
+	}
+	return "go tool cover -html " + m.args.coverprofile
+}
@@ -0,0 +1,32 @@
+package gocoverdir
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestClosingSummary(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/nothere/a/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 6, Count: 1}, {NumStmt: 2}}},
+		{FileName: "example.com/nothere/b/b.go", Blocks: []cover.ProfileBlock{{NumStmt: 2}}},
+	}
+	improvements := topImprovements(profiles, "statements", 1, newFileLocator(profiles, false))
+	if len(improvements) != 1 || improvements[0].file != "example.com/nothere/a/a.go" || improvements[0].gain != 20 {
+		t.Errorf("Unexpected improvements %+v", improvements)
+	}
+	violations := evaluateGates(profiles, "statements", 80, &config{})
+	summary := closingSummary(profiles, "statements", violations, 80, "go tool cover -html c.out", newFileLocator(profiles, false))
+	for _, expected := range []string{"Achieved:  60.0% of statements", "Required:  80.0%", "1.  example.com/nothere/a/a.go  +20.0%  (2 uncovered statements)", "go tool cover -html c.out"} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("Expected %q in summary:\n%s", expected, summary)
+		}
+	}
+	summary = closingSummary(profiles, "blocks", evaluateGates(profiles, "blocks", 80, &config{}), 80, "go tool cover -html c.out", newFileLocator(profiles, false))
+	for _, expected := range []string{"Achieved:  33.3% of blocks", "(1 uncovered blocks)"} {
+		if !strings.Contains(summary, expected) {
+			t.Errorf("Expected %q in summary:\n%s", expected, summary)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// outputFormats are the reports -format can ask for by name, with the flag each sets and its file in -out
+var outputFormats = map[string]struct {
+	flag string
+	file string
+}{
+	"json":      {"json", "report.json"},
+	"html":      {"htmlreport", "report.html"},
+	"lcov":      {"lcov", "lcov.info"},
+	"cobertura": {"cobertura", "cobertura.xml"},
+	"markdown":  {"markdown", "summary.md"},
+	"csv":       {"csv", "coverage.csv"},
+	"pb":        {"pb", "report.pb"},
+	"jenkins":   {"jenkins", "jenkins.json"},
+	"junit":     {"junit", "junit.xml"},
+}
+
+// setupFormats points the flag of every -format that was not set explicitly into -out.  Every format is rendered from
+// the same parsed profile and report at the end of the run
+func (m *gocoverdir) setupFormats() error {
+	if m.args.format == "" {
+		return nil
+	}
+	var unknown []string
+	for _, name := range strings.Split(m.args.format, ",") {
+		format, exists := outputFormats[strings.TrimSpace(name)]
+		if !exists {
+			unknown = append(unknown, name)
+			continue
+		}
+		m.setDefaultFlag(format.flag, filepath.Join(m.args.out, format.file))
+	}
+	if len(unknown) > 0 {
+		names := make([]string, 0, len(outputFormats))
+		for name := range outputFormats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown -format %s: expected a comma separated list of %s", strings.Join(unknown, ","), strings.Join(names, ", "))
+	}
+	return os.MkdirAll(m.args.out, 0755)
+}
+
+// writeLcov writes profiles as lcov tracefile line data, for tools like genhtml and Codecov's lcov uploader
+func writeLcov(w io.Writer, profiles []*cover.Profile, loc *fileLocator) error {
+	for _, p := range profiles {
+		if _, err := fmt.Fprintf(w, "TN:\nSF:%s\n", loc.name(p)); err != nil {
+			return err
+		}
+		found, hit := 0, 0
+		for _, line := range lineHits(p) {
+			found++
+			if line.hits > 0 {
+				hit++
+			}
+			if _, err := fmt.Fprintf(w, "DA:%d,%d\n", line.number, line.hits); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "LF:%d\nLH:%d\nend_of_record\n", found, hit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
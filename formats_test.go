@@ -0,0 +1,57 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestSetupFormats(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdirtest")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "artifacts")
+	m := gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	fs := flag.NewFlagSet("testsetup", flag.PanicOnError)
+	m.setupFlags(fs)
+	noError(t, fs.Parse([]string{"-format", "html,json, lcov,cobertura", "-out", out, "-json", "elsewhere.json"}))
+	noError(t, m.setupFormats())
+	if m.args.htmlreport != filepath.Join(out, "report.html") || m.args.lcov != filepath.Join(out, "lcov.info") || m.args.cobertura != filepath.Join(out, "cobertura.xml") {
+		t.Errorf("Unexpected outputs %s %s %s", m.args.htmlreport, m.args.lcov, m.args.cobertura)
+	}
+	if m.args.json != "elsewhere.json" {
+		t.Errorf("Explicit -json should be kept, saw %s", m.args.json)
+	}
+	if m.args.csv != "" {
+		t.Errorf("Did not expect -csv, saw %s", m.args.csv)
+	}
+	if _, err := os.Stat(out); err != nil {
+		t.Errorf("Expected -out to be created: %s", err)
+	}
+
+	m = gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	fs = flag.NewFlagSet("testsetup", flag.PanicOnError)
+	m.setupFlags(fs)
+	noError(t, fs.Parse([]string{"-format", "json,xml"}))
+	if err := m.setupFormats(); err == nil {
+		t.Errorf("Expected an unknown format to be refused")
+	}
+}
+
+func TestWriteLcov(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{StartLine: 3, EndLine: 4, NumStmt: 2, Count: 2}, {StartLine: 6, EndLine: 6, NumStmt: 1}}},
+	}
+	var buf bytes.Buffer
+	noError(t, writeLcov(&buf, profiles, &fileLocator{}))
+	expected := "TN:\nSF:example.com/a/a.go\nDA:3,2\nDA:4,2\nDA:6,0\nLF:3\nLH:2\nend_of_record\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected lcov %q", buf.String())
+	}
+}
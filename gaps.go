@@ -0,0 +1,172 @@
+package gocoverdir
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"sort"
+
+	"golang.org/x/tools/cover"
+)
+
+// uncoveredRange is a run of uncovered statements, uninterrupted by any covered block
+type uncoveredRange struct {
+	file       string
+	startLine  int
+	endLine    int
+	statements int
+	function   string
+}
+
+func (u uncoveredRange) String() string {
+	ret := fmt.Sprintf("%s:%d-%d (%d stmts)", u.file, u.startLine, u.endLine, u.statements)
+	if u.function != "" {
+		ret += ": func " + u.function
+	}
+	return ret
+}
+
+// uncoveredRanges groups a profile's contiguous uncovered blocks
+func uncoveredRanges(p *cover.Profile) []uncoveredRange {
+	blocks := make([]cover.ProfileBlock, len(p.Blocks))
+	copy(blocks, p.Blocks)
+	sort.Slice(blocks, func(i, j int) bool {
+		if blocks[i].StartLine != blocks[j].StartLine {
+			return blocks[i].StartLine < blocks[j].StartLine
+		}
+		return blocks[i].StartCol < blocks[j].StartCol
+	})
+	var ret []uncoveredRange
+	var current *uncoveredRange
+	for _, block := range blocks {
+		if block.NumStmt == 0 {
+			continue
+		}
+		if block.Count > 0 {
+			current = nil
+			continue
+		}
+		if current == nil {
+			ret = append(ret, uncoveredRange{file: p.FileName, startLine: block.StartLine})
+			current = &ret[len(ret)-1]
+		}
+		if block.EndLine > current.endLine {
+			current.endLine = block.EndLine
+		}
+		current.statements += block.NumStmt
+	}
+	return ret
+}
+
+type funcLines struct {
+	name      string
+	startLine int
+	endLine   int
+}
+
+// funcNames lists the functions declared in a Go source file, with their line ranges
+func funcNames(filename string) ([]funcLines, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	var ret []funcLines
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			name = fmt.Sprintf("(%s) %s", receiverName(fn.Recv.List[0].Type), name)
+		}
+		ret = append(ret, funcLines{
+			name:      name,
+			startLine: fset.Position(fn.Pos()).Line,
+			endLine:   fset.Position(fn.End()).Line,
+		})
+	}
+	return ret, nil
+}
+
+// enclosingFunc is the name of the function of funcs that contains line, or "" if none does
+func enclosingFunc(funcs []funcLines, line int) string {
+	for _, fn := range funcs {
+		if line >= fn.startLine && line <= fn.endLine {
+			return fn.name
+		}
+	}
+	return ""
+}
+
+func receiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return "*" + receiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	case *ast.IndexExpr:
+		return receiverName(t.X)
+	case *ast.IndexListExpr:
+		return receiverName(t.X)
+	}
+	return "?"
+}
+
+// findGaps lists the uncovered ranges of every profile in a package matching pattern, largest first.  Ranges are
+// named by their enclosing function when the source can be found
+func findGaps(profiles []*cover.Profile, pattern string, loc *fileLocator) []uncoveredRange {
+	var ret []uncoveredRange
+	for _, p := range profiles {
+		if pattern != "" && !matchPackage(pattern, path.Dir(p.FileName)) {
+			continue
+		}
+		ranges := uncoveredRanges(p)
+		if len(ranges) == 0 {
+			continue
+		}
+		var funcs []funcLines
+		if filename := loc.path(p); filename != "" {
+			funcs, _ = funcNames(filename)
+		}
+		file := loc.name(p)
+		for _, r := range ranges {
+			r.file = file
+			r.function = enclosingFunc(funcs, r.startLine)
+			ret = append(ret, r)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].statements > ret[j].statements
+	})
+	return ret
+}
+
+// runGaps prints the largest uncovered regions of a profile
+func runGaps(args []string) error {
+	fs := flag.NewFlagSet("gaps", flag.ContinueOnError)
+	profile := fs.String("profile", "coverage.out", "Cover profile to summarize")
+	limit := fs.Int("n", 0, "If set, only print this many of the largest gaps")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: gocoverdir gaps [-profile coverage.out] [package]")
+	}
+	profiles, err := readProfiles(*profile)
+	if err != nil {
+		return err
+	}
+	gaps := findGaps(profiles, fs.Arg(0), newFileLocator(profiles, false))
+	if *limit > 0 && len(gaps) > *limit {
+		gaps = gaps[:*limit]
+	}
+	for _, gap := range gaps {
+		fmt.Println(gap)
+	}
+	return nil
+}
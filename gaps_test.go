@@ -0,0 +1,43 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestUncoveredRanges(t *testing.T) {
+	p := &cover.Profile{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{
+		{StartLine: 20, EndLine: 22, NumStmt: 3, Count: 0},
+		{StartLine: 3, EndLine: 4, NumStmt: 2, Count: 1},
+		{StartLine: 5, EndLine: 7, NumStmt: 2, Count: 0},
+		{StartLine: 8, EndLine: 9, NumStmt: 1, Count: 0},
+		{StartLine: 10, EndLine: 11, NumStmt: 1, Count: 2},
+	}}
+	ranges := uncoveredRanges(p)
+	if len(ranges) != 2 {
+		t.Fatalf("Expected two ranges, saw %v", ranges)
+	}
+	if ranges[0].startLine != 5 || ranges[0].endLine != 9 || ranges[0].statements != 3 {
+		t.Errorf("Unexpected first range %v", ranges[0])
+	}
+	if ranges[1].String() != "example.com/a/a.go:20-22 (3 stmts)" {
+		t.Errorf("Unexpected second range %v", ranges[1])
+	}
+}
+
+func TestFuncNames(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestFuncNames")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "a.go")
+	noError(t, ioutil.WriteFile(filename, []byte("package a\n\nfunc ParseHeader() {\n}\n\ntype h struct{}\n\nfunc (x *h) Len() int {\n\treturn 0\n}\n"), 0644))
+	funcs, err := funcNames(filename)
+	noError(t, err)
+	if len(funcs) != 2 || funcs[0].name != "ParseHeader" || funcs[0].startLine != 3 || funcs[1].name != "(*h) Len" || funcs[1].endLine != 10 {
+		t.Errorf("Unexpected funcs %+v", funcs)
+	}
+}
@@ -0,0 +1,166 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+type violation struct {
+	Rule     string   `json:"rule"`
+	Scope    string   `json:"scope"`
+	Required float64  `json:"required"`
+	Actual   float64  `json:"actual"`
+	Files    []string `json:"files,omitempty"`
+	// Policy is the expression that was violated, for rule policy
+	Policy string `json:"policy,omitempty"`
+	// Link is the -reportlink deep link to the first uncovered line, of Line, of the first of Files
+	Link string `json:"link,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+func (v violation) String() string {
+	if v.Policy != "" {
+		return fmt.Sprintf("%s: %s, with coverage %.1f%%, violates %s", v.Rule, v.Scope, v.Actual, v.Policy)
+	}
+	return fmt.Sprintf("%s: coverage of %s is %.1f%%, less than required %.1f%%", v.Rule, v.Scope, v.Actual, v.Required)
+}
+
+func belowThreshold(actual float64, required float64) bool {
+	return required > 0.0 && actual < required-.001
+}
+
+// evaluateGates checks profiles against the total and per package thresholds.  Packages with fewer than the config's
+// MinStatements are too small for a percentage to mean much, so only count towards the total
+func evaluateGates(profiles []*cover.Profile, metric string, requiredcoverage float64, cfg *config) []violation {
+	var ret []violation
+	if coverage := calculateCoverage(profiles, metric); belowThreshold(coverage, requiredcoverage) {
+		ret = append(ret, violation{Rule: "requiredcoverage", Scope: "total", Required: requiredcoverage, Actual: coverage})
+	}
+	ret = append(ret, surfaceViolations(profiles, metric, cfg)...)
+	patterns := make([]string, 0, len(cfg.Packages))
+	for pattern := range cfg.Packages {
+		patterns = append(patterns, pattern)
+	}
+	sortBySpecificity(patterns)
+	for _, pkg := range packageCoverages(profiles, metric) {
+		if pkg.statements < cfg.MinStatements {
+			continue
+		}
+		for _, pattern := range patterns {
+			if !matchPackage(pattern, pkg.name) {
+				continue
+			}
+			if required := cfg.Packages[pattern]; belowThreshold(pkg.percent(), required) {
+				ret = append(ret, violation{Rule: "packages[" + pattern + "]", Scope: pkg.name, Required: required, Actual: pkg.percent(), Files: uncoveredFiles(profiles, metric, pkg.name)})
+			}
+			break
+		}
+	}
+	return ret
+}
+
+// gateFlags registers the flags of the gates evaluateViolations checks, which runs and gocoverdir check share
+func (m *gocoverdir) gateFlags(fs *flag.FlagSet) {
+	fs.StringVar(&m.args.config, "config", "", "JSON or YAML config file with coverage thresholds, goals and exclude rules")
+	fs.Float64Var(&m.args.requiredcoverage, "requiredcoverage", 0.0, "Fail if total coverage is < this value.  Overrides the config's requiredcoverage")
+	fs.IntVar(&m.args.minstmts, "minstmts", 0, "Packages with fewer statements than this are not held to per package thresholds, and are left out of the markdown summary unless -summaryall.  Overrides the config's minstmts")
+	fs.StringVar(&m.args.metric, "metric", "statements", "What coverage percentages count, in every report and gate: statements like go test, blocks, or lines to match line based dashboards")
+	fs.StringVar(&m.args.diffbase, "diffbase", "", "If set, report the coverage of the lines changed since this branch or commit, and gate it with -diffcoverage")
+	fs.Float64Var(&m.args.diffcoverage, "diffcoverage", 0, "Fail if the coverage of the lines changed since -diffbase is below this percentage")
+	fs.StringVar(&m.args.diffauthor, "diffauthor", "", "Comma separated names or emails.  If set, -diffcoverage only counts changed lines they last touched, per git blame")
+	fs.StringVar(&m.args.diffteam, "diffteam", "", "CODEOWNERS owner, like @org/platform.  If set, -diffcoverage only counts changed lines in files it owns")
+}
+
+// evaluateViolations checks profiles against every gate of a run: the total, package and surface thresholds, goals
+// past their deadline and the coverage of changed lines.  Runs and gocoverdir check both gate through it, so a separate
+// gate job agrees with the run that wrote the profile
+func (m *gocoverdir) evaluateViolations(profiles []*cover.Profile) ([]violation, error) {
+	violations := evaluateGates(profiles, m.args.metric, m.args.requiredcoverage, m.config)
+	goalViolations, goalWarnings := evaluateGoals(profiles, m.args.metric, m.config, time.Now())
+	for _, warning := range goalWarnings {
+		m.log.Printf("Warning: %s", warning)
+	}
+	violations = append(violations, goalViolations...)
+	diffViolations, err := m.evaluateDiffCoverage(profiles)
+	if err != nil {
+		return nil, err
+	}
+	return append(violations, diffViolations...), nil
+}
+
+// uncoveredFiles lists the files of pkg with uncovered statements, the most uncovered first
+func uncoveredFiles(profiles []*cover.Profile, metric string, pkg string) []string {
+	var ret []string
+	uncovered := make(map[string]int)
+	for _, p := range profiles {
+		if path.Dir(p.FileName) != pkg {
+			continue
+		}
+		total, covered := countStatements(p, metric)
+		if total > covered {
+			ret = append(ret, p.FileName)
+			uncovered[p.FileName] = total - covered
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return uncovered[ret[i]] > uncovered[ret[j]]
+	})
+	return ret
+}
+
+// localizeViolations renames the files of violations, which are named like profiles, with loc
+func localizeViolations(violations []violation, profiles []*cover.Profile, loc *fileLocator) {
+	names := make(map[string]string, len(profiles))
+	for _, p := range profiles {
+		names[p.FileName] = loc.name(p)
+	}
+	for _, v := range violations {
+		for i, file := range v.Files {
+			if name, exists := names[file]; exists {
+				v.Files[i] = name
+			}
+		}
+	}
+}
+
+// writeViolations writes violations as a JSON array, so bots can comment on them without parsing error text
+func writeViolations(w io.Writer, violations []violation) error {
+	if violations == nil {
+		violations = []violation{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(violations)
+}
+
+func violationsError(violations []violation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+	msgs := make([]string, 0, len(violations))
+	for _, v := range violations {
+		msgs = append(msgs, v.String())
+	}
+	return fmt.Errorf("%s", strings.Join(msgs, "\n"))
+}
+
+// sortBySpecificity orders package patterns so the most specific is first
+func sortBySpecificity(patterns []string) {
+	specificity := func(pattern string) int {
+		return len(strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "..."))
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		if specificity(patterns[i]) != specificity(patterns[j]) {
+			return specificity(patterns[i]) > specificity(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+}
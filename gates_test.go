@@ -0,0 +1,91 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestMatchPackage(t *testing.T) {
+	matches := []struct {
+		pattern string
+		pkg     string
+		match   bool
+	}{
+		{"./...", "github.com/cep21/gocoverdir", true},
+		{"internal/...", "github.com/a/b/internal/c", true},
+		{"internal/...", "github.com/a/b/internal", true},
+		{"internal/...", "github.com/a/b/internals", false},
+		{"github.com/a/*", "github.com/a/b", true},
+		{"github.com/a/*", "github.com/a/b/c", false},
+		{"b/c", "github.com/a/b/c", true},
+	}
+	for _, m := range matches {
+		if matchPackage(m.pattern, m.pkg) != m.match {
+			t.Errorf("matchPackage(%s, %s) should be %t", m.pattern, m.pkg, m.match)
+		}
+	}
+}
+
+func TestEvaluateGates(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 1, Count: 1}, {NumStmt: 1}}},
+		{FileName: "example.com/b/b.go", Blocks: []cover.ProfileBlock{{NumStmt: 1, Count: 1}}},
+	}
+	cfg := &config{Packages: map[string]float64{"./...": 40, "a/...": 60}}
+	violations := evaluateGates(profiles, "statements", 70, cfg)
+	if len(violations) != 2 {
+		t.Fatalf("Expected 2 violations, saw %v", violations)
+	}
+	if violations[0].Scope != "total" || violations[1].Scope != "example.com/a" || violations[1].Required != 60 {
+		t.Errorf("Unexpected violations %v", violations)
+	}
+	if err := violationsError(evaluateGates(profiles, "statements", 50, &config{})); err != nil {
+		t.Errorf("Expected no violations, saw %s", err)
+	}
+}
+
+func TestEvaluateGatesMinStatements(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/util/u.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 1}, {NumStmt: 1}}},
+		{FileName: "example.com/big/b.go", Blocks: []cover.ProfileBlock{{NumStmt: 9, Count: 1}, {NumStmt: 1}}},
+	}
+	cfg := &config{Packages: map[string]float64{"./...": 80}, MinStatements: 5}
+	if err := violationsError(evaluateGates(profiles, "statements", 0, cfg)); err != nil {
+		t.Errorf("Expected the small package to be ignored, saw %s", err)
+	}
+	cfg.MinStatements = 0
+	if violations := evaluateGates(profiles, "statements", 0, cfg); len(violations) != 1 || violations[0].Scope != "example.com/util" {
+		t.Errorf("Unexpected violations %v", violations)
+	}
+	var buf bytes.Buffer
+	noError(t, writeMarkdownSummary(&buf, profiles, "statements", 0, 5, nil, nil, nil))
+	if strings.Contains(buf.String(), "example.com/util") || !strings.Contains(buf.String(), "1 packages with fewer than 5 statements are not listed.") {
+		t.Errorf("Unexpected summary %s", buf.String())
+	}
+}
+
+func TestWriteViolations(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/a/small.go", Blocks: []cover.ProfileBlock{{NumStmt: 1, Count: 1}, {NumStmt: 1}}},
+		{FileName: "example.com/a/big.go", Blocks: []cover.ProfileBlock{{NumStmt: 5}}},
+		{FileName: "example.com/a/done.go", Blocks: []cover.ProfileBlock{{NumStmt: 5, Count: 1}}},
+	}
+	violations := evaluateGates(profiles, "statements", 0, &config{Packages: map[string]float64{"./...": 90}})
+	buf := bytes.Buffer{}
+	noError(t, writeViolations(&buf, violations))
+	expected := `"files": [
+      "example.com/a/big.go",
+      "example.com/a/small.go"
+    ]`
+	if !strings.Contains(buf.String(), expected) || !strings.Contains(buf.String(), `"rule": "packages[./...]"`) {
+		t.Errorf("Unexpected violations JSON:\n%s", buf.String())
+	}
+	buf.Reset()
+	noError(t, writeViolations(&buf, nil))
+	if buf.String() != "[]\n" {
+		t.Errorf("Expected an empty array, saw %q", buf.String())
+	}
+}
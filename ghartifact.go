@@ -0,0 +1,162 @@
+package gocoverdir
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+const ghaScheme = "gha://"
+
+// ghaArtifact names a cover profile uploaded as a GitHub Actions artifact, like gha://ci.yml/coverage
+type ghaArtifact struct {
+	workflow string
+	artifact string
+}
+
+func parseGHAArtifact(spec string) (ghaArtifact, error) {
+	parts := strings.SplitN(strings.TrimPrefix(spec, ghaScheme), "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return ghaArtifact{}, fmt.Errorf("cannot parse %s: expected gha://workflow-name/artifact-name", spec)
+	}
+	return ghaArtifact{workflow: parts[0], artifact: parts[1]}, nil
+}
+
+func githubAPIURL(getenv func(string) string) string {
+	if api := getenv("GITHUB_API_URL"); api != "" {
+		return api
+	}
+	return "https://api.github.com"
+}
+
+// githubClient reads from the GitHub API of GITHUB_REPOSITORY, authenticated by GITHUB_TOKEN
+type githubClient struct {
+	client *http.Client
+	getenv func(string) string
+}
+
+func (g *githubClient) get(endpoint string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := g.getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %s", endpoint, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (g *githubClient) getJSON(endpoint string, into interface{}) error {
+	body, err := g.get(endpoint)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, into)
+}
+
+// baseBranch is the branch a pull request merges into, or the branch being built otherwise
+func (g *githubClient) baseBranch() string {
+	if base := g.getenv("GITHUB_BASE_REF"); base != "" {
+		return base
+	}
+	return g.getenv("GITHUB_REF_NAME")
+}
+
+// fetchProfiles downloads a's profiles from the latest successful run of its workflow on branch
+func (g *githubClient) fetchProfiles(a ghaArtifact, branch string) ([]*cover.Profile, error) {
+	repository := g.getenv("GITHUB_REPOSITORY")
+	if repository == "" || branch == "" {
+		return nil, fmt.Errorf("fetching %s%s/%s needs GITHUB_REPOSITORY and a base branch", ghaScheme, a.workflow, a.artifact)
+	}
+	repoURL := fmt.Sprintf("%s/repos/%s", githubAPIURL(g.getenv), repository)
+	var runs struct {
+		WorkflowRuns []struct {
+			ID int64 `json:"id"`
+		} `json:"workflow_runs"`
+	}
+	runsURL := fmt.Sprintf("%s/actions/workflows/%s/runs?status=success&per_page=1&branch=%s", repoURL, url.PathEscape(a.workflow), url.QueryEscape(branch))
+	if err := g.getJSON(runsURL, &runs); err != nil {
+		return nil, err
+	}
+	if len(runs.WorkflowRuns) == 0 {
+		return nil, fmt.Errorf("no successful run of workflow %s on %s", a.workflow, branch)
+	}
+	var artifacts struct {
+		Artifacts []struct {
+			Expired            bool   `json:"expired"`
+			ArchiveDownloadURL string `json:"archive_download_url"`
+		} `json:"artifacts"`
+	}
+	artifactsURL := fmt.Sprintf("%s/actions/runs/%d/artifacts?name=%s", repoURL, runs.WorkflowRuns[0].ID, url.QueryEscape(a.artifact))
+	if err := g.getJSON(artifactsURL, &artifacts); err != nil {
+		return nil, err
+	}
+	if len(artifacts.Artifacts) == 0 || artifacts.Artifacts[0].Expired {
+		return nil, fmt.Errorf("run %d of workflow %s has no artifact %s", runs.WorkflowRuns[0].ID, a.workflow, a.artifact)
+	}
+	archive, err := g.get(artifacts.Artifacts[0].ArchiveDownloadURL)
+	if err != nil {
+		return nil, err
+	}
+	return profilesFromZip(archive)
+}
+
+// profilesFromZip merges every cover profile in an artifact archive
+func profilesFromZip(archive []byte) ([]*cover.Profile, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, err
+	}
+	set := newProfileSet()
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		profiles, err := parseProfiles(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %s in artifact: %s", f.Name, err)
+		}
+		if err := set.add(profiles); err != nil {
+			return nil, err
+		}
+	}
+	return set.profiles(), nil
+}
+
+// baseProfiles reads -baseprofile, which is either a file or a gha:// artifact of the base branch
+func (m *gocoverdir) baseProfiles() ([]*cover.Profile, error) {
+	if !strings.HasPrefix(m.args.baseprofile, ghaScheme) {
+		return readProfiles(m.args.baseprofile)
+	}
+	a, err := parseGHAArtifact(m.args.baseprofile)
+	if err != nil {
+		return nil, err
+	}
+	g := &githubClient{client: &http.Client{Timeout: time.Minute}, getenv: os.Getenv}
+	branch := g.baseBranch()
+	m.log.Printf("Downloading artifact %s of workflow %s on %s", a.artifact, a.workflow, branch)
+	return g.fetchProfiles(a, branch)
+}
@@ -0,0 +1,69 @@
+package gocoverdir
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseGHAArtifact(t *testing.T) {
+	a, err := parseGHAArtifact("gha://ci.yml/coverage")
+	noError(t, err)
+	if a.workflow != "ci.yml" || a.artifact != "coverage" {
+		t.Errorf("Unexpected artifact %+v", a)
+	}
+	if _, err := parseGHAArtifact("gha://ci.yml"); err == nil {
+		t.Errorf("Expected an error without an artifact name")
+	}
+}
+
+func TestFetchGHAProfiles(t *testing.T) {
+	archive := bytes.Buffer{}
+	zw := zip.NewWriter(&archive)
+	f, err := zw.Create("coverage.out")
+	noError(t, err)
+	_, err = f.Write([]byte("mode: set\nexample.com/a/a.go:1.1,2.2 3 1\n"))
+	noError(t, err)
+	noError(t, zw.Close())
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/repos/o/r/actions/workflows/ci.yml/runs":
+			if r.URL.Query().Get("branch") != "main" {
+				fmt.Fprint(w, `{"workflow_runs": []}`)
+				return
+			}
+			fmt.Fprint(w, `{"workflow_runs": [{"id": 42}]}`)
+		case "/repos/o/r/actions/runs/42/artifacts":
+			fmt.Fprintf(w, `{"artifacts": [{"archive_download_url": "%s/zip"}]}`, server.URL)
+		case "/zip":
+			w.Write(archive.Bytes())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	env := map[string]string{
+		"GITHUB_API_URL":    server.URL,
+		"GITHUB_REPOSITORY": "o/r",
+		"GITHUB_TOKEN":      "secret",
+		"GITHUB_BASE_REF":   "main",
+	}
+	g := &githubClient{client: server.Client(), getenv: func(key string) string { return env[key] }}
+	profiles, err := g.fetchProfiles(ghaArtifact{workflow: "ci.yml", artifact: "coverage"}, g.baseBranch())
+	noError(t, err)
+	if len(profiles) != 1 || profiles[0].FileName != "example.com/a/a.go" {
+		t.Errorf("Unexpected profiles %v", profiles)
+	}
+	if _, err := g.fetchProfiles(ghaArtifact{workflow: "ci.yml", artifact: "coverage"}, "other"); err == nil {
+		t.Errorf("Expected an error for a branch without runs")
+	}
+}
@@ -0,0 +1,74 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+const goalDateLayout = "2006-01-02"
+
+// goal is a coverage target a package has until a deadline to reach, for improvement plans that a static minimum
+// would fail on day one
+type goal struct {
+	Target float64 `json:"target"`
+	By     string  `json:"by"`
+}
+
+// deadline is the end of the By day: the goal fails the day after it
+func (g goal) deadline() (time.Time, error) {
+	by, err := time.ParseInLocation(goalDateLayout, g.By, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("goal by %q is not a date like 2025-09-01", g.By)
+	}
+	return by.AddDate(0, 0, 1), nil
+}
+
+func verifyGoals(goals map[string]goal) error {
+	for pattern, g := range goals {
+		if _, err := g.deadline(); err != nil {
+			return fmt.Errorf("goals[%s]: %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+// evaluateGoals fails packages that missed a goal whose deadline passed, and warns about packages still short of a
+// goal that has not
+func evaluateGoals(profiles []*cover.Profile, metric string, cfg *config, now time.Time) ([]violation, []string) {
+	patterns := make([]string, 0, len(cfg.Goals))
+	for pattern := range cfg.Goals {
+		patterns = append(patterns, pattern)
+	}
+	sortBySpecificity(patterns)
+	var violations []violation
+	var warnings []string
+	for _, pkg := range packageCoverages(profiles, metric) {
+		if pkg.statements < cfg.MinStatements {
+			continue
+		}
+		for _, pattern := range patterns {
+			if !matchPackage(pattern, pkg.name) {
+				continue
+			}
+			g := cfg.Goals[pattern]
+			deadline, err := g.deadline()
+			if err != nil || !belowThreshold(pkg.percent(), g.Target) {
+				break
+			}
+			rule := "goals[" + pattern + "]"
+			if !now.Before(deadline) {
+				violations = append(violations, violation{Rule: rule, Scope: pkg.name, Required: g.Target, Actual: pkg.percent(), Files: uncoveredFiles(profiles, metric, pkg.name)})
+				break
+			}
+			year, month, day := now.Date()
+			today := time.Date(year, month, day, 0, 0, 0, 0, deadline.Location())
+			days := int(math.Round(deadline.AddDate(0, 0, -1).Sub(today).Hours() / 24))
+			warnings = append(warnings, fmt.Sprintf("%s: coverage of %s is %.1f%%, %d days left to reach %.1f%% by %s", rule, pkg.name, pkg.percent(), days, g.Target, g.By))
+			break
+		}
+	}
+	return violations, warnings
+}
@@ -0,0 +1,38 @@
+package gocoverdir
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestEvaluateGoals(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/internal/billing/b.go", Blocks: []cover.ProfileBlock{{NumStmt: 1, Count: 1}, {NumStmt: 1}}},
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 1, Count: 1}}},
+	}
+	cfg := &config{Goals: map[string]goal{"internal/billing": {Target: 85, By: "2025-09-01"}, "a": {Target: 50, By: "2025-09-01"}}}
+	before := time.Date(2025, 8, 22, 12, 0, 0, 0, time.Local)
+	violations, warnings := evaluateGoals(profiles, "statements", cfg, before)
+	if len(violations) != 0 || len(warnings) != 1 || !strings.Contains(warnings[0], "10 days left") {
+		t.Errorf("Expected one warning with 10 days left, saw %v %v", violations, warnings)
+	}
+	onDeadline := time.Date(2025, 9, 1, 23, 0, 0, 0, time.Local)
+	if violations, _ := evaluateGoals(profiles, "statements", cfg, onDeadline); len(violations) != 0 {
+		t.Errorf("Expected no violation on the deadline itself, saw %v", violations)
+	}
+	after := time.Date(2025, 9, 2, 0, 0, 0, 0, time.Local)
+	violations, warnings = evaluateGoals(profiles, "statements", cfg, after)
+	if len(violations) != 1 || len(warnings) != 0 || violations[0].Rule != "goals[internal/billing]" || violations[0].Required != 85 {
+		t.Errorf("Expected one violation after the deadline, saw %v %v", violations, warnings)
+	}
+}
+
+func TestVerifyGoals(t *testing.T) {
+	noError(t, verifyGoals(map[string]goal{"a": {Target: 80, By: "2025-09-01"}}))
+	if err := verifyGoals(map[string]goal{"a": {Target: 80, By: "September"}}); err == nil {
+		t.Errorf("Expected an error for a deadline that is not a date")
+	}
+}
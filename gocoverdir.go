@@ -1,7 +1,9 @@
-package main
+package gocoverdir
 
 import (
 	"bytes"
+	"context"
+	"crypto"
 	"flag"
 	"fmt"
 	"io"
@@ -11,22 +13,61 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
-	"sync/atomic"
+	"sync"
 	"time"
 
 	"golang.org/x/tools/cover"
 )
 
 type gocoverdir struct {
-	args               args
-	ignoreDirSet       map[string]struct{}
-	storeDir           string
-	currentOutputIndex int64
-	log                *log.Logger
-	godepEnabled       bool
+	args          args
+	ignoreDirSet  map[string]struct{}
+	storeDir      string
+	log           *log.Logger
+	flags         *flag.FlagSet
+	ci            *ciEnvironment
+	config        *config
+	quarantine    []string
+	findings      []finding
+	results       []packageResult
+	skipped       []skippedDir
+	goDirs        []string
+	cgoEnabled    string
+	startTime     time.Time
+	lockfile      string
+	artifactLog   io.WriteCloser
+	profiles      []*cover.Profile
+	report        *report
+	files         *fileLocator
+	goEnv         []byte
+	repo          vcs
+	discoverer    Discoverer
+	importPaths   map[string]string
+	walked        walkCounter
+	stdoutProfile bool
+	explained     explainer
+	events        func(runEvent)
+	// ctx cancels the run: its package tests are killed and it is closed
+	ctx       context.Context
+	closeOnce sync.Once
+	closeErr  error
+	// leakFiles are the -leakcheck TestMains written into packages being tested, and mainFiles the -covermains tests.
+	// leakMu guards both
+	leakFiles []string
+	mainFiles []string
+	leakMu    sync.Mutex
+	// signer signs the JSON report, with -sign
+	signer crypto.Signer
+	// links makes -reportlink deep links, once the profiles are known
+	links *reportLinker
+	// signal logs when the packages -order put first are done
+	signal *orderSignal
 
 	panicPrintBuffer bytes.Buffer
+	// packageLogs splits the buffered output by package, and is nil unless the output is buffered
+	packageLogs      *packageLogs
 	logfile          io.WriteCloser
 	testOutputStderr io.Writer
 	testOutputStdout io.Writer
@@ -44,30 +85,197 @@ type args struct {
 	requiredcoverage float64
 	race             bool
 
-	htmlcoverage bool
+	htmlcoverage  bool
+	azure         bool
+	jenkins       string
+	cobertura     string
+	lcov          string
+	format        string
+	reportlink    string
+	out           string
+	markdown      string
+	ci            string
+	config        string
+	json          string
+	bazel         bool
+	bazelquery    string
+	docker        string
+	dockerenv     string
+	remote        string
+	remotedir     string
+	goos          string
+	goarch        string
+	cgo           string
+	covermains    bool
+	artifacts     string
+	junit         string
+	html          string
+	compress      bool
+	csv           string
+	csvfiles      bool
+	baseprofile   string
+	pb            string
+	history       string
+	failonskips   string
+	flaky         string
+	retries       int
+	parallel      string
+	mutexprofile  bool
+	blockprofile  bool
+	vet           string
+	staticcheck   bool
+	examples      string
+	minstmts      int
+	summaryall    bool
+	htmlreport    string
+	violations    string
+	quiet         bool
+	verbose       bool
+	veryverbose   bool
+	stdin         bool
+	chdir         string
+	checksync     bool
+	smoke         bool
+	fast          bool
+	slowcutoff    time.Duration
+	maxruntime    time.Duration
+	order         string
+	timings       string
+	tags          string
+	racecompare   bool
+	absolutepaths bool
+	explain       string
+	offline       bool
+	discover      string
+	maxpackages   int
+	maxentries    int
+	mergeinputs   string
+	coerce        string
+	strictinputs  bool
+	version       bool
+	printconfig   bool
+	keepwork      bool
+	coverpkg      string
+	contribution  string
+	diffbase      string
+	diffcoverage  float64
+	diffauthor    string
+	diffteam      string
+	bundle        string
+	metric        string
+	leakcheck     bool
+	sign          string
+	prewarm       bool
+	prewarmvet    bool
+	redact        string
+	hashpaths     bool
+	vcs           string
+	portable      bool
+	runid         string
+	// packages are the positional package patterns, like ./services/...
+	packages []string
 }
 
-var mainStruct gocoverdir
-
 func (m *gocoverdir) setupFlags(fs *flag.FlagSet) {
+	m.flags = fs
 	fs.StringVar(&m.args.covermode, "covermode", "", "Same as -covermode in 'go test'.  If running with -race, probably best not to set this.")
 	fs.IntVar(&m.args.cpu, "cpu", -1, "Same as -cpu in 'go test'")
+	fs.StringVar(&m.args.parallel, "parallel", "1", "Number of packages to test at once, or auto to use every CPU while the machine is not overloaded.  Packages expected to be slowest, from -history timings and statement counts, start first")
 	fs.BoolVar(&m.args.race, "race", false, "Same as -race in 'go test'")
+	fs.StringVar(&m.args.examples, "examples", "", "If on, report the examples each package ran and flag packages covered only by examples.  If only, also run nothing but examples")
+	fs.StringVar(&m.args.vet, "vet", "", "Same as -vet in 'go test': all, off, or a comma separated list of vet checks.  Empty uses go test's default")
+	fs.BoolVar(&m.args.staticcheck, "staticcheck", false, "If true, also run staticcheck on every tested package.  Findings are reported next to coverage and fail the run")
+	fs.BoolVar(&m.args.covermains, "covermains", false, "If true, add a temporary empty test to main packages without tests so their statements count as uncovered")
+	fs.StringVar(&m.args.cgo, "cgo", "", "Set CGO_ENABLED for test runs: on, off, or auto to enable cgo only if a C compiler is available.  Packages that need cgo are skipped when it is off")
+	fs.StringVar(&m.args.goos, "goos", "", "If set, also compile and vet every package for this GOOS with 'go vet'")
+	fs.StringVar(&m.args.goarch, "goarch", "", "If set, also compile and vet every package for this GOARCH with 'go vet'")
 	fs.DurationVar(&m.args.timeout, "timeout", time.Second*3, "Same as -timeout in 'go test'")
 	coveroutdir := os.Getenv("GOCOVERDIR_DIR")
 	if coveroutdir == "" {
 		coveroutdir = os.TempDir()
 	}
-	fs.StringVar(&m.args.coverprofile, "coverprofile", filepath.Join(coveroutdir, "coverage.out"), "Same as -coverprofile in 'go test', but will be a combined cover profile.")
+	fs.StringVar(&m.args.coverprofile, "coverprofile", filepath.Join(coveroutdir, "coverage.out"), "Same as -coverprofile in 'go test', but will be a combined cover profile.  - writes it to stdout")
 
 	fs.IntVar(&m.args.depth, "depth", 10, "Directory depth to search.")
-	fs.StringVar(&m.args.ignoreDirs, "ignoredirs", ".git:Godeps:vendor", "Color separated path of directories to ignore")
+	fs.StringVar(&m.args.ignoreDirs, "ignoredirs", defaultList(".git", "Godeps", "vendor"), "Names of directories to ignore, separated like PATH: by colons, or semicolons on Windows")
+
+	fs.StringVar(&m.args.docker, "docker", "", "If set, run each package's tests inside a container of this docker image")
+	fs.StringVar(&m.args.dockerenv, "dockerenv", defaultList("GOFLAGS", "GOPROXY", "GOPRIVATE", "GONOSUMDB", "CGO_ENABLED"), "Environment variables passed into -docker containers, separated like PATH")
+	fs.StringVar(&m.args.remote, "remote", "", "Comma separated user@host list.  If set, rsync the tree to each host and run package tests there over ssh, sharded across the hosts")
+	fs.StringVar(&m.args.remotedir, "remotedir", "gocoverdir-remote", "Directory on -remote hosts the tree is synced into")
+	fs.BoolVar(&m.args.bazel, "bazel", false, "If true, also run 'bazel coverage' on go_test targets and merge its lcov report")
+	fs.StringVar(&m.args.bazelquery, "bazelquery", "kind(go_test, //...)", "Bazel query used to find go_test targets for -bazel")
 
+	fs.BoolVar(&m.args.quiet, "q", false, "If true, only print the final coverage summary and errors.  Debug output is kept and dumped if the run fails")
+	fs.BoolVar(&m.args.verbose, "v", false, "If true, stream the output of every test, like 'go test -v'")
+	fs.BoolVar(&m.args.veryverbose, "vv", false, "If true, stream the output of every test and the commands go test runs to build them")
+	fs.StringVar(&m.args.chdir, "chdir", "", "If set, change to this directory before doing anything else, like 'go -C'.  Relative paths in other flags are relative to it")
+	fs.BoolVar(&m.args.smoke, "smoke", false, "If true, test the riskiest packages first, unless -order is set.  Packages that do not fit in -maxruntime are skipped")
+	fs.BoolVar(&m.args.fast, "fast", false, "If true, skip slow packages for quick local iteration: the config's slowlist, and packages whose -timings are over -slowcutoff")
+	fs.DurationVar(&m.args.slowcutoff, "slowcutoff", 30*time.Second, "Packages whose last test time was over this are skipped by -fast")
+	fs.DurationVar(&m.args.maxruntime, "maxruntime", 2*time.Minute, "Time box of a -smoke run")
+	fs.StringVar(&m.args.runid, "runid", "", "ID of this run, written into every report and the log to correlate them.  Defaults to a random UUID")
+	fs.BoolVar(&m.args.portable, "portable", false, "If true, every artifact names paths relative to the working directory with forward slashes, so artifacts cached on one OS work on another")
+	fs.StringVar(&m.args.vcs, "vcs", "auto", "Version control system for commit metadata and churn: git, hg, none, or auto to detect it")
+	fs.StringVar(&m.args.redact, "redact", "", "If set, also write a JSON report to this file with only coverage numbers, safe to share outside the team: no test names or output, findings, skip reasons or CI details")
+	fs.BoolVar(&m.args.hashpaths, "hashpaths", false, "If true, the -redact report hashes every element of file and package paths, keeping only the shape of the tree")
+	fs.BoolVar(&m.args.prewarm, "prewarm", false, "If true, run 'go build' over every package before testing, so the first package's -timeout is not spent compiling shared dependencies")
+	fs.BoolVar(&m.args.prewarmvet, "prewarmvet", false, "If true, -prewarm also runs 'go vet', which compiles test files too")
+	fs.BoolVar(&m.args.offline, "offline", false, "If true, tests run with GOFLAGS=-mod=mod GOPROXY=off, and fail fast if a required module is not in the module cache")
+	fs.StringVar(&m.args.explain, "explain", "", "If set, write why each directory was tested or not to this file, as JSON if it ends in .json.  - means stderr")
+	fs.BoolVar(&m.args.absolutepaths, "absolutepaths", false, "If true, reports name files by absolute path instead of relative to the module root.  -coverprofile always uses import paths, like go test")
+	fs.BoolVar(&m.args.racecompare, "racecompare", false, "If true, test every package again with -race flipped, and warn about statements only covered in one configuration")
+	fs.StringVar(&m.args.tags, "tags", "", "Same as -tags in 'go test'.  Compare runs with different tags using 'gocoverdir variants'")
+	fs.StringVar(&m.args.timings, "timings", "auto", "File that keeps each package's test time between runs, for scheduling, sharding and progress estimates.  auto keeps it in the user cache directory, outside the tree.  Empty disables it")
+	fs.StringVar(&m.args.order, "order", "", "Order to test packages in, for an earlier pass or fail signal.  risk tests the riskiest first, by churn, complexity, fan in and coverage.  recent-failures-first tests the packages that last failed in -timings first.  changed-first tests the packages changed since -diffbase, or uncommitted, first")
+	fs.BoolVar(&m.args.checksync, "checksync", false, "If true, fail before reporting if the merged profile does not match the current source files")
+	fs.StringVar(&m.args.mergeinputs, "mergeinputs", "", "Comma separated globs of profiles from earlier CI stages, like 'artifacts/*.out', to merge this run into before gating and reporting")
+	fs.StringVar(&m.args.coerce, "coerce", "", "If set to set, convert -mergeinputs profiles, and this run's, to set mode so profiles of different cover modes can be merged")
+	fs.BoolVar(&m.args.printconfig, "print-config", false, "Print the effective configuration as JSON, after the config file, -ci and -artifacts fill in defaults, and exit without testing")
+	fs.StringVar(&m.args.coverpkg, "coverpkg", "", "Same as -coverpkg in 'go test'.  Lets a package's tests count towards the coverage of the packages they exercise")
+	fs.StringVar(&m.args.contribution, "contribution", "", "If set, write how many statements each test package covers, and how many only it covers, to this file, as JSON if it ends in .json.  - means stderr.  Most useful with -coverpkg")
+	m.gateFlags(fs)
+	fs.StringVar(&m.args.bundle, "bundle", "", "If set, also archive the profile, reports, logs, -artifacts and run metadata into this .tar.gz, even if gates fail.  'gocoverdir unbundle' extracts it")
+	fs.BoolVar(&m.args.leakcheck, "leakcheck", false, "If true, fail packages whose tests leave goroutines running, by adding a generated TestMain to each package for its test run.  Packages with their own TestMain are skipped")
+	fs.BoolVar(&m.args.keepwork, "keepwork", false, "If true, keep the directory of per package profiles instead of removing it on exit, and print where it is")
+	fs.BoolVar(&m.args.version, "version", false, "Print the gocoverdir version and commit, which every report also records, and exit")
+	fs.BoolVar(&m.args.strictinputs, "strictinputs", false, "If true, fail instead of warning when -mergeinputs profiles come from a different commit or Go version than this run, per their .meta.json sidecars")
+	fs.IntVar(&m.args.maxpackages, "maxpackages", 0, "If > 0, fail discovery once it finds more than this many packages, naming the directories with the most")
+	fs.IntVar(&m.args.maxentries, "maxwalkentries", 0, "If > 0, fail discovery once it reads more than this many directory entries, naming the directories with the most")
+	fs.StringVar(&m.args.discover, "discover", "walk", "How to find packages without -stdin or package arguments: walk the tree, golist, git to use tracked files, or cmd:<command> that prints one directory per line, optionally followed by a tab and its import path")
+	fs.BoolVar(&m.args.stdin, "stdin", false, "If true, test the newline separated package directories read from stdin instead of searching for them")
 	fs.StringVar(&m.args.logfile, "logfile", "-", "Logfile to print debug output to.  Empty means be silent unless there is an error, then dump to stderr")
 
 	fs.BoolVar(&m.args.printcoverage, "printcoverage", false, "Print coverage amount to stdout")
-	fs.Float64Var(&m.args.requiredcoverage, "requiredcoverage", 0.0, "Program will fatal if coverage is < this value")
+	fs.IntVar(&m.args.retries, "retries", 0, "If > 0, rerun a package's failed tests up to this many times.  Tests that fail then pass are reported as flaky")
+	fs.StringVar(&m.args.flaky, "flaky", "flaky.yml", "YAML list of quarantined tests, like TestX or TestX/case.  They run in a separate pass, in just the packages declaring them, whose failures are reported but do not fail the build")
+	fs.StringVar(&m.args.failonskips, "failonskips", "", "If set, fail if any test is skipped in a package directory matching this glob, like ./integration/...")
+	fs.BoolVar(&m.args.summaryall, "summaryall", false, "If true, list packages under -minstmts in the markdown summary too")
 	fs.BoolVar(&m.args.htmlcoverage, "htmlcoverage", false, "If true, will generate coverage output in a temp file")
+	fs.StringVar(&m.args.html, "html", "", "If set, generate coverage HTML at this file")
+	fs.StringVar(&m.args.htmlreport, "htmlreport", "", "If set, write an HTML coverage summary that groups by package, directory, CODEOWNERS team or build constraint to this file.  With -covermode count or atomic it also colors lines by hit count")
+	fs.StringVar(&m.args.violations, "violations", "", "If set, write threshold violations as JSON to this file when coverage gates fail")
+	fs.StringVar(&m.args.junit, "junit", "", "If set, write JUnit XML with one test suite per package to this file")
+	fs.BoolVar(&m.args.compress, "compress", false, "If true, replace the merged profile and reports with gzipped .gz versions once the run is done")
+	fs.BoolVar(&m.args.mutexprofile, "mutexprofile", false, "If true, write each package's mutex profile, and its test binary, into its -artifacts directory")
+	fs.BoolVar(&m.args.blockprofile, "blockprofile", false, "If true, write each package's blocking profile, and its test binary, into its -artifacts directory")
+	fs.StringVar(&m.args.artifacts, "artifacts", "", "If set, write the profile, reports, logs and per package output into this directory, unless set explicitly")
+	fs.StringVar(&m.args.jenkins, "jenkins", "", "If set, write a Jenkins code-coverage-api JSON summary with per file line coverage to this file")
+	fs.StringVar(&m.args.cobertura, "cobertura", "", "If set, write a cobertura XML coverage report to this file")
+	fs.StringVar(&m.args.lcov, "lcov", "", "If set, write an lcov tracefile to this file")
+	fs.StringVar(&m.args.format, "format", "", "Comma separated reports to write into -out, unless their flag is set explicitly: json, html, lcov, cobertura, markdown, csv, pb, jenkins or junit")
+	fs.StringVar(&m.args.out, "out", ".", "Directory -format writes its reports into")
+	fs.StringVar(&m.args.history, "history", "", "If set, append this run's total and per package coverage to this JSON lines history store")
+	fs.StringVar(&m.args.pb, "pb", "", "If set, write the report as protobuf (see report.proto) to this file")
+	fs.StringVar(&m.args.csv, "csv", "", "If set, write per package coverage as CSV to this file")
+	fs.BoolVar(&m.args.csvfiles, "csvfiles", false, "If true, -csv also has a row per file")
+	fs.StringVar(&m.args.baseprofile, "baseprofile", "", "Cover profile of the base branch, used to report coverage deltas.  gha://workflow-name/artifact-name downloads it from the latest successful GitHub Actions run on the base branch, using GITHUB_TOKEN")
+	fs.StringVar(&m.args.json, "json", "", "If set, write a JSON coverage report to this file")
+	fs.StringVar(&m.args.sign, "sign", "", "PEM private key.  If set, write a detached signature of the -json report next to it, for 'gocoverdir verify-report'")
+	fs.StringVar(&m.args.markdown, "markdown", "", "If set, write a markdown coverage summary to this file")
+	fs.StringVar(&m.args.reportlink, "reportlink", "", "URL template of the uploaded HTML report.  If set, packages in the markdown summary, -violations and GitHub annotations of failed gates link to their first uncovered line.  {package}, {file}, {line}, {commit} and {run_id} are replaced, like https://ci.example.com/{run_id}/report.html#{file}:{line}")
+	fs.StringVar(&m.args.ci, "ci", "", "CI system to integrate with: auto, github, gitlab, drone, woodpecker, circle, buildkite, azure, jenkins or travis.  Enables that system's output formats unless set explicitly")
+	fs.BoolVar(&m.args.azure, "azure", false, "If true, emit Azure DevOps logging commands and publish cobertura coverage and JUnit results")
 }
 
 func (m *gocoverdir) setupLogFile() error {
@@ -76,9 +284,11 @@ func (m *gocoverdir) setupLogFile() error {
 		m.testOutputStderr = os.Stderr
 		m.testOutputStdout = os.Stdout
 	} else if m.args.logfile == "" {
-		m.log = log.New(&m.panicPrintBuffer, "", 0)
-		m.testOutputStderr = &m.panicPrintBuffer
-		m.testOutputStdout = &m.panicPrintBuffer
+		buffer := &lockedWriter{w: &m.panicPrintBuffer}
+		m.log = log.New(buffer, "", 0)
+		m.testOutputStderr = buffer
+		m.testOutputStdout = buffer
+		m.packageLogs = newPackageLogs()
 	} else {
 		var err error
 		m.logfile, err = os.OpenFile(m.args.logfile, os.O_CREATE|os.O_WRONLY, 0644)
@@ -89,6 +299,24 @@ func (m *gocoverdir) setupLogFile() error {
 		m.testOutputStderr = m.logfile
 		m.testOutputStdout = m.logfile
 	}
+	if m.args.quiet {
+		buffer := &lockedWriter{w: &m.panicPrintBuffer}
+		m.log = log.New(buffer, "", 0)
+		m.testOutputStderr = buffer
+		m.testOutputStdout = buffer
+		m.packageLogs = newPackageLogs()
+	}
+	return nil
+}
+
+// verifyVerbosity checks that only one of -q, -v and -vv is set.  Quiet runs still print the coverage they found
+func (m *gocoverdir) verifyVerbosity() error {
+	if m.args.quiet && (m.args.verbose || m.args.veryverbose) {
+		return fmt.Errorf("-q cannot be used with -v or -vv")
+	}
+	if m.args.quiet {
+		m.setDefaultFlag("printcoverage", "true")
+	}
 	return nil
 }
 
@@ -106,18 +334,76 @@ func (m *gocoverdir) setup() error {
 		}
 	}()
 	m.setupLogFile()
-	m.verifyParams()
-
-	if f, err := os.Open("Godeps"); err == nil {
-		if stat, err := f.Stat(); err == nil && stat.IsDir() {
-			m.godepEnabled = true
+	if err = m.setupFormats(); err != nil {
+		return err
+	}
+	if err = m.setupArtifacts(); err != nil {
+		return err
+	}
+	if err = m.setupRunID(); err != nil {
+		return err
+	}
+	m.setupTimings()
+	if err = m.verifyVerbosity(); err != nil {
+		return err
+	}
+	if err = m.verifyContention(); err != nil {
+		return err
+	}
+	if m.args.portable && m.args.absolutepaths {
+		return fmt.Errorf("-portable and -absolutepaths cannot be combined")
+	}
+	if err = verifyMetric(m.args.metric); err != nil {
+		return err
+	}
+	if m.args.sign != "" {
+		if m.args.json == "" || m.args.json == stdio {
+			return fmt.Errorf("-sign needs -json to write the report to a file")
+		}
+		if m.signer, err = loadSigner(m.args.sign); err != nil {
+			return err
 		}
 	}
+	if m.args.coerce != "" && m.args.coerce != "set" {
+		return fmt.Errorf("unknown -coerce %q: only set is safe to convert profiles to", m.args.coerce)
+	}
+	if err = m.verifyOrder(); err != nil {
+		return err
+	}
+	if err = m.verifyExamples(); err != nil {
+		return err
+	}
+	if err = m.applyConfig(); err != nil {
+		return err
+	}
+	if err = m.loadQuarantine(); err != nil {
+		return err
+	}
+	m.verifyParams()
+	if err = m.setupCI(); err != nil {
+		return err
+	}
+	if err = m.setupCgo(); err != nil {
+		return err
+	}
+	if err = m.verifyOffline(); err != nil {
+		return err
+	}
+	if m.repo, err = selectVCS(m.args.vcs); err != nil {
+		return err
+	}
 
+	m.startTime = time.Now()
 	m.storeDir, err = ioutil.TempDir("", "gocoverdir")
 	if err != nil {
 		return err
 	}
+	if err = m.setupStdoutProfile(); err != nil {
+		return err
+	}
+	if err = m.lockOutput(); err != nil {
+		return err
+	}
 	m.log.Printf("coverdir %s", m.storeDir)
 	ignoreDirs := filepath.SplitList(m.args.ignoreDirs)
 	m.ignoreDirSet = make(map[string]struct{}, len(ignoreDirs))
@@ -128,33 +414,36 @@ func (m *gocoverdir) setup() error {
 	return nil
 }
 
+// Close releases what the run holds: the output lock, log files and, unless -keepwork, the store of profiles.  It is
+// safe to call more than once, before setup, and from the signal handler while the run is going
 func (m *gocoverdir) Close() error {
-	if len(m.storeDir) < 4 {
-		panic("mainStruct not setup correctly")
-	}
-	if m.logfile != nil {
-		m.logfile.Close()
-	}
-	return os.RemoveAll(m.storeDir)
-}
-
-func (m *gocoverdir) nextCoverprofileName() string {
-	return fmt.Sprintf("gocoverdirprofile%d.cover", atomic.AddInt64(&m.currentOutputIndex, 1))
+	m.closeOnce.Do(func() {
+		m.removeLeakChecks()
+		m.removeMainTests()
+		m.unlockOutput()
+		if m.logfile != nil {
+			m.logfile.Close()
+		}
+		if m.artifactLog != nil {
+			m.artifactLog.Close()
+		}
+		m.closeErr = m.removeStore()
+	})
+	return m.closeErr
 }
 
-func (m *gocoverdir) coverDir(dirpath string) error {
-	args := []string{}
-	var executable string
-	if m.godepEnabled {
-		args = append(args, "go")
-		executable = "godep"
-	} else {
-		executable = "go"
-	}
-	args = append(args, "test", "-cover", "-coverprofile", m.nextCoverprofileName(), "-outputdir", m.storeDir)
+// testArgs returns the command that runs go test with coverage on dirpath, writing profileName into outputdir.  It
+// runs in the root of dirpath's module, see depStrategy.  If only is set, just those tests run
+func (m *gocoverdir) testArgs(dirpath string, outputdir string, profileName string, only []string) (string, []string) {
+	strategy := m.depStrategy(dirpath)
+	executable, args := strategy.command()
+	args = append(args, "test", "-json", "-cover", "-coverprofile", profileName, "-outputdir", outputdir)
 	if m.args.covermode != "" {
 		args = append(args, "-covermode", m.args.covermode)
 	}
+	if m.args.coverpkg != "" {
+		args = append(args, "-coverpkg", m.args.coverpkg)
+	}
 	if m.args.timeout.Nanoseconds() > 0 {
 		args = append(args, "-timeout", m.args.timeout.String())
 	}
@@ -164,46 +453,223 @@ func (m *gocoverdir) coverDir(dirpath string) error {
 	if m.args.race {
 		args = append(args, "-race")
 	}
-	args = append(args, "./"+dirpath)
-	cmd := exec.Command(executable, args...)
+	if m.args.veryverbose {
+		args = append(args, "-x")
+	}
+	if m.args.tags != "" {
+		args = append(args, "-tags", m.args.tags)
+	}
+	if m.args.vet != "" {
+		args = append(args, "-vet="+m.args.vet)
+	}
+	if len(only) > 0 {
+		args = append(args, "-run", exactTestsPattern(only))
+	} else {
+		if m.args.examples == "only" {
+			args = append(args, "-run", "^Example")
+		}
+		if len(m.quarantine) > 0 {
+			args = append(args, "-skip", exactTestsPattern(m.quarantine))
+		}
+		args = append(args, m.contentionArgs(dirpath)...)
+	}
+	if m.config != nil {
+		args = append(args, packageTestFlags(m.config.TestFlags, dirpath)...)
+	}
+	args = append(args, strategy.packageArg(dirpath))
+	return executable, args
+}
+
+func (m *gocoverdir) coverDir(dirpath string) ([]packageResult, error) {
+	defer m.injectLeakCheck(dirpath)()
+	result, err := m.coverDirPass(dirpath, nil)
+	if err != nil {
+		return nil, err
+	}
+	results := []packageResult{result}
+	quarantined := m.quarantinedIn(dirpath)
+	if result.err != nil || len(quarantined) == 0 {
+		return results, result.err
+	}
+	quarantinedResult, err := m.coverDirPass(dirpath, quarantined)
+	if err != nil {
+		return results, err
+	}
+	return append(results, quarantinedResult), nil
+}
+
+// coverDirPass runs either the given quarantined tests of dirpath, or without any, all the others
+func (m *gocoverdir) coverDirPass(dirpath string, quarantined []string) (packageResult, error) {
+	var cmdErr error
+	var profileNames []string
+	pass := ""
+	if len(quarantined) > 0 {
+		pass = "quarantined"
+	}
+	run := func(only []string) packageResult {
+		profileName := storeProfileName(dirpath, pass, len(profileNames))
+		profileNames = append(profileNames, profileName)
+		executable, args := m.testArgs(dirpath, m.storeDir, profileName, only)
+		cmd, err := m.testCommand(m.depStrategy(dirpath).root, executable, args...)
+		if err != nil {
+			cmdErr = err
+			return packageResult{dir: dirpath, err: err}
+		}
+		return m.runTest(dirpath, cmd, true)
+	}
+	if len(quarantined) > 0 {
+		result := run(quarantined)
+		return m.quarantineResult(result), cmdErr
+	}
+	result := run(nil)
+	result.profiles = m.writtenProfiles(dirpath)
+	result = m.retryFailures(result, run)
+	if cmdErr != nil {
+		return result, cmdErr
+	}
+	if err := m.keepPackageProfile(dirpath, profileNames[0]); err != nil {
+		m.log.Printf("Unable to keep profile of %s: %s", dirpath, err)
+	}
+	if result.err != nil && m.args.artifacts != "" {
+		bundle, err := m.saveFailureBundle(result, profileNames[len(profileNames)-1])
+		if err != nil {
+			m.log.Printf("Unable to save failure bundle of %s: %s", dirpath, err)
+		}
+		result.failureBundle = bundle
+	}
+	return result, nil
+}
+
+// runTest runs the go test command for dirpath.  If jsonOutput is true, the command's output is 'go test -json'
+// events and is decoded to find skipped tests
+func (m *gocoverdir) runTest(dirpath string, cmd *exec.Cmd, jsonOutput bool) packageResult {
 	cmd.Stdout = m.testOutputStdout
 	cmd.Stderr = m.testOutputStderr
+	packageOutput, err := m.packageOutput(dirpath)
+	if err != nil {
+		m.log.Printf("Unable to keep test output of %s: %s", dirpath, err)
+	} else if packageOutput != nil {
+		defer packageOutput.Close()
+		cmd.Stdout = io.MultiWriter(m.testOutputStdout, packageOutput)
+		cmd.Stderr = io.MultiWriter(m.testOutputStderr, packageOutput)
+	}
+	if m.packageLogs != nil {
+		section := m.packageLogs.writer(dirpath)
+		cmd.Stdout = io.MultiWriter(cmd.Stdout, section)
+		cmd.Stderr = io.MultiWriter(cmd.Stderr, section)
+	}
+	var events *testEventWriter
+	if jsonOutput {
+		events = newTestEventWriter(cmd.Stdout)
+		events.verbose = m.args.verbose || m.args.veryverbose
+		if m.config != nil {
+			events.matches.rules = m.config.failOutput
+		}
+		events.leaks.rules = leakRules
+		cmd.Stdout = events
+	}
 	m.log.Printf("Executing %s %s", cmd.Path, strings.Join(cmd.Args, " "))
-	if err := cmd.Start(); err != nil {
+	m.emit(runEvent{Type: "package_start", Package: dirpath})
+	start := time.Now()
+	err = m.runCommand(cmd)
+	m.emit(runEvent{Type: "package_done", Package: dirpath, Error: errorString(err)})
+	result := packageResult{dir: dirpath, command: "go test", commandLine: cmd.Args, duration: time.Since(start), err: err}
+	if events != nil {
+		if closeErr := events.Close(); closeErr != nil {
+			m.log.Printf("Unable to write test output of %s: %s", dirpath, closeErr)
+		}
+		result.skipped = events.skipped
+		result.failed = events.failed
+		result.races = events.races.races
+		result.outputMatches = events.matches.matched
+		result.leaks = leakedGoroutines(events.leaks.matched)
+		if result.err == nil && len(result.outputMatches) > 0 {
+			result.err = &outputMatchError{matched: result.outputMatches}
+		}
+		m.setExamples(&result, events.ran)
+	}
+	return result
+}
+
+func (m *gocoverdir) coverDirs(dirs []string) error {
+	if m.args.remote != "" {
+		return m.coverRemote(dirs)
+	}
+	dirs = m.orderDirs(dirs)
+	if m.args.smoke {
+		return m.coverSmoke(dirs)
+	}
+	workers, err := parseParallel(m.args.parallel)
+	if err != nil {
 		return err
 	}
-	err := cmd.Wait()
-	return err
+	if workers > 1 {
+		return m.coverParallel(dirs, workers)
+	}
+	eta := m.newETA(dirs, 1)
+	for _, dir := range dirs {
+		results, err := m.coverDir(dir)
+		m.results = append(m.results, results...)
+		if err != nil {
+			return err
+		}
+		m.logProgress(eta, dir)
+	}
+	return nil
 }
 
-func (m *gocoverdir) coverDirectory(dirpath string, depth int) error {
+// findTestDirs appends to dirs every directory, at or below dirpath, that go test should run in
+func (m *gocoverdir) findTestDirs(dirpath string, depth int, dirs []string) ([]string, error) {
 	m.log.Printf("Coverdir on %s", dirpath)
 	if depth > m.args.depth {
-		return nil
+		m.explained.record(dirpath, "too deep", fmt.Sprintf("deeper than -depth %d", m.args.depth))
+		return dirs, nil
 	}
 	files, err := ioutil.ReadDir(dirpath)
 	if err != nil {
-		return err
+		return dirs, err
 	}
-	if m.containsGoTest(files) {
-		m.log.Printf("Go files in directory")
-		coverRes := m.coverDir(dirpath)
-		if coverRes != nil {
-			return coverRes
-		}
+	if err := m.countWalked(dirpath, len(files)); err != nil {
+		return dirs, err
+	}
+	dirs = m.considerDir(dirpath, files, dirs)
+	if err := m.checkMaxPackages(dirs); err != nil {
+		return dirs, err
 	}
 	for _, file := range files {
 		if file.IsDir() {
-			if _, ignoredDir := m.ignoreDirSet[file.Name()]; !ignoredDir {
-				finalName := filepath.Join(dirpath, file.Name())
-				err := m.coverDirectory(finalName, depth+1)
-				if err != nil {
-					return err
-				}
+			finalName := filepath.Join(dirpath, file.Name())
+			if _, ignoredDir := m.ignoreDirSet[file.Name()]; ignoredDir {
+				m.explained.record(finalName, "ignored", "named in -ignoredirs")
+				continue
+			}
+			dirs, err = m.findTestDirs(finalName, depth+1, dirs)
+			if err != nil {
+				return dirs, err
 			}
 		}
 	}
-	return nil
+	return dirs, nil
+}
+
+// considerDir adds dirpath to dirs if it has Go files that can be tested here
+func (m *gocoverdir) considerDir(dirpath string, files []os.FileInfo, dirs []string) []string {
+	if !m.containsGoTest(files) {
+		m.explained.record(dirpath, "no Go files", "")
+		return dirs
+	}
+	m.log.Printf("Go files in directory")
+	m.goDirs = append(m.goDirs, dirpath)
+	if reason := m.testableReason(dirpath); reason != "" {
+		m.skip(dirpath, reason)
+		return dirs
+	}
+	if hasTestFiles(files) {
+		m.explained.record(dirpath, "tested", "")
+	} else {
+		m.explained.record(dirpath, "tested", "no test files, so all of its statements are uncovered")
+	}
+	return append(dirs, dirpath)
 }
 
 func (m *gocoverdir) containsGoTest(files []os.FileInfo) bool {
@@ -219,30 +685,100 @@ func (m *gocoverdir) Main() error {
 	if err := m.setup(); err != nil {
 		return err
 	}
-	return m.coverDirectory(".", 0)
+	dirs, err := m.discoverDirs()
+	if err != nil {
+		return err
+	}
+	dirs = m.skipSlow(dirs)
+	defer m.removeMainTests()
+	if err := m.addMainTests(dirs); err != nil {
+		return err
+	}
+	if m.args.remote == "" {
+		m.prewarm(dirs)
+	}
+	err = m.coverDirs(dirs)
+	if explainErr := m.writeExplain(); explainErr != nil {
+		m.log.Printf("Unable to write -explain: %s", explainErr)
+	}
+	if timingsErr := m.saveTimings(); timingsErr != nil {
+		m.log.Printf("Unable to save timings to %s: %s", m.args.timings, timingsErr)
+	}
+	if summary := examplesOnlySummary(m.results); summary != "" {
+		m.log.Print(summary)
+	}
+	if summary := raceSummary(m.results); summary != "" && err != nil {
+		err = fmt.Errorf("%s\n%s", err, summary)
+	} else if summary != "" {
+		m.log.Print(summary)
+	}
+	if err != nil {
+		return err
+	}
+	if err := m.vetTarget(m.goDirs); err != nil {
+		return err
+	}
+	if m.args.racecompare {
+		if err := m.raceComparePass(dirs); err != nil {
+			return err
+		}
+	}
+	if m.args.staticcheck {
+		if err := m.runStaticcheck(dirs); err != nil {
+			return err
+		}
+	}
+	if m.args.bazel {
+		return m.coverBazel()
+	}
+	return nil
 }
 
-func (m *gocoverdir) handleErr(err error) {
-	defer func() {
-		if err != nil {
-			// Panic, rather than fatal, lets the defer Close() happen
-			m.log.Panic(err.Error())
-		}
-	}()
+// handleErr finishes the run and returns the exit code of main
+func (m *gocoverdir) handleErr(err error) int {
+	err = m.finish(err)
+	if m.args.runid != "" && (err != nil || !m.args.quiet) {
+		fmt.Fprintf(os.Stderr, "gocoverdir run %s\n", m.args.runid)
+	}
+	if err == nil {
+		return 0
+	}
+	m.annotateFailure(err)
+	if _, isThreshold := err.(*thresholdError); isThreshold {
+		fmt.Fprint(os.Stderr, err.Error())
+		return 1
+	}
+	// Without a log to stderr, the buffered output is the only way to see what went wrong
+	m.flushBufferedLog(os.Stderr)
+	fmt.Fprintln(os.Stderr, err)
+	return 1
+}
+
+// finish combines the stored profiles and reports on them, unless the run itself failed
+func (m *gocoverdir) finish(err error) error {
 	if err != nil {
-		return
+		if reportErr := m.writeFailedReport(); reportErr != nil {
+			m.log.Printf("Unable to write the report of the failed run: %s", reportErr)
+		}
+		if bundleErr := m.writeRunBundle(); bundleErr != nil {
+			m.log.Printf("Unable to write -bundle: %s", bundleErr)
+		}
+		return err
 	}
 
+	if err = m.writeContribution(); err != nil {
+		return err
+	}
 	files, err := ioutil.ReadDir(m.storeDir)
 	if err != nil {
-		return
+		return err
 	}
 	outputBuffer := bytes.Buffer{}
 	for _, file := range files {
 		if !file.IsDir() {
 			fileContents, err := ioutil.ReadFile(filepath.Join(m.storeDir, file.Name()))
 			if err != nil {
-				return
+				return err
 			}
 			if outputBuffer.Len() == 0 {
 				outputBuffer.Write(fileContents)
@@ -252,77 +788,361 @@ func (m *gocoverdir) handleErr(err error) {
 			outputBuffer.WriteString(strings.Join(fileLines[1:], "\n"))
 		}
 	}
+	if err = m.checkClobber(m.args.coverprofile); err != nil {
+		return err
+	}
 	err = ioutil.WriteFile(m.args.coverprofile, outputBuffer.Bytes(), 0644)
 	if err != nil {
-		return
+		return err
+	}
+	if m.args.mergeinputs != "" {
+		if err = m.mergeInputs(); err != nil {
+			return err
+		}
+	}
+	if len(m.config.Exclude) > 0 {
+		if err = m.excludeConstructs(); err != nil {
+			return err
+		}
+	}
+	if m.stdoutProfile {
+		if err = m.copyProfileToStdout(); err != nil {
+			return err
+		}
+	} else if err = writeProfileMeta(m.args.coverprofile, m.profileMeta()); err != nil {
+		return err
 	}
 	err = m.handleCoverage()
+	if m.args.compress {
+		if compressErr := m.compressOutputs(); compressErr != nil && err == nil {
+			err = compressErr
+		}
+	}
+	if bundleErr := m.writeRunBundle(); bundleErr != nil && err == nil {
+		err = bundleErr
+	}
+	return err
 }
 
 func (m *gocoverdir) handleCoverage() error {
 	var err error
-	if m.args.htmlcoverage {
-		htmlout := filepath.Join(os.TempDir(), "cover.html")
-		m.log.Printf("Generating coverage HTML at %s or %s", htmlout, "file://"+htmlout)
+	if m.args.htmlcoverage || m.args.html != "" {
+		htmlout := m.args.html
+		if htmlout == "" {
+			htmlout = filepath.Join(os.TempDir(), "cover.html")
+		}
+		m.log.Printf("Generating coverage HTML at %s or %s", htmlout, fileURL(htmlout))
 		cmd := exec.Command("go", "tool", "cover", "-html", m.args.coverprofile, "-o", htmlout)
 		if err = cmd.Run(); err != nil {
 			return err
 		}
 	}
 
-	if m.args.printcoverage || m.args.requiredcoverage > 0.0 {
-		var coverage float64
-		coverage, err = m.calculateCoverage()
-		if err != nil {
+	profiles, err := cover.ParseProfiles(m.args.coverprofile)
+	if err != nil {
+		return err
+	}
+	if m.args.checksync {
+		if err = m.checkSync(profiles); err != nil {
 			return err
 		}
+	}
+	if m.args.racecompare {
+		if err = m.warnRaceOnly(profiles); err != nil {
+			return err
+		}
+	}
+	coverage := calculateCoverage(profiles, m.args.metric)
+	m.profiles = profiles
+	m.report = newReport(profiles, m.args.metric, m.emittedResults(), m.emittedSkipped(), m.ci, m.config.Internal)
+	m.report.Findings = m.findings
+	m.report.RunID = m.args.runid
+	addRisks(m.report, profiles, m.repo)
+	m.report.localize(profiles, m.locator(profiles))
+	m.report.Sources = sourceCoverages(profiles, m.args.metric, m.locator(profiles), m.config.SourceMap)
 
-		if m.args.printcoverage {
-			fmt.Printf("coverage: %.1f%% of statements\n", coverage)
+	if m.args.json != "" {
+		if err = m.writeJSONReport(m.report); err != nil {
+			return err
+		}
+	}
+	if m.args.redact != "" {
+		if err = writeFileWith(m.args.redact, func(w io.Writer) error {
+			return writeReport(w, redactReport(m.report, m.args.hashpaths))
+		}); err != nil {
+			return err
+		}
+	}
+	if m.args.jenkins != "" {
+		if err = writeFileWith(m.args.jenkins, func(w io.Writer) error {
+			return writeJenkinsSummary(w, profiles, m.locator(profiles))
+		}); err != nil {
+			return err
+		}
+	}
+	if m.args.cobertura != "" {
+		if err = writeFileWith(m.args.cobertura, func(w io.Writer) error {
+			return writeCobertura(w, profiles, m.locator(profiles))
+		}); err != nil {
+			return err
+		}
+	}
+	if m.args.lcov != "" {
+		if err = writeFileWith(m.args.lcov, func(w io.Writer) error {
+			return writeLcov(w, profiles, m.locator(profiles))
+		}); err != nil {
+			return err
+		}
+	}
+	if m.args.history != "" {
+		if err = appendHistory(m.args.history, newHistoryEntry(m.report, m.repo)); err != nil {
+			return err
+		}
+	}
+	if m.args.pb != "" {
+		if err = writeFileWith(m.args.pb, func(w io.Writer) error {
+			return writePBReport(w, m.report)
+		}); err != nil {
+			return err
+		}
+	}
+	if m.args.csv != "" {
+		if err = m.writeCSV(profiles); err != nil {
+			return err
 		}
-		if m.args.requiredcoverage > 0.0 {
-			if coverage < m.args.requiredcoverage-.001 {
-				msg := fmt.Sprintf("Code coverage %f less than required %f.  See profile.out to debug or run 'go tool cover -html %s -o /tmp/cover.html'", coverage, m.args.requiredcoverage, m.args.coverprofile)
-				m.log.Panic(msg)
-				panic(msg)
+	}
+	if m.args.htmlreport != "" {
+		if err = m.writeHTMLReport(profiles); err != nil {
+			return err
+		}
+	}
+	if m.args.junit != "" {
+		if err = writeFileWith(m.args.junit, func(w io.Writer) error {
+			return writeJUnit(w, m.emittedResults(), m.args.runid)
+		}); err != nil {
+			return err
+		}
+	}
+	if m.args.markdown != "" {
+		if err = writeFileWith(m.args.markdown, func(w io.Writer) error {
+			if err := writeMarkdownSummary(w, profiles, m.args.metric, m.args.requiredcoverage, m.summaryMinStatements(), m.emittedSkipped(), m.findings, m.linker(profiles)); err != nil {
+				return err
 			}
+			return writeMarkdownLeaks(w, m.emittedResults())
+		}); err != nil {
+			return err
+		}
+	}
+	if m.args.azure {
+		if err = m.publishAzure(profiles, coverage); err != nil {
+			return err
+		}
+	}
+
+	if m.args.printcoverage {
+		fmt.Fprintf(m.stdout(), "coverage: %.1f%% of %s\n", coverage, m.args.metric)
+	}
+	if err := m.checkSkips(); err != nil {
+		return err
+	}
+	if err := findingsError(m.findings); err != nil {
+		return err
+	}
+	violations, err := m.evaluateViolations(profiles)
+	if err != nil {
+		return err
+	}
+	localizeViolations(violations, profiles, m.locator(profiles))
+	linkViolations(violations, m.linker(profiles))
+	m.annotateViolations(violations)
+	if len(violations) > 0 && m.args.violations != "" {
+		if err := writeFileWith(m.args.violations, func(w io.Writer) error {
+			return writeViolations(w, violations)
+		}); err != nil {
+			return err
 		}
 	}
+	if len(violations) > 0 {
+		return &thresholdError{summary: closingSummary(profiles, m.args.metric, violations, m.args.requiredcoverage, m.htmlCommand(), m.locator(profiles))}
+	}
 	return nil
 }
 
-func (m *gocoverdir) calculateCoverage() (float64, error) {
-	profiles, err := cover.ParseProfiles(m.args.coverprofile)
-	if err != nil {
-		return 0.0, err
+// applyConfig loads -config.  Its thresholds are defaults for the flags of the same name, which override them
+func (m *gocoverdir) applyConfig() error {
+	var err error
+	if m.config, err = loadConfig(m.args.config); err != nil {
+		return err
+	}
+	if m.config.RequiredCoverage > 0.0 {
+		m.setDefaultFlag("requiredcoverage", fmt.Sprintf("%f", m.config.RequiredCoverage))
+	}
+	if m.config.MinStatements > 0 {
+		m.setDefaultFlag("minstmts", fmt.Sprintf("%d", m.config.MinStatements))
 	}
+	m.config.MinStatements = m.args.minstmts
+	return nil
+}
+
+func calculateCoverage(profiles []*cover.Profile, metric string) float64 {
 	total := 0
 	covered := 0
 	for _, profile := range profiles {
-		for _, block := range profile.Blocks {
-			total += block.NumStmt
-			if block.Count > 0 {
-				covered += block.NumStmt
-			}
-		}
+		profileTotal, profileCovered := countStatements(profile, metric)
+		total += profileTotal
+		covered += profileCovered
 	}
 	if total == 0 {
-		return 0.0, nil
+		return 0.0
+	}
+	return float64(covered) / float64(total) * 100
+}
+
+// writeFileWith creates filename and fills it with write
+func writeFileWith(filename string, write func(w io.Writer) error) error {
+	if filename == stdio {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+var subcommands = map[string]func(args []string) error{
+	"build":             runBuild,
+	"burndown":          runBurndown,
+	"check":             runCheck,
+	"clean":             runClean,
+	"collect":           runCollect,
+	"compact":           runCompact,
+	"compare-branches":  runCompareBranches,
+	"diff":              runProfileDiff,
+	"finalize":          runFinalize,
+	"flakes":            runFlakes,
+	"gaps":              runGaps,
+	"graph":             runGraph,
+	"history":           runHistory,
+	"hot":               runHot,
+	"install-hook":      runInstallHook,
+	"labels":            runLabels,
+	"merge":             runMerge,
+	"prune-advise":      runPruneAdvise,
+	"render-diff":       runRenderDiff,
+	"report":            runReport,
+	"selfupdate":        runSelfUpdate,
+	"snapshot":          runSnapshot,
+	"suggest-threshold": runSuggestThreshold,
+	"unbundle":          runUnbundle,
+	"variants":          runVariants,
+	"verify-report":     runVerifyReport,
+}
+
+// contextSubcommands are the subcommands that run until Options.Context is done
+var contextSubcommands = map[string]func(ctx context.Context, args []string) error{
+	"daemon": runDaemon,
+}
+
+// Options customize a run for programs that embed gocoverdir
+type Options struct {
+	// Discoverer, if set, finds the packages to test instead of -discover, -stdin or package arguments
+	Discoverer Discoverer
+	// Context, if set, cancels the run: its package tests are killed, the coverage collected so far is flushed to the
+	// partial profile, and RunWith returns 130.  The gocoverdir command cancels it on SIGINT and SIGTERM
+	Context context.Context
+	// Chdir, if set, handles -chdir.  The gocoverdir command sets it to os.Chdir.  Without it -chdir is an error, so
+	// embedding never changes the directory of the host process
+	Chdir func(dir string) error
+}
+
+// Run runs gocoverdir with args, the command line without the program name, and returns its exit code.  It runs in
+// the current directory, and neither exits nor handles signals
+func Run(args []string) int {
+	return RunWith(args, Options{})
+}
+
+// runSubcommand runs a subcommand, returning its exit code
+func runSubcommand(run func() error) int {
+	err := run()
+	if err == nil || err == flag.ErrHelp {
+		return 0
 	}
-	return float64(covered) / float64(total) * 100, nil
+	fmt.Fprintln(os.Stderr, err)
+	return 1
 }
 
-func main() {
-	// handleErr may fatal.  Let the close get called
-	defer mainStruct.Close()
+// RunWith is Run customized by opts.  Every way out of a run, a panic, cancellation or an error, goes through Close
+// before RunWith returns, so nothing but -keepwork leaves the store behind
+func RunWith(args []string, opts Options) (code int) {
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(args) > 0 {
+		if subcommand, exists := subcommands[args[0]]; exists {
+			return runSubcommand(func() error {
+				return subcommand(args[1:])
+			})
+		}
+		if subcommand, exists := contextSubcommands[args[0]]; exists {
+			return runSubcommand(func() error {
+				return subcommand(ctx, args[1:])
+			})
+		}
+	}
+	m := &gocoverdir{discoverer: opts.Discoverer, ctx: ctx}
 	defer func() {
 		if panicCondition := recover(); panicCondition != nil {
-			io.Copy(os.Stderr, &mainStruct.panicPrintBuffer)
-			panic(panicCondition)
+			m.flushBufferedLog(os.Stderr)
+			fmt.Fprintf(os.Stderr, "panic: %v\n\n%s", panicCondition, debug.Stack())
+			m.flushPartial()
+			code = 2
+		}
+		if err := m.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to clean up: %s\n", err)
 		}
 	}()
-	mainStruct.setupFlags(flag.CommandLine)
-	flag.Parse()
-	err := mainStruct.Main()
-	mainStruct.handleErr(err)
+	stopCancel := m.closeOnCancel(ctx)
+	defer stopCancel()
+	fs := flag.NewFlagSet("gocoverdir", flag.ContinueOnError)
+	m.setupFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		// The flag set already printed the error and usage
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if m.args.version {
+		fmt.Println("gocoverdir", toolVersion())
+		return 0
+	}
+	m.args.packages = fs.Args()
+	if m.args.chdir != "" {
+		if opts.Chdir == nil {
+			fmt.Fprintf(os.Stderr, "-chdir would change the directory of the program embedding gocoverdir; run it in %s instead\n", m.args.chdir)
+			return 1
+		}
+		if err := opts.Chdir(m.args.chdir); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if m.args.printconfig {
+		if err := m.printConfig(os.Stdout); err != nil {
+			return m.handleErr(err)
+		}
+		return 0
+	}
+	err := m.Main()
+	if ctx.Err() != nil {
+		// closeOnCancel already cleaned up, and the failures of killed tests are not worth reporting
+		return 130
+	}
+	return m.handleErr(err)
 }
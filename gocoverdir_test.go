@@ -1,6 +1,7 @@
-package main
+package gocoverdir
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"testing"
@@ -17,6 +18,27 @@ func TestGocoverdir(t *testing.T) {
 	fs := flag.NewFlagSet("testsetup", flag.PanicOnError)
 	m.setupFlags(fs)
 	noError(t, fs.Parse([]string{""}))
-	fmt.Printf("%+v", m)
+	fmt.Printf("%+v", &m)
 	noError(t, m.setup())
+	defer m.Close()
+}
+
+func TestRunWithKeepsTheProcess(t *testing.T) {
+	if code := Run([]string{"-nosuchflag"}); code != 2 {
+		t.Errorf("Expected a bad flag to return 2, saw %d", code)
+	}
+	if code := Run([]string{"clean", "-h"}); code != 0 {
+		t.Errorf("Expected subcommand help to return 0, saw %d", code)
+	}
+	if code := Run([]string{"-chdir", "elsewhere"}); code != 1 {
+		t.Errorf("Expected -chdir to be refused without Options.Chdir, saw %d", code)
+	}
+	var dir string
+	chdir := func(d string) error {
+		dir = d
+		return errors.New("not changing directory")
+	}
+	if code := RunWith([]string{"-chdir", "elsewhere"}, Options{Chdir: chdir}); code != 1 || dir != "elsewhere" {
+		t.Errorf("Expected -chdir to go through Options.Chdir, saw %d %q", code, dir)
+	}
 }
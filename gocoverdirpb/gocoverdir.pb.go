@@ -0,0 +1,354 @@
+// gRPC API of the daemon, served with -grpc.  Build systems generate clients from this file; Go programs can use
+// the generated client in github.com/cep21/gocoverdir/gocoverdirpb.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: gocoverdir.proto
+
+package gocoverdirpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type RunCoverageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunCoverageRequest) Reset() {
+	*x = RunCoverageRequest{}
+	mi := &file_gocoverdir_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunCoverageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunCoverageRequest) ProtoMessage() {}
+
+func (x *RunCoverageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocoverdir_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunCoverageRequest.ProtoReflect.Descriptor instead.
+func (*RunCoverageRequest) Descriptor() ([]byte, []int) {
+	return file_gocoverdir_proto_rawDescGZIP(), []int{0}
+}
+
+type RunCoverageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Run           int64                  `protobuf:"varint,1,opt,name=run,proto3" json:"run,omitempty"`
+	Started       bool                   `protobuf:"varint,2,opt,name=started,proto3" json:"started,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RunCoverageResponse) Reset() {
+	*x = RunCoverageResponse{}
+	mi := &file_gocoverdir_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RunCoverageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RunCoverageResponse) ProtoMessage() {}
+
+func (x *RunCoverageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gocoverdir_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RunCoverageResponse.ProtoReflect.Descriptor instead.
+func (*RunCoverageResponse) Descriptor() ([]byte, []int) {
+	return file_gocoverdir_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RunCoverageResponse) GetRun() int64 {
+	if x != nil {
+		return x.Run
+	}
+	return 0
+}
+
+func (x *RunCoverageResponse) GetStarted() bool {
+	if x != nil {
+		return x.Started
+	}
+	return false
+}
+
+type GetReportRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetReportRequest) Reset() {
+	*x = GetReportRequest{}
+	mi := &file_gocoverdir_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReportRequest) ProtoMessage() {}
+
+func (x *GetReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocoverdir_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReportRequest.ProtoReflect.Descriptor instead.
+func (*GetReportRequest) Descriptor() ([]byte, []int) {
+	return file_gocoverdir_proto_rawDescGZIP(), []int{2}
+}
+
+type StreamEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only stream this run, or every run if 0
+	Run           int64 `protobuf:"varint,1,opt,name=run,proto3" json:"run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamEventsRequest) Reset() {
+	*x = StreamEventsRequest{}
+	mi := &file_gocoverdir_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamEventsRequest) ProtoMessage() {}
+
+func (x *StreamEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gocoverdir_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamEventsRequest.ProtoReflect.Descriptor instead.
+func (*StreamEventsRequest) Descriptor() ([]byte, []int) {
+	return file_gocoverdir_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StreamEventsRequest) GetRun() int64 {
+	if x != nil {
+		return x.Run
+	}
+	return 0
+}
+
+type Event struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// run_start, package_start, package_done or run_done
+	Type          string  `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Run           int64   `protobuf:"varint,2,opt,name=run,proto3" json:"run,omitempty"`
+	Package       string  `protobuf:"bytes,3,opt,name=package,proto3" json:"package,omitempty"`
+	Error         string  `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	Coverage      float64 `protobuf:"fixed64,5,opt,name=coverage,proto3" json:"coverage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_gocoverdir_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_gocoverdir_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_gocoverdir_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Event) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Event) GetRun() int64 {
+	if x != nil {
+		return x.Run
+	}
+	return 0
+}
+
+func (x *Event) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+func (x *Event) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Event) GetCoverage() float64 {
+	if x != nil {
+		return x.Coverage
+	}
+	return 0
+}
+
+var File_gocoverdir_proto protoreflect.FileDescriptor
+
+const file_gocoverdir_proto_rawDesc = "" +
+	"\n" +
+	"\x10gocoverdir.proto\x12\rgocoverdir.v1\x1a\freport.proto\"\x14\n" +
+	"\x12RunCoverageRequest\"A\n" +
+	"\x13RunCoverageResponse\x12\x10\n" +
+	"\x03run\x18\x01 \x01(\x03R\x03run\x12\x18\n" +
+	"\astarted\x18\x02 \x01(\bR\astarted\"\x12\n" +
+	"\x10GetReportRequest\"'\n" +
+	"\x13StreamEventsRequest\x12\x10\n" +
+	"\x03run\x18\x01 \x01(\x03R\x03run\"y\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x10\n" +
+	"\x03run\x18\x02 \x01(\x03R\x03run\x12\x18\n" +
+	"\apackage\x18\x03 \x01(\tR\apackage\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x1a\n" +
+	"\bcoverage\x18\x05 \x01(\x01R\bcoverage2\xf1\x01\n" +
+	"\bCoverage\x12T\n" +
+	"\vRunCoverage\x12!.gocoverdir.v1.RunCoverageRequest\x1a\".gocoverdir.v1.RunCoverageResponse\x12C\n" +
+	"\tGetReport\x12\x1f.gocoverdir.v1.GetReportRequest\x1a\x15.gocoverdir.v1.Report\x12J\n" +
+	"\fStreamEvents\x12\".gocoverdir.v1.StreamEventsRequest\x1a\x14.gocoverdir.v1.Event0\x01B*Z(github.com/cep21/gocoverdir/gocoverdirpbb\x06proto3"
+
+var (
+	file_gocoverdir_proto_rawDescOnce sync.Once
+	file_gocoverdir_proto_rawDescData []byte
+)
+
+func file_gocoverdir_proto_rawDescGZIP() []byte {
+	file_gocoverdir_proto_rawDescOnce.Do(func() {
+		file_gocoverdir_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gocoverdir_proto_rawDesc), len(file_gocoverdir_proto_rawDesc)))
+	})
+	return file_gocoverdir_proto_rawDescData
+}
+
+var file_gocoverdir_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_gocoverdir_proto_goTypes = []any{
+	(*RunCoverageRequest)(nil),  // 0: gocoverdir.v1.RunCoverageRequest
+	(*RunCoverageResponse)(nil), // 1: gocoverdir.v1.RunCoverageResponse
+	(*GetReportRequest)(nil),    // 2: gocoverdir.v1.GetReportRequest
+	(*StreamEventsRequest)(nil), // 3: gocoverdir.v1.StreamEventsRequest
+	(*Event)(nil),               // 4: gocoverdir.v1.Event
+	(*Report)(nil),              // 5: gocoverdir.v1.Report
+}
+var file_gocoverdir_proto_depIdxs = []int32{
+	0, // 0: gocoverdir.v1.Coverage.RunCoverage:input_type -> gocoverdir.v1.RunCoverageRequest
+	2, // 1: gocoverdir.v1.Coverage.GetReport:input_type -> gocoverdir.v1.GetReportRequest
+	3, // 2: gocoverdir.v1.Coverage.StreamEvents:input_type -> gocoverdir.v1.StreamEventsRequest
+	1, // 3: gocoverdir.v1.Coverage.RunCoverage:output_type -> gocoverdir.v1.RunCoverageResponse
+	5, // 4: gocoverdir.v1.Coverage.GetReport:output_type -> gocoverdir.v1.Report
+	4, // 5: gocoverdir.v1.Coverage.StreamEvents:output_type -> gocoverdir.v1.Event
+	3, // [3:6] is the sub-list for method output_type
+	0, // [0:3] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_gocoverdir_proto_init() }
+func file_gocoverdir_proto_init() {
+	if File_gocoverdir_proto != nil {
+		return
+	}
+	file_report_proto_init()
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gocoverdir_proto_rawDesc), len(file_gocoverdir_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gocoverdir_proto_goTypes,
+		DependencyIndexes: file_gocoverdir_proto_depIdxs,
+		MessageInfos:      file_gocoverdir_proto_msgTypes,
+	}.Build()
+	File_gocoverdir_proto = out.File
+	file_gocoverdir_proto_goTypes = nil
+	file_gocoverdir_proto_depIdxs = nil
+}
@@ -0,0 +1,214 @@
+// gRPC API of the daemon, served with -grpc.  Build systems generate clients from this file; Go programs can use
+// the generated client in github.com/cep21/gocoverdir/gocoverdirpb.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: gocoverdir.proto
+
+package gocoverdirpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Coverage_RunCoverage_FullMethodName  = "/gocoverdir.v1.Coverage/RunCoverage"
+	Coverage_GetReport_FullMethodName    = "/gocoverdir.v1.Coverage/GetReport"
+	Coverage_StreamEvents_FullMethodName = "/gocoverdir.v1.Coverage/StreamEvents"
+)
+
+// CoverageClient is the client API for Coverage service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type CoverageClient interface {
+	// RunCoverage starts a run with the daemon's run flags.  If a run is already in progress it is returned instead,
+	// with started false
+	RunCoverage(ctx context.Context, in *RunCoverageRequest, opts ...grpc.CallOption) (*RunCoverageResponse, error)
+	// GetReport returns the report of the latest successful run, or NOT_FOUND before there is one
+	GetReport(ctx context.Context, in *GetReportRequest, opts ...grpc.CallOption) (*Report, error)
+	// StreamEvents streams the progress of runs until the daemon shuts down.  With a run, it streams only that run's
+	// events and ends after its run_done
+	StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error)
+}
+
+type coverageClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewCoverageClient(cc grpc.ClientConnInterface) CoverageClient {
+	return &coverageClient{cc}
+}
+
+func (c *coverageClient) RunCoverage(ctx context.Context, in *RunCoverageRequest, opts ...grpc.CallOption) (*RunCoverageResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunCoverageResponse)
+	err := c.cc.Invoke(ctx, Coverage_RunCoverage_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coverageClient) GetReport(ctx context.Context, in *GetReportRequest, opts ...grpc.CallOption) (*Report, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Report)
+	err := c.cc.Invoke(ctx, Coverage_GetReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *coverageClient) StreamEvents(ctx context.Context, in *StreamEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Event], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Coverage_ServiceDesc.Streams[0], Coverage_StreamEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamEventsRequest, Event]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Coverage_StreamEventsClient = grpc.ServerStreamingClient[Event]
+
+// CoverageServer is the server API for Coverage service.
+// All implementations must embed UnimplementedCoverageServer
+// for forward compatibility.
+type CoverageServer interface {
+	// RunCoverage starts a run with the daemon's run flags.  If a run is already in progress it is returned instead,
+	// with started false
+	RunCoverage(context.Context, *RunCoverageRequest) (*RunCoverageResponse, error)
+	// GetReport returns the report of the latest successful run, or NOT_FOUND before there is one
+	GetReport(context.Context, *GetReportRequest) (*Report, error)
+	// StreamEvents streams the progress of runs until the daemon shuts down.  With a run, it streams only that run's
+	// events and ends after its run_done
+	StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error
+	mustEmbedUnimplementedCoverageServer()
+}
+
+// UnimplementedCoverageServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedCoverageServer struct{}
+
+func (UnimplementedCoverageServer) RunCoverage(context.Context, *RunCoverageRequest) (*RunCoverageResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method RunCoverage not implemented")
+}
+func (UnimplementedCoverageServer) GetReport(context.Context, *GetReportRequest) (*Report, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetReport not implemented")
+}
+func (UnimplementedCoverageServer) StreamEvents(*StreamEventsRequest, grpc.ServerStreamingServer[Event]) error {
+	return status.Error(codes.Unimplemented, "method StreamEvents not implemented")
+}
+func (UnimplementedCoverageServer) mustEmbedUnimplementedCoverageServer() {}
+func (UnimplementedCoverageServer) testEmbeddedByValue()                  {}
+
+// UnsafeCoverageServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to CoverageServer will
+// result in compilation errors.
+type UnsafeCoverageServer interface {
+	mustEmbedUnimplementedCoverageServer()
+}
+
+func RegisterCoverageServer(s grpc.ServiceRegistrar, srv CoverageServer) {
+	// If the following call panics, it indicates UnimplementedCoverageServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Coverage_ServiceDesc, srv)
+}
+
+func _Coverage_RunCoverage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunCoverageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoverageServer).RunCoverage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Coverage_RunCoverage_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoverageServer).RunCoverage(ctx, req.(*RunCoverageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Coverage_GetReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CoverageServer).GetReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Coverage_GetReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CoverageServer).GetReport(ctx, req.(*GetReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Coverage_StreamEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(CoverageServer).StreamEvents(m, &grpc.GenericServerStream[StreamEventsRequest, Event]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Coverage_StreamEventsServer = grpc.ServerStreamingServer[Event]
+
+// Coverage_ServiceDesc is the grpc.ServiceDesc for Coverage service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Coverage_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gocoverdir.v1.Coverage",
+	HandlerType: (*CoverageServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "RunCoverage",
+			Handler:    _Coverage_RunCoverage_Handler,
+		},
+		{
+			MethodName: "GetReport",
+			Handler:    _Coverage_GetReport_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamEvents",
+			Handler:       _Coverage_StreamEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gocoverdir.proto",
+}
@@ -0,0 +1,852 @@
+// Schema of the -pb report.  Field numbers are stable; bump schema_version on incompatible changes.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: report.proto
+
+package gocoverdirpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Report struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	SchemaVersion     uint32                 `protobuf:"varint,1,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	TimestampUnixNano int64                  `protobuf:"varint,2,opt,name=timestamp_unix_nano,json=timestampUnixNano,proto3" json:"timestamp_unix_nano,omitempty"`
+	Ci                *CI                    `protobuf:"bytes,3,opt,name=ci,proto3" json:"ci,omitempty"`
+	Coverage          float64                `protobuf:"fixed64,4,opt,name=coverage,proto3" json:"coverage,omitempty"`
+	Statements        int64                  `protobuf:"varint,5,opt,name=statements,proto3" json:"statements,omitempty"`
+	Covered           int64                  `protobuf:"varint,6,opt,name=covered,proto3" json:"covered,omitempty"`
+	Packages          []*Package             `protobuf:"bytes,7,rep,name=packages,proto3" json:"packages,omitempty"`
+	Files             []*File                `protobuf:"bytes,8,rep,name=files,proto3" json:"files,omitempty"`
+	Tests             []*Test                `protobuf:"bytes,9,rep,name=tests,proto3" json:"tests,omitempty"`
+	Skipped           []*Skip                `protobuf:"bytes,10,rep,name=skipped,proto3" json:"skipped,omitempty"`
+	Findings          []*Finding             `protobuf:"bytes,11,rep,name=findings,proto3" json:"findings,omitempty"`
+	// Coverage of public API and internal packages, named "public" and "internal"
+	Surfaces []*Package `protobuf:"bytes,12,rep,name=surfaces,proto3" json:"surfaces,omitempty"`
+	// Correlates the reports, logs and history of one run
+	RunId string `protobuf:"bytes,13,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	// The gocoverdir version and commit that wrote the report
+	ToolVersion string `protobuf:"bytes,14,opt,name=tool_version,json=toolVersion,proto3" json:"tool_version,omitempty"`
+	// What coverage, statements and covered count: statements, blocks or lines
+	Metric string `protobuf:"bytes,15,opt,name=metric,proto3" json:"metric,omitempty"`
+	// Coverage of the templates and specs generated files come from
+	Sources       []*Package `protobuf:"bytes,16,rep,name=sources,proto3" json:"sources,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Report) Reset() {
+	*x = Report{}
+	mi := &file_report_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Report) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Report) ProtoMessage() {}
+
+func (x *Report) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Report.ProtoReflect.Descriptor instead.
+func (*Report) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Report) GetSchemaVersion() uint32 {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return 0
+}
+
+func (x *Report) GetTimestampUnixNano() int64 {
+	if x != nil {
+		return x.TimestampUnixNano
+	}
+	return 0
+}
+
+func (x *Report) GetCi() *CI {
+	if x != nil {
+		return x.Ci
+	}
+	return nil
+}
+
+func (x *Report) GetCoverage() float64 {
+	if x != nil {
+		return x.Coverage
+	}
+	return 0
+}
+
+func (x *Report) GetStatements() int64 {
+	if x != nil {
+		return x.Statements
+	}
+	return 0
+}
+
+func (x *Report) GetCovered() int64 {
+	if x != nil {
+		return x.Covered
+	}
+	return 0
+}
+
+func (x *Report) GetPackages() []*Package {
+	if x != nil {
+		return x.Packages
+	}
+	return nil
+}
+
+func (x *Report) GetFiles() []*File {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *Report) GetTests() []*Test {
+	if x != nil {
+		return x.Tests
+	}
+	return nil
+}
+
+func (x *Report) GetSkipped() []*Skip {
+	if x != nil {
+		return x.Skipped
+	}
+	return nil
+}
+
+func (x *Report) GetFindings() []*Finding {
+	if x != nil {
+		return x.Findings
+	}
+	return nil
+}
+
+func (x *Report) GetSurfaces() []*Package {
+	if x != nil {
+		return x.Surfaces
+	}
+	return nil
+}
+
+func (x *Report) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *Report) GetToolVersion() string {
+	if x != nil {
+		return x.ToolVersion
+	}
+	return ""
+}
+
+func (x *Report) GetMetric() string {
+	if x != nil {
+		return x.Metric
+	}
+	return ""
+}
+
+func (x *Report) GetSources() []*Package {
+	if x != nil {
+		return x.Sources
+	}
+	return nil
+}
+
+type CI struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Commit        string                 `protobuf:"bytes,2,opt,name=commit,proto3" json:"commit,omitempty"`
+	Branch        string                 `protobuf:"bytes,3,opt,name=branch,proto3" json:"branch,omitempty"`
+	BuildUrl      string                 `protobuf:"bytes,4,opt,name=build_url,json=buildUrl,proto3" json:"build_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CI) Reset() {
+	*x = CI{}
+	mi := &file_report_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CI) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CI) ProtoMessage() {}
+
+func (x *CI) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CI.ProtoReflect.Descriptor instead.
+func (*CI) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *CI) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CI) GetCommit() string {
+	if x != nil {
+		return x.Commit
+	}
+	return ""
+}
+
+func (x *CI) GetBranch() string {
+	if x != nil {
+		return x.Branch
+	}
+	return ""
+}
+
+func (x *CI) GetBuildUrl() string {
+	if x != nil {
+		return x.BuildUrl
+	}
+	return ""
+}
+
+type Package struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Statements    int64                  `protobuf:"varint,2,opt,name=statements,proto3" json:"statements,omitempty"`
+	Covered       int64                  `protobuf:"varint,3,opt,name=covered,proto3" json:"covered,omitempty"`
+	Coverage      float64                `protobuf:"fixed64,4,opt,name=coverage,proto3" json:"coverage,omitempty"`
+	Risk          float64                `protobuf:"fixed64,5,opt,name=risk,proto3" json:"risk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Package) Reset() {
+	*x = Package{}
+	mi := &file_report_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Package) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Package) ProtoMessage() {}
+
+func (x *Package) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Package.ProtoReflect.Descriptor instead.
+func (*Package) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Package) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *Package) GetStatements() int64 {
+	if x != nil {
+		return x.Statements
+	}
+	return 0
+}
+
+func (x *Package) GetCovered() int64 {
+	if x != nil {
+		return x.Covered
+	}
+	return 0
+}
+
+func (x *Package) GetCoverage() float64 {
+	if x != nil {
+		return x.Coverage
+	}
+	return 0
+}
+
+func (x *Package) GetRisk() float64 {
+	if x != nil {
+		return x.Risk
+	}
+	return 0
+}
+
+type File struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Package       string                 `protobuf:"bytes,2,opt,name=package,proto3" json:"package,omitempty"`
+	Statements    int64                  `protobuf:"varint,3,opt,name=statements,proto3" json:"statements,omitempty"`
+	Covered       int64                  `protobuf:"varint,4,opt,name=covered,proto3" json:"covered,omitempty"`
+	Coverage      float64                `protobuf:"fixed64,5,opt,name=coverage,proto3" json:"coverage,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *File) Reset() {
+	*x = File{}
+	mi := &file_report_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *File) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*File) ProtoMessage() {}
+
+func (x *File) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use File.ProtoReflect.Descriptor instead.
+func (*File) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *File) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *File) GetPackage() string {
+	if x != nil {
+		return x.Package
+	}
+	return ""
+}
+
+func (x *File) GetStatements() int64 {
+	if x != nil {
+		return x.Statements
+	}
+	return 0
+}
+
+func (x *File) GetCovered() int64 {
+	if x != nil {
+		return x.Covered
+	}
+	return 0
+}
+
+func (x *File) GetCoverage() float64 {
+	if x != nil {
+		return x.Coverage
+	}
+	return 0
+}
+
+type Test struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Dir          string                 `protobuf:"bytes,1,opt,name=dir,proto3" json:"dir,omitempty"`
+	Command      string                 `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	Seconds      float64                `protobuf:"fixed64,3,opt,name=seconds,proto3" json:"seconds,omitempty"`
+	Passed       bool                   `protobuf:"varint,4,opt,name=passed,proto3" json:"passed,omitempty"`
+	Error        string                 `protobuf:"bytes,5,opt,name=error,proto3" json:"error,omitempty"`
+	SkippedTests []string               `protobuf:"bytes,6,rep,name=skipped_tests,json=skippedTests,proto3" json:"skipped_tests,omitempty"`
+	Quarantined  bool                   `protobuf:"varint,7,opt,name=quarantined,proto3" json:"quarantined,omitempty"`
+	Flaky        []string               `protobuf:"bytes,8,rep,name=flaky,proto3" json:"flaky,omitempty"`
+	// Mutex and block profile files, by kind
+	Profiles map[string]string `protobuf:"bytes,9,rep,name=profiles,proto3" json:"profiles,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Data race reports from the test output
+	Races []string `protobuf:"bytes,10,rep,name=races,proto3" json:"races,omitempty"`
+	// Example functions that ran, with -examples
+	Examples     []string `protobuf:"bytes,11,rep,name=examples,proto3" json:"examples,omitempty"`
+	ExamplesOnly bool     `protobuf:"varint,12,opt,name=examples_only,json=examplesOnly,proto3" json:"examples_only,omitempty"`
+	// Directory with the command, output, go env and profile of a failed package
+	FailureBundle string `protobuf:"bytes,13,opt,name=failure_bundle,json=failureBundle,proto3" json:"failure_bundle,omitempty"`
+	// Output lines that matched a failoutput config rule
+	OutputMatches []string `protobuf:"bytes,14,rep,name=output_matches,json=outputMatches,proto3" json:"output_matches,omitempty"`
+	// Goroutines the tests left running, with -leakcheck
+	Leaks         []string `protobuf:"bytes,15,rep,name=leaks,proto3" json:"leaks,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Test) Reset() {
+	*x = Test{}
+	mi := &file_report_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Test) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Test) ProtoMessage() {}
+
+func (x *Test) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Test.ProtoReflect.Descriptor instead.
+func (*Test) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Test) GetDir() string {
+	if x != nil {
+		return x.Dir
+	}
+	return ""
+}
+
+func (x *Test) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+func (x *Test) GetSeconds() float64 {
+	if x != nil {
+		return x.Seconds
+	}
+	return 0
+}
+
+func (x *Test) GetPassed() bool {
+	if x != nil {
+		return x.Passed
+	}
+	return false
+}
+
+func (x *Test) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Test) GetSkippedTests() []string {
+	if x != nil {
+		return x.SkippedTests
+	}
+	return nil
+}
+
+func (x *Test) GetQuarantined() bool {
+	if x != nil {
+		return x.Quarantined
+	}
+	return false
+}
+
+func (x *Test) GetFlaky() []string {
+	if x != nil {
+		return x.Flaky
+	}
+	return nil
+}
+
+func (x *Test) GetProfiles() map[string]string {
+	if x != nil {
+		return x.Profiles
+	}
+	return nil
+}
+
+func (x *Test) GetRaces() []string {
+	if x != nil {
+		return x.Races
+	}
+	return nil
+}
+
+func (x *Test) GetExamples() []string {
+	if x != nil {
+		return x.Examples
+	}
+	return nil
+}
+
+func (x *Test) GetExamplesOnly() bool {
+	if x != nil {
+		return x.ExamplesOnly
+	}
+	return false
+}
+
+func (x *Test) GetFailureBundle() string {
+	if x != nil {
+		return x.FailureBundle
+	}
+	return ""
+}
+
+func (x *Test) GetOutputMatches() []string {
+	if x != nil {
+		return x.OutputMatches
+	}
+	return nil
+}
+
+func (x *Test) GetLeaks() []string {
+	if x != nil {
+		return x.Leaks
+	}
+	return nil
+}
+
+type Skip struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dir           string                 `protobuf:"bytes,1,opt,name=dir,proto3" json:"dir,omitempty"`
+	Reason        string                 `protobuf:"bytes,2,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Skip) Reset() {
+	*x = Skip{}
+	mi := &file_report_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Skip) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Skip) ProtoMessage() {}
+
+func (x *Skip) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Skip.ProtoReflect.Descriptor instead.
+func (*Skip) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Skip) GetDir() string {
+	if x != nil {
+		return x.Dir
+	}
+	return ""
+}
+
+func (x *Skip) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type Finding struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Severity      string                 `protobuf:"bytes,2,opt,name=severity,proto3" json:"severity,omitempty"`
+	File          string                 `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"`
+	Line          int64                  `protobuf:"varint,4,opt,name=line,proto3" json:"line,omitempty"`
+	Column        int64                  `protobuf:"varint,5,opt,name=column,proto3" json:"column,omitempty"`
+	Message       string                 `protobuf:"bytes,6,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Finding) Reset() {
+	*x = Finding{}
+	mi := &file_report_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Finding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Finding) ProtoMessage() {}
+
+func (x *Finding) ProtoReflect() protoreflect.Message {
+	mi := &file_report_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Finding.ProtoReflect.Descriptor instead.
+func (*Finding) Descriptor() ([]byte, []int) {
+	return file_report_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Finding) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Finding) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Finding) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *Finding) GetLine() int64 {
+	if x != nil {
+		return x.Line
+	}
+	return 0
+}
+
+func (x *Finding) GetColumn() int64 {
+	if x != nil {
+		return x.Column
+	}
+	return 0
+}
+
+func (x *Finding) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_report_proto protoreflect.FileDescriptor
+
+const file_report_proto_rawDesc = "" +
+	"\n" +
+	"\freport.proto\x12\rgocoverdir.v1\"\xfd\x04\n" +
+	"\x06Report\x12%\n" +
+	"\x0eschema_version\x18\x01 \x01(\rR\rschemaVersion\x12.\n" +
+	"\x13timestamp_unix_nano\x18\x02 \x01(\x03R\x11timestampUnixNano\x12!\n" +
+	"\x02ci\x18\x03 \x01(\v2\x11.gocoverdir.v1.CIR\x02ci\x12\x1a\n" +
+	"\bcoverage\x18\x04 \x01(\x01R\bcoverage\x12\x1e\n" +
+	"\n" +
+	"statements\x18\x05 \x01(\x03R\n" +
+	"statements\x12\x18\n" +
+	"\acovered\x18\x06 \x01(\x03R\acovered\x122\n" +
+	"\bpackages\x18\a \x03(\v2\x16.gocoverdir.v1.PackageR\bpackages\x12)\n" +
+	"\x05files\x18\b \x03(\v2\x13.gocoverdir.v1.FileR\x05files\x12)\n" +
+	"\x05tests\x18\t \x03(\v2\x13.gocoverdir.v1.TestR\x05tests\x12-\n" +
+	"\askipped\x18\n" +
+	" \x03(\v2\x13.gocoverdir.v1.SkipR\askipped\x122\n" +
+	"\bfindings\x18\v \x03(\v2\x16.gocoverdir.v1.FindingR\bfindings\x122\n" +
+	"\bsurfaces\x18\f \x03(\v2\x16.gocoverdir.v1.PackageR\bsurfaces\x12\x15\n" +
+	"\x06run_id\x18\r \x01(\tR\x05runId\x12!\n" +
+	"\ftool_version\x18\x0e \x01(\tR\vtoolVersion\x12\x16\n" +
+	"\x06metric\x18\x0f \x01(\tR\x06metric\x120\n" +
+	"\asources\x18\x10 \x03(\v2\x16.gocoverdir.v1.PackageR\asources\"e\n" +
+	"\x02CI\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06commit\x18\x02 \x01(\tR\x06commit\x12\x16\n" +
+	"\x06branch\x18\x03 \x01(\tR\x06branch\x12\x1b\n" +
+	"\tbuild_url\x18\x04 \x01(\tR\bbuildUrl\"\x87\x01\n" +
+	"\aPackage\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1e\n" +
+	"\n" +
+	"statements\x18\x02 \x01(\x03R\n" +
+	"statements\x12\x18\n" +
+	"\acovered\x18\x03 \x01(\x03R\acovered\x12\x1a\n" +
+	"\bcoverage\x18\x04 \x01(\x01R\bcoverage\x12\x12\n" +
+	"\x04risk\x18\x05 \x01(\x01R\x04risk\"\x8a\x01\n" +
+	"\x04File\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x18\n" +
+	"\apackage\x18\x02 \x01(\tR\apackage\x12\x1e\n" +
+	"\n" +
+	"statements\x18\x03 \x01(\x03R\n" +
+	"statements\x12\x18\n" +
+	"\acovered\x18\x04 \x01(\x03R\acovered\x12\x1a\n" +
+	"\bcoverage\x18\x05 \x01(\x01R\bcoverage\"\x8e\x04\n" +
+	"\x04Test\x12\x10\n" +
+	"\x03dir\x18\x01 \x01(\tR\x03dir\x12\x18\n" +
+	"\acommand\x18\x02 \x01(\tR\acommand\x12\x18\n" +
+	"\aseconds\x18\x03 \x01(\x01R\aseconds\x12\x16\n" +
+	"\x06passed\x18\x04 \x01(\bR\x06passed\x12\x14\n" +
+	"\x05error\x18\x05 \x01(\tR\x05error\x12#\n" +
+	"\rskipped_tests\x18\x06 \x03(\tR\fskippedTests\x12 \n" +
+	"\vquarantined\x18\a \x01(\bR\vquarantined\x12\x14\n" +
+	"\x05flaky\x18\b \x03(\tR\x05flaky\x12=\n" +
+	"\bprofiles\x18\t \x03(\v2!.gocoverdir.v1.Test.ProfilesEntryR\bprofiles\x12\x14\n" +
+	"\x05races\x18\n" +
+	" \x03(\tR\x05races\x12\x1a\n" +
+	"\bexamples\x18\v \x03(\tR\bexamples\x12#\n" +
+	"\rexamples_only\x18\f \x01(\bR\fexamplesOnly\x12%\n" +
+	"\x0efailure_bundle\x18\r \x01(\tR\rfailureBundle\x12%\n" +
+	"\x0eoutput_matches\x18\x0e \x03(\tR\routputMatches\x12\x14\n" +
+	"\x05leaks\x18\x0f \x03(\tR\x05leaks\x1a;\n" +
+	"\rProfilesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"0\n" +
+	"\x04Skip\x12\x10\n" +
+	"\x03dir\x18\x01 \x01(\tR\x03dir\x12\x16\n" +
+	"\x06reason\x18\x02 \x01(\tR\x06reason\"\x93\x01\n" +
+	"\aFinding\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\tR\x04code\x12\x1a\n" +
+	"\bseverity\x18\x02 \x01(\tR\bseverity\x12\x12\n" +
+	"\x04file\x18\x03 \x01(\tR\x04file\x12\x12\n" +
+	"\x04line\x18\x04 \x01(\x03R\x04line\x12\x16\n" +
+	"\x06column\x18\x05 \x01(\x03R\x06column\x12\x18\n" +
+	"\amessage\x18\x06 \x01(\tR\amessageB*Z(github.com/cep21/gocoverdir/gocoverdirpbb\x06proto3"
+
+var (
+	file_report_proto_rawDescOnce sync.Once
+	file_report_proto_rawDescData []byte
+)
+
+func file_report_proto_rawDescGZIP() []byte {
+	file_report_proto_rawDescOnce.Do(func() {
+		file_report_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_report_proto_rawDesc), len(file_report_proto_rawDesc)))
+	})
+	return file_report_proto_rawDescData
+}
+
+var file_report_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_report_proto_goTypes = []any{
+	(*Report)(nil),  // 0: gocoverdir.v1.Report
+	(*CI)(nil),      // 1: gocoverdir.v1.CI
+	(*Package)(nil), // 2: gocoverdir.v1.Package
+	(*File)(nil),    // 3: gocoverdir.v1.File
+	(*Test)(nil),    // 4: gocoverdir.v1.Test
+	(*Skip)(nil),    // 5: gocoverdir.v1.Skip
+	(*Finding)(nil), // 6: gocoverdir.v1.Finding
+	nil,             // 7: gocoverdir.v1.Test.ProfilesEntry
+}
+var file_report_proto_depIdxs = []int32{
+	1, // 0: gocoverdir.v1.Report.ci:type_name -> gocoverdir.v1.CI
+	2, // 1: gocoverdir.v1.Report.packages:type_name -> gocoverdir.v1.Package
+	3, // 2: gocoverdir.v1.Report.files:type_name -> gocoverdir.v1.File
+	4, // 3: gocoverdir.v1.Report.tests:type_name -> gocoverdir.v1.Test
+	5, // 4: gocoverdir.v1.Report.skipped:type_name -> gocoverdir.v1.Skip
+	6, // 5: gocoverdir.v1.Report.findings:type_name -> gocoverdir.v1.Finding
+	2, // 6: gocoverdir.v1.Report.surfaces:type_name -> gocoverdir.v1.Package
+	2, // 7: gocoverdir.v1.Report.sources:type_name -> gocoverdir.v1.Package
+	7, // 8: gocoverdir.v1.Test.profiles:type_name -> gocoverdir.v1.Test.ProfilesEntry
+	9, // [9:9] is the sub-list for method output_type
+	9, // [9:9] is the sub-list for method input_type
+	9, // [9:9] is the sub-list for extension type_name
+	9, // [9:9] is the sub-list for extension extendee
+	0, // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_report_proto_init() }
+func file_report_proto_init() {
+	if File_report_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_report_proto_rawDesc), len(file_report_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   8,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_report_proto_goTypes,
+		DependencyIndexes: file_report_proto_depIdxs,
+		MessageInfos:      file_report_proto_msgTypes,
+	}.Build()
+	File_report_proto = out.File
+	file_report_proto_goTypes = nil
+	file_report_proto_depIdxs = nil
+}
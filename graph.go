@@ -0,0 +1,191 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// importGraph maps each package to the packages it imports
+type importGraph map[string][]string
+
+// listImportGraph asks 'go list -deps' for patterns and everything they depend on, outside the standard library
+func listImportGraph(patterns []string) (importGraph, error) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", append([]string{"list", "-e", "-deps", "-f", "{{if not .Standard}}{{.ImportPath}}{{range .Imports}} {{.}}{{end}}{{end}}"}, patterns...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("cannot list dependencies of %s: %s\n%s", strings.Join(patterns, " "), err, stderr.String())
+	}
+	return parseImportGraph(stdout.String()), nil
+}
+
+func parseImportGraph(output string) importGraph {
+	ret := make(importGraph)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		ret[fields[0]] = fields[1:]
+	}
+	return ret
+}
+
+type graphNode struct {
+	Path     string
+	Coverage float64
+	// Tested is false for packages the profile has nothing for
+	Tested bool
+	FanIn  int
+}
+
+// atRisk is true for a poorly covered package many others import, where a missed bug spreads the furthest
+func (g graphNode) atRisk(below float64, minFanIn int) bool {
+	return g.Tested && g.Coverage < below && g.FanIn >= minFanIn
+}
+
+type coverageGraph struct {
+	Nodes []graphNode
+	// Edges maps each package to the packages in the graph it imports
+	Edges map[string][]string
+}
+
+// buildCoverageGraph keeps the packages of imports that have coverage, or all of them with external, and the imports
+// between them
+func buildCoverageGraph(imports importGraph, coverages map[string]float64, external bool) coverageGraph {
+	ret := coverageGraph{Edges: make(map[string][]string)}
+	inGraph := func(pkg string) bool {
+		_, tested := coverages[pkg]
+		_, listed := imports[pkg]
+		return listed && (tested || external)
+	}
+	fanIn := make(map[string]int)
+	for pkg, imported := range imports {
+		if !inGraph(pkg) {
+			continue
+		}
+		for _, dep := range imported {
+			if inGraph(dep) {
+				ret.Edges[pkg] = append(ret.Edges[pkg], dep)
+				fanIn[dep]++
+			}
+		}
+		sort.Strings(ret.Edges[pkg])
+	}
+	for pkg := range imports {
+		if !inGraph(pkg) {
+			continue
+		}
+		coverage, tested := coverages[pkg]
+		ret.Nodes = append(ret.Nodes, graphNode{Path: pkg, Coverage: coverage, Tested: tested, FanIn: fanIn[pkg]})
+	}
+	sort.Slice(ret.Nodes, func(i, j int) bool {
+		return ret.Nodes[i].Path < ret.Nodes[j].Path
+	})
+	return ret
+}
+
+// untestedColor fills packages without coverage
+const untestedColor = "#9f9f9f"
+
+// writeDOT writes g as a Graphviz graph.  Nodes are filled with the badge color of their coverage, and packages
+// atRisk get a thick red border
+func writeDOT(w io.Writer, g coverageGraph, below float64, minFanIn int) error {
+	var buf bytes.Buffer
+	buf.WriteString("digraph coverage {\n\trankdir=LR;\n\tnode [shape=box, style=filled, fontname=\"Helvetica\"];\n")
+	for _, node := range g.Nodes {
+		label, color := node.Path+"\nuntested", untestedColor
+		if node.Tested {
+			label, color = fmt.Sprintf("%s\n%.1f%%", node.Path, node.Coverage), badgeHexColors[badgeColor(node.Coverage)]
+		}
+		attrs := fmt.Sprintf("label=%s, fillcolor=%q, tooltip=%q", strconv.Quote(label), color, fmt.Sprintf("imported by %d", node.FanIn))
+		if node.atRisk(below, minFanIn) {
+			attrs += ", color=\"#d00\", penwidth=4"
+		}
+		fmt.Fprintf(&buf, "\t%s [%s];\n", strconv.Quote(node.Path), attrs)
+	}
+	for _, node := range g.Nodes {
+		for _, dep := range g.Edges[node.Path] {
+			fmt.Fprintf(&buf, "\t%s -> %s;\n", strconv.Quote(node.Path), strconv.Quote(dep))
+		}
+	}
+	buf.WriteString("}\n")
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// renderDOT writes dot to filename, converting it with Graphviz unless filename ends in .dot or is stdout
+func renderDOT(dot []byte, filename string) error {
+	format := strings.TrimPrefix(filepath.Ext(filename), ".")
+	if filename == stdio || format == "dot" || format == "gv" {
+		return writeFileWith(filename, func(w io.Writer) error {
+			_, err := w.Write(dot)
+			return err
+		})
+	}
+	if _, err := exec.LookPath("dot"); err != nil {
+		return fmt.Errorf("writing %s needs Graphviz's dot.  Install it, or write a .dot file", filename)
+	}
+	var stderr bytes.Buffer
+	cmd := exec.Command("dot", "-T"+format, "-o", filename)
+	cmd.Stdin = bytes.NewReader(dot)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("cannot render %s: %s\n%s", filename, err, stderr.String())
+	}
+	return nil
+}
+
+// runGraph draws the package dependency graph colored by coverage
+func runGraph(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	profile := fs.String("profile", "coverage.out", "Cover profile to color packages by")
+	output := fs.String("o", stdio, "File to write the graph to.  .dot writes Graphviz source, and other extensions, like .svg or .png, are rendered by Graphviz's dot.  - means DOT to stdout")
+	external := fs.Bool("external", false, "If true, include dependencies without coverage, like other modules")
+	below := fs.Float64("below", 60, "Packages covered less than this, and imported by at least -minfanin others, are outlined in red")
+	minFanIn := fs.Int("minfanin", 3, "Packages imported by at least this many others, and covered less than -below, are outlined in red")
+	metric := fs.String("metric", "statements", "What coverage percentages count: statements, blocks or lines")
+	patterns, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := verifyMetric(*metric); err != nil {
+		return err
+	}
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	profiles, err := readProfiles(*profile)
+	if err != nil {
+		return err
+	}
+	coverages := make(map[string]float64)
+	for _, pkg := range newReport(profiles, *metric, nil, nil, nil, nil).Packages {
+		coverages[pkg.Path] = pkg.Coverage
+	}
+	imports, err := listImportGraph(patterns)
+	if err != nil {
+		return err
+	}
+	g := buildCoverageGraph(imports, coverages, *external)
+	var dot bytes.Buffer
+	if err := writeDOT(&dot, g, *below, *minFanIn); err != nil {
+		return err
+	}
+	for _, node := range g.Nodes {
+		if node.atRisk(*below, *minFanIn) {
+			fmt.Fprintf(os.Stderr, "%s is %.1f%% covered and imported by %d packages\n", node.Path, node.Coverage, node.FanIn)
+		}
+	}
+	return renderDOT(dot.Bytes(), *output)
+}
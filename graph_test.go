@@ -0,0 +1,42 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildCoverageGraph(t *testing.T) {
+	imports := parseImportGraph("example.com/api example.com/db example.com/log github.com/lib/pq\nexample.com/db example.com/log github.com/lib/pq\nexample.com/log\n\ngithub.com/lib/pq\n")
+	coverages := map[string]float64{"example.com/api": 90, "example.com/db": 40, "example.com/log": 20}
+	g := buildCoverageGraph(imports, coverages, false)
+	if len(g.Nodes) != 3 || g.Nodes[2].Path != "example.com/log" || g.Nodes[2].FanIn != 2 {
+		t.Errorf("Unexpected nodes %+v", g.Nodes)
+	}
+	if !reflect.DeepEqual(g.Edges["example.com/api"], []string{"example.com/db", "example.com/log"}) {
+		t.Errorf("Unexpected edges %v", g.Edges)
+	}
+	if !g.Nodes[2].atRisk(60, 2) || g.Nodes[1].atRisk(60, 2) {
+		t.Errorf("Expected only the widely imported, poorly covered package to be at risk")
+	}
+	g = buildCoverageGraph(imports, coverages, true)
+	if len(g.Nodes) != 4 || g.Nodes[3].Tested || g.Nodes[3].FanIn != 2 {
+		t.Errorf("Expected external packages without coverage, saw %+v", g.Nodes)
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	g := coverageGraph{
+		Nodes: []graphNode{{Path: "example.com/a", Coverage: 95, Tested: true}, {Path: "example.com/b", Coverage: 10, Tested: true, FanIn: 1}, {Path: "example.com/c", FanIn: 1}},
+		Edges: map[string][]string{"example.com/a": {"example.com/b", "example.com/c"}},
+	}
+	var buf bytes.Buffer
+	noError(t, writeDOT(&buf, g, 60, 1))
+	dot := buf.String()
+	for _, want := range []string{`"example.com/a" [label="example.com/a\n95.0%", fillcolor="#4c1"`, `"example.com/b" [label="example.com/b\n10.0%", fillcolor="#e05d44", tooltip="imported by 1", color="#d00", penwidth=4]`, `fillcolor="#9f9f9f"`, `"example.com/a" -> "example.com/c";`} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("Expected %s in\n%s", want, dot)
+		}
+	}
+}
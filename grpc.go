@@ -0,0 +1,128 @@
+package gocoverdir
+
+//go:generate protoc --go_out=. --go_opt=module=github.com/cep21/gocoverdir --go-grpc_out=. --go-grpc_opt=module=github.com/cep21/gocoverdir report.proto gocoverdir.proto
+
+import (
+	"context"
+	"net"
+
+	"github.com/cep21/gocoverdir/gocoverdirpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// grpcCoverage is the Coverage service of gocoverdir.proto
+type grpcCoverage struct {
+	gocoverdirpb.UnimplementedCoverageServer
+	d *daemon
+}
+
+func eventProto(event runEvent) *gocoverdirpb.Event {
+	return &gocoverdirpb.Event{
+		Type:     event.Type,
+		Run:      int64(event.Run),
+		Package:  event.Package,
+		Error:    event.Error,
+		Coverage: event.Coverage,
+	}
+}
+
+func (s *grpcCoverage) RunCoverage(ctx context.Context, req *gocoverdirpb.RunCoverageRequest) (*gocoverdirpb.RunCoverageResponse, error) {
+	if s.d.isDraining() {
+		return nil, status.Error(codes.Unavailable, "shutting down")
+	}
+	runID, started := s.d.startRun()
+	return &gocoverdirpb.RunCoverageResponse{Run: int64(runID), Started: started}, nil
+}
+
+func (s *grpcCoverage) GetReport(ctx context.Context, req *gocoverdirpb.GetReportRequest) (*gocoverdirpb.Report, error) {
+	s.d.mu.Lock()
+	latest := s.d.latest
+	s.d.mu.Unlock()
+	if latest == nil {
+		return nil, status.Error(codes.NotFound, "no completed run yet")
+	}
+	return reportProto(latest), nil
+}
+
+func (s *grpcCoverage) StreamEvents(req *gocoverdirpb.StreamEventsRequest, stream gocoverdirpb.Coverage_StreamEventsServer) error {
+	d := s.d
+	run := int(req.GetRun())
+	events, unsubscribe := d.subscribe()
+	defer unsubscribe()
+	if run != 0 {
+		// Subscribing first means a run that finishes from here on is in events
+		d.mu.Lock()
+		finished, latestRun := d.finished, d.runID
+		d.mu.Unlock()
+		if finished.Run == run {
+			return stream.Send(eventProto(finished))
+		}
+		if run > latestRun || run < finished.Run {
+			return status.Errorf(codes.NotFound, "run %d is not in progress", run)
+		}
+	}
+	for {
+		select {
+		case <-stream.Context().Done():
+			// The client went away, so there is nobody to send a status to
+			return nil
+		case <-d.done:
+			return nil
+		case event := <-events:
+			if run != 0 && event.Run != run {
+				continue
+			}
+			if err := stream.Send(eventProto(event)); err != nil {
+				return err
+			}
+			if run != 0 && event.Type == "run_done" {
+				return nil
+			}
+		}
+	}
+}
+
+// newGRPCServer serves the Coverage service, and grpc.health.v1.Health so load balancers and Kubernetes gRPC probes
+// stop sending calls once shutdown starts draining
+func (d *daemon) newGRPCServer() *grpc.Server {
+	server := grpc.NewServer()
+	gocoverdirpb.RegisterCoverageServer(server, &grpcCoverage{d: d})
+	d.grpcHealth = health.NewServer()
+	d.grpcHealth.SetServingStatus(gocoverdirpb.Coverage_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, d.grpcHealth)
+	return server
+}
+
+// serveGRPC serves the gRPC API on addr in the background, until shutdown stops it
+func (d *daemon) serveGRPC(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	d.grpcServer = d.newGRPCServer()
+	go func() {
+		if err := d.grpcServer.Serve(listener); err != nil {
+			d.log.Printf("gRPC server stopped: %s", err)
+		}
+	}()
+	d.log.Printf("Serving gRPC on %s", listener.Addr())
+	return nil
+}
+
+// stopGRPC stops server once its calls finish, or right away once ctx is done
+func stopGRPC(ctx context.Context, server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		server.Stop()
+	}
+}
@@ -0,0 +1,177 @@
+package gocoverdir
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/cep21/gocoverdir/gocoverdirpb"
+	"golang.org/x/tools/cover"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func newGRPCTestServer(t *testing.T, d *daemon) *grpc.ClientConn {
+	listener, err := net.Listen("tcp", "localhost:0")
+	noError(t, err)
+	server := d.newGRPCServer()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	noError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return conn
+}
+
+func expectGRPCCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Errorf("Expected gRPC code %s, saw %v", code, err)
+	}
+}
+
+func TestGRPCReport(t *testing.T) {
+	d := newDaemon(nil, log.New(ioutil.Discard, "", 0))
+	client := gocoverdirpb.NewCoverageClient(newGRPCTestServer(t, d))
+	ctx := context.Background()
+	_, err := client.GetReport(ctx, &gocoverdirpb.GetReportRequest{})
+	expectGRPCCode(t, err, codes.NotFound)
+
+	profiles := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{StartLine: 1, EndLine: 1, NumStmt: 3, Count: 1}, {StartLine: 2, EndLine: 2, NumStmt: 1}}},
+	}
+	d.latest = newReport(profiles, "statements", nil, nil, nil, nil)
+	rep, err := client.GetReport(ctx, &gocoverdirpb.GetReportRequest{})
+	noError(t, err)
+	if rep.GetCoverage() != 75 || rep.GetMetric() != "statements" || len(rep.GetFiles()) != 1 || rep.GetFiles()[0].GetPath() != "example.com/a/a.go" {
+		t.Errorf("Unexpected report %v", rep)
+	}
+}
+
+func TestGRPCRunCoverage(t *testing.T) {
+	d := newDaemon(nil, log.New(ioutil.Discard, "", 0))
+	client := gocoverdirpb.NewCoverageClient(newGRPCTestServer(t, d))
+	ctx := context.Background()
+	d.running = true
+	d.runID = 4
+	resp, err := client.RunCoverage(ctx, &gocoverdirpb.RunCoverageRequest{})
+	noError(t, err)
+	if resp.GetRun() != 4 || resp.GetStarted() {
+		t.Errorf("Expected run 4 already in progress, saw %v", resp)
+	}
+	d.running = false
+	d.draining = true
+	_, err = client.RunCoverage(ctx, &gocoverdirpb.RunCoverageRequest{})
+	expectGRPCCode(t, err, codes.Unavailable)
+}
+
+func TestGRPCStreamEvents(t *testing.T) {
+	d := newDaemon(nil, log.New(ioutil.Discard, "", 0))
+	client := gocoverdirpb.NewCoverageClient(newGRPCTestServer(t, d))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	d.runID = 2
+	d.running = true
+	d.finished = runEvent{Type: "run_done", Run: 1, Error: "tests failed"}
+
+	collect := func(run int64) ([]*gocoverdirpb.Event, error) {
+		stream, err := client.StreamEvents(ctx, &gocoverdirpb.StreamEventsRequest{Run: run})
+		if err != nil {
+			return nil, err
+		}
+		var events []*gocoverdirpb.Event
+		for {
+			event, err := stream.Recv()
+			if err == io.EOF {
+				return events, nil
+			}
+			if err != nil {
+				return events, err
+			}
+			events = append(events, event)
+		}
+	}
+	expectEvents := func(events []*gocoverdirpb.Event, expected ...runEvent) {
+		t.Helper()
+		if len(events) != len(expected) {
+			t.Fatalf("Expected %d events, saw %v", len(expected), events)
+		}
+		for i := range expected {
+			if !proto.Equal(events[i], eventProto(expected[i])) {
+				t.Errorf("Event %d is %v, not %+v", i, events[i], expected[i])
+			}
+		}
+	}
+
+	// A finished run streams just its run_done
+	events, err := collect(1)
+	noError(t, err)
+	expectEvents(events, d.finished)
+	_, err = collect(3)
+	expectGRPCCode(t, err, codes.NotFound)
+
+	go func() {
+		for {
+			d.mu.Lock()
+			subscribed := len(d.subscribers) > 0
+			d.mu.Unlock()
+			if subscribed {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+		d.publish(runEvent{Type: "package_done", Package: "./a", Error: "exit status 1\n✗"})
+		d.publish(runEvent{Type: "package_done", Run: 3, Package: "./b"})
+		d.publish(runEvent{Type: "run_done", Coverage: 50})
+	}()
+	events, err = collect(2)
+	noError(t, err)
+	expectEvents(events,
+		runEvent{Type: "package_done", Run: 2, Package: "./a", Error: "exit status 1\n✗"},
+		runEvent{Type: "run_done", Run: 2, Coverage: 50},
+	)
+}
+
+func TestGRPCHealth(t *testing.T) {
+	d := newDaemon(nil, log.New(ioutil.Discard, "", 0))
+	client := healthpb.NewHealthClient(newGRPCTestServer(t, d))
+	ctx := context.Background()
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		t.Helper()
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		noError(t, err)
+		return resp.GetStatus()
+	}
+	for _, service := range []string{"", "gocoverdir.v1.Coverage"} {
+		if status := check(service); status != healthpb.HealthCheckResponse_SERVING {
+			t.Errorf("Expected %q to be serving, saw %s", service, status)
+		}
+	}
+
+	// Draining is not serving while the run in progress finishes
+	d.runs.Add(1)
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- d.shutdown(10 * time.Second)
+	}()
+	for !d.isDraining() {
+		time.Sleep(time.Millisecond)
+	}
+	for _, service := range []string{"", "gocoverdir.v1.Coverage"} {
+		if status := check(service); status != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Errorf("Expected %q to not be serving while draining, saw %s", service, status)
+		}
+	}
+	d.runs.Done()
+	noError(t, <-shutdown)
+}
@@ -0,0 +1,77 @@
+package gocoverdir
+
+import (
+	"strconv"
+
+	"golang.org/x/tools/cover"
+)
+
+// heatBuckets are the lowest hit count of each heat level above uncovered, so level 4 is 1000 or more hits
+var heatBuckets = []int{1, 10, 100, 1000}
+
+// heatLevel is 0 for an uncovered line, otherwise how many buckets its hits reach
+func heatLevel(hits int) int {
+	level := 0
+	for _, bucket := range heatBuckets {
+		if hits >= bucket {
+			level++
+		}
+	}
+	return level
+}
+
+// heatLine is one source line, and how hot it is if it is code
+type heatLine struct {
+	Number int
+	Text   string
+	IsCode bool
+	Hits   int
+}
+
+// Class is the CSS class of the line's heat level, or "" for lines without code
+func (l heatLine) Class() string {
+	if !l.IsCode {
+		return ""
+	}
+	return "heat" + strconv.Itoa(heatLevel(l.Hits))
+}
+
+// heatFile is the hit counts of every line of one file
+type heatFile struct {
+	Name  string
+	Lines []heatLine
+}
+
+// countsHits is true if profiles count hits, which only count and atomic modes do
+func countsHits(profiles []*cover.Profile) bool {
+	return len(profiles) > 0 && (profiles[0].Mode == "count" || profiles[0].Mode == "atomic")
+}
+
+// heatFiles colors each line of profiles by hit count.  Files whose source cannot be read list only their code lines
+func heatFiles(profiles []*cover.Profile, loc *fileLocator) []heatFile {
+	ret := make([]heatFile, 0, len(profiles))
+	for _, p := range profiles {
+		source := readSourceLines(loc.path(p))
+		hits := make(map[int]int)
+		last := len(source)
+		for _, line := range lineHits(p) {
+			hits[line.number] = line.hits
+			if line.number > last {
+				last = line.number
+			}
+		}
+		file := heatFile{Name: loc.name(p)}
+		for number := 1; number <= last; number++ {
+			line := heatLine{Number: number}
+			line.Hits, line.IsCode = hits[number]
+			if number <= len(source) {
+				line.Text = source[number-1]
+			} else if !line.IsCode {
+				continue
+			}
+			file.Lines = append(file.Lines, line)
+		}
+		ret = append(ret, file)
+	}
+	return ret
+}
@@ -0,0 +1,54 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestHeatLevel(t *testing.T) {
+	for hits, expected := range map[int]int{0: 0, 1: 1, 9: 1, 10: 2, 99: 2, 100: 3, 999: 3, 1000: 4, 50000: 4} {
+		if level := heatLevel(hits); level != expected {
+			t.Errorf("heatLevel(%d) = %d, expected %d", hits, level, expected)
+		}
+	}
+}
+
+func TestHeatFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestHeatFiles")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A() {\n\tb()\n}\n"), 0644))
+	profiles := []*cover.Profile{{FileName: "example.com/a/a.go", Mode: "count", Blocks: []cover.ProfileBlock{
+		{StartLine: 3, EndLine: 3, NumStmt: 1, Count: 250},
+		{StartLine: 4, EndLine: 4, NumStmt: 1, Count: 0},
+	}}}
+	if !countsHits(profiles) || countsHits([]*cover.Profile{{Mode: "set"}}) {
+		t.Errorf("Only count and atomic modes count hits")
+	}
+	loc := &fileLocator{pkgDirs: map[string]string{"example.com/a": dir}, root: dir}
+	files := heatFiles(profiles, loc)
+	if len(files) != 1 || len(files[0].Lines) != 5 {
+		t.Fatalf("Unexpected heat files %+v", files)
+	}
+	classes := make([]string, 0, 5)
+	for _, line := range files[0].Lines {
+		classes = append(classes, line.Class())
+	}
+	if strings.Join(classes, ",") != ",,heat3,heat0," {
+		t.Errorf("Unexpected classes %v", classes)
+	}
+
+	buf := bytes.Buffer{}
+	noError(t, writeHTMLReport(&buf, profiles, "statements", &codeowners{}, loc))
+	for _, expected := range []string{"Hit counts", `class="heat3" title="250 hits">func A() {`, `class="heat0" title="0 hits">`} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in report:\n%s", expected, buf.String())
+		}
+	}
+}
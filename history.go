@@ -0,0 +1,110 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// historyEntry is one run's line in the -history store
+type historyEntry struct {
+	RunID       string             `json:"run_id,omitempty"`
+	ToolVersion string             `json:"tool_version,omitempty"`
+	Metric      string             `json:"metric,omitempty"`
+	Timestamp   time.Time          `json:"timestamp"`
+	Commit      string             `json:"commit,omitempty"`
+	Branch      string             `json:"branch,omitempty"`
+	Coverage    float64            `json:"coverage"`
+	Packages    map[string]float64 `json:"packages"`
+	Flaky       []flakyTest        `json:"flaky,omitempty"`
+	// Seconds is how long each directory's tests took
+	Seconds map[string]float64 `json:"seconds,omitempty"`
+}
+
+func newHistoryEntry(r *report, repo vcs) historyEntry {
+	entry := historyEntry{
+		RunID:       r.RunID,
+		ToolVersion: r.ToolVersion,
+		Metric:      r.Metric,
+		Timestamp:   r.Timestamp,
+		Coverage:    r.Coverage,
+		Packages:    make(map[string]float64, len(r.Packages)),
+	}
+	if r.CI != nil {
+		entry.Commit = r.CI.Commit
+		entry.Branch = r.CI.Branch
+	}
+	if entry.Commit == "" {
+		entry.Commit = repo.commit()
+	}
+	if entry.Branch == "" {
+		entry.Branch = repo.branch()
+	}
+	for _, pkg := range r.Packages {
+		entry.Packages[pkg.Path] = pkg.Coverage
+	}
+	for _, test := range r.Tests {
+		if test.Command == "go test" {
+			if entry.Seconds == nil {
+				entry.Seconds = make(map[string]float64)
+			}
+			entry.Seconds[test.Dir] += test.Seconds
+		}
+		for _, name := range test.Flaky {
+			entry.Flaky = append(entry.Flaky, flakyTest{Package: test.Dir, Test: name})
+		}
+	}
+	return entry
+}
+
+func appendHistory(filename string, entry historyEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func readHistory(filename string) ([]historyEntry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var ret []historyEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var entry historyEntry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", filename, lineNumber, err)
+		}
+		ret = append(ret, entry)
+	}
+	return ret, scanner.Err()
+}
+
+func runHistory(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "chart":
+			return runHistoryChart(args[1:])
+		}
+	}
+	return fmt.Errorf("usage: gocoverdir history chart [flags]")
+}
@@ -0,0 +1,35 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistoryRoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdirtest")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "history.jsonl")
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	noError(t, appendHistory(filename, historyEntry{Timestamp: start, Coverage: 50, Packages: map[string]float64{"example.com/a": 50}}))
+	noError(t, appendHistory(filename, historyEntry{Timestamp: start.Add(time.Hour), Coverage: 60, Packages: map[string]float64{"example.com/a": 55, "example.com/b": 70}}))
+	entries, err := readHistory(filename)
+	noError(t, err)
+	if len(entries) != 2 || entries[1].Packages["example.com/b"] != 70 {
+		t.Fatalf("Unexpected entries %+v", entries)
+	}
+	series := historySeries(entries)
+	if len(series) != 3 || series[0].Name != "total" || len(series[1].Points) != 2 || len(series[2].Points) != 1 {
+		t.Errorf("Unexpected series %+v", series)
+	}
+	buf := bytes.Buffer{}
+	noError(t, writeHistoryChart(&buf, "trend", entries))
+	if !strings.Contains(buf.String(), `"name":"example.com/b"`) {
+		t.Errorf("Chart is missing package series: %s", buf.String())
+	}
+}
@@ -0,0 +1,120 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hookMarker is in every hook install-hook writes, so it knows it may replace them
+const hookMarker = "Installed by gocoverdir install-hook"
+
+// hookOptions configure the pre-push hook
+type hookOptions struct {
+	// command runs gocoverdir
+	command string
+	// base is the branch pushes are compared against
+	base         string
+	diffcoverage float64
+	required     float64
+	fast         bool
+	// bypass is the environment variable that skips the hook when set
+	bypass string
+	// extra are more gocoverdir flags, already shell quoted
+	extra string
+}
+
+// prePushHook is a git pre-push hook that tests the packages with Go files changed since the merge base with base,
+// and gates the coverage of the changed lines
+func prePushHook(opts hookOptions) string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "#!/bin/sh\n# %s.  Set %s=1 to push without it\n", hookMarker, opts.bypass)
+	fmt.Fprintf(&buf, "if [ -n \"$%s\" ]; then\n\texit 0\nfi\n", opts.bypass)
+	fmt.Fprintf(&buf, "base=%s\n", shellQuote(opts.base))
+	buf.WriteString(`if ! mergebase=$(git merge-base "$base" HEAD 2>/dev/null); then
+	echo "gocoverdir: cannot find $base to compare against, not checking coverage" >&2
+	exit 0
+fi
+dirs=$(git diff --name-only --diff-filter=d "$mergebase" HEAD -- '*.go' | xargs -n 1 dirname | sort -u)
+if [ -z "$dirs" ]; then
+	exit 0
+fi
+`)
+	args := []string{"-stdin", "-logfile", "''", "-diffbase", `"$mergebase"`, "-coverprofile", `"$(git rev-parse --git-dir)/gocoverdir.out"`}
+	if opts.fast {
+		args = append(args, "-fast")
+	}
+	if opts.diffcoverage > 0 {
+		args = append(args, "-diffcoverage", fmt.Sprintf("%g", opts.diffcoverage))
+	}
+	if opts.required > 0 {
+		args = append(args, "-requiredcoverage", fmt.Sprintf("%g", opts.required))
+	}
+	if opts.extra != "" {
+		args = append(args, opts.extra)
+	}
+	fmt.Fprintf(&buf, "echo \"$dirs\" | %s %s\n", shellQuote(opts.command), strings.Join(args, " "))
+	fmt.Fprintf(&buf, "status=$?\nif [ $status -ne 0 ]; then\n\techo \"gocoverdir: coverage check failed.  Set %s=1 to push anyway\" >&2\nfi\nexit $status\n", opts.bypass)
+	return buf.String()
+}
+
+// hookPath is where git runs the pre-push hook from, which honors core.hooksPath
+func hookPath() (string, error) {
+	out, err := commandOutputErr(".", "git", "rev-parse", "--git-path", "hooks/pre-push")
+	if err != nil {
+		return "", fmt.Errorf("install-hook needs a git repository: %s", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// runInstallHook writes a pre-push git hook that checks the coverage of changed packages before they reach CI
+func runInstallHook(args []string) error {
+	fs := flag.NewFlagSet("install-hook", flag.ContinueOnError)
+	opts := hookOptions{}
+	fs.StringVar(&opts.command, "command", "gocoverdir", "Command the hook runs gocoverdir with")
+	fs.StringVar(&opts.base, "base", "origin/main", "Branch pushes are compared against to find the changed packages and lines")
+	fs.Float64Var(&opts.diffcoverage, "diffcoverage", 80, "Fail the push if the coverage of the changed lines is below this percentage.  0 disables it")
+	fs.Float64Var(&opts.required, "requiredcoverage", 0, "Fail the push if the coverage of the changed packages is below this percentage")
+	fs.BoolVar(&opts.fast, "fast", true, "If true, skip slow packages like -fast")
+	fs.StringVar(&opts.bypass, "bypass", "GOCOVERDIR_SKIP_HOOK", "Environment variable that skips the hook when set")
+	fs.StringVar(&opts.extra, "args", "", "More gocoverdir flags for the hook to pass, like \"-config coverage.json\"")
+	output := fs.String("o", "", "File to write the hook to.  Defaults to the repository's pre-push hook.  - prints it")
+	force := fs.Bool("f", false, "If true, replace a pre-push hook gocoverdir did not write")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: gocoverdir install-hook [-base branch] [-diffcoverage percent] [-bypass env] [-f] [-o file]")
+	}
+	script := prePushHook(opts)
+	if *output == stdio {
+		fmt.Print(script)
+		return nil
+	}
+	target := *output
+	if target == "" {
+		var err error
+		if target, err = hookPath(); err != nil {
+			return err
+		}
+	}
+	if existing, err := ioutil.ReadFile(target); err == nil && !*force && !strings.Contains(string(existing), hookMarker) {
+		return fmt.Errorf("%s already exists and was not written by gocoverdir.  Use -f to replace it", target)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(target, []byte(script), 0755); err != nil {
+		return err
+	}
+	// WriteFile keeps the mode of a file that already exists
+	if err := os.Chmod(target, 0755); err != nil {
+		return err
+	}
+	fmt.Printf("Installed the pre-push hook %s\n", target)
+	return nil
+}
@@ -0,0 +1,52 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrePushHook(t *testing.T) {
+	script := prePushHook(hookOptions{command: "gocoverdir", base: "origin/main", diffcoverage: 80, fast: true, bypass: "SKIP_COVERAGE", extra: "-config cover.json"})
+	for _, expected := range []string{
+		hookMarker,
+		`if [ -n "$SKIP_COVERAGE" ]; then`,
+		"base='origin/main'\n",
+		`echo "$dirs" | 'gocoverdir' -stdin -logfile '' -diffbase "$mergebase" -coverprofile "$(git rev-parse --git-dir)/gocoverdir.out" -fast -diffcoverage 80 -config cover.json` + "\n",
+	} {
+		if !strings.Contains(script, expected) {
+			t.Errorf("Expected the hook to contain %q:\n%s", expected, script)
+		}
+	}
+	if strings.Contains(prePushHook(hookOptions{command: "gocoverdir", bypass: "SKIP"}), "-diffcoverage") {
+		t.Errorf("Expected no -diffcoverage gate when it is 0")
+	}
+}
+
+func TestRunInstallHook(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdir")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	target := filepath.Join(dir, "hooks", "pre-push")
+	noError(t, runInstallHook([]string{"-o", target, "-base", "develop"}))
+	info, err := os.Stat(target)
+	noError(t, err)
+	if info.Mode()&0111 == 0 {
+		t.Errorf("Expected the hook to be executable, got %s", info.Mode())
+	}
+	// Replacing its own hook is fine
+	noError(t, runInstallHook([]string{"-o", target}))
+
+	noError(t, ioutil.WriteFile(target, []byte("#!/bin/sh\nmake lint\n"), 0755))
+	if err := runInstallHook([]string{"-o", target}); err == nil {
+		t.Errorf("Expected an existing hook to be kept")
+	}
+	noError(t, runInstallHook([]string{"-o", target, "-f"}))
+	contents, err := ioutil.ReadFile(target)
+	noError(t, err)
+	if !strings.Contains(string(contents), hookMarker) {
+		t.Errorf("Expected -f to replace the hook, got %s", contents)
+	}
+}
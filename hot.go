@@ -0,0 +1,90 @@
+package gocoverdir
+
+import (
+	"flag"
+	"fmt"
+	"path"
+	"sort"
+
+	"golang.org/x/tools/cover"
+)
+
+// hotBlock is a block of statements and how many times tests ran it
+type hotBlock struct {
+	file       string
+	startLine  int
+	endLine    int
+	statements int
+	count      int
+	function   string
+}
+
+func (h hotBlock) String() string {
+	ret := fmt.Sprintf("%s:%d-%d (%d stmts): %d hits", h.file, h.startLine, h.endLine, h.statements, h.count)
+	if h.function != "" {
+		ret += ": func " + h.function
+	}
+	return ret
+}
+
+// findHot lists the blocks of every profile in a package matching pattern, the most run first.  Blocks are named by
+// their enclosing function when the source can be found
+func findHot(profiles []*cover.Profile, pattern string, loc *fileLocator) []hotBlock {
+	var ret []hotBlock
+	for _, p := range profiles {
+		if pattern != "" && !matchPackage(pattern, path.Dir(p.FileName)) {
+			continue
+		}
+		var funcs []funcLines
+		if filename := loc.path(p); filename != "" {
+			funcs, _ = funcNames(filename)
+		}
+		file := loc.name(p)
+		for _, block := range p.Blocks {
+			if block.NumStmt == 0 || block.Count == 0 {
+				continue
+			}
+			ret = append(ret, hotBlock{
+				file:       file,
+				startLine:  block.StartLine,
+				endLine:    block.EndLine,
+				statements: block.NumStmt,
+				count:      block.Count,
+				function:   enclosingFunc(funcs, block.StartLine),
+			})
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].count > ret[j].count
+	})
+	return ret
+}
+
+// runHot prints the statements of a count or atomic profile that tests ran the most, which is where accidental
+// quadratic behavior in tests shows up
+func runHot(args []string) error {
+	fs := flag.NewFlagSet("hot", flag.ContinueOnError)
+	profile := fs.String("profile", "coverage.out", "Cover profile, from -covermode count or atomic")
+	limit := fs.Int("n", 50, "How many of the most run blocks to print.  0 prints all of them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return fmt.Errorf("usage: gocoverdir hot [-profile coverage.out] [-n 50] [package]")
+	}
+	profiles, err := readProfiles(*profile)
+	if err != nil {
+		return err
+	}
+	if len(profiles) > 0 && !countsHits(profiles) {
+		return fmt.Errorf("%s is mode %s, which does not count hits: use -covermode count or atomic", *profile, profiles[0].Mode)
+	}
+	hot := findHot(profiles, fs.Arg(0), newFileLocator(profiles, false))
+	if *limit > 0 && len(hot) > *limit {
+		hot = hot[:*limit]
+	}
+	for _, h := range hot {
+		fmt.Println(h)
+	}
+	return nil
+}
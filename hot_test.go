@@ -0,0 +1,36 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestFindHot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestFindHot")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc Sort() {\n\tswap()\n}\n\nfunc swap() {\n}\n"), 0644))
+	profiles := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Mode: "count", Blocks: []cover.ProfileBlock{
+			{StartLine: 3, EndLine: 4, NumStmt: 1, Count: 10},
+			{StartLine: 7, EndLine: 8, NumStmt: 1, Count: 10000},
+			{StartLine: 8, EndLine: 8, NumStmt: 1, Count: 0},
+		}},
+		{FileName: "example.com/b/b.go", Mode: "count", Blocks: []cover.ProfileBlock{{StartLine: 1, EndLine: 1, NumStmt: 2, Count: 500}}},
+	}
+	loc := &fileLocator{pkgDirs: map[string]string{"example.com/a": dir}, root: dir}
+	hot := findHot(profiles, "", loc)
+	if len(hot) != 3 {
+		t.Fatalf("Unexpected hot blocks %v", hot)
+	}
+	if hot[0].String() != "a.go:7-8 (1 stmts): 10000 hits: func swap" || hot[1].count != 500 || hot[2].function != "Sort" {
+		t.Errorf("Unexpected hot blocks %v", hot)
+	}
+	if hot := findHot(profiles, "example.com/b", loc); len(hot) != 1 || hot[0].file != "example.com/b/b.go" {
+		t.Errorf("Unexpected hot blocks of b %v", hot)
+	}
+}
@@ -0,0 +1,203 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"html/template"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+// htmlReportFile is one file's coverage, with every attribute the HTML report can group by
+type htmlReportFile struct {
+	Path       string   `json:"path"`
+	Package    string   `json:"package"`
+	Dir        string   `json:"dir"`
+	Owners     []string `json:"owners"`
+	Tags       string   `json:"tags"`
+	Statements int      `json:"statements"`
+	Covered    int      `json:"covered"`
+}
+
+// buildConstraint returns the //go:build expression of a Go file, or "" if it has none
+func buildConstraint(filename string) string {
+	f, err := os.Open(filename)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "//go:build ") {
+			return strings.TrimSpace(strings.TrimPrefix(line, "//go:build "))
+		}
+		if strings.HasPrefix(line, "package ") {
+			return ""
+		}
+	}
+	return ""
+}
+
+func htmlReportFiles(profiles []*cover.Profile, metric string, owners *codeowners, loc *fileLocator) []htmlReportFile {
+	ret := make([]htmlReportFile, 0, len(profiles))
+	for _, p := range profiles {
+		total, covered := countStatements(p, metric)
+		file := loc.name(p)
+		dir := "."
+		if idx := strings.Index(file, "/"); idx > 0 && !strings.HasPrefix(file, "..") {
+			dir = file[:idx]
+		}
+		fileOwners := owners.owners(file)
+		if fileOwners == nil {
+			fileOwners = []string{}
+		}
+		ret = append(ret, htmlReportFile{
+			Path:       file,
+			Package:    path.Dir(p.FileName),
+			Dir:        dir,
+			Owners:     fileOwners,
+			Tags:       buildConstraint(loc.path(p)),
+			Statements: total,
+			Covered:    covered,
+		})
+	}
+	return ret
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage report</title>
+<style>
+body { font-family: sans-serif; margin: 1em; }
+table { border-collapse: collapse; margin-top: 1em; }
+td, th { padding: 4px 12px; border-bottom: 1px solid #ddd; text-align: left; }
+td.num { text-align: right; }
+.bar { background: #f2dede; width: 200px; height: 10px; }
+.bar div { background: #5cb85c; height: 10px; }
+table.source { font-family: monospace; margin-top: 0; }
+table.source td { padding: 0 8px; border: none; white-space: pre; }
+.heat0 { background: #fbd6d6; }
+.heat1 { background: #e3f5e3; }
+.heat2 { background: #b9e6b9; }
+.heat3 { background: #7fcf7f; }
+.heat4 { background: #3fa63f; color: #fff; }
+</style>
+</head>
+<body>
+<h1>Coverage: {{printf "%.1f" .Coverage}}% of {{.Metric}}</h1>
+<p>Generated {{.Generated}} by gocoverdir {{.Version}}</p>
+<label for="group">Group by</label>
+<select id="group">
+<option value="package">Package</option>
+<option value="dir">Top level directory</option>
+<option value="owners">CODEOWNERS team</option>
+<option value="tags">Build constraint</option>
+<option value="path">File</option>
+</select>
+<table>
+<thead><tr><th>Group</th><th>Files</th><th>Statements</th><th>Covered</th><th>Coverage</th><th></th></tr></thead>
+<tbody id="rows"></tbody>
+</table>
+<script>
+var files = {{.Files}};
+var empty = {owners: "(unowned)", tags: "(no build constraint)"};
+function groupsOf(file, by) {
+	var value = file[by];
+	if (Array.isArray(value)) { return value.length ? value : [empty[by]]; }
+	return [value || empty[by]];
+}
+function draw() {
+	var by = document.getElementById("group").value;
+	var groups = {};
+	files.forEach(function(file) {
+		groupsOf(file, by).forEach(function(name) {
+			var g = groups[name] || (groups[name] = {name: name, files: 0, statements: 0, covered: 0});
+			g.files++;
+			g.statements += file.statements;
+			g.covered += file.covered;
+		});
+	});
+	var rows = document.getElementById("rows");
+	while (rows.firstChild) { rows.removeChild(rows.firstChild); }
+	Object.keys(groups).sort().forEach(function(name) {
+		var g = groups[name];
+		var pct = g.statements ? g.covered / g.statements * 100 : 0;
+		var tr = document.createElement("tr");
+		[g.name, g.files, g.statements, g.covered, pct.toFixed(1) + "%"].forEach(function(v, i) {
+			var td = document.createElement("td");
+			td.textContent = v;
+			if (i > 0) { td.className = "num"; }
+			tr.appendChild(td);
+		});
+		var bar = document.createElement("td");
+		bar.innerHTML = '<div class="bar"><div></div></div>';
+		bar.firstChild.firstChild.style.width = pct * 2 + "px";
+		tr.appendChild(bar);
+		rows.appendChild(tr);
+	});
+}
+document.getElementById("group").addEventListener("change", draw);
+draw();
+</script>
+{{if .Heat}}<h2>Hit counts</h2>
+<p>Lines are colored by how often tests ran them: <span class="heat0">never</span> <span class="heat1">1+</span> <span class="heat2">10+</span> <span class="heat3">100+</span> <span class="heat4">1000+</span></p>
+{{range $file := .Heat}}<details id="{{$file.Name}}">
+<summary>{{$file.Name}}</summary>
+<table class="source">
+{{range .Lines}}<tr id="{{$file.Name}}:{{.Number}}"><td class="num">{{.Number}}</td><td class="{{.Class}}"{{if .IsCode}} title="{{.Hits}} hits"{{end}}>{{.Text}}</td></tr>
+{{end}}</table>
+</details>
+{{end}}<script>
+// Open the file a -reportlink deep link, like #a/a.go:12, points at
+var target = location.hash && document.getElementById(decodeURIComponent(location.hash.slice(1)));
+if (target) {
+	var details = target.closest("details");
+	if (details) { details.open = true; }
+	target.scrollIntoView();
+}
+</script>
+{{end}}</body>
+</html>
+`))
+
+// writeHTMLReport writes a self contained page that groups file coverage by package, directory, owner or build
+// constraint in the browser.  In count and atomic modes it also shows each file's lines colored by hit count, so hot
+// paths and code that is barely covered stand out
+func writeHTMLReport(w io.Writer, profiles []*cover.Profile, metric string, owners *codeowners, loc *fileLocator) error {
+	var heat []heatFile
+	if countsHits(profiles) {
+		heat = heatFiles(profiles, loc)
+	}
+	return htmlReportTemplate.Execute(w, struct {
+		Coverage  float64
+		Metric    string
+		Generated string
+		Version   string
+		Files     []htmlReportFile
+		Heat      []heatFile
+	}{
+		Coverage:  calculateCoverage(profiles, metric),
+		Metric:    metric,
+		Generated: time.Now().Format(time.RFC1123),
+		Version:   toolVersion(),
+		Files:     htmlReportFiles(profiles, metric, owners, loc),
+		Heat:      heat,
+	})
+}
+
+func (m *gocoverdir) writeHTMLReport(profiles []*cover.Profile) error {
+	owners, err := loadCodeowners()
+	if err != nil {
+		return err
+	}
+	return writeFileWith(m.args.htmlreport, func(w io.Writer) error {
+		return writeHTMLReport(w, profiles, m.args.metric, owners, m.locator(profiles))
+	})
+}
@@ -0,0 +1,47 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestBuildConstraint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestBuildConstraint")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	tagged := filepath.Join(dir, "tagged.go")
+	noError(t, ioutil.WriteFile(tagged, []byte("// Copyright\n\n//go:build linux && !cgo\n\npackage a\n"), 0644))
+	plain := filepath.Join(dir, "plain.go")
+	noError(t, ioutil.WriteFile(plain, []byte("package a\n\n//go:build ignored\n"), 0644))
+	if got := buildConstraint(tagged); got != "linux && !cgo" {
+		t.Errorf("Unexpected constraint %q", got)
+	}
+	if got := buildConstraint(plain); got != "" {
+		t.Errorf("Unexpected constraint %q", got)
+	}
+}
+
+func TestWriteHTMLReport(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/nothere/a/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 3, Count: 1}, {NumStmt: 1}}},
+	}
+	owners, err := parseCodeowners(strings.NewReader("* @org/team\n"))
+	noError(t, err)
+	files := htmlReportFiles(profiles, "statements", owners, newFileLocator(profiles, false))
+	if len(files) != 1 || files[0].Statements != 4 || files[0].Covered != 3 || files[0].Package != "example.com/nothere/a" {
+		t.Fatalf("Unexpected files %+v", files)
+	}
+	buf := bytes.Buffer{}
+	noError(t, writeHTMLReport(&buf, profiles, "statements", owners, newFileLocator(profiles, false)))
+	for _, expected := range []string{"75.0%", `"owners":["@org/team"]`, `"statements":4`, `<option value="owners">`} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in report:\n%s", expected, buf.String())
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package gocoverdir
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+// runBuild builds coverage instrumented binaries for integration tests and prints the GOCOVERDIR to run them with
+func runBuild(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ContinueOnError)
+	fs.Bool("cover", true, "Accepted for symmetry with 'go build -cover'.  Binaries are always instrumented")
+	covermode := fs.String("covermode", "", "Same as -covermode in 'go build'")
+	coverpkg := fs.String("coverpkg", "", "Same as -coverpkg in 'go build'")
+	output := fs.String("o", "", "Same as -o in 'go build'")
+	gocoverdir := fs.String("gocoverdir", "", "Directory the binaries write counters to.  Empty means a new temp directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("usage: gocoverdir build [flags] packages")
+	}
+	dir := *gocoverdir
+	if dir == "" {
+		var err error
+		if dir, err = ioutil.TempDir("", "gocoverdir-integration"); err != nil {
+			return err
+		}
+	} else if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	buildArgs := []string{"build", "-cover"}
+	if *covermode != "" {
+		buildArgs = append(buildArgs, "-covermode", *covermode)
+	}
+	if *coverpkg != "" {
+		buildArgs = append(buildArgs, "-coverpkg", *coverpkg)
+	}
+	if *output != "" {
+		buildArgs = append(buildArgs, "-o", *output)
+	}
+	cmd := exec.Command("go", append(buildArgs, fs.Args()...)...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	fmt.Printf("export GOCOVERDIR=%s\n", absDir)
+	return nil
+}
+
+// waitForCounters waits until a binary has written a counter file into dir
+func waitForCounters(dir string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		matches, err := filepath.Glob(filepath.Join(dir, "covcounters.*"))
+		if err != nil {
+			return err
+		}
+		if len(matches) > 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("no coverage counters written to %s after %s.  Did the binary exit cleanly with GOCOVERDIR set?", dir, timeout)
+		}
+		time.Sleep(time.Millisecond * 200)
+	}
+}
+
+// runFinalize converts an instrumented binary's counters to a profile and merges them into the unit test profile
+func runFinalize(args []string) error {
+	fs := flag.NewFlagSet("finalize", flag.ContinueOnError)
+	gocoverdir := fs.String("gocoverdir", os.Getenv("GOCOVERDIR"), "Directory the instrumented binaries wrote counters to")
+	profile := fs.String("profile", "coverage.out", "Unit test profile to merge into.  Skipped if it does not exist")
+	output := fs.String("o", "", "Where to write the merged profile.  Empty means overwrite -profile")
+	wait := fs.Duration("wait", time.Second*30, "How long to wait for the binaries to write their counters")
+	metric := fs.String("metric", "statements", "What the printed coverage percentage counts: statements, blocks or lines")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := verifyMetric(*metric); err != nil {
+		return err
+	}
+	if *gocoverdir == "" {
+		return fmt.Errorf("-gocoverdir or GOCOVERDIR must be set")
+	}
+	if *output == "" {
+		*output = *profile
+	}
+	if err := waitForCounters(*gocoverdir, *wait); err != nil {
+		return err
+	}
+	textProfile, err := ioutil.TempFile("", "gocoverdir-integration")
+	if err != nil {
+		return err
+	}
+	textProfile.Close()
+	defer os.Remove(textProfile.Name())
+	cmd := exec.Command("go", "tool", "covdata", "textfmt", "-i="+*gocoverdir, "-o="+textProfile.Name())
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	integrationProfiles, err := cover.ParseProfiles(textProfile.Name())
+	if err != nil {
+		return err
+	}
+	merged := newProfileSet()
+	if unitProfiles, err := readProfiles(*profile); err == nil {
+		if err := merged.add(unitProfiles); err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	if err := merged.add(integrationProfiles); err != nil {
+		return err
+	}
+	profiles := merged.profiles()
+	if err := writeFileWith(*output, func(w io.Writer) error {
+		return writeProfiles(w, profiles)
+	}); err != nil {
+		return err
+	}
+	fmt.Printf("coverage: %.1f%% of %s\n", calculateCoverage(profiles, *metric), *metric)
+	return nil
+}
@@ -0,0 +1,20 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWaitForCounters(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdirtest")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	if err := waitForCounters(dir, time.Millisecond); err == nil {
+		t.Errorf("Expected an error without counters")
+	}
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "covcounters.abc.1.2"), nil, 0644))
+	noError(t, waitForCounters(dir, time.Millisecond))
+}
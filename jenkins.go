@@ -0,0 +1,83 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+type jenkinsCounter struct {
+	Covered    int     `json:"covered"`
+	Missed     int     `json:"missed"`
+	Total      int     `json:"total"`
+	Percentage float64 `json:"percentage"`
+}
+
+type jenkinsLine struct {
+	Number int `json:"number"`
+	Hits   int `json:"hits"`
+}
+
+type jenkinsFile struct {
+	Path      string         `json:"path"`
+	Package   string         `json:"package"`
+	Line      jenkinsCounter `json:"line"`
+	Statement jenkinsCounter `json:"statement"`
+	Lines     []jenkinsLine  `json:"lines"`
+}
+
+type jenkinsSummary struct {
+	Name      string         `json:"name"`
+	Timestamp int64          `json:"timestamp"`
+	Line      jenkinsCounter `json:"line"`
+	Statement jenkinsCounter `json:"statement"`
+	Files     []jenkinsFile  `json:"files"`
+}
+
+func newJenkinsCounter(covered int, total int) jenkinsCounter {
+	return jenkinsCounter{
+		Covered:    covered,
+		Missed:     total - covered,
+		Total:      total,
+		Percentage: lineRate(covered, total) * 100,
+	}
+}
+
+// writeJenkinsSummary writes a JSON summary, with per file line coverage, for the Jenkins code-coverage-api plugin
+func writeJenkinsSummary(w io.Writer, profiles []*cover.Profile, loc *fileLocator) error {
+	summary := jenkinsSummary{
+		Name:      "gocoverdir",
+		Timestamp: time.Now().Unix(),
+		Files:     make([]jenkinsFile, 0, len(profiles)),
+	}
+	var linesCovered, linesTotal, stmtsCovered, stmtsTotal int
+	for _, p := range profiles {
+		file := jenkinsFile{
+			Path:    loc.name(p),
+			Package: path.Dir(p.FileName),
+		}
+		covered := 0
+		for _, line := range lineHits(p) {
+			file.Lines = append(file.Lines, jenkinsLine{Number: line.number, Hits: line.hits})
+			if line.hits > 0 {
+				covered++
+			}
+		}
+		total, stmts := countStatements(p, "statements")
+		file.Line = newJenkinsCounter(covered, len(file.Lines))
+		file.Statement = newJenkinsCounter(stmts, total)
+		summary.Files = append(summary.Files, file)
+		linesCovered += covered
+		linesTotal += len(file.Lines)
+		stmtsCovered += stmts
+		stmtsTotal += total
+	}
+	summary.Line = newJenkinsCounter(linesCovered, linesTotal)
+	summary.Statement = newJenkinsCounter(stmtsCovered, stmtsTotal)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(summary)
+}
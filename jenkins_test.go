@@ -0,0 +1,34 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestWriteJenkinsSummary(t *testing.T) {
+	profiles := []*cover.Profile{
+		{
+			FileName: "example.com/a/a.go",
+			Blocks: []cover.ProfileBlock{
+				{StartLine: 1, EndLine: 1, NumStmt: 1, Count: 1},
+				{StartLine: 3, EndLine: 3, NumStmt: 3, Count: 0},
+			},
+		},
+	}
+	buf := bytes.Buffer{}
+	noError(t, writeJenkinsSummary(&buf, profiles, newFileLocator(profiles, false)))
+	var summary jenkinsSummary
+	noError(t, json.Unmarshal(buf.Bytes(), &summary))
+	if summary.Line.Covered != 1 || summary.Line.Total != 2 {
+		t.Errorf("Unexpected line counter %+v", summary.Line)
+	}
+	if summary.Statement.Covered != 1 || summary.Statement.Missed != 3 {
+		t.Errorf("Unexpected statement counter %+v", summary.Statement)
+	}
+	if len(summary.Files) != 1 || len(summary.Files[0].Lines) != 2 {
+		t.Errorf("Unexpected files %+v", summary.Files)
+	}
+}
@@ -0,0 +1,103 @@
+package gocoverdir
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+)
+
+type packageResult struct {
+	dir     string
+	command string
+	// commandLine is the command that ran, with its arguments
+	commandLine []string
+	duration    time.Duration
+	err         error
+	skipped     []string
+	failed      []string
+	// flaky tests failed, then passed when retried
+	flaky []string
+	// examples are the Example functions that ran, if -examples is set
+	examples     []string
+	examplesOnly bool
+	// races are the data race reports in the test output
+	races []string
+	// outputMatches are the output lines a failoutput config rule matched
+	outputMatches []string
+	// leaks are the goroutines the tests left running, with -leakcheck
+	leaks []string
+	// profiles maps -mutexprofile and -blockprofile kinds to the files written
+	profiles map[string]string
+	// quarantined results are from the flaky test pass and never fail the build
+	quarantined bool
+	// failureBundle is the directory debugging information about a failure was saved to, with -artifacts
+	failureBundle string
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       float64         `xml:"time,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+}
+
+// writeJUnit writes one test suite per package, with a single test case for the package's go test run.  Each suite
+// has the gocoverdir version, and the run's ID if there is one, as properties
+func writeJUnit(w io.Writer, results []packageResult, runID string) error {
+	suites := junitTestSuites{}
+	for _, result := range results {
+		testCase := junitTestCase{
+			Name:      result.command,
+			Classname: result.dir,
+			Time:      result.duration.Seconds(),
+		}
+		suite := junitTestSuite{
+			Name:  result.dir,
+			Tests: 1,
+			Time:  result.duration.Seconds(),
+		}
+		suite.Properties = []junitProperty{{Name: "gocoverdir.version", Value: toolVersion()}}
+		if runID != "" {
+			suite.Properties = append(suite.Properties, junitProperty{Name: "gocoverdir.run_id", Value: runID})
+		}
+		if result.err != nil {
+			testCase.Failure = &junitFailure{Message: result.err.Error()}
+			suite.Failures = 1
+		}
+		suite.Cases = []junitTestCase{testCase}
+		suites.Suites = append(suites.Suites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
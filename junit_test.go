@@ -0,0 +1,27 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	buf := bytes.Buffer{}
+	noError(t, writeJUnit(&buf, []packageResult{
+		{dir: "a", command: "go test", duration: time.Second},
+		{dir: "b", command: "go test", err: errors.New("exit status 1")},
+	}, "8f3a"))
+	out := buf.String()
+	if !strings.Contains(out, `<testsuite name="a" tests="1" failures="0" time="1">`) {
+		t.Errorf("Missing passing suite: %s", out)
+	}
+	if !strings.Contains(out, `<failure message="exit status 1"></failure>`) {
+		t.Errorf("Missing failure: %s", out)
+	}
+	if strings.Count(out, `<property name="gocoverdir.run_id" value="8f3a"></property>`) != 2 {
+		t.Errorf("Missing run ID: %s", out)
+	}
+}
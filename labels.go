@@ -0,0 +1,174 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	labelNeedsTests = "coverage/needs-tests"
+	labelGood       = "coverage/good"
+)
+
+// suggestLabels picks the labels for a pull request from the coverage change it makes, and the labels it manages
+// that no longer apply.  Dropping total or any package's coverage by more than tolerance points, or adding a package
+// with no coverage at all, needs tests
+func suggestLabels(diff reportDiff, tolerance float64) (add []string, remove []string) {
+	needsTests := diff.Total.Old-diff.Total.New > tolerance
+	for _, pkg := range diff.Packages {
+		switch {
+		case !pkg.NewExists:
+		case !pkg.OldExists:
+			needsTests = needsTests || pkg.New == 0
+		default:
+			needsTests = needsTests || pkg.Old-pkg.New > tolerance
+		}
+	}
+	if needsTests {
+		return []string{labelNeedsTests}, []string{labelGood}
+	}
+	return []string{labelGood}, []string{labelNeedsTests}
+}
+
+// labelAPI is the part of a code host's API that labels pull requests
+type labelAPI struct {
+	client *http.Client
+	getenv func(string) string
+	// number of the pull request.  0 finds it from the CI environment
+	number int
+}
+
+func (l *labelAPI) do(method string, endpoint string, header http.Header, body interface{}) error {
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, endpoint, &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header = header
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Removing a label the pull request does not have is fine
+	if resp.StatusCode >= 300 && !(method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
+		return fmt.Errorf("%s %s returned %s", method, endpoint, resp.Status)
+	}
+	return nil
+}
+
+// githubPullRequest finds the pull request number in a GITHUB_REF like refs/pull/123/merge
+func githubPullRequest(ref string) int {
+	var number int
+	if _, err := fmt.Sscanf(ref, "refs/pull/%d/", &number); err != nil {
+		return 0
+	}
+	return number
+}
+
+func (l *labelAPI) applyGitHub(add []string, remove []string) error {
+	number := l.number
+	if number == 0 {
+		number = githubPullRequest(l.getenv("GITHUB_REF"))
+	}
+	repository, token := l.getenv("GITHUB_REPOSITORY"), l.getenv("GITHUB_TOKEN")
+	if number == 0 || repository == "" || token == "" {
+		return fmt.Errorf("labeling on github needs a pull request, GITHUB_REPOSITORY and GITHUB_TOKEN")
+	}
+	issue := fmt.Sprintf("%s/repos/%s/issues/%d/labels", githubAPIURL(l.getenv), repository, number)
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	header.Set("Accept", "application/vnd.github+json")
+	for _, label := range remove {
+		if err := l.do(http.MethodDelete, issue+"/"+url.PathEscape(label), header.Clone(), nil); err != nil {
+			return err
+		}
+	}
+	return l.do(http.MethodPost, issue, header.Clone(), map[string][]string{"labels": add})
+}
+
+func (l *labelAPI) applyGitLab(add []string, remove []string) error {
+	number := l.number
+	if number == 0 {
+		fmt.Sscanf(l.getenv("CI_MERGE_REQUEST_IID"), "%d", &number)
+	}
+	api, project, token := l.getenv("CI_API_V4_URL"), l.getenv("CI_PROJECT_ID"), l.getenv("GITLAB_TOKEN")
+	if number == 0 || api == "" || project == "" || token == "" {
+		return fmt.Errorf("labeling on gitlab needs a merge request, CI_API_V4_URL, CI_PROJECT_ID and GITLAB_TOKEN")
+	}
+	header := http.Header{}
+	header.Set("PRIVATE-TOKEN", token)
+	endpoint := fmt.Sprintf("%s/projects/%s/merge_requests/%d", api, url.PathEscape(project), number)
+	return l.do(http.MethodPut, endpoint, header, map[string]string{
+		"add_labels":    strings.Join(add, ","),
+		"remove_labels": strings.Join(remove, ","),
+	})
+}
+
+// apply labels the pull request on host, which is github or gitlab
+func (l *labelAPI) apply(host string, add []string, remove []string) error {
+	switch host {
+	case "github":
+		return l.applyGitHub(add, remove)
+	case "gitlab":
+		return l.applyGitLab(add, remove)
+	}
+	return fmt.Errorf("cannot apply labels on %s: only github and gitlab are supported", host)
+}
+
+// runLabels prints the labels a pull request should have given the reports of its base and head, and optionally
+// applies them
+func runLabels(args []string) error {
+	fs := flag.NewFlagSet("labels", flag.ContinueOnError)
+	tolerance := fs.Float64("tolerance", .1, "Percentage points coverage may drop before the pull request needs tests")
+	apply := fs.String("apply", "", "If set, apply the labels on this code host: github, gitlab, or auto to detect it from the CI environment")
+	number := fs.Int("pr", 0, "Pull request to label.  Defaults to the one the CI environment is building")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 2 {
+		return fmt.Errorf("usage: gocoverdir labels [-apply github|gitlab|auto] base.json head.json")
+	}
+	before, err := readReport(files[0])
+	if err != nil {
+		return err
+	}
+	after, err := readReport(files[1])
+	if err != nil {
+		return err
+	}
+	add, remove := suggestLabels(diffReports(before, after), *tolerance)
+	for _, label := range add {
+		fmt.Println(label)
+	}
+	if *apply == "" {
+		return nil
+	}
+	host := *apply
+	if host == "auto" {
+		ci, err := detectCI("auto", os.Getenv)
+		if err != nil {
+			return err
+		}
+		if ci == nil {
+			return fmt.Errorf("cannot detect the code host to apply labels on")
+		}
+		host = ci.Name
+	}
+	l := &labelAPI{client: &http.Client{Timeout: time.Second * 30}, getenv: os.Getenv, number: *number}
+	return l.apply(host, add, remove)
+}
@@ -0,0 +1,82 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSuggestLabels(t *testing.T) {
+	good := reportDiff{
+		Total:    coverageDelta{Path: "total", Old: 80, New: 80.05, OldExists: true, NewExists: true},
+		Packages: []coverageDelta{{Path: "a", New: 50, NewExists: true}, {Path: "b", Old: 10, OldExists: true}},
+	}
+	if add, remove := suggestLabels(good, .1); !reflect.DeepEqual(add, []string{labelGood}) || !reflect.DeepEqual(remove, []string{labelNeedsTests}) {
+		t.Errorf("Unexpected labels %v %v", add, remove)
+	}
+	dropped := good
+	dropped.Packages = []coverageDelta{{Path: "a", Old: 60, New: 50, OldExists: true, NewExists: true}}
+	if add, _ := suggestLabels(dropped, .1); !reflect.DeepEqual(add, []string{labelNeedsTests}) {
+		t.Errorf("Expected a package drop to need tests, saw %v", add)
+	}
+	untested := good
+	untested.Packages = []coverageDelta{{Path: "c", NewExists: true}}
+	if add, _ := suggestLabels(untested, .1); !reflect.DeepEqual(add, []string{labelNeedsTests}) {
+		t.Errorf("Expected an untested package to need tests, saw %v", add)
+	}
+}
+
+func TestGithubPullRequest(t *testing.T) {
+	if n := githubPullRequest("refs/pull/123/merge"); n != 123 {
+		t.Errorf("Expected 123, saw %d", n)
+	}
+	if n := githubPullRequest("refs/heads/main"); n != 0 {
+		t.Errorf("Expected no pull request, saw %d", n)
+	}
+}
+
+func TestApplyGitHubLabels(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	var added map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.EscapedPath())
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		noError(t, json.NewDecoder(r.Body).Decode(&added))
+	}))
+	defer server.Close()
+	env := map[string]string{
+		"GITHUB_API_URL":    server.URL,
+		"GITHUB_REPOSITORY": "cep21/gocoverdir",
+		"GITHUB_TOKEN":      "secret",
+		"GITHUB_REF":        "refs/pull/7/merge",
+	}
+	l := &labelAPI{client: server.Client(), getenv: func(key string) string { return env[key] }}
+	noError(t, l.apply("github", []string{labelGood}, []string{labelNeedsTests}))
+	expected := []string{
+		"DELETE /repos/cep21/gocoverdir/issues/7/labels/coverage%2Fneeds-tests",
+		"POST /repos/cep21/gocoverdir/issues/7/labels",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Unexpected requests %v", requests)
+	}
+	if !reflect.DeepEqual(added["labels"], []string{labelGood}) {
+		t.Errorf("Unexpected labels %v", added)
+	}
+	env["GITHUB_TOKEN"] = ""
+	if err := l.apply("github", []string{labelGood}, nil); err == nil {
+		t.Errorf("Expected an error without a token")
+	}
+}
@@ -0,0 +1,201 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// leakCheckFile is the test file -leakcheck writes into each package for the length of its test run
+const leakCheckFile = "zz_gocoverdir_leakcheck_test.go"
+
+// leakPrefix starts each line the generated TestMain prints for a leaked goroutine
+const leakPrefix = "gocoverdir: goroutine leak: "
+
+// leakRules pick the generated TestMain's lines out of the test output
+var leakRules = []*regexp.Regexp{regexp.MustCompile("^" + regexp.QuoteMeta(leakPrefix))}
+
+// leakCheckSource is the generated TestMain, after its package clause.  Like goleak.VerifyTestMain it gives goroutines
+// a moment to exit once the tests are done, then fails the package if any are still running.  It uses only the
+// standard library so modules do not need a new dependency
+const leakCheckSource = `
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	leaks := gocoverdirLeakedGoroutines()
+	for _, leak := range leaks {
+		fmt.Println("` + leakPrefix + `" + leak)
+	}
+	if len(leaks) > 0 && code == 0 {
+		code = 1
+	}
+	os.Exit(code)
+}
+
+func gocoverdirLeakedGoroutines() []string {
+	deadline := time.Now().Add(time.Second)
+	for {
+		var leaks []string
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		for _, g := range strings.Split(string(buf), "\n\n") {
+			lines := strings.Split(strings.TrimSpace(g), "\n")
+			if len(lines) < 2 || strings.Contains(lines[0], "[running]") {
+				continue
+			}
+			top := lines[1]
+			if i := strings.LastIndex(top, "("); i > 0 {
+				top = top[:i]
+			}
+			if strings.HasPrefix(top, "runtime.") || strings.HasPrefix(top, "os/signal.") {
+				continue
+			}
+			leak := top
+			for _, line := range lines {
+				if strings.HasPrefix(line, "created by ") {
+					leak += ", " + strings.Join(strings.Fields(line)[:3], " ")
+				}
+			}
+			leaks = append(leaks, leak)
+		}
+		if len(leaks) == 0 || time.Now().After(deadline) {
+			return leaks
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+`
+
+// leakCheckPackage is the package the generated TestMain goes in: dir's package, or its external test package if all
+// its tests are external.  hasMain is true if the tests already declare a TestMain, which cannot be wrapped
+func leakCheckPackage(dir string) (name string, hasMain bool, err error) {
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return "", false, err
+	}
+	name = pkg.Name
+	if len(pkg.GoFiles)+len(pkg.CgoFiles)+len(pkg.TestGoFiles) == 0 {
+		name += "_test"
+	}
+	fset := token.NewFileSet()
+	for _, file := range append(pkg.TestGoFiles, pkg.XTestGoFiles...) {
+		f, err := parser.ParseFile(fset, filepath.Join(dir, file), nil, 0)
+		if err != nil {
+			return "", false, err
+		}
+		for _, decl := range f.Decls {
+			if fn, isFunc := decl.(*ast.FuncDecl); isFunc && fn.Recv == nil && fn.Name.Name == "TestMain" {
+				return name, true, nil
+			}
+		}
+	}
+	return name, false, nil
+}
+
+// injectLeakCheck writes the leak checking TestMain into dirpath, with -leakcheck.  Packages with their own TestMain
+// are left alone: they can call goleak.VerifyTestMain themselves.  The returned func removes the file again
+func (m *gocoverdir) injectLeakCheck(dirpath string) func() {
+	if !m.args.leakcheck {
+		return func() {}
+	}
+	name, hasMain, err := leakCheckPackage(dirpath)
+	if err != nil {
+		m.log.Printf("Not checking %s for goroutine leaks: %s", dirpath, err)
+		return func() {}
+	}
+	if hasMain {
+		m.log.Printf("Not checking %s for goroutine leaks: it has its own TestMain, which can call goleak.VerifyTestMain", dirpath)
+		return func() {}
+	}
+	file := filepath.Join(dirpath, leakCheckFile)
+	if err := writeFileWith(file, func(w io.Writer) error {
+		_, err := io.WriteString(w, "// Code generated by gocoverdir -leakcheck. DO NOT EDIT.\n\npackage "+name+leakCheckSource)
+		return err
+	}); err != nil {
+		m.log.Printf("Not checking %s for goroutine leaks: %s", dirpath, err)
+		return func() {}
+	}
+	m.leakMu.Lock()
+	m.leakFiles = append(m.leakFiles, file)
+	m.leakMu.Unlock()
+	return func() {
+		m.leakMu.Lock()
+		defer m.leakMu.Unlock()
+		for i, f := range m.leakFiles {
+			if f == file {
+				m.leakFiles = append(m.leakFiles[:i], m.leakFiles[i+1:]...)
+				break
+			}
+		}
+		if err := os.Remove(file); err != nil {
+			m.log.Printf("Unable to remove %s: %s", file, err)
+		}
+	}
+}
+
+// removeLeakChecks removes the generated TestMains of packages still being tested, so an interrupted run does not
+// leave them in the tree
+func (m *gocoverdir) removeLeakChecks() {
+	m.leakMu.Lock()
+	defer m.leakMu.Unlock()
+	for _, file := range m.leakFiles {
+		os.Remove(file)
+	}
+	m.leakFiles = nil
+}
+
+// leakedGoroutines are the leaks the generated TestMain reported, without their prefix
+func leakedGoroutines(lines []string) []string {
+	var ret []string
+	for _, line := range lines {
+		ret = append(ret, strings.TrimPrefix(line, leakPrefix))
+	}
+	return ret
+}
+
+func hasLeaks(results []packageResult) bool {
+	for _, result := range results {
+		if len(result.leaks) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// writeMarkdownLeaks lists the goroutines each package's tests leaked, with -leakcheck
+func writeMarkdownLeaks(w io.Writer, results []packageResult) error {
+	total := 0
+	for _, result := range results {
+		total += len(result.leaks)
+	}
+	if total == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\n### Goroutine leaks: %d\n\n| Package | Goroutine |\n| --- | --- |\n", total); err != nil {
+		return err
+	}
+	for _, result := range results {
+		for _, leak := range result.leaks {
+			if _, err := fmt.Fprintf(w, "| %s | %s |\n", result.dir, strings.Replace(leak, "|", "\\|", -1)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLeakCheckPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdir")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	files := map[string]string{
+		"internal/a.go":      "package a\n",
+		"internal/a_test.go": "package a\n\nimport \"testing\"\n\nfunc TestA(t *testing.T) {}\n",
+		"external/b.go":      "package b\n",
+		"external/b_test.go": "package b_test\n\nimport \"testing\"\n\nfunc TestB(t *testing.T) {}\n",
+		"testonly/c_test.go": "package c_test\n\nimport \"testing\"\n\nfunc TestC(t *testing.T) {}\n",
+		"main/d.go":          "package d\n",
+		"main/d_test.go":     "package d\n\nimport \"testing\"\n\nfunc TestMain(m *testing.M) { m.Run() }\n",
+	}
+	for name, contents := range files {
+		noError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		noError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0644))
+	}
+	tests := []struct {
+		dir     string
+		name    string
+		hasMain bool
+	}{
+		{"internal", "a", false},
+		{"external", "b", false},
+		{"testonly", "c_test", false},
+		{"main", "d", true},
+	}
+	for _, test := range tests {
+		name, hasMain, err := leakCheckPackage(filepath.Join(dir, test.dir))
+		noError(t, err)
+		if name != test.name || hasMain != test.hasMain {
+			t.Errorf("%s: expected package %s with TestMain %v, got %s %v", test.dir, test.name, test.hasMain, name, hasMain)
+		}
+	}
+
+	m := gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	m.args.leakcheck = true
+	remove := m.injectLeakCheck(filepath.Join(dir, "internal"))
+	generated := filepath.Join(dir, "internal", leakCheckFile)
+	contents, err := ioutil.ReadFile(generated)
+	noError(t, err)
+	if !strings.Contains(string(contents), "\npackage a\n") || !strings.Contains(string(contents), leakPrefix) {
+		t.Errorf("Unexpected generated TestMain:\n%s", contents)
+	}
+	remove()
+	if _, err := os.Stat(generated); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed", generated)
+	}
+	m.injectLeakCheck(filepath.Join(dir, "internal"))
+	m.removeLeakChecks()
+	if _, err := os.Stat(generated); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed when the run is closed", generated)
+	}
+}
+
+func TestWriteMarkdownLeaks(t *testing.T) {
+	var buf bytes.Buffer
+	noError(t, writeMarkdownLeaks(&buf, []packageResult{{dir: "a"}}))
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing without leaks, got %s", buf.String())
+	}
+	results := []packageResult{{dir: "a", leaks: leakedGoroutines([]string{leakPrefix + "example.com/a.worker, created by example.com/a.Start"})}}
+	noError(t, writeMarkdownLeaks(&buf, results))
+	expected := "\n### Goroutine leaks: 1\n\n| Package | Goroutine |\n| --- | --- |\n| a | example.com/a.worker, created by example.com/a.Start |\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected markdown %q", buf.String())
+	}
+}
@@ -0,0 +1,73 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// topOffenders is how many directories a limit error names
+const topOffenders = 5
+
+// walkCounter counts what discovery has seen under each top level directory, so exceeding a limit can say where
+type walkCounter struct {
+	total int
+	byTop map[string]int
+}
+
+func (w *walkCounter) add(dir string, n int) {
+	if w.byTop == nil {
+		w.byTop = make(map[string]int)
+	}
+	w.total += n
+	w.byTop[topLevelDir(dir)] += n
+}
+
+// topLevelDir is the first element of dir, which is relative to where discovery started
+func topLevelDir(dir string) string {
+	return strings.SplitN(filepath.ToSlash(filepath.Clean(dir)), "/", 2)[0]
+}
+
+// offenders lists the n top level directories with the most counted, the most first
+func (w *walkCounter) offenders(n int) string {
+	dirs := make([]string, 0, len(w.byTop))
+	for dir := range w.byTop {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if w.byTop[dirs[i]] != w.byTop[dirs[j]] {
+			return w.byTop[dirs[i]] > w.byTop[dirs[j]]
+		}
+		return dirs[i] < dirs[j]
+	})
+	if len(dirs) > n {
+		dirs = dirs[:n]
+	}
+	parts := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		parts = append(parts, fmt.Sprintf("%s (%d)", dir, w.byTop[dir]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// countWalked counts the entries of a directory the walk read, failing once there are more than -maxwalkentries
+func (m *gocoverdir) countWalked(dirpath string, entries int) error {
+	m.walked.add(dirpath, entries)
+	if m.args.maxentries > 0 && m.walked.total > m.args.maxentries {
+		return fmt.Errorf("discovery read more than -maxwalkentries %d entries, so the root is probably wrong or needs -ignoredirs.  Most entries are under: %s", m.args.maxentries, m.walked.offenders(topOffenders))
+	}
+	return nil
+}
+
+// checkMaxPackages fails if discovery found more than -maxpackages directories to test
+func (m *gocoverdir) checkMaxPackages(dirs []string) error {
+	if m.args.maxpackages <= 0 || len(dirs) <= m.args.maxpackages {
+		return nil
+	}
+	counts := walkCounter{}
+	for _, dir := range dirs {
+		counts.add(dir, 1)
+	}
+	return fmt.Errorf("discovery found %d packages, more than -maxpackages %d, so the root is probably wrong or needs -ignoredirs.  Most packages are under: %s", len(dirs), m.args.maxpackages, counts.offenders(topOffenders))
+}
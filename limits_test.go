@@ -0,0 +1,63 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWalkCounterOffenders(t *testing.T) {
+	w := walkCounter{}
+	w.add(".", 3)
+	w.add("node_modules/x", 40)
+	w.add("node_modules/y/z", 60)
+	w.add("a", 5)
+	w.add("b/c", 5)
+	if w.total != 113 {
+		t.Errorf("Unexpected total %d", w.total)
+	}
+	if offenders := w.offenders(3); offenders != "node_modules (100), a (5), b (5)" {
+		t.Errorf("Unexpected offenders %q", offenders)
+	}
+}
+
+func TestDiscoveryLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDiscoveryLimits")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"a/a.go", "big/x/x.go", "big/y/y.go", "big/z/z.go"} {
+		noError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		noError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("package p\n"), 0644))
+	}
+	cwd, err := os.Getwd()
+	noError(t, err)
+	noError(t, os.Chdir(dir))
+	defer os.Chdir(cwd)
+
+	newM := func() *gocoverdir {
+		m := &gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+		m.args.depth = 10
+		return m
+	}
+	m := newM()
+	dirs, err := m.findTestDirs(".", 0, nil)
+	noError(t, err)
+	if len(dirs) != 4 {
+		t.Fatalf("Unexpected dirs %v", dirs)
+	}
+
+	m = newM()
+	m.args.maxpackages = 2
+	if _, err := m.findTestDirs(".", 0, nil); err == nil || !strings.Contains(err.Error(), "big (2)") {
+		t.Errorf("Expected a -maxpackages error naming big, got %v", err)
+	}
+
+	m = newM()
+	m.args.maxentries = 6
+	if _, err := m.findTestDirs(".", 0, nil); err == nil || !strings.Contains(err.Error(), "-maxwalkentries 6") {
+		t.Errorf("Expected a -maxwalkentries error, got %v", err)
+	}
+}
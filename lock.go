@@ -0,0 +1,86 @@
+package gocoverdir
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// processAlive is true if pid is running.  On Windows, FindProcess opens the process and fails once it is gone, and
+// Signal(0) is unsupported.  Elsewhere, EPERM means the process exists but belongs to another user
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		proc.Release()
+		return true
+	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// lockOutput stops two runs from writing the same cover profile at once.  Locks left by dead processes are taken over.
+func (m *gocoverdir) lockOutput() error {
+	lockName := m.args.coverprofile + ".lock"
+	for attempt := 0; attempt < 2; attempt++ {
+		f, err := os.OpenFile(lockName, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%d\n", os.Getpid())
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(lockName)
+				return err
+			}
+			m.lockfile = lockName
+			return nil
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+		contents, err := ioutil.ReadFile(lockName)
+		if err != nil {
+			return err
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+		if err == nil && processAlive(pid) {
+			return fmt.Errorf("another gocoverdir (pid %d) is writing %s.  Use a different -coverprofile for concurrent runs", pid, m.args.coverprofile)
+		}
+		m.log.Printf("Removing stale lock %s", lockName)
+		if err := os.Remove(lockName); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return fmt.Errorf("unable to lock %s", lockName)
+}
+
+func (m *gocoverdir) unlockOutput() {
+	if m.lockfile == "" {
+		return
+	}
+	if err := os.Remove(m.lockfile); err != nil {
+		m.log.Printf("Unable to remove lock %s: %s", m.lockfile, err)
+	}
+	m.lockfile = ""
+}
+
+// checkClobber refuses to overwrite a profile another run wrote after this one started
+func (m *gocoverdir) checkClobber(filename string) error {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return nil
+	}
+	if stat.ModTime().After(m.startTime) {
+		return fmt.Errorf("%s was modified at %s, after this run started at %s.  Refusing to overwrite a concurrent run's profile", filename, stat.ModTime().Format(time.RFC3339), m.startTime.Format(time.RFC3339))
+	}
+	return nil
+}
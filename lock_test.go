@@ -0,0 +1,47 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdirtest")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	m := gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	m.args.coverprofile = filepath.Join(dir, "coverage.out")
+	noError(t, m.lockOutput())
+	other := gocoverdir{log: m.log, args: m.args}
+	if err := other.lockOutput(); err == nil {
+		t.Errorf("Expected the second lock to fail")
+	}
+	m.unlockOutput()
+	noError(t, other.lockOutput())
+	other.unlockOutput()
+
+	// A lock from a process that no longer exists is stale
+	noError(t, ioutil.WriteFile(m.args.coverprofile+".lock", []byte(fmt.Sprintf("%d\n", 1<<30)), 0644))
+	noError(t, m.lockOutput())
+	m.unlockOutput()
+}
+
+func TestCheckClobber(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdirtest")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "coverage.out")
+	m := gocoverdir{startTime: time.Now().Add(-time.Hour)}
+	noError(t, m.checkClobber(filename))
+	noError(t, ioutil.WriteFile(filename, nil, 0644))
+	if err := m.checkClobber(filename); err == nil {
+		t.Errorf("Expected a newer profile to be protected")
+	}
+	m.startTime = time.Now().Add(time.Hour)
+	noError(t, m.checkClobber(filename))
+}
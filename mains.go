@@ -0,0 +1,67 @@
+package gocoverdir
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const syntheticMainTestName = "gocoverdir_main_test.go"
+
+const syntheticMainTest = `// Code generated by gocoverdir -covermains. DO NOT EDIT.
+
+package main
+
+import "testing"
+
+func TestGocoverdirMain(t *testing.T) {}
+`
+
+// needsSyntheticTest is true for main packages without tests, whose statements would otherwise not be in the profile
+func needsSyntheticTest(ctx *build.Context, dir string) bool {
+	pkg, err := ctx.ImportDir(dir, 0)
+	if err != nil {
+		return false
+	}
+	return pkg.Name == "main" && len(pkg.TestGoFiles) == 0 && len(pkg.XTestGoFiles) == 0
+}
+
+// addMainTests writes a trivial test into every untested main package in dirs.  removeMainTests removes them
+func (m *gocoverdir) addMainTests(dirs []string) error {
+	if !m.args.covermains {
+		return nil
+	}
+	ctx := m.buildContext()
+	for _, dir := range dirs {
+		if !needsSyntheticTest(ctx, dir) {
+			continue
+		}
+		filename := filepath.Join(dir, syntheticMainTestName)
+		if _, err := os.Stat(filename); err == nil {
+			continue
+		}
+		m.log.Printf("Adding synthetic test %s", filename)
+		if err := ioutil.WriteFile(filename, []byte(syntheticMainTest), 0644); err != nil {
+			m.removeMainTests()
+			return err
+		}
+		m.leakMu.Lock()
+		m.mainFiles = append(m.mainFiles, filename)
+		m.leakMu.Unlock()
+	}
+	return nil
+}
+
+// removeMainTests removes the synthetic tests addMainTests wrote.  Close calls it too, so an interrupted run does not
+// leave them in the tree
+func (m *gocoverdir) removeMainTests() {
+	m.leakMu.Lock()
+	defer m.leakMu.Unlock()
+	for _, filename := range m.mainFiles {
+		if err := os.Remove(filename); err != nil {
+			m.log.Printf("Unable to remove synthetic test %s: %s", filename, err)
+		}
+	}
+	m.mainFiles = nil
+}
@@ -0,0 +1,30 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddMainTests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdirtest")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644))
+	m := gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	m.args.covermains = true
+	noError(t, m.addMainTests([]string{dir}))
+	synthetic := filepath.Join(dir, syntheticMainTestName)
+	if _, err := os.Stat(synthetic); err != nil {
+		t.Errorf("Expected a synthetic test: %s", err)
+	}
+	if needsSyntheticTest(m.buildContext(), dir) {
+		t.Errorf("Package with the synthetic test should not need another")
+	}
+	noError(t, m.Close())
+	if _, err := os.Stat(synthetic); !os.IsNotExist(err) {
+		t.Errorf("Expected the synthetic test to be removed: %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// writeMarkdownSummary writes the total and per package coverage as a markdown table, followed by skipped directories
+// and staticcheck findings.  Packages with fewer than minstmts statements are left out of the table.  With links,
+// packages link to their first uncovered line in the report
+func writeMarkdownSummary(w io.Writer, profiles []*cover.Profile, metric string, requiredcoverage float64, minstmts int, skipped []skippedDir, findings []finding, links *reportLinker) error {
+	coverage := calculateCoverage(profiles, metric)
+	if _, err := fmt.Fprintf(w, "## Coverage: %.1f%% of %s\n\n", coverage, metric); err != nil {
+		return err
+	}
+	if requiredcoverage > 0.0 {
+		status := "passed"
+		if coverage < requiredcoverage-.001 {
+			status = "failed"
+		}
+		if _, err := fmt.Fprintf(w, "Required coverage of %.1f%% %s.\n\n", requiredcoverage, status); err != nil {
+			return err
+		}
+	}
+	if _, err := io.WriteString(w, "| Package | Statements | Covered | Coverage |\n| --- | ---: | ---: | ---: |\n"); err != nil {
+		return err
+	}
+	hidden := 0
+	for _, pkg := range packageCoverages(profiles, metric) {
+		if pkg.statements < minstmts {
+			hidden++
+			continue
+		}
+		name := pkg.name
+		if link := links.packageLink(pkg.name); link != "" {
+			name = fmt.Sprintf("[%s](%s)", pkg.name, link)
+		}
+		if _, err := fmt.Fprintf(w, "| %s | %d | %d | %.1f%% |\n", name, pkg.statements, pkg.covered, pkg.percent()); err != nil {
+			return err
+		}
+	}
+	if hidden > 0 {
+		if _, err := fmt.Fprintf(w, "\n%d packages with fewer than %d statements are not listed.\n", hidden, minstmts); err != nil {
+			return err
+		}
+	}
+	if len(skipped) > 0 {
+		if _, err := io.WriteString(w, "\n### Skipped\n\n| Directory | Reason |\n| --- | --- |\n"); err != nil {
+			return err
+		}
+		for _, skip := range skipped {
+			if _, err := fmt.Fprintf(w, "| %s | %s |\n", skip.dir, skip.reason); err != nil {
+				return err
+			}
+		}
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "\n### Staticcheck: %d problems\n\n| Location | Check | Message |\n| --- | --- | --- |\n", len(findings)); err != nil {
+		return err
+	}
+	for _, f := range findings {
+		if _, err := fmt.Fprintf(w, "| %s:%d | %s | %s |\n", f.File, f.Line, f.Code, strings.Replace(f.Message, "|", "\\|", -1)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// summaryMinStatements is the smallest package listed in the markdown summary
+func (m *gocoverdir) summaryMinStatements() int {
+	if m.args.summaryall {
+		return 0
+	}
+	return m.args.minstmts
+}
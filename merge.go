@@ -0,0 +1,175 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// profileSet accumulates profiles from several sources, merging blocks at the same location
+type profileSet struct {
+	mode  string
+	files map[string]*cover.Profile
+}
+
+func newProfileSet() *profileSet {
+	return &profileSet{
+		files: make(map[string]*cover.Profile),
+	}
+}
+
+func (s *profileSet) add(profiles []*cover.Profile) error {
+	for _, p := range profiles {
+		if s.mode == "" {
+			s.mode = p.Mode
+		} else if p.Mode != s.mode {
+			return fmt.Errorf("cannot merge cover mode %s of %s into mode %s", p.Mode, p.FileName, s.mode)
+		}
+	}
+	for _, p := range profiles {
+		existing, exists := s.files[p.FileName]
+		if !exists {
+			existing = &cover.Profile{FileName: p.FileName, Mode: p.Mode}
+			s.files[p.FileName] = existing
+		}
+		existing.Blocks = append(existing.Blocks, p.Blocks...)
+	}
+	return nil
+}
+
+// coerceToSet converts profiles of any mode to set mode, where a block is only covered or not
+func coerceToSet(profiles []*cover.Profile) {
+	for _, p := range profiles {
+		p.Mode = "set"
+		for i := range p.Blocks {
+			if p.Blocks[i].Count > 0 {
+				p.Blocks[i].Count = 1
+			}
+		}
+	}
+}
+
+// mergeFiles merges the profiles of filenames.  They must all have the same cover mode unless coerce is set, which
+// converts them to that mode first.  Only set is safe to convert to
+func mergeFiles(filenames []string, coerce string) (*profileSet, error) {
+	if coerce != "" && coerce != "set" {
+		return nil, fmt.Errorf("cannot coerce profiles to mode %s: only set is safe", coerce)
+	}
+	set := newProfileSet()
+	modeSource := ""
+	for _, filename := range filenames {
+		profiles, err := readProfiles(filename)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s: %s", filename, err)
+		}
+		if coerce != "" {
+			coerceToSet(profiles)
+		}
+		if len(profiles) > 0 && set.mode != "" && profiles[0].Mode != set.mode {
+			return nil, fmt.Errorf("%s has cover mode %s, but %s has mode %s.  Merging them would mix hit counts with covered flags: use -coerce=set to merge them as set mode", filename, profiles[0].Mode, modeSource, set.mode)
+		}
+		if err := set.add(profiles); err != nil {
+			return nil, fmt.Errorf("cannot merge %s: %s", filename, err)
+		}
+		if modeSource == "" && set.mode != "" {
+			modeSource = filename
+		}
+	}
+	return set, nil
+}
+
+func sameBlockPosition(a cover.ProfileBlock, b cover.ProfileBlock) bool {
+	return a.StartLine == b.StartLine && a.StartCol == b.StartCol && a.EndLine == b.EndLine && a.EndCol == b.EndCol
+}
+
+// profiles returns the merged profiles, sorted by file name
+func (s *profileSet) profiles() []*cover.Profile {
+	ret := make([]*cover.Profile, 0, len(s.files))
+	for _, p := range s.files {
+		sort.SliceStable(p.Blocks, func(i, j int) bool {
+			bi, bj := p.Blocks[i], p.Blocks[j]
+			return bi.StartLine < bj.StartLine || bi.StartLine == bj.StartLine && bi.StartCol < bj.StartCol
+		})
+		merged := p.Blocks[:0]
+		for _, block := range p.Blocks {
+			if len(merged) > 0 && sameBlockPosition(merged[len(merged)-1], block) {
+				if s.mode == "set" {
+					merged[len(merged)-1].Count |= block.Count
+				} else {
+					merged[len(merged)-1].Count += block.Count
+				}
+				continue
+			}
+			merged = append(merged, block)
+		}
+		p.Blocks = merged
+		ret = append(ret, p)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].FileName < ret[j].FileName })
+	return ret
+}
+
+func writeProfiles(w io.Writer, profiles []*cover.Profile) error {
+	mode := "set"
+	if len(profiles) > 0 {
+		mode = profiles[0].Mode
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "mode: %s\n", mode)
+	for _, p := range profiles {
+		for _, b := range p.Blocks {
+			fmt.Fprintf(bw, "%s:%d.%d,%d.%d %d %d\n", p.FileName, b.StartLine, b.StartCol, b.EndLine, b.EndCol, b.NumStmt, b.Count)
+		}
+	}
+	return bw.Flush()
+}
+
+// runMerge merges profiles, like the ones of several CI stages, into one.  - reads a profile from stdin
+func runMerge(args []string) error {
+	fs := flag.NewFlagSet("merge", flag.ContinueOnError)
+	output := fs.String("o", stdio, "File to write the merged profile to.  - means stdout")
+	coerce := fs.String("coerce", "", "If set to set, convert count and atomic profiles to set mode so profiles of different modes can be merged")
+	strict := fs.Bool("strict", false, "If true, fail instead of warning when profiles come from different commits or Go versions, per their .meta.json sidecars")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("usage: gocoverdir merge [-coerce set] [-strict] [-o merged.out] a.out b.out ...")
+	}
+	meta, metaSource, err := firstProfileMeta(files)
+	if err != nil {
+		return err
+	}
+	if meta != nil {
+		mismatches, err := metaMismatches(*meta, metaSource, files)
+		if err != nil {
+			return err
+		}
+		if len(mismatches) > 0 && *strict {
+			return fmt.Errorf("profiles do not match:\n%s", strings.Join(mismatches, "\n"))
+		}
+		for _, mismatch := range mismatches {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", mismatch)
+		}
+	}
+	set, err := mergeFiles(files, *coerce)
+	if err != nil {
+		return err
+	}
+	if err := writeFileWith(*output, func(w io.Writer) error {
+		return writeProfiles(w, set.profiles())
+	}); err != nil {
+		return err
+	}
+	if meta != nil && *output != stdio {
+		return writeProfileMeta(*output, *meta)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// expandMergeInputs expands the comma separated globs of -mergeinputs into the profiles they match, sorted and without
+// exclude, which is the profile being written
+func expandMergeInputs(globs string, exclude string) ([]string, error) {
+	seen := make(map[string]struct{})
+	var ret []string
+	for _, glob := range strings.Split(globs, ",") {
+		glob = strings.TrimSpace(glob)
+		if glob == "" {
+			continue
+		}
+		matches, err := filepath.Glob(glob)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -mergeinputs glob %q: %s", glob, err)
+		}
+		for _, match := range matches {
+			if _, exists := seen[match]; exists || sameFile(match, exclude) {
+				continue
+			}
+			seen[match] = struct{}{}
+			ret = append(ret, match)
+		}
+	}
+	sort.Strings(ret)
+	return ret, nil
+}
+
+// sameFile is true if a and b name the same path
+func sameFile(a string, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// mergeInputs merges the profiles of earlier CI stages, matched by -mergeinputs, into -coverprofile, so gates and
+// reports see the coverage of the whole pipeline
+func (m *gocoverdir) mergeInputs() error {
+	inputs, err := expandMergeInputs(m.args.mergeinputs, m.args.coverprofile)
+	if err != nil {
+		return err
+	}
+	if len(inputs) == 0 {
+		m.log.Printf("Warning: -mergeinputs %s matched no profiles", m.args.mergeinputs)
+		return nil
+	}
+	if err := m.checkInputMeta(inputs); err != nil {
+		return err
+	}
+	set, err := mergeFiles(append([]string{m.args.coverprofile}, inputs...), m.args.coerce)
+	if err != nil {
+		return err
+	}
+	m.log.Printf("Merged %s into %s", strings.Join(inputs, ", "), m.args.coverprofile)
+	return writeFileWith(m.args.coverprofile, func(w io.Writer) error {
+		return writeProfiles(w, set.profiles())
+	})
+}
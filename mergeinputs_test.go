@@ -0,0 +1,66 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandMergeInputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestExpandMergeInputs")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"unit.out", "e2e.out", "coverage.out", "notes.txt"} {
+		noError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("mode: set\n"), 0644))
+	}
+	inputs, err := expandMergeInputs(filepath.Join(dir, "*.out")+", "+filepath.Join(dir, "unit.out"), filepath.Join(dir, "coverage.out"))
+	noError(t, err)
+	if !reflect.DeepEqual(inputs, []string{filepath.Join(dir, "e2e.out"), filepath.Join(dir, "unit.out")}) {
+		t.Errorf("Unexpected inputs %v", inputs)
+	}
+	if _, err := expandMergeInputs("[", ""); err == nil {
+		t.Errorf("Expected an error for a bad glob")
+	}
+}
+
+func TestMergeInputs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestMergeInputs")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	write := func(name string, contents string) string {
+		filename := filepath.Join(dir, name)
+		noError(t, ioutil.WriteFile(filename, []byte(contents), 0644))
+		return filename
+	}
+	m := &gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	m.args.coverprofile = write("coverage.out", "mode: set\nexample.com/a/a.go:1.1,2.1 1 1\nexample.com/a/a.go:3.1,4.1 1 0\n")
+	write("unit.out", "mode: set\nexample.com/a/a.go:3.1,4.1 1 1\n")
+	write("e2e.out", "mode: set\nexample.com/b/b.go:1.1,2.1 2 0\n")
+	m.args.mergeinputs = filepath.Join(dir, "*.out")
+	noError(t, m.mergeInputs())
+	profiles, err := readProfiles(m.args.coverprofile)
+	noError(t, err)
+	if len(profiles) != 2 || calculateCoverage(profiles, "statements") != 50 {
+		t.Errorf("Unexpected merged profiles %v", profiles)
+	}
+
+	write("count.out", "mode: count\nexample.com/c/c.go:1.1,2.1 1 4\n")
+	if err := m.mergeInputs(); err == nil || !strings.Contains(err.Error(), "-coerce=set") {
+		t.Errorf("Expected an error suggesting -coerce merging a different cover mode, got %v", err)
+	}
+	m.args.coerce = "set"
+	noError(t, m.mergeInputs())
+	contents, err := ioutil.ReadFile(m.args.coverprofile)
+	noError(t, err)
+	if !strings.Contains(string(contents), "mode: set\n") || !strings.Contains(string(contents), "example.com/c/c.go:1.1,2.1 1 1\n") {
+		t.Errorf("Unexpected coerced profile:\n%s", contents)
+	}
+	m.args.coerce = "atomic"
+	if err := m.mergeInputs(); err == nil {
+		t.Errorf("Expected an error coercing to atomic")
+	}
+}
@@ -0,0 +1,122 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// offlineEnv keeps go from reaching the network during -offline runs
+func offlineEnv(goflags string) []string {
+	return []string{"GOFLAGS=" + strings.TrimSpace(goflags+" -mod=mod"), "GOPROXY=off"}
+}
+
+// testEnv is the environment test runs add to their own
+func (m *gocoverdir) testEnv() []string {
+	env := m.cgoEnv()
+	if m.args.offline {
+		env = append(env, offlineEnv(os.Getenv("GOFLAGS"))...)
+	}
+	return env
+}
+
+type goModVersion struct {
+	Path    string
+	Version string
+}
+
+// goModFile is the part of 'go mod edit -json' needed to list required modules
+type goModFile struct {
+	Require []goModVersion
+	Replace []struct {
+		Old goModVersion
+		New goModVersion
+	}
+}
+
+// requiredModules lists path@version of every module the go.mod requires, following replacements.  Modules
+// replaced by directories are already on disk
+func requiredModules(mod goModFile) []string {
+	replaced := make(map[string]goModVersion, len(mod.Replace))
+	for _, r := range mod.Replace {
+		replaced[r.Old.Path+"@"+r.Old.Version] = r.New
+		if r.Old.Version == "" {
+			replaced[r.Old.Path] = r.New
+		}
+	}
+	var ret []string
+	for _, req := range mod.Require {
+		actual := req
+		if r, exists := replaced[req.Path+"@"+req.Version]; exists {
+			actual = r
+		} else if r, exists := replaced[req.Path]; exists {
+			actual = r
+		}
+		if actual.Version == "" {
+			continue
+		}
+		ret = append(ret, actual.Path+"@"+actual.Version)
+	}
+	return ret
+}
+
+// missingModules reads the JSON stream of 'go mod download -json' and lists the modules that failed
+func missingModules(r io.Reader) ([]string, error) {
+	var ret []string
+	dec := json.NewDecoder(r)
+	for {
+		var download struct {
+			Path    string
+			Version string
+			Error   string
+		}
+		if err := dec.Decode(&download); err == io.EOF {
+			return ret, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if download.Error != "" {
+			ret = append(ret, download.Path+"@"+download.Version)
+		}
+	}
+}
+
+// verifyOffline fails fast, naming every missing module, if -offline tests cannot build from the module cache
+func (m *gocoverdir) verifyOffline() error {
+	if !m.args.offline {
+		return nil
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command("go", "mod", "edit", "-json")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		m.log.Printf("Not in a module, so there is nothing to check for -offline: %s", err)
+		return nil
+	}
+	var mod goModFile
+	if err := json.Unmarshal(stdout.Bytes(), &mod); err != nil {
+		return err
+	}
+	modules := requiredModules(mod)
+	if len(modules) == 0 {
+		return nil
+	}
+	stdout.Reset()
+	cmd = exec.Command("go", append([]string{"mod", "download", "-json"}, modules...)...)
+	cmd.Env = append(cmd.Environ(), offlineEnv(os.Getenv("GOFLAGS"))...)
+	cmd.Stdout = &stdout
+	// The exit code only says something is missing.  The JSON says what
+	_ = cmd.Run()
+	missing, err := missingModules(&stdout)
+	if err != nil {
+		return err
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("-offline, but these modules are not in the module cache: %s.  Run 'go mod download' with network access first", strings.Join(missing, " "))
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequiredModules(t *testing.T) {
+	var mod goModFile
+	noError(t, json.Unmarshal([]byte(`{
+		"Require": [{"Path": "a.com/x", "Version": "v1.0.0"}, {"Path": "b.com/y", "Version": "v1.1.0"}, {"Path": "c.com/z", "Version": "v0.1.0"}],
+		"Replace": [{"Old": {"Path": "b.com/y"}, "New": {"Path": "fork.com/y", "Version": "v1.2.0"}}, {"Old": {"Path": "c.com/z", "Version": "v0.1.0"}, "New": {"Path": "../z"}}]
+	}`), &mod))
+	if modules := requiredModules(mod); !reflect.DeepEqual(modules, []string{"a.com/x@v1.0.0", "fork.com/y@v1.2.0"}) {
+		t.Errorf("Unexpected modules %v", modules)
+	}
+}
+
+func TestMissingModules(t *testing.T) {
+	stream := `{"Path": "a.com/x", "Version": "v1.0.0", "Dir": "/cache/a.com/x@v1.0.0"}
+{"Path": "b.com/y", "Version": "v1.1.0", "Error": "b.com/y@v1.1.0: module lookup disabled by GOPROXY=off"}`
+	missing, err := missingModules(strings.NewReader(stream))
+	noError(t, err)
+	if !reflect.DeepEqual(missing, []string{"b.com/y@v1.1.0"}) {
+		t.Errorf("Unexpected missing modules %v", missing)
+	}
+}
+
+func TestOfflineEnv(t *testing.T) {
+	if env := offlineEnv(""); !reflect.DeepEqual(env, []string{"GOFLAGS=-mod=mod", "GOPROXY=off"}) {
+		t.Errorf("Unexpected env %v", env)
+	}
+	if env := offlineEnv("-race"); env[0] != "GOFLAGS=-race -mod=mod" {
+		t.Errorf("Expected existing GOFLAGS to be kept, saw %v", env)
+	}
+}
@@ -0,0 +1,78 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// listPackageDirs expands package patterns, like 'go test' arguments, into directories relative to the current one
+func listPackageDirs(patterns []string) ([]string, error) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd := exec.Command("go", append([]string{"list", "-e", "-f", "{{.Dir}}"}, patterns...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("cannot list packages %s: %s\n%s", strings.Join(patterns, " "), err, stderr.String())
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, dir := range strings.Split(stdout.String(), "\n") {
+		if dir == "" {
+			continue
+		}
+		rel, err := filepath.Rel(cwd, dir)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return nil, fmt.Errorf("package directory %s is outside the current directory", dir)
+		}
+		ret = append(ret, rel)
+	}
+	return ret, nil
+}
+
+// dirImportPaths maps dirs to their import paths, asking go list only if discovery did not already say
+func (m *gocoverdir) dirImportPaths(dirs []string) map[string]string {
+	ret := make(map[string]string, len(dirs))
+	for _, dir := range dirs {
+		importPath, exists := m.importPaths[dir]
+		if !exists {
+			return dirImportPaths(dirs)
+		}
+		ret[dir] = importPath
+	}
+	return ret
+}
+
+// dirImportPaths maps directories, relative to the current one, to their import paths
+func dirImportPaths(dirs []string) map[string]string {
+	ret := make(map[string]string, len(dirs))
+	if len(dirs) == 0 {
+		return ret
+	}
+	args := []string{"list", "-e", "-f", "{{.ImportPath}}"}
+	for _, dir := range dirs {
+		args = append(args, "./"+dir)
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command("go", args...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return ret
+	}
+	// go list prints one line per argument, in order
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != len(dirs) {
+		return ret
+	}
+	for i, dir := range dirs {
+		ret[dir] = lines[i]
+	}
+	return ret
+}
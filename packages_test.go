@@ -0,0 +1,16 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"log"
+	"testing"
+)
+
+func TestDiscoverDirsConflict(t *testing.T) {
+	m := &gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	m.args.stdin = true
+	m.args.packages = []string{"./..."}
+	if _, err := m.discoverDirs(); err == nil {
+		t.Errorf("Expected -stdin and package arguments to conflict")
+	}
+}
@@ -0,0 +1,192 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/cep21/gocoverdir/gocoverdirpb"
+	"google.golang.org/protobuf/proto"
+)
+
+// pbSchemaVersion is Report.schema_version in report.proto
+const pbSchemaVersion = 1
+
+func packageProtos(pkgs []reportPackage) []*gocoverdirpb.Package {
+	var ret []*gocoverdirpb.Package
+	for _, pkg := range pkgs {
+		ret = append(ret, &gocoverdirpb.Package{
+			Path:       pkg.Path,
+			Statements: int64(pkg.Statements),
+			Covered:    int64(pkg.Covered),
+			Coverage:   pkg.Coverage,
+			Risk:       pkg.Risk,
+		})
+	}
+	return ret
+}
+
+func packagesFromProto(pkgs []*gocoverdirpb.Package) []reportPackage {
+	var ret []reportPackage
+	for _, pkg := range pkgs {
+		ret = append(ret, reportPackage{
+			Path:       pkg.GetPath(),
+			Statements: int(pkg.GetStatements()),
+			Covered:    int(pkg.GetCovered()),
+			Coverage:   pkg.GetCoverage(),
+			Risk:       pkg.GetRisk(),
+		})
+	}
+	return ret
+}
+
+// reportProto is r as the Report message of report.proto
+func reportProto(r *report) *gocoverdirpb.Report {
+	ret := &gocoverdirpb.Report{
+		SchemaVersion:     pbSchemaVersion,
+		TimestampUnixNano: r.Timestamp.UnixNano(),
+		Coverage:          r.Coverage,
+		Statements:        int64(r.Statements),
+		Covered:           int64(r.Covered),
+		Packages:          packageProtos(r.Packages),
+		Surfaces:          packageProtos(r.Surfaces),
+		Sources:           packageProtos(r.Sources),
+		RunId:             r.RunID,
+		ToolVersion:       r.ToolVersion,
+		Metric:            r.Metric,
+	}
+	if r.CI != nil {
+		ret.Ci = &gocoverdirpb.CI{Name: r.CI.Name, Commit: r.CI.Commit, Branch: r.CI.Branch, BuildUrl: r.CI.BuildURL}
+	}
+	for _, file := range r.Files {
+		ret.Files = append(ret.Files, &gocoverdirpb.File{
+			Path:       file.Path,
+			Package:    file.Package,
+			Statements: int64(file.Statements),
+			Covered:    int64(file.Covered),
+			Coverage:   file.Coverage,
+		})
+	}
+	for _, test := range r.Tests {
+		ret.Tests = append(ret.Tests, &gocoverdirpb.Test{
+			Dir:           test.Dir,
+			Command:       test.Command,
+			Seconds:       test.Seconds,
+			Passed:        test.Passed,
+			Error:         test.Error,
+			SkippedTests:  test.SkippedTests,
+			Quarantined:   test.Quarantined,
+			Flaky:         test.Flaky,
+			Profiles:      test.Profiles,
+			Races:         test.Races,
+			Examples:      test.Examples,
+			ExamplesOnly:  test.ExamplesOnly,
+			FailureBundle: test.FailureBundle,
+			OutputMatches: test.OutputMatches,
+			Leaks:         test.Leaks,
+		})
+	}
+	for _, skip := range r.Skipped {
+		ret.Skipped = append(ret.Skipped, &gocoverdirpb.Skip{Dir: skip.Dir, Reason: skip.Reason})
+	}
+	for _, f := range r.Findings {
+		ret.Findings = append(ret.Findings, &gocoverdirpb.Finding{
+			Code:     f.Code,
+			Severity: f.Severity,
+			File:     f.File,
+			Line:     int64(f.Line),
+			Column:   int64(f.Column),
+			Message:  f.Message,
+		})
+	}
+	return ret
+}
+
+// reportFromProto is the report of the Report message pb
+func reportFromProto(pb *gocoverdirpb.Report) (*report, error) {
+	if pb.GetSchemaVersion() > pbSchemaVersion {
+		return nil, fmt.Errorf("report schema version %d is newer than supported version %d", pb.GetSchemaVersion(), pbSchemaVersion)
+	}
+	r := &report{
+		Timestamp:   time.Unix(0, pb.GetTimestampUnixNano()),
+		Coverage:    pb.GetCoverage(),
+		Statements:  int(pb.GetStatements()),
+		Covered:     int(pb.GetCovered()),
+		Packages:    packagesFromProto(pb.GetPackages()),
+		Surfaces:    packagesFromProto(pb.GetSurfaces()),
+		Sources:     packagesFromProto(pb.GetSources()),
+		RunID:       pb.GetRunId(),
+		ToolVersion: pb.GetToolVersion(),
+		Metric:      pb.GetMetric(),
+	}
+	if ci := pb.GetCi(); ci != nil {
+		r.CI = &ciEnvironment{Name: ci.GetName(), Commit: ci.GetCommit(), Branch: ci.GetBranch(), BuildURL: ci.GetBuildUrl()}
+	}
+	for _, file := range pb.GetFiles() {
+		r.Files = append(r.Files, reportFile{
+			Path:       file.GetPath(),
+			Package:    file.GetPackage(),
+			Statements: int(file.GetStatements()),
+			Covered:    int(file.GetCovered()),
+			Coverage:   file.GetCoverage(),
+		})
+	}
+	for _, test := range pb.GetTests() {
+		r.Tests = append(r.Tests, reportTest{
+			Dir:           test.GetDir(),
+			Command:       test.GetCommand(),
+			Seconds:       test.GetSeconds(),
+			Passed:        test.GetPassed(),
+			Error:         test.GetError(),
+			Skipped:       len(test.GetSkippedTests()),
+			SkippedTests:  test.GetSkippedTests(),
+			Quarantined:   test.GetQuarantined(),
+			Flaky:         test.GetFlaky(),
+			Profiles:      test.GetProfiles(),
+			Races:         test.GetRaces(),
+			Examples:      test.GetExamples(),
+			ExamplesOnly:  test.GetExamplesOnly(),
+			FailureBundle: test.GetFailureBundle(),
+			OutputMatches: test.GetOutputMatches(),
+			Leaks:         test.GetLeaks(),
+		})
+	}
+	for _, skip := range pb.GetSkipped() {
+		r.Skipped = append(r.Skipped, reportSkip{Dir: skip.GetDir(), Reason: skip.GetReason()})
+	}
+	for _, f := range pb.GetFindings() {
+		r.Findings = append(r.Findings, finding{
+			Code:     f.GetCode(),
+			Severity: f.GetSeverity(),
+			File:     f.GetFile(),
+			Line:     int(f.GetLine()),
+			Column:   int(f.GetColumn()),
+			Message:  f.GetMessage(),
+		})
+	}
+	return r, nil
+}
+
+func writePBReport(w io.Writer, r *report) error {
+	// Deterministic orders the profiles map, so the same report is always the same bytes
+	buf, err := proto.MarshalOptions{Deterministic: true}.Marshal(reportProto(r))
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buf)
+	return err
+}
+
+// readPBReport reads a report written by -pb
+func readPBReport(rd io.Reader) (*report, error) {
+	buf, err := ioutil.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	var pb gocoverdirpb.Report
+	if err := proto.Unmarshal(buf, &pb); err != nil {
+		return nil, fmt.Errorf("cannot parse protobuf report: %s", err)
+	}
+	return reportFromProto(&pb)
+}
@@ -0,0 +1,41 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestPBReportRoundTrip(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 3, Count: 1}, {NumStmt: 1}}},
+	}
+	results := []packageResult{
+		{dir: "a", command: "go test", duration: time.Second, err: errors.New("exit status 1"), failureBundle: "artifacts/failures/a", skipped: []string{"TestS"}, flaky: []string{"TestF"}, profiles: map[string]string{"mutex": "a/mutex.pprof"}, races: []string{"WARNING: DATA RACE"}, outputMatches: []string{"goroutine leak: 1"}, leaks: []string{"example.com/a.worker"}, examples: []string{"ExampleA"}, examplesOnly: true},
+		{dir: "a", command: "go test (quarantined)", quarantined: true},
+	}
+	skipped := []skippedDir{{dir: "b", reason: "requires cgo, which is disabled"}}
+	original := newReport(profiles, "statements", results, skipped, &ciEnvironment{Name: "github", Commit: "abc"}, nil)
+	original.Packages[0].Risk = 1.25
+	original.RunID = "8f3a"
+	original.Sources = []reportPackage{{Path: "sql/query.sql", Statements: 4, Covered: 3, Coverage: 75}}
+	original.Findings = []finding{{Code: "SA4006", Severity: "error", File: "a/a.go", Line: 3, Column: 2, Message: "value never used"}}
+	buf := bytes.Buffer{}
+	noError(t, writePBReport(&buf, original))
+	decoded, err := readPBReport(&buf)
+	noError(t, err)
+	if !decoded.Timestamp.Equal(original.Timestamp) {
+		t.Errorf("Timestamp %s != %s", decoded.Timestamp, original.Timestamp)
+	}
+	decoded.Timestamp = original.Timestamp
+	if !reflect.DeepEqual(decoded, original) {
+		t.Errorf("Decoded report %+v != %+v", decoded, original)
+	}
+	if _, err := readPBReport(bytes.NewReader([]byte{0x0a, 0x05})); err == nil {
+		t.Errorf("Expected an error for a truncated report")
+	}
+}
@@ -0,0 +1,73 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"go/build"
+	"os/exec"
+	"runtime"
+)
+
+type skippedDir struct {
+	dir    string
+	reason string
+}
+
+// buildableIn returns "" if dir has Go files that build in ctx, or why it does not.  Only a directory whose files
+// build constraints all exclude is not applicable: any other error, like files of two packages, is left for go test
+// or go vet to report so the run fails
+func buildableIn(ctx *build.Context, dir string) string {
+	_, err := ctx.ImportDir(dir, 0)
+	if _, isNoGo := err.(*build.NoGoError); isNoGo {
+		return fmt.Sprintf("not applicable: no Go files for %s/%s", ctx.GOOS, ctx.GOARCH)
+	}
+	return ""
+}
+
+func (m *gocoverdir) skip(dir string, reason string) {
+	m.log.Printf("Skipping %s: %s", dir, reason)
+	m.explained.record(dir, "skipped", reason)
+	m.skipped = append(m.skipped, skippedDir{dir: dir, reason: reason})
+}
+
+func (m *gocoverdir) targetContext() (*build.Context, bool) {
+	if m.args.goos == "" && m.args.goarch == "" {
+		return nil, false
+	}
+	ctx := build.Default
+	if m.args.goos != "" {
+		ctx.GOOS = m.args.goos
+	}
+	if m.args.goarch != "" {
+		ctx.GOARCH = m.args.goarch
+	}
+	return &ctx, ctx.GOOS != runtime.GOOS || ctx.GOARCH != runtime.GOARCH
+}
+
+// vetTarget compiles and vets every Go directory for -goos/-goarch, since tests cannot run for other platforms
+func (m *gocoverdir) vetTarget(goDirs []string) error {
+	ctx, isCross := m.targetContext()
+	if ctx == nil {
+		return nil
+	}
+	if !isCross {
+		m.log.Printf("-goos/-goarch match the host, so tests already cover %s/%s", ctx.GOOS, ctx.GOARCH)
+		return nil
+	}
+	command := fmt.Sprintf("go vet %s/%s", ctx.GOOS, ctx.GOARCH)
+	var firstErr error
+	for _, dir := range goDirs {
+		if reason := buildableIn(ctx, dir); reason != "" {
+			m.skip(dir, command+" "+reason)
+			continue
+		}
+		cmd := exec.Command("go", "vet", "./"+dir)
+		cmd.Env = append(cmd.Environ(), "GOOS="+ctx.GOOS, "GOARCH="+ctx.GOARCH)
+		result := m.runTest(dir, cmd, false)
+		result.command = command
+		m.results = append(m.results, result)
+		if result.err != nil && firstErr == nil {
+			firstErr = result.err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,30 @@
+package gocoverdir
+
+import (
+	"go/build"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildableIn(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdirtest")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("//go:build plan9\n\npackage a\n"), 0644))
+	ctx := build.Default
+	ctx.GOOS = "linux"
+	if reason := buildableIn(&ctx, dir); !strings.HasPrefix(reason, "not applicable") {
+		t.Errorf("Expected directory to be not applicable, saw %q", reason)
+	}
+	ctx.GOOS = "plan9"
+	if reason := buildableIn(&ctx, dir); reason != "" {
+		t.Errorf("Expected directory to build, saw %q", reason)
+	}
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "b.go"), []byte("//go:build plan9\n\npackage b\n"), 0644))
+	if reason := buildableIn(&ctx, dir); reason != "" {
+		t.Errorf("Expected a broken directory to be left for go test to fail, saw %q", reason)
+	}
+}
@@ -0,0 +1,151 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/google/cel-go/cel"
+)
+
+// policy is one gate written as a CEL expression over the report.  It is violated when it evaluates to true, like
+//
+//	pkg.path.startsWith("internal/payments") && pkg.coverage < 90
+//
+// Policies that use pkg are checked against every package, the others once against report
+type policy struct {
+	source     string
+	line       int
+	program    cel.Program
+	perPackage bool
+}
+
+// policyEnv declares the variables policies see.  Numbers compare across int and double, so pkg.coverage < 90 works
+func policyEnv() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("report", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("pkg", cel.MapType(cel.StringType, cel.DynType)),
+		cel.CrossTypeNumericComparisons(true),
+	)
+}
+
+// parsePolicies reads one policy per line.  Blank lines and lines starting with # or // are ignored
+func parsePolicies(r io.Reader) ([]policy, error) {
+	env, err := policyEnv()
+	if err != nil {
+		return nil, err
+	}
+	var ret []policy
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		checked, issues := env.Compile(line)
+		if issues.Err() != nil {
+			return nil, fmt.Errorf("line %d: cannot parse policy %q: %s", lineNumber, line, issues.Err())
+		}
+		if out := checked.OutputType(); !out.IsExactType(cel.BoolType) && !out.IsExactType(cel.DynType) {
+			return nil, fmt.Errorf("line %d: policy %q is a %s, not true or false", lineNumber, line, out)
+		}
+		program, err := env.Program(checked)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNumber, err)
+		}
+		p := policy{source: line, line: lineNumber, program: program}
+		for _, reference := range checked.NativeRep().ReferenceMap() {
+			if reference.Name == "pkg" {
+				p.perPackage = true
+			}
+		}
+		ret = append(ret, p)
+	}
+	return ret, scanner.Err()
+}
+
+func loadPolicies(filename string) ([]policy, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	ret, err := parsePolicies(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return ret, nil
+}
+
+// policyVars are the variables of a policy, report and, for policies per package, pkg
+type policyVars map[string]interface{}
+
+// packageVars describes pkg.  Its path is relative to module, like internal/payments, and importpath is the full
+// import path
+func packageVars(pkg reportPackage, module string) map[string]interface{} {
+	relative := pkg.Path
+	if pkg.Path == module {
+		relative = "."
+	} else if module != "" {
+		relative = strings.TrimPrefix(pkg.Path, module+"/")
+	}
+	return map[string]interface{}{
+		"path":       relative,
+		"importpath": pkg.Path,
+		"statements": pkg.Statements,
+		"covered":    pkg.Covered,
+		"coverage":   pkg.Coverage,
+		"risk":       pkg.Risk,
+	}
+}
+
+func reportVars(r *report) map[string]interface{} {
+	return map[string]interface{}{
+		"coverage":   r.Coverage,
+		"statements": r.Statements,
+		"covered":    r.Covered,
+		"packages":   len(r.Packages),
+	}
+}
+
+// violated evaluates p, which must be true or false
+func (p policy) violated(vars policyVars) (bool, error) {
+	v, _, err := p.program.Eval(map[string]interface{}(vars))
+	if err != nil {
+		return false, fmt.Errorf("policy on line %d, %s: %s", p.line, p.source, err)
+	}
+	b, ok := v.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("policy on line %d, %s: is %v, not true or false", p.line, p.source, v)
+	}
+	return b, nil
+}
+
+// policyViolations checks every policy against r, a report on module
+func policyViolations(policies []policy, r *report, module string) ([]violation, error) {
+	var ret []violation
+	for _, p := range policies {
+		if !p.perPackage {
+			violated, err := p.violated(policyVars{"report": reportVars(r)})
+			if err != nil {
+				return nil, err
+			}
+			if violated {
+				ret = append(ret, violation{Rule: "policy", Scope: "total", Actual: r.Coverage, Policy: p.source})
+			}
+			continue
+		}
+		for _, pkg := range r.Packages {
+			violated, err := p.violated(policyVars{"report": reportVars(r), "pkg": packageVars(pkg, module)})
+			if err != nil {
+				return nil, err
+			}
+			if violated {
+				ret = append(ret, violation{Rule: "policy", Scope: pkg.Path, Actual: pkg.Coverage, Policy: p.source})
+			}
+		}
+	}
+	return ret, nil
+}
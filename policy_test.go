@@ -0,0 +1,51 @@
+package gocoverdir
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestPolicyViolations(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/m/internal/payments/p.go", Blocks: []cover.ProfileBlock{{NumStmt: 8, Count: 1}, {NumStmt: 2}}},
+		{FileName: "example.com/m/cmd/tool/main.go", Blocks: []cover.ProfileBlock{{NumStmt: 1}}},
+	}
+	policies, err := parsePolicies(strings.NewReader(`# payments must be well tested
+pkg.path.startsWith("internal/payments") && pkg.coverage < 90
+
+// small packages are fine, and the short circuit keeps the regexp from running
+pkg.statements > 5 && pkg.importpath.matches("^example\\.com/m/cmd/")
+report.coverage < 2 * 40
+!(report.packages == 2)
+`))
+	noError(t, err)
+	if len(policies) != 4 || !policies[0].perPackage || policies[2].perPackage {
+		t.Fatalf("Unexpected policies %+v", policies)
+	}
+	violations, err := policyViolations(policies, newReport(profiles, "statements", nil, nil, nil, nil), "example.com/m")
+	noError(t, err)
+	if len(violations) != 2 || violations[0].Scope != "example.com/m/internal/payments" || violations[1].Scope != "total" {
+		t.Fatalf("Unexpected violations %v", violations)
+	}
+	if expected := `policy: example.com/m/internal/payments, with coverage 80.0%, violates pkg.path.startsWith("internal/payments") && pkg.coverage < 90`; violations[0].String() != expected {
+		t.Errorf("Unexpected message %s", violations[0])
+	}
+}
+
+func TestPolicyErrors(t *testing.T) {
+	for _, source := range []string{"pkg.coverage <", "len(report.coverage)", "other.coverage > 1", `"a" + 1`} {
+		if _, err := parsePolicies(strings.NewReader(source)); err == nil {
+			t.Errorf("Expected %s to not parse", source)
+		}
+	}
+	r := newReport(nil, "statements", nil, nil, nil, nil)
+	for _, source := range []string{"report.coverage", "report.missing > 1", `report.coverage > "a"`} {
+		policies, err := parsePolicies(strings.NewReader(source))
+		noError(t, err)
+		if _, err := policyViolations(policies, r, ""); err == nil {
+			t.Errorf("Expected %s to fail", source)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package gocoverdir
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// portablePath is p relative to the working directory, if it is under it, with forward slashes.  Artifacts cached
+// by one OS's CI then name the same files on another's
+func portablePath(p string) string {
+	if filepath.IsAbs(p) {
+		if cwd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(cwd, p); err == nil && !strings.HasPrefix(rel, "..") {
+				p = rel
+			}
+		}
+	}
+	return filepath.ToSlash(p)
+}
+
+// emittedPath is how artifacts name p: as is, or portably with -portable
+func (m *gocoverdir) emittedPath(p string) string {
+	if !m.args.portable {
+		return p
+	}
+	return portablePath(p)
+}
+
+// emittedResults are the results with their paths named like artifacts name them
+func (m *gocoverdir) emittedResults() []packageResult {
+	if !m.args.portable {
+		return m.results
+	}
+	ret := make([]packageResult, 0, len(m.results))
+	for _, result := range m.results {
+		result.dir = m.emittedPath(result.dir)
+		if result.failureBundle != "" {
+			result.failureBundle = m.emittedPath(result.failureBundle)
+		}
+		if result.profiles != nil {
+			profiles := make(map[string]string, len(result.profiles))
+			for kind, file := range result.profiles {
+				profiles[kind] = m.emittedPath(file)
+			}
+			result.profiles = profiles
+		}
+		ret = append(ret, result)
+	}
+	return ret
+}
+
+// emittedSkipped are the skipped directories named like artifacts name them
+func (m *gocoverdir) emittedSkipped() []skippedDir {
+	if !m.args.portable {
+		return m.skipped
+	}
+	ret := make([]skippedDir, 0, len(m.skipped))
+	for _, skip := range m.skipped {
+		ret = append(ret, skippedDir{dir: m.emittedPath(skip.dir), reason: skip.reason})
+	}
+	return ret
+}
+
+// fileURL is the file:// URL of p.  Windows paths like C:\x become file:///C:/x
+func fileURL(p string) string {
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	p = filepath.ToSlash(p)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// openCommand is the command that opens a file or URL in the desktop's default application
+func openCommand(goos string) string {
+	switch goos {
+	case "darwin":
+		return "open"
+	case "windows":
+		return "start"
+	}
+	return "xdg-open"
+}
+
+// defaultList joins a default list flag's values with the OS's path list separator, which is how the flag is split
+func defaultList(values ...string) string {
+	return strings.Join(values, string(filepath.ListSeparator))
+}
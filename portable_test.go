@@ -0,0 +1,53 @@
+package gocoverdir
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPortablePath(t *testing.T) {
+	cwd, err := os.Getwd()
+	noError(t, err)
+	if p := portablePath(filepath.Join(cwd, "a", "b.out")); p != "a/b.out" {
+		t.Errorf("Expected a relative forward slash path, saw %s", p)
+	}
+	if p := portablePath(filepath.Join("a", "b")); p != "a/b" {
+		t.Errorf("Expected a/b, saw %s", p)
+	}
+	outside := filepath.Join(filepath.Dir(cwd), "elsewhere")
+	if p := portablePath(outside); p != filepath.ToSlash(outside) {
+		t.Errorf("Expected a path outside the working directory to stay absolute, saw %s", p)
+	}
+}
+
+func TestEmittedResults(t *testing.T) {
+	cwd, err := os.Getwd()
+	noError(t, err)
+	m := &gocoverdir{results: []packageResult{{dir: filepath.Join("a", "b"), profiles: map[string]string{"mutex": filepath.Join(cwd, "a", "b", "mutex.pprof")}}}}
+	if results := m.emittedResults(); results[0].dir != filepath.Join("a", "b") {
+		t.Errorf("Expected paths to be kept without -portable, saw %s", results[0].dir)
+	}
+	m.args.portable = true
+	results := m.emittedResults()
+	if results[0].dir != "a/b" || results[0].profiles["mutex"] != "a/b/mutex.pprof" {
+		t.Errorf("Unexpected portable result %+v", results[0])
+	}
+}
+
+func TestFileURL(t *testing.T) {
+	u := fileURL(filepath.Join(os.TempDir(), "cover.html"))
+	if !strings.HasPrefix(u, "file:///") || !strings.HasSuffix(u, "/cover.html") {
+		t.Errorf("Unexpected URL %s", u)
+	}
+	if openCommand("darwin") != "open" || openCommand("windows") != "start" || openCommand("linux") != "xdg-open" {
+		t.Errorf("Unexpected open commands")
+	}
+}
+
+func TestDefaultList(t *testing.T) {
+	if split := filepath.SplitList(defaultList(".git", "vendor")); len(split) != 2 || split[1] != "vendor" {
+		t.Errorf("Expected the default to split back into its values, saw %v", split)
+	}
+}
@@ -0,0 +1,42 @@
+package gocoverdir
+
+import (
+	"time"
+)
+
+// prewarmCommands are the go commands -prewarm runs over every package, so the build cache already holds shared
+// dependencies when the first package's test timeout starts
+func prewarmCommands(vet bool, dirs []string) [][]string {
+	packages := make([]string, 0, len(dirs))
+	for _, dir := range dirs {
+		packages = append(packages, "./"+dir)
+	}
+	ret := [][]string{append([]string{"build"}, packages...)}
+	if vet {
+		ret = append(ret, append([]string{"vet"}, packages...))
+	}
+	return ret
+}
+
+// prewarm compiles dirs once before testing them.  It only fills the build cache: a package that does not build is
+// reported by its tests, so failures here are logged and ignored
+func (m *gocoverdir) prewarm(dirs []string) {
+	if !m.args.prewarm || len(dirs) == 0 {
+		return
+	}
+	for _, args := range prewarmCommands(m.args.prewarmvet, dirs) {
+		cmd, err := m.testCommand(".", "go", args...)
+		if err != nil {
+			m.log.Printf("Unable to prewarm: %s", err)
+			return
+		}
+		cmd.Stdout = m.testOutputStdout
+		cmd.Stderr = m.testOutputStderr
+		start := time.Now()
+		m.log.Printf("Prewarming with go %s", args[0])
+		if err := cmd.Run(); err != nil {
+			m.log.Printf("Prewarm go %s failed, the tests will report why: %s", args[0], err)
+		}
+		m.log.Printf("Prewarm go %s took %s", args[0], time.Since(start))
+	}
+}
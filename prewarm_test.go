@@ -0,0 +1,16 @@
+package gocoverdir
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPrewarmCommands(t *testing.T) {
+	if commands := prewarmCommands(false, []string{"a", "b/c"}); !reflect.DeepEqual(commands, [][]string{{"build", "./a", "./b/c"}}) {
+		t.Errorf("Unexpected commands %v", commands)
+	}
+	commands := prewarmCommands(true, []string{"a"})
+	if !reflect.DeepEqual(commands, [][]string{{"build", "./a"}, {"vet", "./a"}}) {
+		t.Errorf("Unexpected commands %v", commands)
+	}
+}
@@ -0,0 +1,61 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+)
+
+// effectiveFlag is a flag's value once the config file, -ci and -artifacts have filled in their defaults
+type effectiveFlag struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Source is "command line", "default", or "derived" when the config, CI or another flag set it
+	Source string `json:"source"`
+}
+
+// effectiveConfig is everything that decides what an invocation does, for -print-config
+type effectiveConfig struct {
+	Version  string          `json:"version"`
+	Flags    []effectiveFlag `json:"flags"`
+	Config   *config         `json:"config"`
+	CI       *ciEnvironment  `json:"ci,omitempty"`
+	Packages []string        `json:"packages,omitempty"`
+}
+
+// effectiveFlags lists every flag of fs, in name order, with where its value came from.  explicit are the flags set
+// on the command line
+func effectiveFlags(fs *flag.FlagSet, explicit map[string]struct{}) []effectiveFlag {
+	var ret []effectiveFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		source := "derived"
+		if _, exists := explicit[f.Name]; exists {
+			source = "command line"
+		} else if f.Value.String() == f.DefValue {
+			source = "default"
+		}
+		ret = append(ret, effectiveFlag{Name: f.Name, Value: f.Value.String(), Source: source})
+	})
+	return ret
+}
+
+// printConfig sets up the run, which resolves every default, and writes the resulting configuration as JSON without
+// testing anything
+func (m *gocoverdir) printConfig(w io.Writer) error {
+	explicit := make(map[string]struct{})
+	m.flags.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = struct{}{}
+	})
+	if err := m.setup(); err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(effectiveConfig{
+		Version:  toolVersion(),
+		Flags:    effectiveFlags(m.flags, explicit),
+		Config:   m.config,
+		CI:       m.ci,
+		Packages: m.args.packages,
+	})
+}
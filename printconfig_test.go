@@ -0,0 +1,44 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrintConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestPrintConfig")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	configFile := filepath.Join(dir, "config.json")
+	noError(t, ioutil.WriteFile(configFile, []byte(`{"requiredcoverage": 80}`), 0644))
+	m := gocoverdir{}
+	fs := flag.NewFlagSet("TestPrintConfig", flag.PanicOnError)
+	m.setupFlags(fs)
+	noError(t, fs.Parse([]string{"-logfile", "", "-config", configFile, "-coverprofile", filepath.Join(dir, "coverage.out"), "-timings", ""}))
+	buf := bytes.Buffer{}
+	noError(t, m.printConfig(&buf))
+	defer m.Close()
+	var printed effectiveConfig
+	noError(t, json.Unmarshal(buf.Bytes(), &printed))
+	if printed.Config == nil || printed.Config.RequiredCoverage != 80 || printed.Version == "" {
+		t.Errorf("Unexpected config %+v", printed)
+	}
+	sources := make(map[string]effectiveFlag)
+	for _, f := range printed.Flags {
+		sources[f.Name] = f
+	}
+	if f := sources["config"]; f.Source != "command line" || f.Value != configFile {
+		t.Errorf("Unexpected -config %+v", f)
+	}
+	if f := sources["requiredcoverage"]; f.Source != "derived" || f.Value != "80" {
+		t.Errorf("Unexpected -requiredcoverage %+v", f)
+	}
+	if f := sources["depth"]; f.Source != "default" || f.Value != "10" {
+		t.Errorf("Unexpected -depth %+v", f)
+	}
+}
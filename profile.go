@@ -0,0 +1,226 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+type lineHit struct {
+	number int
+	hits   int
+}
+
+// lineHits flattens a profile's blocks into per-line hit counts, using the
+// highest count of any block touching a line.
+func lineHits(p *cover.Profile) []lineHit {
+	byLine := make(map[int]int)
+	for _, block := range p.Blocks {
+		for line := block.StartLine; line <= block.EndLine; line++ {
+			if hits, exists := byLine[line]; !exists || block.Count > hits {
+				byLine[line] = block.Count
+			}
+		}
+	}
+	ret := make([]lineHit, 0, len(byLine))
+	for number, hits := range byLine {
+		ret = append(ret, lineHit{number: number, hits: hits})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].number < ret[j].number })
+	return ret
+}
+
+// verifyMetric checks what coverage percentages count: statements like go test, blocks, or lines like most line based
+// dashboards.  -metric sets it for every report and gate of a run
+func verifyMetric(metric string) error {
+	switch metric {
+	case "statements", "blocks", "lines":
+		return nil
+	}
+	return fmt.Errorf("unknown -metric %q: expected statements, blocks or lines", metric)
+}
+
+// countStatements counts the coverable units of p, and how many were covered, in metric.  A line is covered if any
+// block on it is
+func countStatements(p *cover.Profile, metric string) (total int, covered int) {
+	switch metric {
+	case "blocks":
+		for _, block := range p.Blocks {
+			if block.NumStmt == 0 {
+				continue
+			}
+			total++
+			if block.Count > 0 {
+				covered++
+			}
+		}
+		return total, covered
+	case "lines":
+		coveredLines := make(map[int]bool)
+		for _, block := range p.Blocks {
+			if block.NumStmt == 0 {
+				continue
+			}
+			for line := block.StartLine; line <= block.EndLine; line++ {
+				coveredLines[line] = coveredLines[line] || block.Count > 0
+			}
+		}
+		for _, isCovered := range coveredLines {
+			total++
+			if isCovered {
+				covered++
+			}
+		}
+		return total, covered
+	}
+	for _, block := range p.Blocks {
+		total += block.NumStmt
+		if block.Count > 0 {
+			covered += block.NumStmt
+		}
+	}
+	return total, covered
+}
+
+// resolvePackageDirs maps the import paths used in profiles to directories on disk
+func resolvePackageDirs(profiles []*cover.Profile) map[string]string {
+	pkgs := make(map[string]struct{})
+	for _, p := range profiles {
+		pkgs[path.Dir(p.FileName)] = struct{}{}
+	}
+	ret := make(map[string]string, len(pkgs))
+	if len(pkgs) == 0 {
+		return ret
+	}
+	args := []string{"list", "-e", "-f", "{{.ImportPath}} {{.Dir}}"}
+	for pkg := range pkgs {
+		args = append(args, pkg)
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command("go", args...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return ret
+	}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) == 2 && parts[1] != "" {
+			ret[parts[0]] = parts[1]
+		}
+	}
+	return ret
+}
+
+// moduleRoot is the directory of the main module's go.mod, or the working directory outside of a module
+func moduleRoot() string {
+	var stdout bytes.Buffer
+	cmd := exec.Command("go", "env", "GOMOD")
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err == nil {
+		if gomod := strings.TrimSpace(stdout.String()); gomod != "" && gomod != os.DevNull {
+			return filepath.Dir(gomod)
+		}
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "."
+	}
+	return cwd
+}
+
+// fileLocator finds the files of profiles on disk, and names them the same way in every report no matter where
+// gocoverdir runs from: relative to the module root, or absolute
+type fileLocator struct {
+	pkgDirs  map[string]string
+	root     string
+	absolute bool
+	// portable names the root like -portable does
+	portable bool
+}
+
+func newFileLocator(profiles []*cover.Profile, absolute bool) *fileLocator {
+	return &fileLocator{pkgDirs: resolvePackageDirs(profiles), root: moduleRoot(), absolute: absolute}
+}
+
+// locator names files for this run's reports.  It is created once, since finding package directories runs go list
+func (m *gocoverdir) locator(profiles []*cover.Profile) *fileLocator {
+	if m.files == nil {
+		m.files = newFileLocator(profiles, m.args.absolutepaths)
+		m.files.portable = m.args.portable
+	}
+	return m.files
+}
+
+// source is the directory file names are relative to
+func (l *fileLocator) source() string {
+	if l.portable {
+		return portablePath(l.root)
+	}
+	return l.root
+}
+
+// path is the file of p on disk, or "" if it cannot be found
+func (l *fileLocator) path(p *cover.Profile) string {
+	dir, exists := l.pkgDirs[path.Dir(p.FileName)]
+	if !exists {
+		return ""
+	}
+	return filepath.Join(dir, path.Base(p.FileName))
+}
+
+// name is how reports refer to the file of p.  Files that cannot be found keep their import path
+func (l *fileLocator) name(p *cover.Profile) string {
+	fullPath := l.path(p)
+	if fullPath == "" {
+		return p.FileName
+	}
+	if l.absolute {
+		return filepath.ToSlash(fullPath)
+	}
+	rel, err := filepath.Rel(l.root, fullPath)
+	if err != nil {
+		return filepath.ToSlash(fullPath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+type packageCoverage struct {
+	name       string
+	statements int
+	covered    int
+}
+
+func (p packageCoverage) percent() float64 {
+	return lineRate(p.covered, p.statements) * 100
+}
+
+// packageCoverages groups profile statements by package, sorted by package name
+func packageCoverages(profiles []*cover.Profile, metric string) []packageCoverage {
+	byName := make(map[string]*packageCoverage)
+	names := make([]string, 0)
+	for _, p := range profiles {
+		name := path.Dir(p.FileName)
+		pkg, exists := byName[name]
+		if !exists {
+			pkg = &packageCoverage{name: name}
+			byName[name] = pkg
+			names = append(names, name)
+		}
+		total, covered := countStatements(p, metric)
+		pkg.statements += total
+		pkg.covered += covered
+	}
+	sort.Strings(names)
+	ret := make([]packageCoverage, 0, len(names))
+	for _, name := range names {
+		ret = append(ret, *byName[name])
+	}
+	return ret
+}
@@ -0,0 +1,66 @@
+package gocoverdir
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestLineHits(t *testing.T) {
+	p := &cover.Profile{
+		FileName: "example.com/a/a.go",
+		Blocks: []cover.ProfileBlock{
+			{StartLine: 1, EndLine: 2, NumStmt: 2, Count: 0},
+			{StartLine: 2, EndLine: 3, NumStmt: 1, Count: 3},
+		},
+	}
+	lines := lineHits(p)
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, saw %d", len(lines))
+	}
+	if lines[0].hits != 0 || lines[1].hits != 3 || lines[2].hits != 3 {
+		t.Errorf("Unexpected line hits %+v", lines)
+	}
+	total, covered := countStatements(p, "statements")
+	if total != 3 || covered != 1 {
+		t.Errorf("Unexpected statement counts %d %d", total, covered)
+	}
+}
+
+func TestCountMetrics(t *testing.T) {
+	p := &cover.Profile{
+		FileName: "example.com/a/a.go",
+		Blocks: []cover.ProfileBlock{
+			{StartLine: 1, EndLine: 2, NumStmt: 4, Count: 0},
+			{StartLine: 2, EndLine: 3, NumStmt: 1, Count: 3},
+			{StartLine: 5, EndLine: 5, NumStmt: 0, Count: 0},
+		},
+	}
+	for metric, want := range map[string][2]int{"statements": {5, 1}, "blocks": {2, 1}, "lines": {3, 2}} {
+		noError(t, verifyMetric(metric))
+		if total, covered := countStatements(p, metric); total != want[0] || covered != want[1] {
+			t.Errorf("Expected %s counts %v, saw %d %d", metric, want, total, covered)
+		}
+	}
+	if err := verifyMetric("branches"); err == nil {
+		t.Errorf("Expected an error for an unknown metric")
+	}
+}
+
+func TestFileLocator(t *testing.T) {
+	root := filepath.FromSlash("/src/mod")
+	loc := &fileLocator{pkgDirs: map[string]string{"example.com/mod/a": filepath.Join(root, "a")}, root: root}
+	found := &cover.Profile{FileName: "example.com/mod/a/a.go"}
+	missing := &cover.Profile{FileName: "example.com/other/b.go"}
+	if name := loc.name(found); name != "a/a.go" {
+		t.Errorf("Unexpected name %s", name)
+	}
+	if name := loc.name(missing); name != "example.com/other/b.go" || loc.path(missing) != "" {
+		t.Errorf("Unexpected name %s", name)
+	}
+	loc.absolute = true
+	if name := loc.name(found); name != filepath.ToSlash(filepath.Join(root, "a", "a.go")) {
+		t.Errorf("Unexpected name %s", name)
+	}
+}
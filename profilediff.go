@@ -0,0 +1,235 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"golang.org/x/tools/cover"
+)
+
+// lineState is a line's coverage in one profile
+type lineState int
+
+const (
+	lineNotCode lineState = iota
+	lineUncovered
+	lineCovered
+)
+
+func (s lineState) class() string {
+	switch s {
+	case lineUncovered:
+		return "uncovered"
+	case lineCovered:
+		return "covered"
+	}
+	return ""
+}
+
+// diffLine is one source line and its coverage before and after
+type diffLine struct {
+	Number int
+	Text   string
+	Old    lineState
+	New    lineState
+}
+
+// Transition is how the line's coverage changed: newly-covered, newly-uncovered, or "" if it did not
+func (l diffLine) Transition() string {
+	switch {
+	case l.New == lineCovered && l.Old != lineCovered:
+		return "newly-covered"
+	case l.Old == lineCovered && l.New != lineCovered:
+		return "newly-uncovered"
+	}
+	return ""
+}
+
+func (l diffLine) OldClass() string {
+	return l.Old.class()
+}
+
+func (l diffLine) NewClass() string {
+	return l.New.class()
+}
+
+// fileDiff is the line coverage transitions of one file
+type fileDiff struct {
+	Name           string
+	NewlyCovered   int
+	NewlyUncovered int
+	Lines          []diffLine
+}
+
+func lineStates(p *cover.Profile) map[int]lineState {
+	ret := make(map[int]lineState)
+	if p == nil {
+		return ret
+	}
+	for _, line := range lineHits(p) {
+		ret[line.number] = lineUncovered
+		if line.hits > 0 {
+			ret[line.number] = lineCovered
+		}
+	}
+	return ret
+}
+
+// readSourceLines reads filename, or returns nil if it cannot be read
+func readSourceLines(filename string) []string {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var ret []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		ret = append(ret, scanner.Text())
+	}
+	return ret
+}
+
+// diffFile lines up the coverage of one file in two profiles, either of which may be nil.  Without source, only the
+// lines with code are listed
+func diffFile(name string, before *cover.Profile, after *cover.Profile, source []string) fileDiff {
+	ret := fileDiff{Name: name}
+	oldStates, newStates := lineStates(before), lineStates(after)
+	last := len(source)
+	for _, states := range []map[int]lineState{oldStates, newStates} {
+		for number := range states {
+			if number > last {
+				last = number
+			}
+		}
+	}
+	for number := 1; number <= last; number++ {
+		line := diffLine{Number: number, Old: oldStates[number], New: newStates[number]}
+		if number <= len(source) {
+			line.Text = source[number-1]
+		} else if line.Old == lineNotCode && line.New == lineNotCode {
+			continue
+		}
+		switch line.Transition() {
+		case "newly-covered":
+			ret.NewlyCovered++
+		case "newly-uncovered":
+			ret.NewlyUncovered++
+		}
+		ret.Lines = append(ret.Lines, line)
+	}
+	return ret
+}
+
+// diffProfiles compares the line coverage of every file in before and after, returning the files whose coverage
+// changed sorted by name
+func diffProfiles(before []*cover.Profile, after []*cover.Profile, loc *fileLocator) []fileDiff {
+	oldByName := make(map[string]*cover.Profile, len(before))
+	newByName := make(map[string]*cover.Profile, len(after))
+	var names []string
+	for _, p := range before {
+		oldByName[p.FileName] = p
+		names = append(names, p.FileName)
+	}
+	for _, p := range after {
+		if _, exists := oldByName[p.FileName]; !exists {
+			names = append(names, p.FileName)
+		}
+		newByName[p.FileName] = p
+	}
+	sort.Strings(names)
+	var ret []fileDiff
+	for _, name := range names {
+		p := newByName[name]
+		if p == nil {
+			p = oldByName[name]
+		}
+		diff := diffFile(loc.name(p), oldByName[name], newByName[name], readSourceLines(loc.path(p)))
+		if diff.NewlyCovered > 0 || diff.NewlyUncovered > 0 {
+			ret = append(ret, diff)
+		}
+	}
+	return ret
+}
+
+func writeProfileDiffText(w io.Writer, diffs []fileDiff) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "FILE\tNEWLY COVERED\tNEWLY UNCOVERED\n")
+	for _, diff := range diffs {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", diff.Name, diff.NewlyCovered, diff.NewlyUncovered)
+	}
+	return tw.Flush()
+}
+
+var profileDiffTemplate = template.Must(template.New("profilediff").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage changes</title>
+<style>
+body { font-family: sans-serif; }
+table.source { border-collapse: collapse; font-family: monospace; width: 100%; margin-bottom: 2em; }
+table.source td { padding: 0 8px; white-space: pre; vertical-align: top; }
+td.num { text-align: right; color: #999; }
+td.covered { color: #2a7d2a; }
+td.uncovered { color: #c0392b; }
+tr.newly-covered td.code { background: #d4f7d4; }
+tr.newly-uncovered td.code { background: #fbd6d6; }
+body.changedonly tr.unchanged { display: none; }
+</style>
+</head>
+<body>
+<h2>Coverage changes</h2>
+<p><label><input type="checkbox" onchange="document.body.classList.toggle('changedonly', this.checked)"> Only changed lines</label></p>
+{{range .}}<h3 id="{{.Name}}">{{.Name}}: +{{.NewlyCovered}} newly covered, -{{.NewlyUncovered}} newly uncovered</h3>
+<table class="source">
+<tr><th></th><th>Old</th><th></th><th>New</th></tr>
+{{range .Lines}}<tr class="{{or .Transition "unchanged"}}"><td class="num">{{.Number}}</td><td class="code {{.OldClass}}">{{.Text}}</td><td class="num">{{.Number}}</td><td class="code {{.NewClass}}">{{.Text}}</td></tr>
+{{end}}</table>
+{{else}}<p>No line changed coverage.</p>
+{{end}}</body>
+</html>
+`))
+
+func writeProfileDiffHTML(w io.Writer, diffs []fileDiff) error {
+	return profileDiffTemplate.Execute(w, diffs)
+}
+
+// runProfileDiff compares two cover profiles line by line, as a table of files or side by side HTML
+func runProfileDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	htmlOut := fs.String("html", "", "If set, write side by side source colored by coverage to this file, instead of a table of files to stdout")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 2 {
+		return fmt.Errorf("usage: gocoverdir diff [-html out.html] old.out new.out")
+	}
+	before, err := readProfiles(files[0])
+	if err != nil {
+		return err
+	}
+	after, err := readProfiles(files[1])
+	if err != nil {
+		return err
+	}
+	diffs := diffProfiles(before, after, newFileLocator(append(append([]*cover.Profile(nil), before...), after...), false))
+	if *htmlOut == "" {
+		if len(diffs) == 0 {
+			_, err := io.WriteString(os.Stdout, "No line changed coverage\n")
+			return err
+		}
+		return writeProfileDiffText(os.Stdout, diffs)
+	}
+	return writeFileWith(*htmlOut, func(w io.Writer) error {
+		return writeProfileDiffHTML(w, diffs)
+	})
+}
@@ -0,0 +1,51 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestDiffFile(t *testing.T) {
+	before := &cover.Profile{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{
+		{StartLine: 2, EndLine: 2, NumStmt: 1, Count: 1},
+		{StartLine: 3, EndLine: 3, NumStmt: 1},
+	}}
+	after := &cover.Profile{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{
+		{StartLine: 2, EndLine: 2, NumStmt: 1},
+		{StartLine: 3, EndLine: 3, NumStmt: 1, Count: 1},
+		{StartLine: 4, EndLine: 4, NumStmt: 1, Count: 1},
+	}}
+	source := []string{"package a", "x()", "y()", "z()", "// end"}
+	diff := diffFile("a/a.go", before, after, source)
+	if diff.NewlyCovered != 2 || diff.NewlyUncovered != 1 || len(diff.Lines) != 5 {
+		t.Fatalf("Unexpected diff %+v", diff)
+	}
+	transitions := []string{"", "newly-uncovered", "newly-covered", "newly-covered", ""}
+	for i, line := range diff.Lines {
+		if line.Transition() != transitions[i] {
+			t.Errorf("Line %d: expected %q, saw %q", line.Number, transitions[i], line.Transition())
+		}
+	}
+	if withoutSource := diffFile("a/a.go", before, after, nil); len(withoutSource.Lines) != 3 {
+		t.Errorf("Expected only lines with code without source, saw %+v", withoutSource.Lines)
+	}
+}
+
+func TestWriteProfileDiffHTML(t *testing.T) {
+	diffs := []fileDiff{{Name: "a/a.go", NewlyCovered: 1, Lines: []diffLine{{Number: 1, Text: "if a < b {", Old: lineUncovered, New: lineCovered}}}}
+	buf := bytes.Buffer{}
+	noError(t, writeProfileDiffHTML(&buf, diffs))
+	for _, expected := range []string{`<tr class="newly-covered">`, `<td class="code uncovered">if a &lt; b {</td>`, `<td class="code covered">`} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in %s", expected, buf.String())
+		}
+	}
+	buf.Reset()
+	noError(t, writeProfileDiffText(&buf, diffs))
+	if !strings.Contains(buf.String(), "a/a.go") {
+		t.Errorf("Unexpected text %s", buf.String())
+	}
+}
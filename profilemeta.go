@@ -0,0 +1,117 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// profileMeta is what produced a profile, kept in a sidecar file next to it so merges can tell when profiles of
+// different commits or toolchains would be combined into a nonsensical report
+type profileMeta struct {
+	GoVersion string `json:"go_version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	RunID     string `json:"run_id,omitempty"`
+	// ToolVersion is the gocoverdir that wrote the profile
+	ToolVersion string `json:"tool_version,omitempty"`
+}
+
+// profileMetaFile is the sidecar of profile, which is the same once -compress gzips the profile
+func profileMetaFile(profile string) string {
+	return strings.TrimSuffix(profile, ".gz") + ".meta.json"
+}
+
+// readProfileMeta reads the sidecar of profile, returning nil if it has none
+func readProfileMeta(profile string) (*profileMeta, error) {
+	f, err := os.Open(profileMetaFile(profile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var ret profileMeta
+	if err := json.NewDecoder(f).Decode(&ret); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %s", profileMetaFile(profile), err)
+	}
+	return &ret, nil
+}
+
+func writeProfileMeta(profile string, meta profileMeta) error {
+	return writeFileWith(profileMetaFile(profile), func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(meta)
+	})
+}
+
+// goEnvValue finds key in the output of 'go env', which quotes values differently on each OS
+func goEnvValue(goEnv []byte, key string) string {
+	for _, line := range strings.Split(string(goEnv), "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "set ")
+		if strings.HasPrefix(line, key+"=") {
+			return strings.Trim(strings.TrimPrefix(line, key+"="), `'"`)
+		}
+	}
+	return ""
+}
+
+// metaMismatches describes how the sidecars of profiles differ from reference, the metadata of referenceName.
+// Profiles without a sidecar, and fields either side does not know, are not compared
+func metaMismatches(reference profileMeta, referenceName string, profiles []string) ([]string, error) {
+	var ret []string
+	for _, profile := range profiles {
+		meta, err := readProfileMeta(profile)
+		if err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			continue
+		}
+		if meta.Commit != "" && reference.Commit != "" && meta.Commit != reference.Commit {
+			ret = append(ret, fmt.Sprintf("%s is from commit %s, but %s is from commit %s", profile, meta.Commit, referenceName, reference.Commit))
+		}
+		if meta.GoVersion != "" && reference.GoVersion != "" && meta.GoVersion != reference.GoVersion {
+			ret = append(ret, fmt.Sprintf("%s was built with %s, but %s was built with %s", profile, meta.GoVersion, referenceName, reference.GoVersion))
+		}
+	}
+	return ret, nil
+}
+
+// firstProfileMeta is the sidecar of the first of profiles that has one
+func firstProfileMeta(profiles []string) (*profileMeta, string, error) {
+	for _, profile := range profiles {
+		meta, err := readProfileMeta(profile)
+		if err != nil || meta != nil {
+			return meta, profile, err
+		}
+	}
+	return nil, "", nil
+}
+
+// profileMeta is the metadata of this run's profile
+func (m *gocoverdir) profileMeta() profileMeta {
+	ret := profileMeta{GoVersion: goEnvValue(m.goEnvOutput(), "GOVERSION"), RunID: m.args.runid, ToolVersion: toolVersion()}
+	if m.repo != nil {
+		ret.Commit = m.repo.commit()
+	}
+	return ret
+}
+
+// checkInputMeta warns, or fails with -strictinputs, if -mergeinputs profiles came from another commit or toolchain
+func (m *gocoverdir) checkInputMeta(inputs []string) error {
+	mismatches, err := metaMismatches(m.profileMeta(), "this run", inputs)
+	if err != nil {
+		return err
+	}
+	if len(mismatches) > 0 && m.args.strictinputs {
+		return fmt.Errorf("inputs do not match this run:\n%s", strings.Join(mismatches, "\n"))
+	}
+	for _, mismatch := range mismatches {
+		m.log.Printf("Warning: %s", mismatch)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGoEnvValue(t *testing.T) {
+	unix := []byte("GOARCH='amd64'\nGOVERSION='go1.22.1'\n")
+	windows := []byte("set GOARCH=amd64\r\nset GOVERSION=go1.21.0\r\n")
+	if v := goEnvValue(unix, "GOVERSION"); v != "go1.22.1" {
+		t.Errorf("Unexpected unix GOVERSION %q", v)
+	}
+	if v := goEnvValue(windows, "GOVERSION"); v != "go1.21.0" {
+		t.Errorf("Unexpected windows GOVERSION %q", v)
+	}
+	if v := goEnvValue(unix, "GOOS"); v != "" {
+		t.Errorf("Unexpected GOOS %q", v)
+	}
+}
+
+func TestMetaMismatches(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestMetaMismatches")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	unit, e2e, bare := filepath.Join(dir, "unit.out"), filepath.Join(dir, "e2e.out.gz"), filepath.Join(dir, "bare.out")
+	noError(t, writeProfileMeta(unit, profileMeta{GoVersion: "go1.22.1", Commit: "abc"}))
+	noError(t, writeProfileMeta(e2e, profileMeta{GoVersion: "go1.21.0", Commit: "def"}))
+	if _, err := os.Stat(filepath.Join(dir, "e2e.out.meta.json")); err != nil {
+		t.Errorf("The sidecar of a gzipped profile should be named after the profile: %s", err)
+	}
+
+	meta, source, err := firstProfileMeta([]string{bare, unit, e2e})
+	noError(t, err)
+	if source != unit || !reflect.DeepEqual(*meta, profileMeta{GoVersion: "go1.22.1", Commit: "abc"}) {
+		t.Errorf("Unexpected first meta %v of %s", meta, source)
+	}
+	mismatches, err := metaMismatches(*meta, source, []string{bare, unit, e2e})
+	noError(t, err)
+	if len(mismatches) != 2 || !strings.Contains(mismatches[0], "from commit def") || !strings.Contains(mismatches[1], "built with go1.21.0") {
+		t.Errorf("Unexpected mismatches %v", mismatches)
+	}
+	mismatches, err = metaMismatches(profileMeta{Commit: "def"}, "this run", []string{e2e})
+	noError(t, err)
+	if len(mismatches) != 0 {
+		t.Errorf("Fields the reference does not know should not be compared: %v", mismatches)
+	}
+}
@@ -0,0 +1,276 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+// testAttribution is what one test covers when it runs alone, and how long it takes
+type testAttribution struct {
+	dir     string
+	name    string
+	seconds float64
+	// covered statements, by block
+	covered map[blockKey]int
+}
+
+// coveredBlocks counts the statements of every covered block in profiles
+func coveredBlocks(profiles []*cover.Profile) map[blockKey]int {
+	ret := make(map[blockKey]int)
+	for _, p := range profiles {
+		for _, b := range p.Blocks {
+			if b.Count > 0 {
+				ret[blockKey{file: p.FileName, startLine: b.StartLine, startCol: b.StartCol, endLine: b.EndLine, endCol: b.EndCol}] = b.NumStmt
+			}
+		}
+	}
+	return ret
+}
+
+// pruneAdvice splits tests into the ones worth keeping and the ones that can go, and what dropping them loses
+type pruneAdvice struct {
+	keep    []testAttribution
+	drop    []testAttribution
+	seconds float64
+	// covered is the statements the kept tests cover, out of current that all of them cover
+	covered int
+	current int
+	lost    []blockKey
+	// reached is false if the time budget ran out before keeping enough coverage
+	reached bool
+}
+
+func sumStatements(blocks map[blockKey]int) int {
+	ret := 0
+	for _, stmts := range blocks {
+		ret += stmts
+	}
+	return ret
+}
+
+// advisePrune greedily keeps the test that adds the most uncovered statements per second, until the kept tests cover
+// minPercent of what all tests cover.  Tests that do not fit in the rest of maxSeconds are passed over
+func advisePrune(tests []testAttribution, minPercent float64, maxSeconds float64) pruneAdvice {
+	all := make(map[blockKey]int)
+	for _, t := range tests {
+		for key, stmts := range t.covered {
+			all[key] = stmts
+		}
+	}
+	ret := pruneAdvice{current: sumStatements(all)}
+	target := float64(ret.current) * minPercent / 100
+	covered := make(map[blockKey]int)
+	kept := make([]bool, len(tests))
+	for float64(ret.covered) < target {
+		best := -1
+		bestRate := 0.0
+		for i, t := range tests {
+			if kept[i] || ret.seconds+t.seconds > maxSeconds {
+				continue
+			}
+			gained := 0
+			for key, stmts := range t.covered {
+				if _, exists := covered[key]; !exists {
+					gained += stmts
+				}
+			}
+			// Instant tests are all equally cheap
+			if rate := float64(gained) / (t.seconds + .001); gained > 0 && rate > bestRate {
+				best, bestRate = i, rate
+			}
+		}
+		if best < 0 {
+			break
+		}
+		kept[best] = true
+		ret.seconds += tests[best].seconds
+		for key, stmts := range tests[best].covered {
+			covered[key] = stmts
+		}
+		ret.covered = sumStatements(covered)
+	}
+	ret.reached = float64(ret.covered) >= target
+	for i, t := range tests {
+		if kept[i] {
+			ret.keep = append(ret.keep, t)
+		} else {
+			ret.drop = append(ret.drop, t)
+		}
+	}
+	for key := range all {
+		if _, exists := covered[key]; !exists {
+			ret.lost = append(ret.lost, key)
+		}
+	}
+	sort.Slice(ret.lost, func(i, j int) bool {
+		a, b := ret.lost[i], ret.lost[j]
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		if a.startLine != b.startLine {
+			return a.startLine < b.startLine
+		}
+		return a.startCol < b.startCol
+	})
+	return ret
+}
+
+// runPattern is a go test -run pattern matching exactly names
+func runPattern(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, regexp.QuoteMeta(name))
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
+
+func writePruneAdvice(w io.Writer, advice pruneAdvice, minPercent float64, maxTime time.Duration) error {
+	percent := 100.0
+	if advice.current > 0 {
+		percent = 100 * float64(advice.covered) / float64(advice.current)
+	}
+	if !advice.reached {
+		fmt.Fprintf(w, "Cannot keep %.1f%% of current coverage within -maxtime %s.  The best found:\n", minPercent, maxTime)
+	}
+	fmt.Fprintf(w, "Keep %d of %d tests, taking %s and covering %d of %d statements (%.1f%% of current coverage)\n", len(advice.keep), len(advice.keep)+len(advice.drop), time.Duration(advice.seconds*float64(time.Second)).Round(time.Millisecond), advice.covered, advice.current, percent)
+	var dirs []string
+	byDir := make(map[string][]string)
+	for _, t := range advice.keep {
+		if _, exists := byDir[t.dir]; !exists {
+			dirs = append(dirs, t.dir)
+		}
+		byDir[t.dir] = append(byDir[t.dir], t.name)
+	}
+	for _, dir := range dirs {
+		fmt.Fprintf(w, "  go test -run '%s' ./%s\n", runPattern(byDir[dir]), dir)
+	}
+	if len(advice.drop) > 0 {
+		fmt.Fprintf(w, "\nDrop:\n")
+		for _, t := range advice.drop {
+			fmt.Fprintf(w, "  ./%s %s (%.2fs)\n", t.dir, t.name, t.seconds)
+		}
+	}
+	if len(advice.lost) > 0 {
+		fmt.Fprintf(w, "\nStatements lost:\n")
+		for _, key := range advice.lost {
+			fmt.Fprintf(w, "  %s (%d stmts)\n", key, advice.lostStatements(key))
+		}
+	}
+	return nil
+}
+
+// lostStatements is the statements in a lost block, which a dropped test covered
+func (p pruneAdvice) lostStatements(key blockKey) int {
+	for _, t := range p.drop {
+		if stmts, exists := t.covered[key]; exists {
+			return stmts
+		}
+	}
+	return 0
+}
+
+// parseTestList reads the output of 'go test -list', keeping the names that go test runs
+func parseTestList(r io.Reader) []string {
+	var ret []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(name, "Test") || strings.HasPrefix(name, "Example") || strings.HasPrefix(name, "Fuzz") {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+// testElapsed finds how long name took in 'go test -json' output
+func testElapsed(r io.Reader, name string) (float64, error) {
+	dec := json.NewDecoder(r)
+	for {
+		var event testEvent
+		if err := dec.Decode(&event); err == io.EOF {
+			return 0, fmt.Errorf("no result for %s", name)
+		} else if err != nil {
+			return 0, err
+		}
+		if event.Test == name && (event.Action == "pass" || event.Action == "fail" || event.Action == "skip") {
+			return event.Elapsed, nil
+		}
+	}
+}
+
+// attributeTests runs every test of dir alone, recording what it covers and how long it takes
+func attributeTests(dir string, storeDir string) ([]testAttribution, error) {
+	var stdout bytes.Buffer
+	cmd := exec.Command("go", "test", "-list", ".", "./"+dir)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("cannot list tests of %s: %s", dir, err)
+	}
+	var ret []testAttribution
+	for _, name := range parseTestList(&stdout) {
+		profile := filepath.Join(storeDir, "test.out")
+		stdout.Reset()
+		cmd := exec.Command("go", "test", "-json", "-count=1", "-run", runPattern([]string{name}), "-coverprofile", profile, "./"+dir)
+		cmd.Stdout = &stdout
+		cmd.Stderr = os.Stderr
+		// A failing test still covers what it ran
+		runErr := cmd.Run()
+		seconds, err := testElapsed(&stdout, name)
+		if err != nil {
+			return nil, fmt.Errorf("%s in %s: %s (go test: %v)", name, dir, err, runErr)
+		}
+		profiles, err := readProfiles(profile)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, testAttribution{dir: dir, name: name, seconds: seconds, covered: coveredBlocks(profiles)})
+	}
+	return ret, nil
+}
+
+// runPruneAdvise proposes a subset of tests that keeps most of the current coverage within a time budget
+func runPruneAdvise(args []string) error {
+	fs := flag.NewFlagSet("prune-advise", flag.ContinueOnError)
+	maxTime := fs.Duration("maxtime", 5*time.Minute, "Time budget of the tests to keep")
+	minPercent := fs.Float64("coverage", 95, "Percent of the statements currently covered that the kept tests must still cover")
+	patterns, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+	dirs, err := listPackageDirs(patterns)
+	if err != nil {
+		return err
+	}
+	storeDir, err := ioutil.TempDir("", "gocoverdir-prune")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(storeDir)
+	var tests []testAttribution
+	for _, dir := range dirs {
+		attributed, err := attributeTests(dir, storeDir)
+		if err != nil {
+			return err
+		}
+		tests = append(tests, attributed...)
+	}
+	return writePruneAdvice(os.Stdout, advisePrune(tests, *minPercent, maxTime.Seconds()), *minPercent, *maxTime)
+}
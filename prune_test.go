@@ -0,0 +1,61 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdvisePrune(t *testing.T) {
+	a := blockKey{file: "example.com/a/a.go", startLine: 1, endLine: 2}
+	b := blockKey{file: "example.com/a/a.go", startLine: 3, endLine: 4}
+	c := blockKey{file: "example.com/a/a.go", startLine: 5, endLine: 6}
+	tests := []testAttribution{
+		{dir: "a", name: "TestSlow", seconds: 10, covered: map[blockKey]int{a: 5, b: 4, c: 1}},
+		{dir: "a", name: "TestFast", seconds: 1, covered: map[blockKey]int{a: 5, b: 4}},
+		{dir: "a", name: "TestDuplicate", seconds: 1, covered: map[blockKey]int{a: 5}},
+	}
+	advice := advisePrune(tests, 90, 60)
+	if !advice.reached || len(advice.keep) != 1 || advice.keep[0].name != "TestFast" {
+		t.Errorf("Expected to keep only TestFast, saw %+v", advice.keep)
+	}
+	if advice.covered != 9 || advice.current != 10 || len(advice.lost) != 1 || advice.lost[0] != c {
+		t.Errorf("Unexpected advice %+v", advice)
+	}
+	if all := advisePrune(tests, 100, 60); len(all.keep) != 2 || len(all.lost) != 0 {
+		t.Errorf("Expected keeping everything to need TestSlow too, saw %+v", all.keep)
+	}
+	if tight := advisePrune(tests, 100, 5); tight.reached {
+		t.Errorf("Expected a 5s budget to be too small for full coverage")
+	}
+	buf := bytes.Buffer{}
+	noError(t, writePruneAdvice(&buf, advice, 90, time.Minute))
+	for _, expected := range []string{"Keep 1 of 3 tests", "go test -run '^(TestFast)$' ./a", "example.com/a/a.go:5.0,6.0 (1 stmts)"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in %s", expected, buf.String())
+		}
+	}
+}
+
+func TestParseTestList(t *testing.T) {
+	names := parseTestList(strings.NewReader("TestA\nBenchmarkB\nExampleC\nFuzzD\nok  \texample.com/a\t0.01s\n"))
+	if strings.Join(names, ",") != "TestA,ExampleC,FuzzD" {
+		t.Errorf("Unexpected names %v", names)
+	}
+}
+
+func TestTestElapsed(t *testing.T) {
+	output := `{"Action":"run","Test":"TestA"}
+{"Action":"pass","Test":"TestA","Elapsed":1.5}
+{"Action":"pass","Elapsed":1.6}
+`
+	seconds, err := testElapsed(strings.NewReader(output), "TestA")
+	noError(t, err)
+	if seconds != 1.5 {
+		t.Errorf("Expected 1.5, saw %f", seconds)
+	}
+	if _, err := testElapsed(strings.NewReader(output), "TestB"); err == nil {
+		t.Errorf("Expected an error for a test that did not run")
+	}
+}
@@ -0,0 +1,117 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// parseQuarantine reads a YAML or JSON list of test names.  Subtests are named like go test reports them, TestX/case
+func parseQuarantine(contents []byte) ([]string, error) {
+	asJSON, err := yamlToJSON(contents)
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	if err := json.Unmarshal(asJSON, &ret); err != nil {
+		return nil, fmt.Errorf("expected a list of test names: %s", err)
+	}
+	return ret, nil
+}
+
+// loadQuarantine reads the -flaky file.  The default file is optional
+func (m *gocoverdir) loadQuarantine() error {
+	if m.args.flaky == "" {
+		return nil
+	}
+	contents, err := ioutil.ReadFile(m.args.flaky)
+	if os.IsNotExist(err) && !m.isFlagSet("flaky") {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if m.quarantine, err = parseQuarantine(contents); err != nil {
+		return fmt.Errorf("cannot parse %s: %s", m.args.flaky, err)
+	}
+	m.log.Printf("Quarantined tests: %s", strings.Join(m.quarantine, " "))
+	return nil
+}
+
+// exactTestsPattern is a -run or -skip pattern matching exactly the given tests and subtests.  go test splits patterns
+// into alternatives at |, then into a pattern per subtest level at /, so each level is quoted and anchored on its own.
+// Shallower tests go first, because the first alternative to match decides whether a subtest matches only in part
+func exactTestsPattern(tests []string) string {
+	sorted := append([]string(nil), tests...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return strings.Count(sorted[i], "/") < strings.Count(sorted[j], "/")
+	})
+	alternatives := make([]string, 0, len(sorted))
+	for _, test := range sorted {
+		levels := strings.Split(test, "/")
+		for i, level := range levels {
+			levels[i] = "^" + regexp.QuoteMeta(level) + "$"
+		}
+		alternatives = append(alternatives, strings.Join(levels, "/"))
+	}
+	return strings.Join(alternatives, "|")
+}
+
+// quarantinedIn is the quarantined tests whose top level test is declared in dirpath, so packages without any skip
+// the quarantined pass.  If the test files cannot be parsed, go test will say why, so every test is kept
+func (m *gocoverdir) quarantinedIn(dirpath string) []string {
+	if len(m.quarantine) == 0 {
+		return nil
+	}
+	declared, err := testFuncs(dirpath)
+	if err != nil {
+		return m.quarantine
+	}
+	var ret []string
+	for _, test := range m.quarantine {
+		if declared[strings.SplitN(test, "/", 2)[0]] {
+			ret = append(ret, test)
+		}
+	}
+	return ret
+}
+
+// testFuncs is the names of the functions, not methods, declared in the test files of dirpath
+func testFuncs(dirpath string) (map[string]bool, error) {
+	files, err := filepath.Glob(filepath.Join(dirpath, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+	ret := make(map[string]bool)
+	fset := token.NewFileSet()
+	for _, file := range files {
+		f, err := parser.ParseFile(fset, file, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range f.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil {
+				ret[fn.Name.Name] = true
+			}
+		}
+	}
+	return ret, nil
+}
+
+// quarantineResult marks a result from the quarantined pass, whose failure is reported but does not fail the build
+func (m *gocoverdir) quarantineResult(result packageResult) packageResult {
+	result.command = "go test (quarantined)"
+	result.quarantined = true
+	if result.err != nil {
+		m.log.Printf("Quarantined tests failed in %s: %s", result.dir, result.err)
+	}
+	return result
+}
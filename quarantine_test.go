@@ -0,0 +1,56 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseQuarantine(t *testing.T) {
+	tests, err := parseQuarantine([]byte("# flaky tests\n- TestNetwork\n- \"TestTimer\"  # ticket 12\n- TestX/case#01\n\n"))
+	noError(t, err)
+	if strings.Join(tests, ",") != "TestNetwork,TestTimer,TestX/case#01" {
+		t.Errorf("Unexpected tests %v", tests)
+	}
+	tests, err = parseQuarantine([]byte(`["TestA"]`))
+	noError(t, err)
+	if strings.Join(tests, ",") != "TestA" {
+		t.Errorf("Unexpected tests %v", tests)
+	}
+	if _, err := parseQuarantine([]byte("tests: TestA\n")); err == nil {
+		t.Errorf("Expected an error for a non list")
+	}
+}
+
+func TestExactTestsPattern(t *testing.T) {
+	pattern := exactTestsPattern([]string{"TestX/case#01", "TestA", "TestB.c/a/b"})
+	if pattern != `^TestA$|^TestX$/^case#01$|^TestB\.c$/^a$/^b$` {
+		t.Errorf("Unexpected pattern %s", pattern)
+	}
+}
+
+func TestQuarantineArgs(t *testing.T) {
+	m := &gocoverdir{quarantine: []string{"TestA", "TestB.c"}}
+	m.args.cpu = -1
+	_, args := m.testArgs("a", "/tmp", "p.cover", nil)
+	if !strings.Contains(strings.Join(args, " "), `-skip ^TestA$|^TestB\.c$ ./a`) {
+		t.Errorf("Expected quarantined tests to be skipped: %v", args)
+	}
+	_, args = m.testArgs("a", "/tmp", "p.cover", m.quarantine)
+	if !strings.Contains(strings.Join(args, " "), `-run ^TestA$|^TestB\.c$ ./a`) {
+		t.Errorf("Expected only quarantined tests to run: %v", args)
+	}
+}
+
+func TestQuarantinedIn(t *testing.T) {
+	dir := t.TempDir()
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a_test.go"), []byte("package a\n\nfunc TestA(t *testing.T) {}\n\nfunc (s suite) TestB() {}\n"), 0644))
+	m := &gocoverdir{quarantine: []string{"TestA/case#01", "TestB", "TestC"}}
+	if quarantined := m.quarantinedIn(dir); strings.Join(quarantined, ",") != "TestA/case#01" {
+		t.Errorf("Expected only TestA to be quarantined in the package, saw %v", quarantined)
+	}
+	if quarantined := m.quarantinedIn(t.TempDir()); len(quarantined) != 0 {
+		t.Errorf("Expected no quarantined tests without test files, saw %v", quarantined)
+	}
+}
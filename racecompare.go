@@ -0,0 +1,106 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// raceCompareDir keeps the profiles of the -racecompare pass apart from the ones merged into -coverprofile
+func (m *gocoverdir) raceCompareDir() string {
+	return filepath.Join(m.storeDir, "racecompare")
+}
+
+// raceCompareArgs tests dirpath like the main run, but with -race flipped
+func (m *gocoverdir) raceCompareArgs(dirpath string, profileName string) []string {
+	args := []string{"test", "-json", "-cover", "-coverprofile", profileName, "-outputdir", m.raceCompareDir()}
+	if m.args.covermode != "" {
+		args = append(args, "-covermode", m.args.covermode)
+	}
+	if m.args.timeout.Nanoseconds() > 0 {
+		args = append(args, "-timeout", m.args.timeout.String())
+	}
+	if m.args.tags != "" {
+		args = append(args, "-tags", m.args.tags)
+	}
+	if !m.args.race {
+		args = append(args, "-race")
+	}
+	if len(m.quarantine) > 0 {
+		args = append(args, "-skip", exactTestsPattern(m.quarantine))
+	}
+	return append(args, m.depStrategy(dirpath).packageArg(dirpath))
+}
+
+// raceComparePass tests dirs again with -race flipped.  Failures are logged: the comparison is advice, and the main
+// run already reported on the tests
+func (m *gocoverdir) raceComparePass(dirs []string) error {
+	if err := os.MkdirAll(m.raceCompareDir(), 0755); err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		strategy := m.depStrategy(dir)
+		executable, args := strategy.command()
+		cmd, err := m.testCommand(strategy.root, executable, append(args, m.raceCompareArgs(dir, storeProfileName(dir, "", 0))...)...)
+		if err != nil {
+			return err
+		}
+		events := newTestEventWriter(m.testOutputStdout)
+		cmd.Stdout = events
+		cmd.Stderr = m.testOutputStderr
+		m.log.Printf("Executing %s", strings.Join(cmd.Args, " "))
+		err = cmd.Run()
+		if closeErr := events.Close(); closeErr != nil {
+			m.log.Printf("Unable to write test output of %s: %s", dir, closeErr)
+		}
+		if err != nil {
+			m.log.Printf("Unable to compare %s with -race flipped: %s", dir, err)
+		}
+	}
+	return nil
+}
+
+// raceOnlyWarnings lists the blocks covered only with or only without -race.  race says if main was run with -race
+func raceOnlyWarnings(main []*cover.Profile, flipped []*cover.Profile, race bool) []string {
+	names := []string{"without -race", "with -race"}
+	if race {
+		names[0], names[1] = names[1], names[0]
+	}
+	c := compareVariants([]coverageVariant{{name: names[0], profiles: main}, {name: names[1], profiles: flipped}})
+	var ret []string
+	for i, name := range c.names {
+		for _, block := range c.exclusiveBlocks(i) {
+			ret = append(ret, fmt.Sprintf("%s (%d stmts) is only covered %s", block.key, block.statements, name))
+		}
+	}
+	return ret
+}
+
+// warnRaceOnly logs the statements only covered in one of the -race configurations.  They are usually build tag or
+// runtime conditional code that deserves its own test
+func (m *gocoverdir) warnRaceOnly(profiles []*cover.Profile) error {
+	files, err := ioutil.ReadDir(m.raceCompareDir())
+	if err != nil {
+		return err
+	}
+	flipped := newProfileSet()
+	for _, file := range files {
+		p, err := cover.ParseProfiles(filepath.Join(m.raceCompareDir(), file.Name()))
+		if err != nil {
+			return err
+		}
+		if err := flipped.add(p); err != nil {
+			return err
+		}
+	}
+	warnings := raceOnlyWarnings(profiles, flipped.profiles(), m.args.race)
+	for _, warning := range warnings {
+		m.log.Printf("Warning: %s", warning)
+	}
+	m.log.Printf("%d blocks are only covered in one -race configuration", len(warnings))
+	return nil
+}
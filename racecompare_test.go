@@ -0,0 +1,30 @@
+package gocoverdir
+
+import (
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestRaceOnlyWarnings(t *testing.T) {
+	withRace := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{
+			{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 1, NumStmt: 1, Count: 1},
+			{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 1, NumStmt: 2, Count: 1},
+		}},
+	}
+	withoutRace := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{
+			{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 1, NumStmt: 1, Count: 1},
+			{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 1, NumStmt: 2},
+		}},
+	}
+	expected := []string{"example.com/a/a.go:3.1,4.1 (2 stmts) is only covered with -race"}
+	if warnings := raceOnlyWarnings(withRace, withoutRace, true); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Unexpected warnings %v", warnings)
+	}
+	if warnings := raceOnlyWarnings(withoutRace, withRace, false); !reflect.DeepEqual(warnings, expected) {
+		t.Errorf("Unexpected warnings %v", warnings)
+	}
+}
@@ -0,0 +1,61 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"strings"
+)
+
+const raceSeparator = "=================="
+
+// raceCollector pulls data race reports out of test output, one line at a time
+type raceCollector struct {
+	current []string
+	inRace  bool
+	races   []string
+}
+
+func (r *raceCollector) line(line string) {
+	trimmed := strings.TrimRight(line, "\n")
+	if !r.inRace {
+		if strings.TrimSpace(trimmed) == "WARNING: DATA RACE" {
+			r.inRace = true
+			r.current = []string{trimmed}
+		}
+		return
+	}
+	if strings.TrimSpace(trimmed) == raceSeparator {
+		r.races = append(r.races, strings.Join(r.current, "\n"))
+		r.inRace = false
+		r.current = nil
+		return
+	}
+	r.current = append(r.current, trimmed)
+}
+
+// raceLocation is the first function named in a race report, which is usually one side of the race
+func raceLocation(race string) string {
+	lines := strings.Split(race, "\n")
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if (strings.HasPrefix(line, "Write at") || strings.HasPrefix(line, "Read at")) && i+1 < len(lines) {
+			return strings.TrimSpace(lines[i+1])
+		}
+	}
+	return "unknown location"
+}
+
+// raceSummary lists every race found, by package, or "" if there were none
+func raceSummary(results []packageResult) string {
+	var lines []string
+	total := 0
+	for _, result := range results {
+		for _, race := range result.races {
+			lines = append(lines, fmt.Sprintf("  %s: %s", result.dir, raceLocation(race)))
+			total++
+		}
+	}
+	if total == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d data races found:\n%s", total, strings.Join(lines, "\n"))
+}
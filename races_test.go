@@ -0,0 +1,37 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const exampleRace = `==================
+WARNING: DATA RACE
+Write at 0x00c00001c0f8 by goroutine 8:
+  example.com/a.(*Counter).Inc()
+      /src/a/a.go:9 +0x44
+
+Previous read at 0x00c00001c0f8 by goroutine 7:
+  example.com/a.(*Counter).Get()
+      /src/a/a.go:13 +0x3a
+==================
+`
+
+func TestRaceCollector(t *testing.T) {
+	var out bytes.Buffer
+	w := newTestEventWriter(&out)
+	_, err := w.Write([]byte("some output\n" + exampleRace + "--- FAIL: TestInc\n"))
+	noError(t, err)
+	noError(t, w.Close())
+	if len(w.races.races) != 1 || !strings.HasPrefix(w.races.races[0], "WARNING: DATA RACE\nWrite at") {
+		t.Fatalf("Unexpected races %q", w.races.races)
+	}
+	summary := raceSummary([]packageResult{{dir: "a", races: w.races.races}, {dir: "b"}})
+	if summary != "1 data races found:\n  a: example.com/a.(*Counter).Inc()" {
+		t.Errorf("Unexpected summary %q", summary)
+	}
+	if raceSummary([]packageResult{{dir: "b"}}) != "" {
+		t.Errorf("Expected no summary without races")
+	}
+}
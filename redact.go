@@ -0,0 +1,75 @@
+package gocoverdir
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// hashPath replaces every element of a slash separated path with a short hash, so paths can be compared and grouped
+// into a tree without revealing names
+func hashPath(p string) string {
+	elements := strings.Split(p, "/")
+	for i, element := range elements {
+		if element == "" || element == "." || element == ".." {
+			continue
+		}
+		sum := sha256.Sum256([]byte(element))
+		elements[i] = hex.EncodeToString(sum[:4])
+	}
+	return strings.Join(elements, "/")
+}
+
+// redactReport copies r with only structural coverage numbers: no test names or output, no findings, no reasons for
+// skipping packages, which name slowlist patterns and paths, and no CI details beyond the CI's name.  If hash is true,
+// paths are hashed too
+func redactReport(r *report, hash bool) *report {
+	name := func(p string) string {
+		if hash {
+			return hashPath(p)
+		}
+		return p
+	}
+	ret := &report{
+		RunID:       r.RunID,
+		ToolVersion: r.ToolVersion,
+		Metric:      r.Metric,
+		Timestamp:   r.Timestamp,
+		Coverage:    r.Coverage,
+		Statements:  r.Statements,
+		Covered:     r.Covered,
+		Packages:    make([]reportPackage, 0, len(r.Packages)),
+		Surfaces:    r.Surfaces,
+		Files:       make([]reportFile, 0, len(r.Files)),
+	}
+	if r.CI != nil {
+		ret.CI = &ciEnvironment{Name: r.CI.Name}
+	}
+	for _, pkg := range r.Packages {
+		pkg.Path = name(pkg.Path)
+		ret.Packages = append(ret.Packages, pkg)
+	}
+	for _, source := range r.Sources {
+		source.Path = name(source.Path)
+		ret.Sources = append(ret.Sources, source)
+	}
+	for _, file := range r.Files {
+		file.Path = name(file.Path)
+		file.Package = name(file.Package)
+		ret.Files = append(ret.Files, file)
+	}
+	for _, test := range r.Tests {
+		ret.Tests = append(ret.Tests, reportTest{
+			Dir:         name(test.Dir),
+			Command:     test.Command,
+			Seconds:     test.Seconds,
+			Passed:      test.Passed,
+			Skipped:     test.Skipped,
+			Quarantined: test.Quarantined,
+		})
+	}
+	for _, skip := range r.Skipped {
+		ret.Skipped = append(ret.Skipped, reportSkip{Dir: name(skip.Dir)})
+	}
+	return ret
+}
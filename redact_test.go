@@ -0,0 +1,44 @@
+package gocoverdir
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestHashPath(t *testing.T) {
+	hashed := hashPath("example.com/a/a.go")
+	elements := strings.Split(hashed, "/")
+	if len(elements) != 3 || strings.Contains(hashed, "example") || len(elements[0]) != 8 {
+		t.Errorf("Unexpected hashed path %s", hashed)
+	}
+	if !strings.HasPrefix(hashPath("example.com/a/b.go"), elements[0]+"/"+elements[1]+"/") {
+		t.Errorf("Expected paths in the same directory to share a hashed prefix")
+	}
+}
+
+func TestRedactReport(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 3, Count: 1}, {NumStmt: 1}}},
+	}
+	results := []packageResult{{dir: "a", command: "go test", err: errors.New("exit status 1"), skipped: []string{"TestSecret"}}}
+	skipped := []skippedDir{{dir: "secret/slow", reason: "matches slowlist secret/..."}}
+	r := newReport(profiles, "statements", results, skipped, &ciEnvironment{Name: "github", Branch: "secret-branch"}, nil)
+	r.Findings = []finding{{Code: "SA4006", File: "a/a.go", Message: "secret"}}
+	redacted := redactReport(r, false)
+	if redacted.Coverage != r.Coverage || redacted.Packages[0].Path != "example.com/a" || redacted.Files[0].Path != "example.com/a/a.go" {
+		t.Errorf("Expected coverage numbers and paths to be kept, saw %+v", redacted)
+	}
+	if redacted.Findings != nil || redacted.CI.Branch != "" || redacted.Tests[0].Error != "" || redacted.Tests[0].SkippedTests != nil || redacted.Tests[0].Skipped != 1 || redacted.Skipped[0].Reason != "" {
+		t.Errorf("Expected details to be dropped, saw %+v", redacted)
+	}
+	hashed := redactReport(r, true)
+	if hashed.Packages[0].Path != hashPath("example.com/a") || hashed.Files[0].Package != hashPath("example.com/a") || hashed.Skipped[0] != (reportSkip{Dir: hashPath("secret/slow")}) {
+		t.Errorf("Expected hashed paths, saw %+v", hashed)
+	}
+	if r.Packages[0].Path != "example.com/a" {
+		t.Errorf("Expected the original report to be unchanged")
+	}
+}
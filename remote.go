@@ -0,0 +1,132 @@
+package gocoverdir
+
+import (
+	"io"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// lockedWriter serializes writes from concurrently running tests
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+func (m *gocoverdir) remoteCommand(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = m.testOutputStdout
+	cmd.Stderr = m.testOutputStderr
+	m.log.Printf("Executing %s", strings.Join(cmd.Args, " "))
+	return cmd.Run()
+}
+
+// shardDirs splits dirs round robin into count shards
+func shardDirs(dirs []string, count int) [][]string {
+	shards := make([][]string, count)
+	for i, dir := range dirs {
+		shards[i%count] = append(shards[i%count], dir)
+	}
+	return shards
+}
+
+// coverRemote runs package tests over ssh, sharded across every -remote host.  With timings from an earlier run,
+// shards are balanced by expected test time
+func (m *gocoverdir) coverRemote(dirs []string) error {
+	// The -leakcheck TestMains are written before syncing, so they reach every host with the rest of the tree
+	for _, dir := range dirs {
+		defer m.injectLeakCheck(dir)()
+	}
+	hosts := strings.Split(m.args.remote, ",")
+	shards := shardDirs(dirs, len(hosts))
+	if lastSeconds := m.lastTimings(); len(lastSeconds) > 0 {
+		shards = balanceShards(dirs, len(hosts), estimateCosts(dirs, lastSeconds, dirStatements))
+	}
+	type hostResult struct {
+		results []packageResult
+		err     error
+	}
+	hostResults := make(chan hostResult, len(hosts))
+	for i, host := range hosts {
+		go func(host string, dirs []string) {
+			results, err := m.coverOnHost(host, dirs)
+			hostResults <- hostResult{results: results, err: err}
+		}(strings.TrimSpace(host), shards[i])
+	}
+	var firstErr error
+	for range hosts {
+		hr := <-hostResults
+		m.results = append(m.results, hr.results...)
+		if hr.err != nil && firstErr == nil {
+			firstErr = hr.err
+		}
+	}
+	return firstErr
+}
+
+// coverOnHost syncs the tree to host, runs dirs' tests there, and copies the profiles back into the store directory
+func (m *gocoverdir) coverOnHost(host string, dirs []string) ([]packageResult, error) {
+	const remoteStore = ".gocoverdir-store"
+	if err := m.remoteCommand("rsync", "-az", "--delete", "--exclude", ".git", "./", host+":"+m.args.remotedir+"/"); err != nil {
+		return nil, err
+	}
+	if err := m.remoteCommand("ssh", host, "mkdir -p "+shellQuote(path.Join(m.args.remotedir, remoteStore))); err != nil {
+		return nil, err
+	}
+	var results []packageResult
+	attempts := make(map[string]int)
+	runOnHost := func(dir string, pass string, only []string) packageResult {
+		profileName := storeProfileName(dir, pass, attempts[dir+"."+pass])
+		attempts[dir+"."+pass]++
+		// The test runs in the root of dir's module, so the store is found back up from there
+		root := filepath.ToSlash(m.depStrategy(dir).root)
+		outputdir := remoteStore
+		if root != "." {
+			outputdir = strings.Repeat("../", strings.Count(root, "/")+1) + remoteStore
+		}
+		executable, args := m.testArgs(dir, outputdir, profileName, only)
+		var quoted []string
+		for _, env := range append(m.testEnv(), depToolEnv(detectDepTool(root))...) {
+			kv := strings.SplitN(env, "=", 2)
+			quoted = append(quoted, kv[0]+"="+shellQuote(kv[1]))
+		}
+		for _, arg := range append([]string{executable}, args...) {
+			quoted = append(quoted, shellQuote(arg))
+		}
+		remoteCmd := "cd " + shellQuote(path.Join(m.args.remotedir, root)) + " && " + strings.Join(quoted, " ")
+		return m.runTest(dir, exec.Command("ssh", host, remoteCmd), true)
+	}
+	for _, dir := range dirs {
+		result := m.retryFailures(runOnHost(dir, "", nil), func(only []string) packageResult {
+			return runOnHost(dir, "", only)
+		})
+		results = append(results, result)
+		if result.err != nil {
+			break
+		}
+		if quarantined := m.quarantinedIn(dir); len(quarantined) > 0 {
+			results = append(results, m.quarantineResult(runOnHost(dir, "quarantined", quarantined)))
+		}
+	}
+	if err := m.remoteCommand("rsync", "-az", host+":"+path.Join(m.args.remotedir, remoteStore)+"/", m.storeDir+"/"); err != nil {
+		return results, err
+	}
+	for _, result := range results {
+		if result.err != nil && !result.quarantined {
+			return results, result.err
+		}
+	}
+	return results, nil
+}
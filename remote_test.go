@@ -0,0 +1,48 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShardDirs(t *testing.T) {
+	shards := shardDirs([]string{"a", "b", "c"}, 2)
+	if !reflect.DeepEqual(shards, [][]string{{"a", "c"}, {"b"}}) {
+		t.Errorf("Unexpected shards %v", shards)
+	}
+	if shellQuote("it's") != `'it'\''s'` {
+		t.Errorf("Unexpected quoting %s", shellQuote("it's"))
+	}
+}
+
+func TestCoverRemoteLeakCheck(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	noError(t, os.MkdirAll(bin, 0755))
+	noError(t, os.MkdirAll(filepath.Join(dir, "a"), 0755))
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a", "a_test.go"), []byte("package a\n"), 0644))
+	// rsync records what it would sync, and ssh runs nothing
+	synced := filepath.Join(dir, "synced")
+	noError(t, ioutil.WriteFile(filepath.Join(bin, "rsync"), []byte("#!/bin/sh\nls a >> "+synced+"\n"), 0755))
+	noError(t, ioutil.WriteFile(filepath.Join(bin, "ssh"), []byte("#!/bin/sh\nexit 0\n"), 0755))
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Chdir(dir)
+
+	m := gocoverdir{log: log.New(ioutil.Discard, "", 0), testOutputStdout: ioutil.Discard, testOutputStderr: ioutil.Discard, storeDir: t.TempDir()}
+	m.args.remote = "host"
+	m.args.leakcheck = true
+	m.coverRemote([]string{"a"})
+	contents, err := ioutil.ReadFile(synced)
+	noError(t, err)
+	if !strings.Contains(string(contents), leakCheckFile) {
+		t.Errorf("Expected the leak check to be synced to the host, saw %s", contents)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a", leakCheckFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected the leak check to be removed after the run")
+	}
+}
@@ -0,0 +1,220 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"text/tabwriter"
+)
+
+// coverageDelta is the coverage of one path in an old and a new report.  A path missing from a report has
+// the matching exists field false
+type coverageDelta struct {
+	Path      string
+	Old       float64
+	New       float64
+	OldExists bool
+	NewExists bool
+}
+
+func (c coverageDelta) changed() bool {
+	return c.OldExists != c.NewExists || math.Abs(c.New-c.Old) >= .05
+}
+
+func (c coverageDelta) OldString() string {
+	if !c.OldExists {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", c.Old)
+}
+
+func (c coverageDelta) NewString() string {
+	if !c.NewExists {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", c.New)
+}
+
+func (c coverageDelta) DeltaString() string {
+	switch {
+	case !c.OldExists:
+		return "new"
+	case !c.NewExists:
+		return "removed"
+	}
+	return fmt.Sprintf("%+.1f", c.New-c.Old)
+}
+
+// reportDiff is the change in coverage between two reports
+type reportDiff struct {
+	Total     coverageDelta
+	Packages  []coverageDelta
+	Unchanged int
+}
+
+func readReport(filename string) (*report, error) {
+	f, err := openInput(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	r, err := maybeGunzip(f)
+	if err != nil {
+		return nil, err
+	}
+	var ret report
+	if err := json.NewDecoder(r).Decode(&ret); err != nil {
+		return nil, fmt.Errorf("cannot parse report %s: %s", filename, err)
+	}
+	return &ret, nil
+}
+
+// diffReports lists, sorted by path, every package whose coverage changed between before and after
+func diffReports(before *report, after *report) reportDiff {
+	ret := reportDiff{
+		Total: coverageDelta{Path: "total", Old: before.Coverage, New: after.Coverage, OldExists: true, NewExists: true},
+	}
+	deltas := make(map[string]*coverageDelta)
+	for _, pkg := range before.Packages {
+		deltas[pkg.Path] = &coverageDelta{Path: pkg.Path, Old: pkg.Coverage, OldExists: true}
+	}
+	for _, pkg := range after.Packages {
+		if deltas[pkg.Path] == nil {
+			deltas[pkg.Path] = &coverageDelta{Path: pkg.Path}
+		}
+		deltas[pkg.Path].New = pkg.Coverage
+		deltas[pkg.Path].NewExists = true
+	}
+	for _, delta := range deltas {
+		if !delta.changed() {
+			ret.Unchanged++
+			continue
+		}
+		ret.Packages = append(ret.Packages, *delta)
+	}
+	sort.Slice(ret.Packages, func(i, j int) bool {
+		return ret.Packages[i].Path < ret.Packages[j].Path
+	})
+	return ret
+}
+
+func renderDiffMarkdown(w io.Writer, diff reportDiff) error {
+	if _, err := fmt.Fprintf(w, "## Coverage: %s (%s)\n\n", diff.Total.NewString(), diff.Total.DeltaString()); err != nil {
+		return err
+	}
+	if len(diff.Packages) > 0 {
+		if _, err := io.WriteString(w, "| Package | Old | New | Delta |\n| --- | ---: | ---: | ---: |\n"); err != nil {
+			return err
+		}
+		for _, pkg := range diff.Packages {
+			if _, err := fmt.Fprintf(w, "| %s | %s | %s | %s |\n", pkg.Path, pkg.OldString(), pkg.NewString(), pkg.DeltaString()); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d packages unchanged.\n", diff.Unchanged)
+	return err
+}
+
+func renderDiffText(w io.Writer, diff reportDiff) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "PACKAGE\tOLD\tNEW\tDELTA\n")
+	for _, pkg := range append(diff.Packages, diff.Total) {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", pkg.Path, pkg.OldString(), pkg.NewString(), pkg.DeltaString())
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d packages unchanged\n", diff.Unchanged)
+	return err
+}
+
+var diffTemplate = template.Must(template.New("diff").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage diff</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+td, th { padding: 4px 12px; border-bottom: 1px solid #ddd; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h2>Coverage: {{.Total.NewString}} ({{.Total.DeltaString}})</h2>
+{{if .Packages}}<table>
+<tr><th>Package</th><th>Old</th><th>New</th><th>Delta</th></tr>
+{{range .Packages}}<tr><td>{{.Path}}</td><td class="num">{{.OldString}}</td><td class="num">{{.NewString}}</td><td class="num">{{.DeltaString}}</td></tr>
+{{end}}</table>
+{{end}}<p>{{.Unchanged}} packages unchanged.</p>
+</body>
+</html>
+`))
+
+func renderDiffHTML(w io.Writer, diff reportDiff) error {
+	return diffTemplate.Execute(w, diff)
+}
+
+var diffRenderers = map[string]func(w io.Writer, diff reportDiff) error{
+	"markdown": renderDiffMarkdown,
+	"text":     renderDiffText,
+	"html":     renderDiffHTML,
+}
+
+// parseInterspersed parses flags that may come before, between or after the positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// runRenderDiff prints the coverage change between two JSON reports
+func runRenderDiff(args []string) error {
+	fs := flag.NewFlagSet("render-diff", flag.ContinueOnError)
+	format := fs.String("format", "markdown", "Output format: markdown, text or html")
+	output := fs.String("o", "", "File to write the diff to.  Defaults to stdout")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 2 {
+		return fmt.Errorf("usage: gocoverdir render-diff [-format markdown|text|html] old.json new.json")
+	}
+	render, exists := diffRenderers[*format]
+	if !exists {
+		return fmt.Errorf("unknown format %s", *format)
+	}
+	before, err := readReport(files[0])
+	if err != nil {
+		return err
+	}
+	after, err := readReport(files[1])
+	if err != nil {
+		return err
+	}
+	diff := diffReports(before, after)
+	if *output == "" {
+		return render(os.Stdout, diff)
+	}
+	return writeFileWith(*output, func(w io.Writer) error {
+		return render(w, diff)
+	})
+}
@@ -0,0 +1,39 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestDiffReports(t *testing.T) {
+	before := &report{Coverage: 50, Packages: []reportPackage{{Path: "a", Coverage: 50}, {Path: "b", Coverage: 20}, {Path: "c", Coverage: 10}}}
+	after := &report{Coverage: 60, Packages: []reportPackage{{Path: "a", Coverage: 50}, {Path: "b", Coverage: 40}, {Path: "d", Coverage: 80}}}
+	diff := diffReports(before, after)
+	if diff.Unchanged != 1 || len(diff.Packages) != 3 {
+		t.Fatalf("Unexpected diff %+v", diff)
+	}
+	var buf bytes.Buffer
+	noError(t, renderDiffMarkdown(&buf, diff))
+	for _, expected := range []string{"## Coverage: 60.0% (+10.0)", "| b | 20.0% | 40.0% | +20.0 |", "| c | 10.0% | - | removed |", "| d | - | 80.0% | new |", "1 packages unchanged."} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected %q in %s", expected, buf.String())
+		}
+	}
+	buf.Reset()
+	noError(t, renderDiffHTML(&buf, diff))
+	if !strings.Contains(buf.String(), `<td class="num">&#43;20.0</td>`) {
+		t.Errorf("Unexpected html %s", buf.String())
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	format := fs.String("format", "markdown", "")
+	files, err := parseInterspersed(fs, []string{"old.json", "new.json", "-format", "text"})
+	noError(t, err)
+	if *format != "text" || strings.Join(files, " ") != "old.json new.json" {
+		t.Errorf("Unexpected parse %s %v", *format, files)
+	}
+}
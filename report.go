@@ -0,0 +1,166 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"io"
+	"path"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+// report is the JSON summary of a run
+type report struct {
+	// RunID correlates the reports, logs and history of one run
+	RunID string `json:"run_id,omitempty"`
+	// ToolVersion is the gocoverdir that wrote the report
+	ToolVersion string          `json:"tool_version,omitempty"`
+	Timestamp   time.Time       `json:"timestamp"`
+	CI          *ciEnvironment  `json:"ci,omitempty"`
+	Coverage    float64         `json:"coverage"`
+	Statements  int             `json:"statements"`
+	Covered     int             `json:"covered"`
+	Packages    []reportPackage `json:"packages"`
+	// Surfaces is the coverage of public API and internal packages
+	Surfaces []reportPackage `json:"surfaces"`
+	// Sources is the coverage of the templates and specs generated files come from, by the config's sourcemap and
+	// .gocovermap files
+	Sources  []reportPackage `json:"sources,omitempty"`
+	Files    []reportFile    `json:"files"`
+	Tests    []reportTest    `json:"tests,omitempty"`
+	Skipped  []reportSkip    `json:"skipped,omitempty"`
+	Findings []finding       `json:"findings,omitempty"`
+	// Metric is what Coverage, Statements and Covered count: statements, blocks or lines
+	Metric string `json:"metric,omitempty"`
+}
+
+type reportPackage struct {
+	Path       string  `json:"path"`
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"`
+	Coverage   float64 `json:"coverage"`
+	// Risk combines recent churn, complexity, fan in and coverage.  Higher is riskier
+	Risk float64 `json:"risk,omitempty"`
+}
+
+// reportSkip is a directory with Go files that was not tested
+type reportSkip struct {
+	Dir    string `json:"dir"`
+	Reason string `json:"reason"`
+}
+
+type reportFile struct {
+	Path       string  `json:"path"`
+	Package    string  `json:"package"`
+	Statements int     `json:"statements"`
+	Covered    int     `json:"covered"`
+	Coverage   float64 `json:"coverage"`
+}
+
+// reportTest is the result of running go test, or another check, in one directory
+type reportTest struct {
+	Dir          string   `json:"dir"`
+	Command      string   `json:"command"`
+	Seconds      float64  `json:"seconds"`
+	Passed       bool     `json:"passed"`
+	Error        string   `json:"error,omitempty"`
+	Skipped      int      `json:"skipped,omitempty"`
+	SkippedTests []string `json:"skipped_tests,omitempty"`
+	Quarantined  bool     `json:"quarantined,omitempty"`
+	Flaky        []string `json:"flaky,omitempty"`
+	// Examples are the Example functions that ran.  ExamplesOnly is true if nothing else ran
+	Examples     []string `json:"examples,omitempty"`
+	ExamplesOnly bool     `json:"examples_only,omitempty"`
+	// Races are the data race reports found in the package's test output
+	Races []string `json:"races,omitempty"`
+	// OutputMatches are the output lines that matched a failoutput config rule
+	OutputMatches []string `json:"output_matches,omitempty"`
+	// Leaks are the goroutines the package's tests left running, with -leakcheck
+	Leaks []string `json:"leaks,omitempty"`
+	// Profiles are the mutex and block profiles written for the package
+	Profiles map[string]string `json:"profiles,omitempty"`
+	// FailureBundle is the directory with the command, output, go env and profile of a failed package
+	FailureBundle string `json:"failure_bundle,omitempty"`
+}
+
+// localize names the files of r, which were created from profiles, with loc
+func (r *report) localize(profiles []*cover.Profile, loc *fileLocator) {
+	for i, p := range profiles {
+		if i < len(r.Files) {
+			r.Files[i].Path = loc.name(p)
+		}
+	}
+}
+
+func newReport(profiles []*cover.Profile, metric string, results []packageResult, skipped []skippedDir, ci *ciEnvironment, internal []string) *report {
+	ret := &report{
+		ToolVersion: toolVersion(),
+		Metric:      metric,
+		Timestamp:   time.Now(),
+		CI:          ci,
+		Packages:    make([]reportPackage, 0),
+		Files:       make([]reportFile, 0, len(profiles)),
+	}
+	for _, pkg := range packageCoverages(profiles, metric) {
+		ret.Packages = append(ret.Packages, reportPackage{
+			Path:       pkg.name,
+			Statements: pkg.statements,
+			Covered:    pkg.covered,
+			Coverage:   pkg.percent(),
+		})
+		ret.Statements += pkg.statements
+		ret.Covered += pkg.covered
+	}
+	ret.Coverage = lineRate(ret.Covered, ret.Statements) * 100
+	for _, surface := range surfaceCoverages(profiles, metric, internal) {
+		ret.Surfaces = append(ret.Surfaces, reportPackage{
+			Path:       surface.name,
+			Statements: surface.statements,
+			Covered:    surface.covered,
+			Coverage:   surface.percent(),
+		})
+	}
+	for _, p := range profiles {
+		total, covered := countStatements(p, metric)
+		ret.Files = append(ret.Files, reportFile{
+			Path:       p.FileName,
+			Package:    path.Dir(p.FileName),
+			Statements: total,
+			Covered:    covered,
+			Coverage:   lineRate(covered, total) * 100,
+		})
+	}
+	for _, result := range results {
+		test := reportTest{
+			Dir:           result.dir,
+			Command:       result.command,
+			Seconds:       result.duration.Seconds(),
+			Passed:        result.err == nil,
+			Skipped:       len(result.skipped),
+			SkippedTests:  result.skipped,
+			Quarantined:   result.quarantined,
+			Flaky:         result.flaky,
+			Profiles:      result.profiles,
+			Races:         result.races,
+			OutputMatches: result.outputMatches,
+			Leaks:         result.leaks,
+			Examples:      result.examples,
+			ExamplesOnly:  result.examplesOnly,
+			FailureBundle: result.failureBundle,
+		}
+		if result.err != nil {
+			test.Error = result.err.Error()
+		}
+		ret.Tests = append(ret.Tests, test)
+	}
+	for _, skip := range skipped {
+		ret.Skipped = append(ret.Skipped, reportSkip{Dir: skip.dir, Reason: skip.reason})
+	}
+	return ret
+}
+
+func writeReport(w io.Writer, r *report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
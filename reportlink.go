@@ -0,0 +1,159 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// reportLinker turns -reportlink, a URL template of the uploaded HTML report, into deep links at a package's first
+// uncovered line.  A nil reportLinker makes no links
+type reportLinker struct {
+	template string
+	commit   string
+	runID    string
+	// packages is where each package's link points: its most uncovered file
+	packages map[string]linkTarget
+	// files is the first uncovered line of each file, named like reports name files
+	files map[string]linkTarget
+}
+
+type linkTarget struct {
+	pkg  string
+	file string
+	line int
+}
+
+// escapeLinkPath escapes each element of p for a URL, keeping the slashes between them
+func escapeLinkPath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// firstUncoveredLine is the first line of p with an uncovered statement, or 0 if it is fully covered
+func firstUncoveredLine(p *cover.Profile) int {
+	if ranges := uncoveredRanges(p); len(ranges) > 0 {
+		return ranges[0].startLine
+	}
+	return 0
+}
+
+func newReportLinker(template string, commit string, runID string, profiles []*cover.Profile, metric string, loc *fileLocator) *reportLinker {
+	if template == "" {
+		return nil
+	}
+	ret := &reportLinker{template: template, commit: commit, runID: runID, packages: make(map[string]linkTarget), files: make(map[string]linkTarget)}
+	uncovered := make(map[string]int)
+	for _, p := range profiles {
+		total, covered := countStatements(p, metric)
+		if total == covered {
+			continue
+		}
+		target := linkTarget{pkg: path.Dir(p.FileName), file: loc.name(p), line: firstUncoveredLine(p)}
+		ret.files[target.file] = target
+		if _, exists := ret.packages[target.pkg]; !exists || total-covered > uncovered[target.pkg] {
+			ret.packages[target.pkg] = target
+			uncovered[target.pkg] = total - covered
+		}
+	}
+	return ret
+}
+
+func (l *reportLinker) expand(target linkTarget) string {
+	lineText := ""
+	if target.line > 0 {
+		lineText = strconv.Itoa(target.line)
+	}
+	return strings.NewReplacer(
+		"{package}", escapeLinkPath(target.pkg),
+		"{file}", escapeLinkPath(target.file),
+		"{line}", lineText,
+		"{commit}", url.PathEscape(l.commit),
+		"{run_id}", url.PathEscape(l.runID),
+	).Replace(l.template)
+}
+
+// packageLink links to pkg's most uncovered file, or "" without a link template or anything uncovered in pkg
+func (l *reportLinker) packageLink(pkg string) string {
+	if l == nil {
+		return ""
+	}
+	target, exists := l.packages[pkg]
+	if !exists {
+		return ""
+	}
+	return l.expand(target)
+}
+
+// fileLink links to the first uncovered line of file, named like reports name files
+func (l *reportLinker) fileLink(file string) (string, int) {
+	if l == nil {
+		return "", 0
+	}
+	target, exists := l.files[file]
+	if !exists {
+		return "", 0
+	}
+	return l.expand(target), target.line
+}
+
+// linker makes the deep links of this run's reports and annotations
+func (m *gocoverdir) linker(profiles []*cover.Profile) *reportLinker {
+	if m.args.reportlink == "" {
+		return nil
+	}
+	if m.links == nil {
+		commit := ""
+		if m.ci != nil {
+			commit = m.ci.Commit
+		}
+		if commit == "" && m.repo != nil {
+			commit = m.repo.commit()
+		}
+		m.links = newReportLinker(m.args.reportlink, commit, m.args.runid, profiles, m.args.metric, m.locator(profiles))
+	}
+	return m.links
+}
+
+// linkViolations points each violation with uncovered files at the first uncovered line of the most uncovered one.
+// The files must already be localized
+func linkViolations(violations []violation, links *reportLinker) {
+	for i := range violations {
+		if len(violations[i].Files) > 0 {
+			violations[i].Link, violations[i].Line = links.fileLink(violations[i].Files[0])
+		}
+	}
+}
+
+// annotateViolations emits a GitHub annotation per failed gate, on the file to start with and linking to the report,
+// so reviewers land on the uncovered lines in one click
+func (m *gocoverdir) annotateViolations(violations []violation) {
+	if m.ci == nil || m.ci.Name != "github" || m.args.reportlink == "" {
+		return
+	}
+	for _, v := range violations {
+		// Gates without files, like the total, are already in the annotation of the failed run
+		if len(v.Files) == 0 {
+			continue
+		}
+		location := "file=" + githubPropertyEscaper.Replace(v.Files[0])
+		if v.Line > 0 {
+			location += fmt.Sprintf(",line=%d", v.Line)
+		}
+		message := v.String()
+		if v.Link != "" {
+			message += "\nReport: " + v.Link
+		}
+		fmt.Fprintf(m.stdout(), "::error %s,title=%s::%s\n", location, githubPropertyEscaper.Replace("gocoverdir "+v.Rule), githubEscaper.Replace(message))
+	}
+}
+
+// githubPropertyEscaper escapes the properties of GitHub workflow commands, which also end at : and ,
+var githubPropertyEscaper = strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C")
@@ -0,0 +1,43 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestReportLinker(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{StartLine: 3, EndLine: 4, NumStmt: 2, Count: 1}, {StartLine: 6, EndLine: 6, NumStmt: 1}}},
+		{FileName: "example.com/a/big file.go", Blocks: []cover.ProfileBlock{{StartLine: 8, EndLine: 9, NumStmt: 3}, {StartLine: 12, EndLine: 12, NumStmt: 1}}},
+		{FileName: "example.com/b/b.go", Blocks: []cover.ProfileBlock{{StartLine: 3, EndLine: 4, NumStmt: 2, Count: 1}}},
+	}
+	links := newReportLinker("https://ci.example.com/{run_id}/{commit}/report.html#{file}:{line}", "abc123", "8f3a", profiles, "statements", &fileLocator{})
+	if link := links.packageLink("example.com/a"); link != "https://ci.example.com/8f3a/abc123/report.html#example.com/a/big%20file.go:8" {
+		t.Errorf("Expected a link to the most uncovered file, got %s", link)
+	}
+	if link := links.packageLink("example.com/b"); link != "" {
+		t.Errorf("Expected no link to a fully covered package, got %s", link)
+	}
+	if link, line := links.fileLink("example.com/a/a.go"); line != 6 || !strings.HasSuffix(link, "#example.com/a/a.go:6") {
+		t.Errorf("Unexpected file link %s %d", link, line)
+	}
+	var none *reportLinker
+	if none.packageLink("example.com/a") != "" {
+		t.Errorf("Expected no links without a template")
+	}
+
+	violations := []violation{{Rule: "packages[a/...]", Scope: "example.com/a", Files: []string{"example.com/a/a.go"}}, {Rule: "requiredcoverage", Scope: "total"}}
+	linkViolations(violations, links)
+	if violations[0].Line != 6 || violations[0].Link == "" || violations[1].Link != "" {
+		t.Errorf("Unexpected violation links %+v", violations)
+	}
+
+	var buf bytes.Buffer
+	noError(t, writeMarkdownSummary(&buf, profiles, "statements", 0, 0, nil, nil, links))
+	if !strings.Contains(buf.String(), "| [example.com/a](https://ci.example.com/8f3a/abc123/report.html#example.com/a/big%20file.go:8) |") || !strings.Contains(buf.String(), "| example.com/b |") {
+		t.Errorf("Unexpected markdown:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,168 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/cover"
+)
+
+// riskFactors are what make a package likely to hide bugs
+type riskFactors struct {
+	// churn is how many recent commits touched the package
+	churn int
+	// complexity is the cyclomatic complexity of all its functions
+	complexity int
+	// fanIn is how many of the tested packages import it
+	fanIn    int
+	coverage float64
+}
+
+// score grows with the uncovered fraction of the package, scaled by the log of each other factor.  A fully covered
+// package has no risk
+func (f riskFactors) score() float64 {
+	uncovered := math.Max(0, 100-f.coverage) / 100
+	scale := func(n int) float64 {
+		return 1 + math.Log2(1+float64(n))
+	}
+	return math.Round(uncovered*scale(f.churn)*scale(f.complexity)*scale(f.fanIn)*100) / 100
+}
+
+// dirComplexity sums the cyclomatic complexity of the functions in a directory's non test Go files
+func dirComplexity(dir string) int {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	fset := token.NewFileSet()
+	complexity := 0
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".go") || strings.HasSuffix(file.Name(), "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, file.Name()), nil, 0)
+		if err != nil {
+			continue
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.FuncDecl, *ast.FuncLit, *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt, *ast.CaseClause, *ast.CommClause:
+				complexity++
+			case *ast.BinaryExpr:
+				if n.Op == token.LAND || n.Op == token.LOR {
+					complexity++
+				}
+			}
+			return true
+		})
+	}
+	return complexity
+}
+
+// fanIns counts, for each directory, how many of the other directories import its package
+func fanIns(dirs []string) map[string]int {
+	ret := make(map[string]int, len(dirs))
+	if len(dirs) == 0 {
+		return ret
+	}
+	args := []string{"list", "-e", "-f", "{{.ImportPath}}{{range .Imports}} {{.}}{{end}}"}
+	for _, dir := range dirs {
+		args = append(args, "./"+dir)
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command("go", args...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return ret
+	}
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != len(dirs) {
+		return ret
+	}
+	dirOf := make(map[string]string, len(dirs))
+	for i, line := range lines {
+		dirOf[strings.Fields(line)[0]] = dirs[i]
+	}
+	for _, line := range lines {
+		for _, imported := range strings.Fields(line)[1:] {
+			if dir, exists := dirOf[imported]; exists {
+				ret[dir]++
+			}
+		}
+	}
+	return ret
+}
+
+// churnWindow is how far back commits count towards a directory's churn
+const churnWindow = 14 * 24 * time.Hour
+
+// riskScores scores each directory, given its coverage and recent changes.  Directories without a coverage count as
+// uncovered
+func riskScores(dirs []string, coverages map[string]float64, changes map[string]int) map[string]float64 {
+	fanIn := fanIns(dirs)
+	ret := make(map[string]float64, len(dirs))
+	for _, dir := range dirs {
+		ret[dir] = riskFactors{
+			churn:      changes[dir],
+			complexity: dirComplexity(dir),
+			fanIn:      fanIn[dir],
+			coverage:   coverages[dir],
+		}.score()
+	}
+	return ret
+}
+
+// riskiestFirst sorts dirs by score, highest first
+func riskiestFirst(dirs []string, scores map[string]float64) []string {
+	ret := append([]string(nil), dirs...)
+	sort.SliceStable(ret, func(i, j int) bool {
+		return scores[ret[i]] > scores[ret[j]]
+	})
+	return ret
+}
+
+// riskOrder sorts dirs by risk, using the coverage of the last -history entry
+func (m *gocoverdir) riskOrder(dirs []string) []string {
+	return riskiestFirst(dirs, riskScores(dirs, m.lastCoverages(dirs), m.repo.recentChanges(time.Now().Add(-churnWindow))))
+}
+
+// addRisks sets the risk score of every package in r whose directory can be found
+func addRisks(r *report, profiles []*cover.Profile, repo vcs) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	pkgDirs := resolvePackageDirs(profiles)
+	dirs := make([]string, 0, len(r.Packages))
+	coverages := make(map[string]float64, len(r.Packages))
+	dirOf := make(map[string]string, len(r.Packages))
+	for _, pkg := range r.Packages {
+		abs, exists := pkgDirs[pkg.Path]
+		if !exists {
+			continue
+		}
+		dir, err := filepath.Rel(cwd, abs)
+		if err != nil || strings.HasPrefix(dir, "..") {
+			continue
+		}
+		dirs = append(dirs, dir)
+		coverages[dir] = pkg.Coverage
+		dirOf[pkg.Path] = dir
+	}
+	scores := riskScores(dirs, coverages, repo.recentChanges(time.Now().Add(-churnWindow)))
+	for i, pkg := range r.Packages {
+		if dir, exists := dirOf[pkg.Path]; exists {
+			r.Packages[i].Risk = scores[dir]
+		}
+	}
+}
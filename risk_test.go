@@ -0,0 +1,52 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRiskScore(t *testing.T) {
+	if score := (riskFactors{churn: 10, complexity: 50, fanIn: 3, coverage: 100}).score(); score != 0 {
+		t.Errorf("Expected a covered package to have no risk, saw %f", score)
+	}
+	if score := (riskFactors{coverage: 50}).score(); score != 0.5 {
+		t.Errorf("Expected half the risk of an untouched uncovered package, saw %f", score)
+	}
+	low := riskFactors{churn: 1, complexity: 10, coverage: 80}.score()
+	high := riskFactors{churn: 1, complexity: 10, fanIn: 4, coverage: 80}.score()
+	if high <= low {
+		t.Errorf("Expected fan in to raise risk: %f <= %f", high, low)
+	}
+	scores := map[string]float64{"a": 1, "b": 3, "c": 2}
+	if order := riskiestFirst([]string{"a", "b", "c"}, scores); !reflect.DeepEqual(order, []string{"b", "c", "a"}) {
+		t.Errorf("Unexpected order %v", order)
+	}
+}
+
+func TestDirComplexity(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDirComplexity")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte(`package a
+
+func A(x int) int {
+	if x > 0 && x < 10 {
+		return 1
+	}
+	for i := 0; i < x; i++ {
+		switch i {
+		case 1:
+		default:
+		}
+	}
+	return 0
+}
+`), 0644))
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a_test.go"), []byte("package a\n\nfunc T() {}\n"), 0644))
+	if complexity := dirComplexity(dir); complexity != 6 {
+		t.Errorf("Unexpected complexity %d", complexity)
+	}
+}
@@ -0,0 +1,32 @@
+package gocoverdir
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// newRunID is a random UUID identifying one run in every artifact it writes.  Cover profiles cannot hold it:
+// go tool cover rejects any line that is not a block
+func newRunID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	// Version 4, variant 10
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// setupRunID uses -runid, so shards of one CI run can share an ID, or generates one
+func (m *gocoverdir) setupRunID() error {
+	if m.args.runid == "" {
+		id, err := newRunID()
+		if err != nil {
+			return err
+		}
+		m.args.runid = id
+	}
+	m.log.Printf("Run ID %s", m.args.runid)
+	return nil
+}
@@ -0,0 +1,17 @@
+package gocoverdir
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewRunID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first, err := newRunID()
+	noError(t, err)
+	second, err := newRunID()
+	noError(t, err)
+	if !uuid.MatchString(first) || first == second {
+		t.Errorf("Expected two different version 4 UUIDs, saw %s and %s", first, second)
+	}
+}
@@ -0,0 +1,398 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseParallel converts -parallel into a worker count
+func parseParallel(parallel string) (int, error) {
+	if parallel == "auto" {
+		return runtime.NumCPU(), nil
+	}
+	workers, err := strconv.Atoi(parallel)
+	if err != nil || workers < 1 {
+		return 0, fmt.Errorf("-parallel must be a positive number or auto, not %s", parallel)
+	}
+	return workers, nil
+}
+
+// dirStatements counts the statements in a directory's non test Go files
+func dirStatements(dir string) int {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0
+	}
+	fset := token.NewFileSet()
+	count := 0
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".go") || strings.HasSuffix(file.Name(), "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, file.Name()), nil, 0)
+		if err != nil {
+			continue
+		}
+		ast.Inspect(f, func(n ast.Node) bool {
+			if _, ok := n.(ast.Stmt); ok {
+				if _, isBlock := n.(*ast.BlockStmt); !isBlock {
+					count++
+				}
+			}
+			return true
+		})
+	}
+	return count
+}
+
+// estimateCosts guesses how long each directory's tests take.  Directories timed in the last run use that time.
+// The others are estimated from their statement count, at the seconds per statement of the timed directories.
+func estimateCosts(dirs []string, lastSeconds map[string]float64, statements func(dir string) int) map[string]float64 {
+	stmts := make(map[string]int, len(dirs))
+	var timedSeconds float64
+	var timedStatements int
+	for _, dir := range dirs {
+		stmts[dir] = statements(dir)
+		if seconds, exists := lastSeconds[dir]; exists {
+			timedSeconds += seconds
+			timedStatements += stmts[dir]
+		}
+	}
+	secondsPerStatement := 1.0
+	if timedStatements > 0 {
+		secondsPerStatement = timedSeconds / float64(timedStatements)
+	}
+	ret := make(map[string]float64, len(dirs))
+	for _, dir := range dirs {
+		if seconds, exists := lastSeconds[dir]; exists {
+			ret[dir] = seconds
+		} else {
+			ret[dir] = float64(stmts[dir]) * secondsPerStatement
+		}
+	}
+	return ret
+}
+
+// lastTimings returns the per directory test times of -timings, or else of the newest -history entry that has them
+func (m *gocoverdir) lastTimings() map[string]float64 {
+	if m.args.timings != "" {
+		seconds, err := readTimings(m.args.timings)
+		if err != nil && !os.IsNotExist(err) {
+			m.log.Printf("Unable to read timings from %s: %s", m.args.timings, err)
+		}
+		if len(seconds) > 0 {
+			return seconds
+		}
+	}
+	if m.args.history == "" {
+		return nil
+	}
+	entries, err := readHistory(m.args.history)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			m.log.Printf("Unable to read timings from %s: %s", m.args.history, err)
+		}
+		return nil
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if len(entries[i].Seconds) > 0 {
+			return entries[i].Seconds
+		}
+	}
+	return nil
+}
+
+// verifyOrder checks -order
+func (m *gocoverdir) verifyOrder() error {
+	switch m.args.order {
+	case "", "risk", "recent-failures-first", "changed-first":
+		return nil
+	}
+	return fmt.Errorf("-order must be risk, recent-failures-first or changed-first, not %s", m.args.order)
+}
+
+// orderDirs sorts dirs by -order.  Without it, discovery order is kept
+func (m *gocoverdir) orderDirs(dirs []string) []string {
+	switch m.args.order {
+	case "risk":
+		return m.riskOrder(dirs)
+	case "recent-failures-first":
+		ordered, first := recentFailuresFirst(dirs, m.lastFailures())
+		m.signal = newOrderSignal("recently failed", ordered[:first])
+		return ordered
+	case "changed-first":
+		ordered, first := changedFirst(dirs, m.changedDirs())
+		m.signal = newOrderSignal("changed", ordered[:first])
+		return ordered
+	}
+	return dirs
+}
+
+// lastFailures is when each directory last failed, from -timings
+func (m *gocoverdir) lastFailures() map[string]time.Time {
+	if m.args.timings == "" {
+		return nil
+	}
+	timings, err := readTimingsFile(m.args.timings)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			m.log.Printf("Unable to read failures from %s: %s", m.args.timings, err)
+		}
+		return nil
+	}
+	return timings.Failed
+}
+
+// recentFailuresFirst moves the directories that failed before to the front, the most recent failure first.  It
+// returns how many there are
+func recentFailuresFirst(dirs []string, failed map[string]time.Time) ([]string, int) {
+	ret := append([]string(nil), dirs...)
+	sort.SliceStable(ret, func(i, j int) bool {
+		return failed[ret[i]].After(failed[ret[j]])
+	})
+	first := 0
+	for _, dir := range ret {
+		if _, exists := failed[dir]; exists {
+			first++
+		}
+	}
+	return ret, first
+}
+
+// changedDirs are the directories, relative to the working directory, with lines changed since -diffbase, or with
+// uncommitted changes without it
+func (m *gocoverdir) changedDirs() map[string]struct{} {
+	base := m.args.diffbase
+	if base == "" {
+		base = "HEAD"
+	}
+	changed, err := m.repo.changedLines(base)
+	if err != nil {
+		m.log.Printf("Unable to find changed packages: %s", err)
+		return nil
+	}
+	prefix, err := repoPrefix(m.repo.root())
+	if err != nil {
+		m.log.Printf("Unable to find changed packages: %s", err)
+		return nil
+	}
+	ret := make(map[string]struct{})
+	for file := range changed {
+		dir, err := filepath.Rel(prefix, filepath.Dir(filepath.FromSlash(file)))
+		if err == nil && !strings.HasPrefix(dir, "..") {
+			ret[dir] = struct{}{}
+		}
+	}
+	return ret
+}
+
+// changedFirst moves the changed directories to the front, keeping the order of both groups.  It returns how many
+// there are
+func changedFirst(dirs []string, changed map[string]struct{}) ([]string, int) {
+	ret := make([]string, 0, len(dirs))
+	var rest []string
+	for _, dir := range dirs {
+		if _, exists := changed[filepath.Clean(dir)]; exists {
+			ret = append(ret, dir)
+		} else {
+			rest = append(rest, dir)
+		}
+	}
+	return append(ret, rest...), len(ret)
+}
+
+// orderSignal reports as soon as the packages -order put first are done, which is the early red or green signal
+// the ordering is for, while the rest of the run goes on for coverage
+type orderSignal struct {
+	label   string
+	total   int
+	pending map[string]struct{}
+	start   time.Time
+}
+
+func newOrderSignal(label string, first []string) *orderSignal {
+	if len(first) == 0 {
+		return nil
+	}
+	ret := &orderSignal{label: label, total: len(first), pending: make(map[string]struct{}, len(first)), start: time.Now()}
+	for _, dir := range first {
+		ret.pending[dir] = struct{}{}
+	}
+	return ret
+}
+
+// done marks dir finished, logging once the last of the first packages is
+func (s *orderSignal) done(m *gocoverdir, dir string) {
+	if s == nil {
+		return
+	}
+	if _, exists := s.pending[dir]; !exists {
+		return
+	}
+	delete(s.pending, dir)
+	for _, result := range m.results {
+		if result.dir == dir && result.err != nil && !result.quarantined {
+			m.log.Printf("Signal: %s package %s failed after %s", s.label, dir, time.Since(s.start).Round(time.Millisecond))
+			s.pending = nil
+			return
+		}
+	}
+	if len(s.pending) == 0 {
+		m.log.Printf("Signal: all %d %s packages passed after %s", s.total, s.label, time.Since(s.start).Round(time.Millisecond))
+	}
+}
+
+// heaviestFirst sorts dirs by estimated cost, most expensive first
+func heaviestFirst(dirs []string, costs map[string]float64) []string {
+	ret := append([]string(nil), dirs...)
+	sort.SliceStable(ret, func(i, j int) bool {
+		return costs[ret[i]] > costs[ret[j]]
+	})
+	return ret
+}
+
+func readProcValue(filename string, key string) (float64, bool) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if key == "" && len(fields) > 0 {
+			value, err := strconv.ParseFloat(fields[0], 64)
+			return value, err == nil
+		}
+		if len(fields) >= 2 && fields[0] == key {
+			value, err := strconv.ParseFloat(fields[1], 64)
+			return value, err == nil
+		}
+	}
+	return 0, false
+}
+
+// overloaded is true if the load average is over the CPU count or less than a tenth of memory is available.  It is
+// always false where /proc does not exist
+func overloaded() bool {
+	if load, exists := readProcValue("/proc/loadavg", ""); exists && load > float64(runtime.NumCPU()) {
+		return true
+	}
+	total, hasTotal := readProcValue("/proc/meminfo", "MemTotal:")
+	available, hasAvailable := readProcValue("/proc/meminfo", "MemAvailable:")
+	return hasTotal && hasAvailable && available < total/10
+}
+
+// serialMarker in any Go file of a directory, on its own line, keeps its tests from running alongside other packages
+const serialMarker = "//gocoverdir:serial"
+
+func hasSerialMarker(dir string) bool {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".go") {
+			continue
+		}
+		contents, err := ioutil.ReadFile(filepath.Join(dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		for _, line := range strings.Split(string(contents), "\n") {
+			if strings.TrimSpace(line) == serialMarker {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSerial is true if dir is marked serial by the config or a serialMarker
+func (m *gocoverdir) isSerial(dir string) bool {
+	if m.config != nil {
+		for _, pattern := range m.config.Serial {
+			if matchPackage(pattern, dir) {
+				return true
+			}
+		}
+	}
+	return hasSerialMarker(dir)
+}
+
+// coverParallel tests up to workers directories at once, heaviest first.  No new package starts while the machine
+// is overloaded, unless nothing else is running.  Serial directories run afterwards, one at a time.
+func (m *gocoverdir) coverParallel(dirs []string, workers int) error {
+	var parallel, serial []string
+	for _, dir := range dirs {
+		if m.isSerial(dir) {
+			serial = append(serial, dir)
+		} else {
+			parallel = append(parallel, dir)
+		}
+	}
+	if err := m.coverConcurrently(parallel, workers); err != nil {
+		return err
+	}
+	for _, dir := range serial {
+		m.log.Printf("Testing serial package %s alone", dir)
+		results, err := m.coverDir(dir)
+		m.results = append(m.results, results...)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *gocoverdir) coverConcurrently(dirs []string, workers int) error {
+	queue := dirs
+	if m.args.order == "" {
+		queue = heaviestFirst(dirs, estimateCosts(dirs, m.lastTimings(), dirStatements))
+	}
+	m.log.Printf("Testing %d packages with up to %d at once", len(queue), workers)
+	eta := m.newETA(dirs, workers)
+	type dirResult struct {
+		dir     string
+		results []packageResult
+		err     error
+	}
+	done := make(chan dirResult, workers)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	running := 0
+	var firstErr error
+	for running > 0 || (len(queue) > 0 && firstErr == nil) {
+		if firstErr == nil && len(queue) > 0 && running < workers && (running == 0 || !overloaded()) {
+			running++
+			go func(dir string) {
+				results, err := m.coverDir(dir)
+				done <- dirResult{dir: dir, results: results, err: err}
+			}(queue[0])
+			queue = queue[1:]
+			continue
+		}
+		select {
+		case result := <-done:
+			running--
+			m.results = append(m.results, result.results...)
+			m.logProgress(eta, result.dir)
+			if result.err != nil && firstErr == nil {
+				firstErr = result.err
+			}
+		case <-ticker.C:
+		}
+	}
+	return firstErr
+}
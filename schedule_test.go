@@ -0,0 +1,115 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseParallel(t *testing.T) {
+	workers, err := parseParallel("3")
+	noError(t, err)
+	if workers != 3 {
+		t.Errorf("Expected 3 workers, saw %d", workers)
+	}
+	if workers, err := parseParallel("auto"); err != nil || workers < 1 {
+		t.Errorf("Unexpected auto workers %d %v", workers, err)
+	}
+	if _, err := parseParallel("0"); err == nil {
+		t.Errorf("Expected an error for 0 workers")
+	}
+}
+
+func TestEstimateCosts(t *testing.T) {
+	stmts := map[string]int{"a": 10, "b": 100, "c": 50}
+	costs := estimateCosts([]string{"a", "b", "c"}, map[string]float64{"a": 20, "c": 5}, func(dir string) int {
+		return stmts[dir]
+	})
+	// 25 seconds over 60 timed statements
+	if costs["a"] != 20 || costs["c"] != 5 || costs["b"] < 41.6 || costs["b"] > 41.7 {
+		t.Errorf("Unexpected costs %v", costs)
+	}
+	if order := strings.Join(heaviestFirst([]string{"a", "b", "c"}, costs), ","); order != "b,a,c" {
+		t.Errorf("Unexpected order %s", order)
+	}
+}
+
+func TestDirStatements(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDirStatements")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n\nfunc A(x int) int {\n\tif x > 0 {\n\t\treturn 1\n\t}\n\treturn 0\n}\n"), 0644))
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a_test.go"), []byte("package a\n\nfunc init() {\n\tA(1)\n}\n"), 0644))
+	if count := dirStatements(dir); count != 3 {
+		t.Errorf("Expected 3 statements, saw %d", count)
+	}
+}
+
+func TestIsSerial(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestIsSerial")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	marked := filepath.Join(dir, "db")
+	noError(t, os.Mkdir(marked, 0755))
+	noError(t, ioutil.WriteFile(filepath.Join(marked, "db_test.go"), []byte("package db\n\n//gocoverdir:serial\n"), 0644))
+	m := &gocoverdir{config: &config{Serial: []string{"./ports/..."}}}
+	if !m.isSerial(marked) {
+		t.Errorf("Expected the marker to make %s serial", marked)
+	}
+	if !m.isSerial("ports/http") {
+		t.Errorf("Expected the config to make ports/http serial")
+	}
+	if m.isSerial(dir) {
+		t.Errorf("Did not expect %s to be serial", dir)
+	}
+}
+
+func TestRecentFailuresFirst(t *testing.T) {
+	now := time.Now()
+	ordered, first := recentFailuresFirst([]string{"a", "b", "c", "d"}, map[string]time.Time{"b": now.Add(-time.Hour), "d": now})
+	if !reflect.DeepEqual(ordered, []string{"d", "b", "a", "c"}) || first != 2 {
+		t.Errorf("Unexpected order %v %d", ordered, first)
+	}
+}
+
+func TestChangedFirst(t *testing.T) {
+	ordered, first := changedFirst([]string{"./a", "./b", "./c"}, map[string]struct{}{"c": {}, "e": {}})
+	if !reflect.DeepEqual(ordered, []string{"./c", "./a", "./b"}) || first != 1 {
+		t.Errorf("Unexpected order %v %d", ordered, first)
+	}
+}
+
+func TestOrderSignal(t *testing.T) {
+	var buf bytes.Buffer
+	m := &gocoverdir{log: log.New(&buf, "", 0)}
+	s := newOrderSignal("changed", []string{"a", "b"})
+	m.results = []packageResult{{dir: "a"}}
+	s.done(m, "a")
+	s.done(m, "c")
+	if buf.Len() != 0 {
+		t.Errorf("Expected no signal before b is done, got %s", buf.String())
+	}
+	s.done(m, "b")
+	if !strings.Contains(buf.String(), "all 2 changed packages passed") {
+		t.Errorf("Expected a pass signal, got %s", buf.String())
+	}
+
+	buf.Reset()
+	s = newOrderSignal("recently failed", []string{"a", "b"})
+	m.results = []packageResult{{dir: "a", err: errors.New("failed")}}
+	s.done(m, "a")
+	s.done(m, "b")
+	if !strings.HasPrefix(buf.String(), "Signal: recently failed package a failed") || strings.Count(buf.String(), "\n") != 1 {
+		t.Errorf("Expected one fail signal, got %s", buf.String())
+	}
+	if newOrderSignal("changed", nil) != nil {
+		t.Errorf("Expected no signal without packages first")
+	}
+}
@@ -0,0 +1,237 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// reportSignature is the detached signature -sign writes next to the JSON report
+type reportSignature struct {
+	Algorithm string `json:"algorithm"`
+	// KeyID is the start of the SHA-256 of the public key, so a gate trusting several keys knows which one to use
+	KeyID     string `json:"key_id"`
+	Signature []byte `json:"signature"`
+}
+
+// signatureFile is where the signature of the report written to filename goes
+func signatureFile(filename string) string {
+	if filename == "" {
+		return ""
+	}
+	return filename + ".sig"
+}
+
+// loadSigner reads a PEM private key: PKCS#8, or the PKCS#1 RSA and SEC 1 EC keys openssl writes by default
+func loadSigner(filename string) (crypto.Signer, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", filename)
+	}
+	var key interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the private key in %s: %s", filename, err)
+	}
+	signer, isSigner := key.(crypto.Signer)
+	if !isSigner {
+		return nil, fmt.Errorf("%s holds a %T, which cannot sign", filename, key)
+	}
+	if _, err := signatureAlgorithm(signer.Public()); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+	return signer, nil
+}
+
+// loadPublicKey reads a PEM public key, or takes it from a certificate
+func loadPublicKey(filename string) (crypto.PublicKey, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(contents)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not a PEM file", filename)
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse the public key in %s: %s", filename, err)
+	}
+	return key, nil
+}
+
+func signatureAlgorithm(pub crypto.PublicKey) (string, error) {
+	switch pub.(type) {
+	case ed25519.PublicKey:
+		return "ed25519", nil
+	case *ecdsa.PublicKey:
+		return "ecdsa-sha256", nil
+	case *rsa.PublicKey:
+		return "rsa-pkcs1v15-sha256", nil
+	}
+	return "", fmt.Errorf("unsupported key type %T: use an Ed25519, ECDSA or RSA key", pub)
+}
+
+func keyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// signReport signs contents, the bytes of a JSON report
+func signReport(signer crypto.Signer, contents []byte) (*reportSignature, error) {
+	algorithm, err := signatureAlgorithm(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	id, err := keyID(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	digest, opts := contents, crypto.SignerOpts(crypto.Hash(0))
+	if algorithm != "ed25519" {
+		sum := sha256.Sum256(contents)
+		digest, opts = sum[:], crypto.SHA256
+	}
+	signature, err := signer.Sign(rand.Reader, digest, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &reportSignature{Algorithm: algorithm, KeyID: id, Signature: signature}, nil
+}
+
+// verifySignature checks that sig was made over contents by the private key of pub
+func verifySignature(pub crypto.PublicKey, contents []byte, sig *reportSignature) error {
+	algorithm, err := signatureAlgorithm(pub)
+	if err != nil {
+		return err
+	}
+	if sig.Algorithm != algorithm {
+		return fmt.Errorf("the report is signed with %s, but the key is for %s", sig.Algorithm, algorithm)
+	}
+	if id, err := keyID(pub); err == nil && sig.KeyID != id {
+		return fmt.Errorf("the report is signed by key %s, not %s", sig.KeyID, id)
+	}
+	sum := sha256.Sum256(contents)
+	valid := false
+	switch key := pub.(type) {
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, contents, sig.Signature)
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, sum[:], sig.Signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig.Signature) == nil
+	}
+	if !valid {
+		return fmt.Errorf("the signature does not match: the report was changed after it was signed")
+	}
+	return nil
+}
+
+// writeJSONReport writes r to -json, and its signature next to it with -sign
+func (m *gocoverdir) writeJSONReport(r *report) error {
+	var buf bytes.Buffer
+	if err := writeReport(&buf, r); err != nil {
+		return err
+	}
+	if err := writeFileWith(m.args.json, func(w io.Writer) error {
+		_, err := w.Write(buf.Bytes())
+		return err
+	}); err != nil {
+		return err
+	}
+	if m.signer == nil {
+		return nil
+	}
+	sig, err := signReport(m.signer, buf.Bytes())
+	if err != nil {
+		return err
+	}
+	m.log.Printf("Signed %s with key %s", m.args.json, sig.KeyID)
+	return writeFileWith(signatureFile(m.args.json), func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sig)
+	})
+}
+
+// runVerifyReport checks a JSON report against the signature -sign wrote for it, so release gates can trust its numbers
+func runVerifyReport(args []string) error {
+	fs := flag.NewFlagSet("verify-report", flag.ContinueOnError)
+	keyFile := fs.String("key", "", "PEM public key, or certificate, of the key the report was signed with")
+	sigFile := fs.String("sig", "", "Signature file.  Defaults to the report with .sig appended")
+	files, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 || *keyFile == "" {
+		return fmt.Errorf("usage: gocoverdir verify-report -key public.pem [-sig report.json.sig] report.json")
+	}
+	pub, err := loadPublicKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	if *sigFile == "" {
+		*sigFile = signatureFile(strings.TrimSuffix(files[0], ".gz"))
+	}
+	sigContents, err := ioutil.ReadFile(*sigFile)
+	if err != nil {
+		return err
+	}
+	var sig reportSignature
+	if err := json.Unmarshal(sigContents, &sig); err != nil {
+		return fmt.Errorf("cannot parse signature %s: %s", *sigFile, err)
+	}
+	f, err := openInput(files[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := maybeGunzip(f)
+	if err != nil {
+		return err
+	}
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := verifySignature(pub, contents, &sig); err != nil {
+		return fmt.Errorf("%s: %s", files[0], err)
+	}
+	fmt.Printf("%s: signed by key %s\n", files[0], sig.KeyID)
+	return nil
+}
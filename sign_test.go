@@ -0,0 +1,74 @@
+package gocoverdir
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSignReport(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	noError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	noError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	noError(t, err)
+	contents := []byte(`{"coverage": 81.5}` + "\n")
+	for _, signer := range []crypto.Signer{edKey, ecKey, rsaKey} {
+		sig, err := signReport(signer, contents)
+		noError(t, err)
+		if err := verifySignature(signer.Public(), contents, sig); err != nil {
+			t.Errorf("%s: %s", sig.Algorithm, err)
+		}
+		if err := verifySignature(signer.Public(), []byte(`{"coverage": 91.5}`+"\n"), sig); err == nil {
+			t.Errorf("%s: expected an edited report to fail verification", sig.Algorithm)
+		}
+	}
+	sig, err := signReport(edKey, contents)
+	noError(t, err)
+	if err := verifySignature(ecKey.Public(), contents, sig); err == nil {
+		t.Errorf("Expected the wrong key to fail verification")
+	}
+}
+
+func TestWriteJSONReportSigned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdir")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	noError(t, err)
+	der, err := x509.MarshalECPrivateKey(ecKey)
+	noError(t, err)
+	privateFile := filepath.Join(dir, "key.pem")
+	noError(t, ioutil.WriteFile(privateFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+	der, err = x509.MarshalPKIXPublicKey(ecKey.Public())
+	noError(t, err)
+	publicFile := filepath.Join(dir, "public.pem")
+	noError(t, ioutil.WriteFile(publicFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0644))
+
+	m := gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	m.args.json = filepath.Join(dir, "report.json")
+	m.signer, err = loadSigner(privateFile)
+	noError(t, err)
+	noError(t, m.writeJSONReport(&report{Coverage: 81.5}))
+	noError(t, runVerifyReport([]string{"-key", publicFile, m.args.json}))
+
+	noError(t, gzipFile(m.args.json))
+	noError(t, runVerifyReport([]string{m.args.json + ".gz", "-key", publicFile}))
+
+	noError(t, m.writeJSONReport(&report{Coverage: 81.5}))
+	noError(t, ioutil.WriteFile(m.args.json, []byte(`{"coverage": 99}`), 0644))
+	if err := runVerifyReport([]string{"-key", publicFile, m.args.json}); err == nil {
+		t.Errorf("Expected an edited report to fail verification")
+	}
+}
@@ -0,0 +1,75 @@
+package gocoverdir
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// countChanges counts the commits touching each directory in 'git log --name-only' output, where each commit's
+// files follow a line that is just "commit"
+func countChanges(log string) map[string]int {
+	ret := make(map[string]int)
+	seen := make(map[string]struct{})
+	for _, line := range strings.Split(log, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if line == "commit" {
+			seen = make(map[string]struct{})
+			continue
+		}
+		dir := filepath.Dir(filepath.FromSlash(line))
+		if _, exists := seen[dir]; !exists {
+			seen[dir] = struct{}{}
+			ret[dir]++
+		}
+	}
+	return ret
+}
+
+// lastCoverages returns each directory's coverage in the newest -history entry
+func (m *gocoverdir) lastCoverages(dirs []string) map[string]float64 {
+	if m.args.history == "" {
+		return nil
+	}
+	entries, err := readHistory(m.args.history)
+	if err != nil || len(entries) == 0 {
+		return nil
+	}
+	latest := entries[len(entries)-1]
+	ret := make(map[string]float64, len(dirs))
+	for dir, importPath := range m.dirImportPaths(dirs) {
+		if coverage, exists := latest.Packages[importPath]; exists {
+			ret[dir] = coverage
+		}
+	}
+	return ret
+}
+
+// coverSmoke tests directories in -order, or riskiest first, and stops starting new ones once -maxruntime would be
+// exceeded.  Directories left out are reported as skipped, so the partial coverage says what it covers
+func (m *gocoverdir) coverSmoke(dirs []string) error {
+	start := time.Now()
+	lastSeconds := m.lastTimings()
+	tested := 0
+	if m.args.order == "" {
+		dirs = m.riskOrder(dirs)
+	}
+	for _, dir := range dirs {
+		expected := time.Duration(lastSeconds[dir] * float64(time.Second))
+		if time.Since(start)+expected > m.args.maxruntime {
+			m.skip(dir, "does not fit in the -maxruntime smoke time box")
+			continue
+		}
+		results, err := m.coverDir(dir)
+		m.results = append(m.results, results...)
+		if err != nil {
+			return err
+		}
+		tested++
+	}
+	m.log.Printf("Smoke run tested %d of %d packages in %s", tested, len(dirs), time.Since(start))
+	return nil
+}
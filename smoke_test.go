@@ -0,0 +1,14 @@
+package gocoverdir
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCountChanges(t *testing.T) {
+	log := "commit\n\na/a.go\na/b.go\nmain.go\ncommit\n\na/a.go\nb/c/d.go\n"
+	expected := map[string]int{"a": 2, ".": 1, "b/c": 1}
+	if changes := countChanges(log); !reflect.DeepEqual(changes, expected) {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+}
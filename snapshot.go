@@ -0,0 +1,247 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	snapshotProfile = "coverage.out"
+	snapshotReport  = "coverage.json"
+)
+
+// snapshotDir is where the snapshot tagged tag is kept in store.  Tags like release/1.2 are escaped into one directory
+func snapshotDir(store string, tag string) (string, error) {
+	if tag == "" || tag == "." || tag == ".." {
+		return "", fmt.Errorf("invalid snapshot tag %q", tag)
+	}
+	return filepath.Join(store, url.PathEscape(tag)), nil
+}
+
+// copySnapshotFile copies from, decompressing it if gzipped, to to
+func copySnapshotFile(from string, to string) error {
+	f, err := openInput(from)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	r, err := maybeGunzip(f)
+	if err != nil {
+		return err
+	}
+	return writeFileWith(to, func(w io.Writer) error {
+		_, err := io.Copy(w, r)
+		return err
+	})
+}
+
+// saveSnapshot stores the merged profile and JSON report of a run in store under tag
+func saveSnapshot(store string, tag string, profile string, reportFile string, force bool) error {
+	dir, err := snapshotDir(store, tag)
+	if err != nil {
+		return err
+	}
+	if _, err := readProfiles(profile); err != nil {
+		return fmt.Errorf("cannot read profile %s: %s", profile, err)
+	}
+	if _, err := os.Stat(dir); err == nil && !force {
+		return fmt.Errorf("snapshot %s already exists in %s: use -f to replace it", tag, store)
+	}
+	// Writing into a new directory first means a failed save never leaves half a snapshot behind
+	if err := os.MkdirAll(store, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(store, ".snapshot")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	if err := copySnapshotFile(profile, filepath.Join(tmp, snapshotProfile)); err != nil {
+		return err
+	}
+	if reportFile != "" {
+		if err := copySnapshotFile(reportFile, filepath.Join(tmp, snapshotReport)); err != nil {
+			return err
+		}
+		if _, err := readReport(filepath.Join(tmp, snapshotReport)); err != nil {
+			return err
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dir)
+}
+
+// getSnapshot copies the snapshot tagged tag out of store.  Empty destinations are skipped
+func getSnapshot(store string, tag string, profile string, reportFile string) error {
+	dir, err := snapshotDir(store, tag)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return fmt.Errorf("no snapshot %s in %s", tag, store)
+	}
+	if profile != "" {
+		if err := copySnapshotFile(filepath.Join(dir, snapshotProfile), profile); err != nil {
+			return err
+		}
+	}
+	if reportFile != "" {
+		if err := copySnapshotFile(filepath.Join(dir, snapshotReport), reportFile); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("snapshot %s was saved without a report", tag)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotInfo describes one saved snapshot
+type snapshotInfo struct {
+	Tag   string    `json:"tag"`
+	Saved time.Time `json:"saved"`
+	// HasReport is false if the snapshot was saved without a JSON report, which leaves Commit and Coverage empty
+	HasReport bool    `json:"has_report"`
+	Commit    string  `json:"commit,omitempty"`
+	Coverage  float64 `json:"coverage,omitempty"`
+}
+
+// listSnapshots returns the snapshots in store, newest first
+func listSnapshots(store string) ([]snapshotInfo, error) {
+	entries, err := ioutil.ReadDir(store)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ret []snapshotInfo
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		tag, err := url.PathUnescape(entry.Name())
+		if err != nil {
+			continue
+		}
+		profile, err := os.Stat(filepath.Join(store, entry.Name(), snapshotProfile))
+		if err != nil {
+			continue
+		}
+		info := snapshotInfo{Tag: tag, Saved: profile.ModTime()}
+		if r, err := readReport(filepath.Join(store, entry.Name(), snapshotReport)); err == nil {
+			info.HasReport = true
+			info.Coverage = r.Coverage
+			if r.CI != nil {
+				info.Commit = r.CI.Commit
+			}
+		}
+		ret = append(ret, info)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if !ret[i].Saved.Equal(ret[j].Saved) {
+			return ret[i].Saved.After(ret[j].Saved)
+		}
+		return ret[i].Tag < ret[j].Tag
+	})
+	return ret, nil
+}
+
+func writeSnapshotList(w io.Writer, snapshots []snapshotInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "TAG\tSAVED\tCOMMIT\tCOVERAGE\n")
+	for _, snapshot := range snapshots {
+		commit, coverage := "-", "-"
+		if snapshot.Commit != "" {
+			commit = snapshot.Commit
+		}
+		if snapshot.HasReport {
+			coverage = fmt.Sprintf("%.1f%%", snapshot.Coverage)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", snapshot.Tag, snapshot.Saved.Format(time.RFC3339), commit, coverage)
+	}
+	return tw.Flush()
+}
+
+// runSnapshot keeps named merged profiles and reports, so releases can be compared by tag
+func runSnapshot(args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "save":
+			return runSnapshotSave(args[1:])
+		case "get":
+			return runSnapshotGet(args[1:])
+		case "list":
+			return runSnapshotList(args[1:])
+		}
+	}
+	return fmt.Errorf("usage: gocoverdir snapshot save|get|list [flags]")
+}
+
+func runSnapshotSave(args []string) error {
+	fs := flag.NewFlagSet("snapshot save", flag.ContinueOnError)
+	store := fs.String("store", "gocoverdir-snapshots", "Directory snapshots are kept in")
+	profile := fs.String("coverprofile", "coverage.out", "Merged profile to save, as written by -coverprofile")
+	reportFile := fs.String("json", "", "JSON report to save with the profile, as written by -json")
+	force := fs.Bool("f", false, "If true, replace an existing snapshot with the same tag")
+	tags, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(tags) != 1 {
+		return fmt.Errorf("usage: gocoverdir snapshot save [-store dir] [-coverprofile coverage.out] [-json coverage.json] [-f] tag")
+	}
+	if err := saveSnapshot(*store, tags[0], *profile, *reportFile, *force); err != nil {
+		return err
+	}
+	fmt.Printf("Saved snapshot %s\n", tags[0])
+	return nil
+}
+
+func runSnapshotGet(args []string) error {
+	fs := flag.NewFlagSet("snapshot get", flag.ContinueOnError)
+	store := fs.String("store", "gocoverdir-snapshots", "Directory snapshots are kept in")
+	profile := fs.String("coverprofile", "", "Where to write the snapshot's profile.  Defaults to tag.out")
+	reportFile := fs.String("json", "", "Where to write the snapshot's JSON report, if wanted")
+	tags, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(tags) != 1 {
+		return fmt.Errorf("usage: gocoverdir snapshot get [-store dir] [-coverprofile out] [-json report.json] tag")
+	}
+	if *profile == "" {
+		*profile = url.PathEscape(tags[0]) + ".out"
+	}
+	return getSnapshot(*store, tags[0], *profile, *reportFile)
+}
+
+func runSnapshotList(args []string) error {
+	fs := flag.NewFlagSet("snapshot list", flag.ContinueOnError)
+	store := fs.String("store", "gocoverdir-snapshots", "Directory snapshots are kept in")
+	asJSON := fs.Bool("json", false, "If true, list the snapshots as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	snapshots, err := listSnapshots(*store)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(snapshots)
+	}
+	return writeSnapshotList(os.Stdout, snapshots)
+}
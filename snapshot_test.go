@@ -0,0 +1,54 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshots(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdir")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	profile := filepath.Join(dir, "coverage.out")
+	noError(t, ioutil.WriteFile(profile, []byte("mode: set\nexample.com/a/a.go:1.1,2.2 1 1\n"), 0644))
+	reportFile := filepath.Join(dir, "coverage.json")
+	noError(t, ioutil.WriteFile(reportFile, []byte(`{"coverage": 81.5, "ci": {"commit": "abc123"}}`), 0644))
+	store := filepath.Join(dir, "snapshots")
+
+	noError(t, saveSnapshot(store, "release/1.2", profile, reportFile, false))
+	if err := saveSnapshot(store, "release/1.2", profile, "", false); err == nil {
+		t.Errorf("Expected an existing snapshot to be kept")
+	}
+	noError(t, saveSnapshot(store, "v1.3.0", profile, "", false))
+	if err := saveSnapshot(store, "..", profile, "", false); err == nil {
+		t.Errorf("Expected .. to be refused as a tag")
+	}
+
+	out := filepath.Join(dir, "out.out")
+	outReport := filepath.Join(dir, "out.json")
+	noError(t, getSnapshot(store, "release/1.2", out, outReport))
+	if profiles, err := readProfiles(out); err != nil || len(profiles) != 1 {
+		t.Errorf("Unexpected profile %v %v", profiles, err)
+	}
+	if r, err := readReport(outReport); err != nil || r.Coverage != 81.5 {
+		t.Errorf("Unexpected report %v %v", r, err)
+	}
+	if err := getSnapshot(store, "v1.3.0", "", outReport); err == nil {
+		t.Errorf("Expected an error getting the report of a snapshot saved without one")
+	}
+	if err := getSnapshot(store, "v9", out, ""); err == nil {
+		t.Errorf("Expected an error getting a missing snapshot")
+	}
+
+	snapshots, err := listSnapshots(store)
+	noError(t, err)
+	tags := make(map[string]snapshotInfo)
+	for _, snapshot := range snapshots {
+		tags[snapshot.Tag] = snapshot
+	}
+	if len(snapshots) != 2 || tags["release/1.2"].Commit != "abc123" || !tags["release/1.2"].HasReport || tags["v1.3.0"].HasReport {
+		t.Errorf("Unexpected snapshots %+v", snapshots)
+	}
+}
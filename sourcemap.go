@@ -0,0 +1,128 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// sourceMapFile lists, in a package directory, the templates or specs its generated files come from.  Each line is a
+// generated file and its source, relative to the directory, like
+//
+//	query.sql.go ../../sql/query.sql
+const sourceMapFile = ".gocovermap"
+
+// sourceMapRule maps generated files, by a regular expression over their report path, to the source they are
+// generated from.  Source can use the expression's groups, like $1
+type sourceMapRule struct {
+	Generated string `json:"generated"`
+	Source    string `json:"source"`
+	generated *regexp.Regexp
+}
+
+func compileSourceMap(rules []sourceMapRule) error {
+	for i := range rules {
+		re, err := regexp.Compile(rules[i].Generated)
+		if err != nil {
+			return fmt.Errorf("invalid sourcemap rule %q: %s", rules[i].Generated, err)
+		}
+		if rules[i].Source == "" {
+			return fmt.Errorf("sourcemap rule %q has no source", rules[i].Generated)
+		}
+		rules[i].generated = re
+	}
+	return nil
+}
+
+// readSourceMapFile reads the .gocovermap of dir, keyed by generated file name.  A missing file is an empty map
+func readSourceMapFile(dir string) (map[string]string, error) {
+	f, err := os.Open(filepath.Join(dir, sourceMapFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+	ret := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected a generated file and its source", f.Name(), lineNumber)
+		}
+		ret[fields[0]] = fields[1]
+	}
+	return ret, scanner.Err()
+}
+
+// mappedSource is the source p's file is generated from, named like reports name files, or "" if it is not generated.
+// A .gocovermap entry wins over the config's rules, which are tried in order
+func mappedSource(p *cover.Profile, loc *fileLocator, rules []sourceMapRule, mapFiles map[string]map[string]string) string {
+	if dir, exists := loc.pkgDirs[path.Dir(p.FileName)]; exists {
+		entries, read := mapFiles[dir]
+		if !read {
+			var err error
+			if entries, err = readSourceMapFile(dir); err != nil {
+				entries = nil
+			}
+			mapFiles[dir] = entries
+		}
+		if source, exists := entries[path.Base(p.FileName)]; exists {
+			full := filepath.Join(dir, filepath.FromSlash(source))
+			if loc.absolute {
+				return filepath.ToSlash(full)
+			}
+			if rel, err := filepath.Rel(loc.root, full); err == nil {
+				return filepath.ToSlash(rel)
+			}
+			return filepath.ToSlash(full)
+		}
+	}
+	name := loc.name(p)
+	for _, rule := range rules {
+		if match := rule.generated.FindStringSubmatchIndex(name); match != nil {
+			return string(rule.generated.ExpandString(nil, rule.Source, name, match))
+		}
+	}
+	return ""
+}
+
+// sourceCoverages re-attributes the statements of generated files to the templates or specs they come from, sorted by
+// source
+func sourceCoverages(profiles []*cover.Profile, metric string, loc *fileLocator, rules []sourceMapRule) []reportPackage {
+	bySource := make(map[string]*reportPackage)
+	mapFiles := make(map[string]map[string]string)
+	for _, p := range profiles {
+		source := mappedSource(p, loc, rules, mapFiles)
+		if source == "" {
+			continue
+		}
+		total, covered := countStatements(p, metric)
+		if bySource[source] == nil {
+			bySource[source] = &reportPackage{Path: source}
+		}
+		bySource[source].Statements += total
+		bySource[source].Covered += covered
+	}
+	var ret []reportPackage
+	for _, source := range bySource {
+		source.Coverage = lineRate(source.Covered, source.Statements) * 100
+		ret = append(ret, *source)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Path < ret[j].Path
+	})
+	return ret
+}
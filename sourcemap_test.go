@@ -0,0 +1,40 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestSourceCoverages(t *testing.T) {
+	root, err := ioutil.TempDir("", "gocoverdir")
+	noError(t, err)
+	defer os.RemoveAll(root)
+	apiDir := filepath.Join(root, "api")
+	noError(t, os.Mkdir(apiDir, 0755))
+	noError(t, ioutil.WriteFile(filepath.Join(apiDir, sourceMapFile), []byte("# oapi-codegen\nserver.gen.go ../spec/openapi.yaml\ntypes.gen.go ../spec/openapi.yaml\n"), 0644))
+
+	rules := []sourceMapRule{{Generated: `^db/(\w+)\.sql\.go$`, Source: "sql/$1.sql"}}
+	noError(t, compileSourceMap(rules))
+	loc := &fileLocator{pkgDirs: map[string]string{"example.com/api": apiDir, "example.com/db": filepath.Join(root, "db")}, root: root}
+	profiles := []*cover.Profile{
+		{FileName: "example.com/api/server.gen.go", Blocks: []cover.ProfileBlock{{NumStmt: 3, Count: 1}, {NumStmt: 1}}},
+		{FileName: "example.com/api/types.gen.go", Blocks: []cover.ProfileBlock{{NumStmt: 4, Count: 1}}},
+		{FileName: "example.com/api/handler.go", Blocks: []cover.ProfileBlock{{NumStmt: 10}}},
+		{FileName: "example.com/db/users.sql.go", Blocks: []cover.ProfileBlock{{NumStmt: 2, Count: 1}, {NumStmt: 2}}},
+	}
+	expected := []reportPackage{
+		{Path: "spec/openapi.yaml", Statements: 8, Covered: 7, Coverage: 87.5},
+		{Path: "sql/users.sql", Statements: 4, Covered: 2, Coverage: 50},
+	}
+	if sources := sourceCoverages(profiles, "statements", loc, rules); !reflect.DeepEqual(sources, expected) {
+		t.Errorf("Unexpected sources %+v", sources)
+	}
+	if err := compileSourceMap([]sourceMapRule{{Generated: `\.pb\.go$`}}); err == nil {
+		t.Errorf("Expected a rule without a source to be refused")
+	}
+}
@@ -0,0 +1,109 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// finding is one problem reported by the -staticcheck pass
+type finding struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity,omitempty"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (f finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", f.File, f.Line, f.Column, f.Message, f.Code)
+}
+
+// parseStaticcheck reads the output of 'staticcheck -f json', making file names relative to dir
+func parseStaticcheck(r io.Reader, dir string) ([]finding, error) {
+	var ret []finding
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var problem struct {
+			Code     string `json:"code"`
+			Severity string `json:"severity"`
+			Location struct {
+				File   string `json:"file"`
+				Line   int    `json:"line"`
+				Column int    `json:"column"`
+			} `json:"location"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(line, &problem); err != nil {
+			return nil, fmt.Errorf("cannot parse staticcheck output %q: %s", line, err)
+		}
+		file := problem.Location.File
+		if rel, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = filepath.ToSlash(rel)
+		}
+		ret = append(ret, finding{
+			Code:     problem.Code,
+			Severity: problem.Severity,
+			File:     file,
+			Line:     problem.Location.Line,
+			Column:   problem.Location.Column,
+			Message:  problem.Message,
+		})
+	}
+	return ret, scanner.Err()
+}
+
+// runStaticcheck runs staticcheck on every tested directory and keeps its findings for the reports
+func (m *gocoverdir) runStaticcheck(dirs []string) error {
+	if len(dirs) == 0 {
+		return nil
+	}
+	args := []string{"-f", "json"}
+	for _, dir := range dirs {
+		args = append(args, "./"+dir)
+	}
+	var stdout bytes.Buffer
+	cmd := exec.Command("staticcheck", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = m.testOutputStderr
+	m.log.Printf("Executing %s", strings.Join(cmd.Args, " "))
+	runErr := cmd.Run()
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if m.findings, err = parseStaticcheck(&stdout, cwd); err != nil {
+		return err
+	}
+	// staticcheck exits non zero when it has findings
+	if runErr != nil && len(m.findings) == 0 {
+		return fmt.Errorf("staticcheck failed: %s", runErr)
+	}
+	for _, f := range m.findings {
+		m.log.Printf("staticcheck: %s", f)
+	}
+	return nil
+}
+
+func findingsError(findings []finding) error {
+	if len(findings) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(findings))
+	for _, f := range findings {
+		lines = append(lines, f.String())
+	}
+	return fmt.Errorf("staticcheck found %d problems:\n%s", len(findings), strings.Join(lines, "\n"))
+}
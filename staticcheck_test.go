@@ -0,0 +1,41 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestParseStaticcheck(t *testing.T) {
+	output := `{"code":"SA4006","severity":"error","location":{"file":"/src/proj/a/a.go","line":3,"column":2},"end":{"file":"/src/proj/a/a.go","line":3,"column":5},"message":"this value of x is never used"}
+{"code":"compile","severity":"error","location":{"file":"/elsewhere/b.go","line":1,"column":1},"message":"syntax error"}
+`
+	findings, err := parseStaticcheck(strings.NewReader(output), "/src/proj")
+	noError(t, err)
+	if len(findings) != 2 {
+		t.Fatalf("Expected two findings, saw %v", findings)
+	}
+	if findings[0].String() != "a/a.go:3:2: this value of x is never used (SA4006)" {
+		t.Errorf("Unexpected finding %s", findings[0])
+	}
+	if findings[1].File != "/elsewhere/b.go" {
+		t.Errorf("Expected files outside the directory to stay absolute, saw %s", findings[1].File)
+	}
+	if err := findingsError(findings); err == nil || !strings.HasPrefix(err.Error(), "staticcheck found 2 problems") {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if _, err := parseStaticcheck(strings.NewReader("not json\n"), "/src/proj"); err == nil {
+		t.Errorf("Expected an error for invalid output")
+	}
+}
+
+func TestMarkdownFindings(t *testing.T) {
+	var buf bytes.Buffer
+	profiles := []*cover.Profile{{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 1, Count: 1}}}}
+	noError(t, writeMarkdownSummary(&buf, profiles, "statements", 0, 0, nil, []finding{{Code: "S1000", File: "a/a.go", Line: 7, Message: "use a | b"}}, nil))
+	if !strings.Contains(buf.String(), "### Staticcheck: 1 problems") || !strings.Contains(buf.String(), `| a/a.go:7 | S1000 | use a \| b |`) {
+		t.Errorf("Unexpected markdown %s", buf.String())
+	}
+}
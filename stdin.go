@@ -0,0 +1,48 @@
+package gocoverdir
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readPackageList reads one package directory per line, cleaned and without duplicates
+func readPackageList(r io.Reader) ([]string, error) {
+	var ret []string
+	seen := make(map[string]struct{})
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		dir := filepath.Clean(line)
+		if _, exists := seen[dir]; exists {
+			continue
+		}
+		seen[dir] = struct{}{}
+		ret = append(ret, dir)
+	}
+	return ret, scanner.Err()
+}
+
+// listedDirs are the testable directories of listed, which came from source
+func (m *gocoverdir) listedDirs(listed []string, source string) ([]string, error) {
+	var dirs []string
+	for _, dir := range listed {
+		files, err := ioutil.ReadDir(dir)
+		if os.IsNotExist(err) {
+			m.log.Printf("Ignoring %s from %s: it does not exist", dir, source)
+			m.explained.record(dir, "ignored", "listed in "+source+", but does not exist")
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		dirs = m.considerDir(dir, files, dirs)
+	}
+	return dirs, nil
+}
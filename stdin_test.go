@@ -0,0 +1,35 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadPackageList(t *testing.T) {
+	dirs, err := readPackageList(strings.NewReader("./a\n\n  b/c  \na\n"))
+	noError(t, err)
+	if !reflect.DeepEqual(dirs, []string{"a", "b/c"}) {
+		t.Errorf("Unexpected dirs %v", dirs)
+	}
+}
+
+func TestStdinDirs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestStdinDirs")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	noError(t, os.Mkdir(filepath.Join(dir, "a"), 0755))
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a", "a.go"), []byte("package a\n"), 0644))
+	noError(t, os.Mkdir(filepath.Join(dir, "docs"), 0755))
+	input := strings.Join([]string{filepath.Join(dir, "a"), filepath.Join(dir, "docs"), filepath.Join(dir, "gone")}, "\n")
+	m := &gocoverdir{log: log.New(ioutil.Discard, "", 0), discoverer: readerDiscoverer{r: strings.NewReader(input)}}
+	dirs, err := m.discoverDirs()
+	noError(t, err)
+	if !reflect.DeepEqual(dirs, []string{filepath.Join(dir, "a")}) {
+		t.Errorf("Unexpected dirs %v", dirs)
+	}
+}
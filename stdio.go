@@ -0,0 +1,57 @@
+package gocoverdir
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// stdio is the file name that means stdin when reading and stdout when writing, so gocoverdir works in pipelines
+const stdio = "-"
+
+// openInput opens filename, or stdin for -
+func openInput(filename string) (io.ReadCloser, error) {
+	if filename == stdio {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(filename)
+}
+
+// setupStdoutProfile handles -coverprofile -.  The profile is still written to a file, since reports reread it, and
+// copied to stdout once it is complete.  Anything else that would print to stdout goes to stderr instead
+func (m *gocoverdir) setupStdoutProfile() error {
+	if m.args.coverprofile != stdio {
+		return nil
+	}
+	// A directory, since finish merges every file in storeDir
+	dir := filepath.Join(m.storeDir, "stdout")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return err
+	}
+	m.stdoutProfile = true
+	m.args.coverprofile = filepath.Join(dir, "coverage.out")
+	if m.testOutputStdout == os.Stdout {
+		m.testOutputStdout = os.Stderr
+	}
+	return nil
+}
+
+// stdout is where output meant for people goes: stderr if stdout is the profile
+func (m *gocoverdir) stdout() io.Writer {
+	if m.stdoutProfile {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// copyProfileToStdout writes the combined profile to stdout for -coverprofile -
+func (m *gocoverdir) copyProfileToStdout() error {
+	f, err := os.Open(m.args.coverprofile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(os.Stdout, f)
+	return err
+}
@@ -0,0 +1,69 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// withStdio replaces stdin with a file of input and stdout with a file, returning what was written to stdout
+func withStdio(t *testing.T, input string, f func()) string {
+	dir, err := ioutil.TempDir("", "withStdio")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "stdin"), []byte(input), 0644))
+	stdin, err := os.Open(filepath.Join(dir, "stdin"))
+	noError(t, err)
+	defer stdin.Close()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	noError(t, err)
+	oldStdin, oldStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdin, stdout
+	defer func() {
+		os.Stdin, os.Stdout = oldStdin, oldStdout
+	}()
+	f()
+	noError(t, stdout.Close())
+	written, err := ioutil.ReadFile(filepath.Join(dir, "stdout"))
+	noError(t, err)
+	return string(written)
+}
+
+func TestMergeStdio(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestMergeStdio")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	other := filepath.Join(dir, "b.out")
+	noError(t, ioutil.WriteFile(other, []byte("mode: set\nexample.com/a/a.go:1.1,2.1 1 0\nexample.com/b/b.go:1.1,2.1 1 1\n"), 0644))
+	out := withStdio(t, "mode: set\nexample.com/a/a.go:1.1,2.1 1 1\n", func() {
+		noError(t, runMerge([]string{"-", other, "-o", "-"}))
+	})
+	expected := "mode: set\nexample.com/a/a.go:1.1,2.1 1 1\nexample.com/b/b.go:1.1,2.1 1 1\n"
+	if out != expected {
+		t.Errorf("Unexpected merged profile %q", out)
+	}
+}
+
+func TestSetupStdoutProfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestSetupStdoutProfile")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	m := &gocoverdir{storeDir: dir, testOutputStdout: os.Stdout}
+	m.args.coverprofile = "-"
+	noError(t, m.setupStdoutProfile())
+	if !m.stdoutProfile || !strings.HasPrefix(m.args.coverprofile, filepath.Join(dir, "stdout")) {
+		t.Errorf("Unexpected profile %s", m.args.coverprofile)
+	}
+	if m.testOutputStdout != os.Stderr || m.stdout() != os.Stderr {
+		t.Errorf("Test output and the coverage summary should move to stderr")
+	}
+	noError(t, ioutil.WriteFile(m.args.coverprofile, []byte("mode: set\n"), 0644))
+	out := withStdio(t, "", func() {
+		noError(t, m.copyProfileToStdout())
+	})
+	if out != "mode: set\n" {
+		t.Errorf("Unexpected stdout %q", out)
+	}
+}
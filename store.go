@@ -0,0 +1,29 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// storeProfileName names the profile of one go test run of dirpath in the store directory.  Names come from the
+// package directory, so a kept store is easy to find your way around and rerunning a package overwrites its own
+// profile instead of colliding with another's.  pass separates runs of the same package, like quarantined tests, and
+// attempt counts retries
+func storeProfileName(dirpath string, pass string, attempt int) string {
+	// go ignores directories starting with _, so no package is named like this
+	name := "_root"
+	if dir := filepath.ToSlash(filepath.Clean(dirpath)); dir != "." {
+		// Escaping dots keeps the suffixes below from making two packages' names the same
+		name = strings.Replace(url.PathEscape(dir), ".", "%2E", -1)
+	}
+	parts := []string{name}
+	if pass != "" {
+		parts = append(parts, pass)
+	}
+	if attempt > 0 {
+		parts = append(parts, fmt.Sprintf("retry%d", attempt))
+	}
+	return strings.Join(append(parts, "cover"), ".")
+}
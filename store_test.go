@@ -0,0 +1,22 @@
+package gocoverdir
+
+import "testing"
+
+func TestStoreProfileName(t *testing.T) {
+	for _, tc := range []struct {
+		dir      string
+		pass     string
+		attempt  int
+		expected string
+	}{
+		{dir: ".", expected: "_root.cover"},
+		{dir: "a/b", expected: "a%2Fb.cover"},
+		{dir: "./a/b/", pass: "quarantined", expected: "a%2Fb.quarantined.cover"},
+		{dir: "a", attempt: 2, expected: "a.retry2.cover"},
+		{dir: "a.quarantined", expected: "a%2Equarantined.cover"},
+	} {
+		if name := storeProfileName(tc.dir, tc.pass, tc.attempt); name != tc.expected {
+			t.Errorf("storeProfileName(%q, %q, %d) = %s, expected %s", tc.dir, tc.pass, tc.attempt, name, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// suggestedConfig is the part of config that suggest-threshold fills in
+type suggestedConfig struct {
+	RequiredCoverage float64            `json:"requiredcoverage"`
+	Packages         map[string]float64 `json:"packages"`
+}
+
+// suggestThreshold is margin points below the lowest of observed, rounded down to a whole percent
+func suggestThreshold(observed []float64, margin float64) float64 {
+	lowest := math.Inf(1)
+	for _, o := range observed {
+		lowest = math.Min(lowest, o)
+	}
+	if math.IsInf(lowest, 1) {
+		return 0
+	}
+	return math.Max(0, math.Floor(lowest-margin))
+}
+
+// suggestThresholds recommends thresholds from the last runs of entries, so a normal run passes but a real drop
+// fails.  Packages that are not covered at all get no threshold
+func suggestThresholds(entries []historyEntry, runs int, margin float64) suggestedConfig {
+	if runs > 0 && len(entries) > runs {
+		entries = entries[len(entries)-runs:]
+	}
+	var totals []float64
+	byPackage := make(map[string][]float64)
+	for _, entry := range entries {
+		totals = append(totals, entry.Coverage)
+		for pkg, coverage := range entry.Packages {
+			byPackage[pkg] = append(byPackage[pkg], coverage)
+		}
+	}
+	ret := suggestedConfig{
+		RequiredCoverage: suggestThreshold(totals, margin),
+		Packages:         make(map[string]float64, len(byPackage)),
+	}
+	for pkg, observed := range byPackage {
+		if threshold := suggestThreshold(observed, margin); threshold > 0 {
+			ret.Packages[pkg] = threshold
+		}
+	}
+	return ret
+}
+
+func writeSuggestedConfig(w io.Writer, cfg suggestedConfig) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(cfg)
+}
+
+// runSuggestThreshold prints a -config file with thresholds a little below current coverage, from either the history
+// store or a single cover profile
+func runSuggestThreshold(args []string) error {
+	fs := flag.NewFlagSet("suggest-threshold", flag.ContinueOnError)
+	history := fs.String("history", "gocoverdir-history.jsonl", "History store written by -history")
+	profile := fs.String("profile", "", "If set, suggest thresholds from this cover profile instead of the history store")
+	runs := fs.Int("n", 10, "Number of most recent runs in the history store to consider.  0 considers all of them")
+	margin := fs.Float64("margin", 2, "Percentage points to leave below the lowest coverage seen")
+	out := fs.String("o", "", "If set, write the config to this file instead of stdout")
+	metric := fs.String("metric", "statements", "What coverage percentages of -profile count: statements, blocks or lines.  Use the -metric thresholds will be checked with")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if err := verifyMetric(*metric); err != nil {
+		return err
+	}
+	var entries []historyEntry
+	if *profile != "" {
+		profiles, err := readProfiles(*profile)
+		if err != nil {
+			return err
+		}
+		entry := historyEntry{Coverage: calculateCoverage(profiles, *metric), Packages: make(map[string]float64)}
+		for _, pkg := range packageCoverages(profiles, *metric) {
+			entry.Packages[pkg.name] = pkg.percent()
+		}
+		entries = append(entries, entry)
+	} else {
+		var err error
+		if entries, err = readHistory(*history); err != nil {
+			return err
+		}
+	}
+	if len(entries) == 0 {
+		return fmt.Errorf("no coverage to suggest thresholds from")
+	}
+	cfg := suggestThresholds(entries, *runs, *margin)
+	if *out == "" {
+		return writeSuggestedConfig(os.Stdout, cfg)
+	}
+	return writeFileWith(*out, func(w io.Writer) error {
+		return writeSuggestedConfig(w, cfg)
+	})
+}
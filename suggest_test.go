@@ -0,0 +1,31 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSuggestThresholds(t *testing.T) {
+	entries := []historyEntry{
+		{Coverage: 10, Packages: map[string]float64{"example.com/a": 10}},
+		{Coverage: 81.5, Packages: map[string]float64{"example.com/a": 90.4, "example.com/b": 0}},
+		{Coverage: 80.2, Packages: map[string]float64{"example.com/a": 91, "example.com/c": 1.5}},
+	}
+	cfg := suggestThresholds(entries, 2, 2)
+	expected := suggestedConfig{RequiredCoverage: 78, Packages: map[string]float64{"example.com/a": 88}}
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Unexpected suggestion %+v", cfg)
+	}
+	f, err := ioutil.TempFile("", "TestSuggestThresholds")
+	noError(t, err)
+	defer os.Remove(f.Name())
+	noError(t, writeSuggestedConfig(f, cfg))
+	noError(t, f.Close())
+	loaded, err := loadConfig(f.Name())
+	noError(t, err)
+	if loaded.RequiredCoverage != 78 || !reflect.DeepEqual(loaded.Packages, expected.Packages) {
+		t.Errorf("Unexpected config %+v", loaded)
+	}
+}
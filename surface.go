@@ -0,0 +1,53 @@
+package gocoverdir
+
+import (
+	"strings"
+
+	"golang.org/x/tools/cover"
+)
+
+// isInternal is true if pkg is under an internal directory or matches one of patterns
+func isInternal(pkg string, patterns []string) bool {
+	for _, element := range strings.Split(pkg, "/") {
+		if element == "internal" {
+			return true
+		}
+	}
+	for _, pattern := range patterns {
+		if matchPackage(pattern, pkg) {
+			return true
+		}
+	}
+	return false
+}
+
+// surfaceCoverages splits statements into public API and internal packages, in that order
+func surfaceCoverages(profiles []*cover.Profile, metric string, internalPatterns []string) []packageCoverage {
+	public := packageCoverage{name: "public"}
+	internal := packageCoverage{name: "internal"}
+	for _, pkg := range packageCoverages(profiles, metric) {
+		surface := &public
+		if isInternal(pkg.name, internalPatterns) {
+			surface = &internal
+		}
+		surface.statements += pkg.statements
+		surface.covered += pkg.covered
+	}
+	return []packageCoverage{public, internal}
+}
+
+// surfaceViolations checks the public API and internal thresholds of cfg
+func surfaceViolations(profiles []*cover.Profile, metric string, cfg *config) []violation {
+	if cfg.RequiredPublic <= 0 && cfg.RequiredInternal <= 0 {
+		return nil
+	}
+	var ret []violation
+	surfaces := surfaceCoverages(profiles, metric, cfg.Internal)
+	for i, required := range []float64{cfg.RequiredPublic, cfg.RequiredInternal} {
+		surface := surfaces[i]
+		if surface.statements > 0 && belowThreshold(surface.percent(), required) {
+			ret = append(ret, violation{Rule: "required" + surface.name, Scope: surface.name, Required: required, Actual: surface.percent()})
+		}
+	}
+	return ret
+}
@@ -0,0 +1,26 @@
+package gocoverdir
+
+import (
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestSurfaces(t *testing.T) {
+	profiles := []*cover.Profile{
+		{FileName: "example.com/api/a.go", Blocks: []cover.ProfileBlock{{NumStmt: 8, Count: 1}, {NumStmt: 2}}},
+		{FileName: "example.com/internal/db/d.go", Blocks: []cover.ProfileBlock{{NumStmt: 1, Count: 1}, {NumStmt: 1}}},
+		{FileName: "example.com/cmd/tool/main.go", Blocks: []cover.ProfileBlock{{NumStmt: 2}}},
+	}
+	surfaces := surfaceCoverages(profiles, "statements", []string{"cmd/..."})
+	if surfaces[0].name != "public" || surfaces[0].statements != 10 || surfaces[0].covered != 8 {
+		t.Errorf("Unexpected public surface %+v", surfaces[0])
+	}
+	if surfaces[1].name != "internal" || surfaces[1].statements != 4 || surfaces[1].covered != 1 {
+		t.Errorf("Unexpected internal surface %+v", surfaces[1])
+	}
+	violations := evaluateGates(profiles, "statements", 0, &config{Internal: []string{"cmd/..."}, RequiredPublic: 90, RequiredInternal: 20})
+	if len(violations) != 1 || violations[0].Rule != "requiredpublic" || violations[0].Actual != 80 {
+		t.Errorf("Unexpected violations %v", violations)
+	}
+}
@@ -0,0 +1,148 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// testEvent is one line of 'go test -json' (see 'go doc test2json')
+type testEvent struct {
+	Action  string
+	Test    string
+	Output  string
+	Elapsed float64
+}
+
+// testEventWriter decodes 'go test -json' output, collecting skipped tests and data races and writing the output a plain 'go test'
+// would print: package results, plus the output of tests that did not pass.  If verbose, the output of every test is
+// written as it happens, like 'go test -v'
+type testEventWriter struct {
+	out     io.Writer
+	verbose bool
+	partial []byte
+	pending map[string][]string
+	skipped []string
+	// failed are the top level tests that failed
+	failed []string
+	// ran are the top level tests, benchmarks, fuzz tests and examples that passed or failed
+	ran   []string
+	races raceCollector
+	// matches collects the output lines the failoutput config rules match
+	matches outputMatcher
+	// leaks collects the goroutines a -leakcheck TestMain reported
+	leaks outputMatcher
+}
+
+func newTestEventWriter(out io.Writer) *testEventWriter {
+	return &testEventWriter{
+		out:     out,
+		pending: make(map[string][]string),
+	}
+}
+
+func (t *testEventWriter) Write(p []byte) (int, error) {
+	t.partial = append(t.partial, p...)
+	for {
+		idx := bytes.IndexByte(t.partial, '\n')
+		if idx < 0 {
+			return len(p), nil
+		}
+		line := t.partial[:idx+1]
+		t.partial = t.partial[idx+1:]
+		if err := t.handleLine(line); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+func (t *testEventWriter) handleLine(line []byte) error {
+	var event testEvent
+	if !bytes.HasPrefix(line, []byte("{")) || json.Unmarshal(line, &event) != nil {
+		t.races.line(string(line))
+		t.matches.line(string(line))
+		t.leaks.line(string(line))
+		_, err := t.out.Write(line)
+		return err
+	}
+	if event.Action == "output" {
+		t.races.line(event.Output)
+		t.matches.line(event.Output)
+		t.leaks.line(event.Output)
+	}
+	if event.Test == "" {
+		if event.Action != "output" && event.Action != "build-output" {
+			return nil
+		}
+		_, err := io.WriteString(t.out, event.Output)
+		return err
+	}
+	switch event.Action {
+	case "output":
+		if t.verbose {
+			_, err := io.WriteString(t.out, event.Output)
+			return err
+		}
+		t.pending[event.Test] = append(t.pending[event.Test], event.Output)
+	case "skip":
+		t.skipped = append(t.skipped, event.Test)
+		delete(t.pending, event.Test)
+	case "pass":
+		if !strings.Contains(event.Test, "/") {
+			t.ran = append(t.ran, event.Test)
+		}
+		delete(t.pending, event.Test)
+	case "fail":
+		if !strings.Contains(event.Test, "/") {
+			t.failed = append(t.failed, event.Test)
+			t.ran = append(t.ran, event.Test)
+		}
+		output := t.pending[event.Test]
+		delete(t.pending, event.Test)
+		_, err := io.WriteString(t.out, strings.Join(output, ""))
+		return err
+	}
+	return nil
+}
+
+// Close writes anything left over, like the output of a test that was running when the binary panicked
+func (t *testEventWriter) Close() error {
+	if len(t.partial) > 0 {
+		if err := t.handleLine(t.partial); err != nil {
+			return err
+		}
+		t.partial = nil
+	}
+	tests := make([]string, 0, len(t.pending))
+	for test := range t.pending {
+		tests = append(tests, test)
+	}
+	sort.Strings(tests)
+	for _, test := range tests {
+		if _, err := io.WriteString(t.out, strings.Join(t.pending[test], "")); err != nil {
+			return err
+		}
+	}
+	t.pending = make(map[string][]string)
+	return nil
+}
+
+// checkSkips fails the run if any package matching -failonskips skipped a test
+func (m *gocoverdir) checkSkips() error {
+	if m.args.failonskips == "" {
+		return nil
+	}
+	var failures []string
+	for _, result := range m.results {
+		if len(result.skipped) > 0 && matchPackage(m.args.failonskips, result.dir) {
+			failures = append(failures, fmt.Sprintf("%s skipped %s", result.dir, strings.Join(result.skipped, ", ")))
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("tests were skipped in packages matching -failonskips %s:\n%s", m.args.failonskips, strings.Join(failures, "\n"))
+}
@@ -0,0 +1,72 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTestEventWriter(t *testing.T) {
+	var out bytes.Buffer
+	w := newTestEventWriter(&out)
+	input := `{"Action":"run","Test":"TestA"}
+{"Action":"output","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"pass","Test":"TestA"}
+{"Action":"output","Test":"TestB","Output":"    b_test.go:3: needs fixture\n"}
+{"Action":"skip","Test":"TestB"}
+{"Action":"output","Test":"TestC","Output":"    c_test.go:9: broken\n"}
+{"Action":"fail","Test":"TestC"}
+{"Action":"output","Test":"TestD","Output":"panic: oops\n"}
+{"Action":"output","Output":"FAIL\texample.com/a\t0.1s\n"}
+not json
+{"Action":"fail"}`
+	_, err := w.Write([]byte(input[:40]))
+	noError(t, err)
+	_, err = w.Write([]byte(input[40:]))
+	noError(t, err)
+	noError(t, w.Close())
+	expected := "    c_test.go:9: broken\nFAIL\texample.com/a\t0.1s\nnot json\npanic: oops\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+	if len(w.skipped) != 1 || w.skipped[0] != "TestB" {
+		t.Errorf("Unexpected skips %v", w.skipped)
+	}
+}
+
+func TestCheckSkips(t *testing.T) {
+	m := &gocoverdir{}
+	m.results = []packageResult{{dir: "a", skipped: []string{"TestA"}}, {dir: "integration/db", skipped: []string{"TestDB"}}}
+	noError(t, m.checkSkips())
+	m.args.failonskips = "./integration/..."
+	if err := m.checkSkips(); err == nil || err.Error() != "tests were skipped in packages matching -failonskips ./integration/...:\nintegration/db skipped TestDB" {
+		t.Errorf("Unexpected error %v", err)
+	}
+}
+
+func TestTestEventWriterVerbose(t *testing.T) {
+	var out bytes.Buffer
+	w := newTestEventWriter(&out)
+	w.verbose = true
+	_, err := w.Write([]byte(`{"Action":"output","Test":"TestA","Output":"=== RUN   TestA\n"}
+{"Action":"output","Test":"TestA","Output":"--- PASS: TestA\n"}
+{"Action":"pass","Test":"TestA"}
+{"Action":"output","Output":"ok\texample.com/a\t0.1s\n"}
+`))
+	noError(t, err)
+	noError(t, w.Close())
+	if expected := "=== RUN   TestA\n--- PASS: TestA\nok\texample.com/a\t0.1s\n"; out.String() != expected {
+		t.Errorf("Unexpected output %q", out.String())
+	}
+	if len(w.ran) != 1 {
+		t.Errorf("Unexpected tests %v", w.ran)
+	}
+}
+
+func TestVerifyVerbosity(t *testing.T) {
+	m := &gocoverdir{}
+	m.args.quiet = true
+	m.args.verbose = true
+	if err := m.verifyVerbosity(); err == nil {
+		t.Errorf("Expected -q and -v to conflict")
+	}
+}
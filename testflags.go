@@ -0,0 +1,45 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"strings"
+)
+
+// managedTestFlags are go test flags gocoverdir sets itself.  Changing them for some packages would break collecting
+// and merging their profiles
+var managedTestFlags = []string{"-cover", "-coverprofile", "-covermode", "-coverpkg", "-outputdir", "-json"}
+
+// verifyTestFlags checks the testflags rules of the config, so a typo fails before any package is tested
+func verifyTestFlags(rules map[string]string) error {
+	for pattern, flags := range rules {
+		for _, flag := range strings.Fields(flags) {
+			if !strings.HasPrefix(flag, "-") {
+				return fmt.Errorf("testflags for %s: %q is not a flag.  Give flag values with =, like -count=3", pattern, flag)
+			}
+			name := "-" + strings.TrimLeft(strings.SplitN(flag, "=", 2)[0], "-")
+			for _, managed := range managedTestFlags {
+				if name == managed {
+					return fmt.Errorf("testflags for %s: %s is set by gocoverdir", pattern, name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// packageTestFlags are the extra go test flags of every testflags rule matching dir, least specific first so the most
+// specific rule wins when they set the same flag
+func packageTestFlags(rules map[string]string, dir string) []string {
+	patterns := make([]string, 0, len(rules))
+	for pattern := range rules {
+		if matchPackage(pattern, dir) {
+			patterns = append(patterns, pattern)
+		}
+	}
+	sortBySpecificity(patterns)
+	var ret []string
+	for i := len(patterns) - 1; i >= 0; i-- {
+		ret = append(ret, strings.Fields(rules[patterns[i]])...)
+	}
+	return ret
+}
@@ -0,0 +1,42 @@
+package gocoverdir
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPackageTestFlags(t *testing.T) {
+	rules := map[string]string{
+		"./hardware/...":     "-count=3 -timeout=10m",
+		"./hardware/usb/...": "-timeout=20m",
+		"./api":              "-short",
+	}
+	if flags := packageTestFlags(rules, "hardware/usb/hid"); !reflect.DeepEqual(flags, []string{"-count=3", "-timeout=10m", "-timeout=20m"}) {
+		t.Errorf("Expected the most specific rule last, saw %v", flags)
+	}
+	if flags := packageTestFlags(rules, "api"); !reflect.DeepEqual(flags, []string{"-short"}) {
+		t.Errorf("Unexpected flags %v", flags)
+	}
+	if flags := packageTestFlags(rules, "storage"); len(flags) != 0 {
+		t.Errorf("Expected no flags for an unmatched package, saw %v", flags)
+	}
+}
+
+func TestVerifyTestFlags(t *testing.T) {
+	noError(t, verifyTestFlags(map[string]string{"./hardware/...": "-count=3 -run=TestSlow"}))
+	if err := verifyTestFlags(map[string]string{"./a": "-count 3"}); err == nil || !strings.Contains(err.Error(), "-count=3") {
+		t.Errorf("Expected an error for a flag value without =, saw %v", err)
+	}
+	if err := verifyTestFlags(map[string]string{"./a": "--coverprofile=x.out"}); err == nil {
+		t.Errorf("Expected an error overriding a flag gocoverdir sets")
+	}
+}
+
+func TestTestArgsTestFlags(t *testing.T) {
+	m := &gocoverdir{config: &config{TestFlags: map[string]string{"./hardware/...": "-count=3"}}}
+	_, args := m.testArgs("hardware/gpio", "/store", "a.cover", nil)
+	if args[len(args)-2] != "-count=3" || args[len(args)-1] != "./hardware/gpio" {
+		t.Errorf("Expected the package's flags before it, saw %v", args)
+	}
+}
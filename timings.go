@@ -0,0 +1,205 @@
+package gocoverdir
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// timingsFile stores how long each directory's tests took, so the next run can schedule and estimate with them.
+// It is small and stable enough to commit, or to keep in a CI cache
+type timingsFile struct {
+	Updated time.Time          `json:"updated"`
+	Seconds map[string]float64 `json:"seconds"`
+	// Failed is when each directory's tests last failed, or were flaky, for -order recent-failures-first
+	Failed map[string]time.Time `json:"failed,omitempty"`
+}
+
+// autoTimings is the -timings that keeps timings in the user cache directory
+const autoTimings = "auto"
+
+// cachedTimingsFile is where -timings auto keeps the timings of the tree in dir
+func cachedTimingsFile(dir string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(abs))
+	return filepath.Join(cacheDir, "gocoverdir", "timings", hex.EncodeToString(sum[:8])+".json"), nil
+}
+
+// setupTimings resolves -timings auto.  Without a cache directory, timings are not kept
+func (m *gocoverdir) setupTimings() {
+	if m.args.timings != autoTimings {
+		return
+	}
+	filename, err := cachedTimingsFile(".")
+	if err != nil {
+		m.log.Printf("Not keeping timings: %s", err)
+		filename = ""
+	}
+	m.args.timings = filename
+}
+
+func readTimingsFile(filename string) (*timingsFile, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var ret timingsFile
+	if err := json.NewDecoder(f).Decode(&ret); err != nil {
+		return nil, fmt.Errorf("cannot parse timings %s: %s", filename, err)
+	}
+	return &ret, nil
+}
+
+func readTimings(filename string) (map[string]float64, error) {
+	timings, err := readTimingsFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return timings.Seconds, nil
+}
+
+func writeTimings(w io.Writer, seconds map[string]float64) error {
+	return writeTimingsFile(w, timingsFile{Seconds: seconds})
+}
+
+func writeTimingsFile(w io.Writer, timings timingsFile) error {
+	timings.Updated = time.Now().UTC()
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(timings)
+}
+
+// resultSeconds returns how long each directory's tests took in results.  Quarantine passes and retries are not
+// counted
+func resultSeconds(results []packageResult) map[string]float64 {
+	ret := make(map[string]float64, len(results))
+	for _, result := range results {
+		if result.command == "go test" {
+			ret[result.dir] = result.duration.Seconds()
+		}
+	}
+	return ret
+}
+
+// resultFailures returns the directories whose tests failed in results, or only passed on retry
+func resultFailures(results []packageResult) []string {
+	var ret []string
+	for _, result := range results {
+		if result.command == "go test" && (result.err != nil || len(result.flaky) > 0) {
+			ret = append(ret, result.dir)
+		}
+	}
+	return ret
+}
+
+// saveTimings updates -timings with this run, keeping the times of directories that did not run
+func (m *gocoverdir) saveTimings() error {
+	if m.args.timings == "" {
+		return nil
+	}
+	timings, err := readTimingsFile(m.args.timings)
+	if os.IsNotExist(err) {
+		timings, err = &timingsFile{}, nil
+	}
+	if err != nil {
+		return err
+	}
+	if timings.Seconds == nil {
+		timings.Seconds = make(map[string]float64)
+	}
+	for dir, s := range resultSeconds(m.results) {
+		timings.Seconds[dir] = s
+	}
+	for _, dir := range resultFailures(m.results) {
+		if timings.Failed == nil {
+			timings.Failed = make(map[string]time.Time)
+		}
+		timings.Failed[dir] = time.Now().UTC()
+	}
+	if err := os.MkdirAll(filepath.Dir(m.args.timings), 0755); err != nil {
+		return err
+	}
+	return writeFileWith(m.args.timings, func(w io.Writer) error {
+		return writeTimingsFile(w, *timings)
+	})
+}
+
+// balanceShards splits dirs into count shards of about the same total cost, placing the most expensive directories
+// first, each on the shard with the least work so far
+func balanceShards(dirs []string, count int, costs map[string]float64) [][]string {
+	shards := make([][]string, count)
+	load := make([]float64, count)
+	for _, dir := range heaviestFirst(dirs, costs) {
+		lightest := 0
+		for i := range load {
+			if load[i] < load[lightest] {
+				lightest = i
+			}
+		}
+		shards[lightest] = append(shards[lightest], dir)
+		load[lightest] += costs[dir]
+	}
+	return shards
+}
+
+// etaTracker estimates how long the rest of a run takes, from the expected cost of the directories not done yet.
+// Without costs, it only counts
+type etaTracker struct {
+	costs     map[string]float64
+	remaining map[string]struct{}
+	total     int
+	workers   int
+}
+
+func newETATracker(dirs []string, costs map[string]float64, workers int) *etaTracker {
+	remaining := make(map[string]struct{}, len(dirs))
+	for _, dir := range dirs {
+		remaining[dir] = struct{}{}
+	}
+	return &etaTracker{costs: costs, remaining: remaining, total: len(dirs), workers: workers}
+}
+
+// done marks dir finished and returns how long the remaining directories should take
+func (e *etaTracker) done(dir string) time.Duration {
+	delete(e.remaining, dir)
+	var seconds float64
+	for d := range e.remaining {
+		seconds += e.costs[d]
+	}
+	return time.Duration(seconds / float64(e.workers) * float64(time.Second)).Round(time.Second)
+}
+
+// newETA tracks the progress of testing dirs, estimating from -timings or -history if either has timings
+func (m *gocoverdir) newETA(dirs []string, workers int) *etaTracker {
+	var costs map[string]float64
+	if lastSeconds := m.lastTimings(); len(lastSeconds) > 0 {
+		costs = estimateCosts(dirs, lastSeconds, dirStatements)
+	}
+	return newETATracker(dirs, costs, workers)
+}
+
+// logProgress marks dir done and logs how many directories are done, with an estimate of the time left if there is
+// one
+func (m *gocoverdir) logProgress(eta *etaTracker, dir string) {
+	m.signal.done(m, dir)
+	left := eta.done(dir)
+	done := eta.total - len(eta.remaining)
+	if eta.costs == nil {
+		m.log.Printf("Tested %d of %d packages", done, eta.total)
+		return
+	}
+	m.log.Printf("Tested %d of %d packages, about %s left", done, eta.total, left)
+}
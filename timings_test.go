@@ -0,0 +1,67 @@
+package gocoverdir
+
+import (
+	"errors"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveTimings(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestSaveTimings")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	m := &gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	m.args.timings = filepath.Join(dir, "timings.json")
+	m.results = []packageResult{{dir: "a", command: "go test", duration: 2 * time.Second, err: errors.New("failed")}, {dir: "a", command: "go test (quarantined)", duration: time.Hour}}
+	noError(t, m.saveTimings())
+	m.results = []packageResult{{dir: "b", command: "go test", duration: time.Second}}
+	noError(t, m.saveTimings())
+	if timings := m.lastTimings(); !reflect.DeepEqual(timings, map[string]float64{"a": 2, "b": 1}) {
+		t.Errorf("Unexpected timings %v", timings)
+	}
+	if failed := m.lastFailures(); len(failed) != 1 || failed["a"].IsZero() {
+		t.Errorf("Expected a to be recorded as failed, got %v", failed)
+	}
+}
+
+func TestSetupTimings(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	t.Setenv("HOME", cacheDir)
+	t.Setenv("LocalAppData", cacheDir)
+	m := &gocoverdir{log: log.New(ioutil.Discard, "", 0)}
+	m.args.timings = autoTimings
+	m.setupTimings()
+	if !strings.HasPrefix(m.args.timings, cacheDir) {
+		t.Errorf("Expected auto timings in the cache directory %s, saw %s", cacheDir, m.args.timings)
+	}
+	m.results = []packageResult{{dir: "a", command: "go test", duration: time.Second}}
+	noError(t, m.saveTimings())
+	if timings := m.lastTimings(); !reflect.DeepEqual(timings, map[string]float64{"a": 1}) {
+		t.Errorf("Unexpected timings %v", timings)
+	}
+}
+
+func TestBalanceShards(t *testing.T) {
+	costs := map[string]float64{"a": 10, "b": 6, "c": 5, "d": 1}
+	shards := balanceShards([]string{"a", "b", "c", "d"}, 2, costs)
+	if !reflect.DeepEqual(shards, [][]string{{"a", "d"}, {"b", "c"}}) {
+		t.Errorf("Unexpected shards %v", shards)
+	}
+}
+
+func TestETATracker(t *testing.T) {
+	eta := newETATracker([]string{"a", "b", "c"}, map[string]float64{"a": 4, "b": 30, "c": 30}, 2)
+	if left := eta.done("a"); left != 30*time.Second {
+		t.Errorf("Unexpected time left %s", left)
+	}
+	if left := eta.done("b"); left != 15*time.Second {
+		t.Errorf("Unexpected time left %s", left)
+	}
+}
@@ -0,0 +1,211 @@
+package gocoverdir
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"golang.org/x/tools/cover"
+)
+
+// coverageVariant is the merged profile of one configuration of a matrix run, like one set of build tags
+type coverageVariant struct {
+	name     string
+	profiles []*cover.Profile
+}
+
+// blockKey identifies a block across profiles of the same source
+type blockKey struct {
+	file      string
+	startLine int
+	startCol  int
+	endLine   int
+	endCol    int
+}
+
+func (b blockKey) String() string {
+	return fmt.Sprintf("%s:%d.%d,%d.%d", b.file, b.startLine, b.startCol, b.endLine, b.endCol)
+}
+
+// variantBlock is a block, and which variants covered it
+type variantBlock struct {
+	key        blockKey
+	statements int
+	coveredBy  []bool
+}
+
+// variantPackage counts the statements of one package, in any variant, and which variants covered them
+type variantPackage struct {
+	name       string
+	statements int
+	// covered and only are indexed like the variants.  only counts statements covered by that variant alone
+	covered []int
+	only    []int
+}
+
+// variantComparison lines up the blocks of every variant
+type variantComparison struct {
+	names    []string
+	blocks   []variantBlock
+	packages []variantPackage
+}
+
+func compareVariants(variants []coverageVariant) *variantComparison {
+	ret := &variantComparison{}
+	byKey := make(map[blockKey]*variantBlock)
+	var keys []blockKey
+	for i, v := range variants {
+		ret.names = append(ret.names, v.name)
+		for _, p := range v.profiles {
+			for _, b := range p.Blocks {
+				key := blockKey{file: p.FileName, startLine: b.StartLine, startCol: b.StartCol, endLine: b.EndLine, endCol: b.EndCol}
+				block, exists := byKey[key]
+				if !exists {
+					block = &variantBlock{key: key, statements: b.NumStmt, coveredBy: make([]bool, len(variants))}
+					byKey[key] = block
+					keys = append(keys, key)
+				}
+				block.coveredBy[i] = block.coveredBy[i] || b.Count > 0
+			}
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.file != b.file {
+			return a.file < b.file
+		}
+		if a.startLine != b.startLine {
+			return a.startLine < b.startLine
+		}
+		return a.startCol < b.startCol
+	})
+	packages := make(map[string]*variantPackage)
+	var names []string
+	for _, key := range keys {
+		block := byKey[key]
+		ret.blocks = append(ret.blocks, *block)
+		name := path.Dir(key.file)
+		pkg, exists := packages[name]
+		if !exists {
+			pkg = &variantPackage{name: name, covered: make([]int, len(variants)), only: make([]int, len(variants))}
+			packages[name] = pkg
+			names = append(names, name)
+		}
+		pkg.statements += block.statements
+		if only := block.onlyCoveredBy(); only >= 0 {
+			pkg.only[only] += block.statements
+		}
+		for i, covered := range block.coveredBy {
+			if covered {
+				pkg.covered[i] += block.statements
+			}
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ret.packages = append(ret.packages, *packages[name])
+	}
+	return ret
+}
+
+// onlyCoveredBy returns the index of the one variant that covered the block, or -1 if none or several did
+func (v variantBlock) onlyCoveredBy() int {
+	only := -1
+	for i, covered := range v.coveredBy {
+		if !covered {
+			continue
+		}
+		if only >= 0 {
+			return -1
+		}
+		only = i
+	}
+	return only
+}
+
+// exclusiveBlocks lists, for variant, the blocks no other variant covered
+func (c *variantComparison) exclusiveBlocks(variant int) []variantBlock {
+	var ret []variantBlock
+	for _, block := range c.blocks {
+		if block.onlyCoveredBy() == variant {
+			ret = append(ret, block)
+		}
+	}
+	return ret
+}
+
+// writeVariantTable writes, per package, how much each variant covers and how much only it covers
+func writeVariantTable(w io.Writer, c *variantComparison) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	header := []string{"PACKAGE", "STATEMENTS"}
+	for _, name := range c.names {
+		header = append(header, strings.ToUpper(name), "ONLY "+strings.ToUpper(name))
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for _, pkg := range c.packages {
+		row := []string{pkg.name, fmt.Sprintf("%d", pkg.statements)}
+		for i := range c.names {
+			row = append(row, fmt.Sprintf("%.1f%%", lineRate(pkg.covered[i], pkg.statements)*100), fmt.Sprintf("%d", pkg.only[i]))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// parseVariantArgs reads name=profile arguments
+func parseVariantArgs(args []string) ([]coverageVariant, error) {
+	var ret []coverageVariant
+	for _, arg := range args {
+		parts := strings.SplitN(arg, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("expected name=profile, not %s", arg)
+		}
+		profiles, err := readProfiles(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, coverageVariant{name: parts[0], profiles: profiles})
+	}
+	return ret, nil
+}
+
+// runVariants compares the profiles of a matrix run, like one run per set of -tags, showing what each
+// configuration alone covers
+func runVariants(args []string) error {
+	fs := flag.NewFlagSet("variants", flag.ContinueOnError)
+	list := fs.Bool("list", false, "If true, also list the blocks only covered by each variant")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) < 2 {
+		return fmt.Errorf("usage: gocoverdir variants [-list] unit=unit.out integration=integration.out ...")
+	}
+	variants, err := parseVariantArgs(positional)
+	if err != nil {
+		return err
+	}
+	c := compareVariants(variants)
+	if err := writeVariantTable(os.Stdout, c); err != nil {
+		return err
+	}
+	if !*list {
+		return nil
+	}
+	for i, name := range c.names {
+		blocks := c.exclusiveBlocks(i)
+		if len(blocks) == 0 {
+			continue
+		}
+		fmt.Printf("\nOnly covered by %s:\n", name)
+		for _, block := range blocks {
+			fmt.Printf("  %s (%d stmts)\n", block.key, block.statements)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/cover"
+)
+
+func TestCompareVariants(t *testing.T) {
+	unit := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{
+			{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 1, NumStmt: 2, Count: 1},
+			{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 1, NumStmt: 3},
+		}},
+	}
+	integration := []*cover.Profile{
+		{FileName: "example.com/a/a.go", Blocks: []cover.ProfileBlock{
+			{StartLine: 1, StartCol: 1, EndLine: 2, EndCol: 1, NumStmt: 2, Count: 1},
+			{StartLine: 3, StartCol: 1, EndLine: 4, EndCol: 1, NumStmt: 3, Count: 2},
+		}},
+		{FileName: "example.com/a/db.go", Blocks: []cover.ProfileBlock{
+			{StartLine: 5, StartCol: 2, EndLine: 6, EndCol: 1, NumStmt: 1},
+		}},
+	}
+	c := compareVariants([]coverageVariant{{name: "unit", profiles: unit}, {name: "integration", profiles: integration}})
+	if len(c.packages) != 1 {
+		t.Fatalf("Unexpected packages %+v", c.packages)
+	}
+	pkg := c.packages[0]
+	if pkg.statements != 6 || pkg.covered[0] != 2 || pkg.covered[1] != 5 || pkg.only[0] != 0 || pkg.only[1] != 3 {
+		t.Errorf("Unexpected package %+v", pkg)
+	}
+	if blocks := c.exclusiveBlocks(1); len(blocks) != 1 || blocks[0].key.String() != "example.com/a/a.go:3.1,4.1" {
+		t.Errorf("Unexpected blocks %+v", blocks)
+	}
+	buf := bytes.Buffer{}
+	noError(t, writeVariantTable(&buf, c))
+	if !strings.Contains(buf.String(), "ONLY INTEGRATION") || !strings.Contains(buf.String(), "33.3%") {
+		t.Errorf("Unexpected table:\n%s", buf.String())
+	}
+	if _, err := parseVariantArgs([]string{"unit"}); err == nil {
+		t.Errorf("Expected an error without a profile")
+	}
+}
@@ -0,0 +1,378 @@
+package gocoverdir
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// vcs is the version control system of the working tree
+type vcs interface {
+	// commit is the checked out revision, or "" if unknown
+	commit() string
+	// branch is the checked out branch, or "" if unknown
+	branch() string
+	// recentChanges counts the commits since since that touched each directory, relative to the current one
+	recentChanges(since time.Time) map[string]int
+	// update checks out the latest revision of branch from the default remote, writing progress to out
+	update(branch string, out io.Writer) error
+	// root is the top of the working tree, or "" if unknown
+	root() string
+	// checkout puts the tree of ref in dir, which must not exist, without touching the working tree
+	checkout(ref string, dir string, out io.Writer) error
+	// removeCheckout deletes a dir made by checkout
+	removeCheckout(dir string) error
+	// changedFiles lists the files that differ between base and head, relative to the current directory
+	changedFiles(base string, head string) ([]string, error)
+	// changedLines maps each file changed since the fork point of base, relative to root, to its added or modified
+	// lines in the working tree
+	changedLines(base string) (map[string][]int, error)
+	// lineAuthors is the author of each line of file, relative to root, in the working tree
+	lineAuthors(file string) ([]string, error)
+}
+
+var vcsMarkers = []struct {
+	marker string
+	vcs    vcs
+}{
+	{".git", gitVCS{}},
+	{".hg", hgVCS{}},
+}
+
+// detectVCS finds the version control system of the nearest directory, at or above dir, that has one
+func detectVCS(dir string) vcs {
+	for {
+		for _, m := range vcsMarkers {
+			if _, err := os.Stat(filepath.Join(dir, m.marker)); err == nil {
+				return m.vcs
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nullVCS{}
+		}
+		dir = parent
+	}
+}
+
+// selectVCS resolves -vcs: git, hg, none, or auto to detect it from the working directory
+func selectVCS(name string) (vcs, error) {
+	switch name {
+	case "git":
+		return gitVCS{}, nil
+	case "hg":
+		return hgVCS{}, nil
+	case "none":
+		return nullVCS{}, nil
+	case "auto":
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+		return detectVCS(cwd), nil
+	}
+	return nil, fmt.Errorf("-vcs must be git, hg, none or auto, not %s", name)
+}
+
+// commandOutput runs name, returning its trimmed stdout or "" if it fails
+func commandOutput(name string, args ...string) string {
+	var stdout bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(stdout.String())
+}
+
+// commandOutputErr runs name in dir, returning its stdout, or an error with its stderr if it fails
+func commandOutputErr(dir string, name string, args ...string) (string, error) {
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s: %s\n%s", name, strings.Join(args, " "), err, stderr.String())
+	}
+	return stdout.String(), nil
+}
+
+// runCommands runs each command of name in order, stopping at the first failure
+func runCommands(out io.Writer, name string, commands [][]string) error {
+	for _, args := range commands {
+		cmd := exec.Command(name, args...)
+		cmd.Stdout = out
+		cmd.Stderr = out
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s %s: %s", name, strings.Join(args, " "), err)
+		}
+	}
+	return nil
+}
+
+type gitVCS struct{}
+
+func gitOutput(args ...string) string {
+	return commandOutput("git", args...)
+}
+
+func (gitVCS) commit() string {
+	return gitOutput("rev-parse", "HEAD")
+}
+
+func (gitVCS) branch() string {
+	return gitOutput("rev-parse", "--abbrev-ref", "HEAD")
+}
+
+func (gitVCS) recentChanges(since time.Time) map[string]int {
+	return countChanges(gitOutput("log", "--since="+since.Format(time.RFC3339), "--name-only", "--relative", "--format=tformat:commit"))
+}
+
+func (gitVCS) update(branch string, out io.Writer) error {
+	return runCommands(out, "git", [][]string{{"fetch", "origin", branch}, {"checkout", "-B", branch, "FETCH_HEAD"}})
+}
+
+func (gitVCS) root() string {
+	return gitOutput("rev-parse", "--show-toplevel")
+}
+
+func (gitVCS) checkout(ref string, dir string, out io.Writer) error {
+	return runCommands(out, "git", [][]string{{"worktree", "add", "--detach", dir, ref}})
+}
+
+func (gitVCS) removeCheckout(dir string) error {
+	return runCommands(ioutil.Discard, "git", [][]string{{"worktree", "remove", "--force", dir}})
+}
+
+func (gitVCS) changedFiles(base string, head string) ([]string, error) {
+	out, err := commandOutputErr("", "git", "diff", "--name-only", "--relative", base+"..."+head)
+	if err != nil {
+		return nil, fmt.Errorf("cannot diff %s...%s: %s", base, head, err)
+	}
+	return splitLines(out), nil
+}
+
+func (g gitVCS) changedLines(base string) (map[string][]int, error) {
+	mergeBase, err := commandOutputErr("", "git", "merge-base", base, "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("cannot find where HEAD forked from %s: %s", base, err)
+	}
+	diff, err := commandOutputErr(g.root(), "git", "diff", "-U0", "--no-color", "--no-ext-diff", strings.TrimSpace(mergeBase), "--")
+	if err != nil {
+		return nil, err
+	}
+	return parseUnifiedDiff(diff), nil
+}
+
+func (g gitVCS) lineAuthors(file string) ([]string, error) {
+	out, err := commandOutputErr(g.root(), "git", "blame", "--line-porcelain", "--", file)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlamePorcelain(out), nil
+}
+
+type hgVCS struct{}
+
+func (hgVCS) commit() string {
+	return commandOutput("hg", "log", "-r", ".", "-T", "{node}")
+}
+
+func (hgVCS) branch() string {
+	return commandOutput("hg", "branch")
+}
+
+func (hgVCS) recentChanges(since time.Time) map[string]int {
+	log := commandOutput("hg", "log", "-d", ">"+since.Format("2006-01-02 15:04:05"), "-T", "commit\\n{join(files, '\\n')}\\n")
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil
+	}
+	return countChanges(relativeChanges(log, commandOutput("hg", "root"), cwd))
+}
+
+func (hgVCS) update(branch string, out io.Writer) error {
+	return runCommands(out, "hg", [][]string{{"pull", "-b", branch}, {"update", "-C", branch}})
+}
+
+func (hgVCS) root() string {
+	return commandOutput("hg", "root")
+}
+
+func (hgVCS) checkout(ref string, dir string, out io.Writer) error {
+	return runCommands(out, "hg", [][]string{{"archive", "-r", ref, dir}})
+}
+
+func (hgVCS) removeCheckout(dir string) error {
+	return os.RemoveAll(dir)
+}
+
+func (h hgVCS) changedFiles(base string, head string) ([]string, error) {
+	out, err := commandOutputErr("", "hg", "status", "-n", "--rev", base, "--rev", head)
+	if err != nil {
+		return nil, fmt.Errorf("cannot diff %s and %s: %s", base, head, err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(relativeChanges(out, h.root(), cwd)), nil
+}
+
+func (h hgVCS) changedLines(base string) (map[string][]int, error) {
+	diff, err := commandOutputErr(h.root(), "hg", "diff", "--git", "-U0", "-r", "ancestor("+base+", .)")
+	if err != nil {
+		return nil, fmt.Errorf("cannot diff from %s: %s", base, err)
+	}
+	return parseUnifiedDiff(diff), nil
+}
+
+func (h hgVCS) lineAuthors(file string) ([]string, error) {
+	out, err := commandOutputErr(h.root(), "hg", "annotate", "-u", "--", file)
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+		ret = append(ret, strings.TrimSpace(strings.SplitN(line, ":", 2)[0]))
+	}
+	return ret, nil
+}
+
+// parseUnifiedDiff maps each file of a -U0 diff to the lines its hunks add or change.  Deleted files have none
+func parseUnifiedDiff(diff string) map[string][]int {
+	ret := make(map[string][]int)
+	file := ""
+	for _, line := range strings.Split(diff, "\n") {
+		if strings.HasPrefix(line, "+++ ") {
+			file = strings.SplitN(strings.TrimPrefix(line, "+++ "), "\t", 2)[0]
+			if file == "/dev/null" {
+				file = ""
+			}
+			file = strings.TrimPrefix(file, "b/")
+			continue
+		}
+		if file == "" || !strings.HasPrefix(line, "@@ ") {
+			continue
+		}
+		// @@ -old,count +new,count @@, where a missing count is 1
+		fields := strings.Fields(line)
+		if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+			continue
+		}
+		count := 1
+		parts := strings.SplitN(strings.TrimPrefix(fields[2], "+"), ",", 2)
+		start, err := strconv.Atoi(parts[0])
+		if err != nil {
+			continue
+		}
+		if len(parts) == 2 {
+			if count, err = strconv.Atoi(parts[1]); err != nil {
+				continue
+			}
+		}
+		for i := 0; i < count; i++ {
+			ret[file] = append(ret[file], start+i)
+		}
+	}
+	return ret
+}
+
+// parseBlamePorcelain reads the author of every line from 'git blame --line-porcelain', as "Name <email>"
+func parseBlamePorcelain(blame string) []string {
+	var ret []string
+	name, mail := "", ""
+	for _, line := range strings.Split(blame, "\n") {
+		switch {
+		case strings.HasPrefix(line, "author "):
+			name = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "author-mail "):
+			mail = strings.TrimPrefix(line, "author-mail ")
+		case strings.HasPrefix(line, "\t"):
+			ret = append(ret, name+" "+mail)
+		}
+	}
+	return ret
+}
+
+// splitLines splits output into its non empty lines
+func splitLines(output string) []string {
+	var ret []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			ret = append(ret, line)
+		}
+	}
+	return ret
+}
+
+// relativeChanges rewrites the files in a log, which hg names relative to the repository root, relative to cwd like
+// 'git log --relative' does.  Files outside of cwd are dropped
+func relativeChanges(log string, root string, cwd string) string {
+	lines := strings.Split(log, "\n")
+	ret := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line == "" || line == "commit" {
+			ret = append(ret, line)
+			continue
+		}
+		rel, err := filepath.Rel(cwd, filepath.Join(root, filepath.FromSlash(line)))
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		ret = append(ret, filepath.ToSlash(rel))
+	}
+	return strings.Join(ret, "\n")
+}
+
+// nullVCS is a working tree outside of version control: nothing is known about its history
+type nullVCS struct{}
+
+func (nullVCS) commit() string {
+	return ""
+}
+
+func (nullVCS) branch() string {
+	return ""
+}
+
+func (nullVCS) recentChanges(since time.Time) map[string]int {
+	return nil
+}
+
+func (nullVCS) update(branch string, out io.Writer) error {
+	return fmt.Errorf("cannot check out %s outside of version control", branch)
+}
+
+func (nullVCS) root() string {
+	return ""
+}
+
+func (nullVCS) checkout(ref string, dir string, out io.Writer) error {
+	return fmt.Errorf("cannot check out %s outside of version control", ref)
+}
+
+func (nullVCS) removeCheckout(dir string) error {
+	return os.RemoveAll(dir)
+}
+
+func (nullVCS) changedFiles(base string, head string) ([]string, error) {
+	return nil, fmt.Errorf("cannot diff %s and %s outside of version control", base, head)
+}
+
+func (nullVCS) changedLines(base string) (map[string][]int, error) {
+	return nil, fmt.Errorf("cannot diff from %s outside of version control", base)
+}
+
+func (nullVCS) lineAuthors(file string) ([]string, error) {
+	return nil, fmt.Errorf("cannot find the authors of %s outside of version control", file)
+}
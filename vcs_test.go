@@ -0,0 +1,92 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDetectVCS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDetectVCS")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	nested := filepath.Join(dir, "a", "b")
+	noError(t, os.MkdirAll(nested, 0755))
+	noError(t, os.Mkdir(filepath.Join(dir, ".hg"), 0755))
+	if _, isHg := detectVCS(nested).(hgVCS); !isHg {
+		t.Errorf("Expected hg, saw %T", detectVCS(nested))
+	}
+	noError(t, ioutil.WriteFile(filepath.Join(dir, "a", ".git"), []byte("gitdir: elsewhere\n"), 0644))
+	if _, isGit := detectVCS(nested).(gitVCS); !isGit {
+		t.Errorf("Expected the nearest repository, git, saw %T", detectVCS(nested))
+	}
+	if _, err := selectVCS("svn"); err == nil {
+		t.Errorf("Expected an error for an unsupported vcs")
+	}
+}
+
+func TestRelativeChanges(t *testing.T) {
+	log := "commit\nsvc/a/a.go\nother/b.go\ncommit\nsvc/a/c.go\nsvc/d/d.go\n"
+	changes := countChanges(relativeChanges(log, "/repo", "/repo/svc"))
+	if !reflect.DeepEqual(changes, map[string]int{"a": 2, "d": 1}) {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+}
+
+func TestNullVCS(t *testing.T) {
+	var repo vcs = nullVCS{}
+	if repo.commit() != "" || repo.branch() != "" || len(repo.recentChanges(time.Now())) != 0 {
+		t.Errorf("Expected a null vcs to know nothing")
+	}
+	if err := repo.update("main", ioutil.Discard); err == nil {
+		t.Errorf("Expected updating without a vcs to fail")
+	}
+	if repo.root() != "" || repo.checkout("main", "dir", ioutil.Discard) == nil {
+		t.Errorf("Expected checking out without a vcs to fail")
+	}
+	if _, err := repo.changedFiles("main", "feature"); err == nil {
+		t.Errorf("Expected diffing without a vcs to fail")
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	if lines := splitLines("a.go\n\n b/c.go \n"); !reflect.DeepEqual(lines, []string{"a.go", "b/c.go"}) {
+		t.Errorf("Unexpected lines %v", lines)
+	}
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	diff := `diff --git a/a/a.go b/a/a.go
+--- a/a/a.go
++++ b/a/a.go
+@@ -3 +3 @@ func A() {
+-	return 1
++	return 2
+@@ -10,0 +11,2 @@ func B() {
++	x++
++	y++
+@@ -20,3 +22,0 @@
+-gone
+diff --git a/old.go b/old.go
+--- a/old.go
++++ /dev/null
+@@ -1,2 +0,0 @@
+-package old
++++ b/hg.go	Thu Jan 01 00:00:00 1970 +0000
+@@ -0,0 +1 @@
++package hg
+`
+	if lines := parseUnifiedDiff(diff); !reflect.DeepEqual(lines, map[string][]int{"a/a.go": {3, 11, 12}, "hg.go": {1}}) {
+		t.Errorf("Unexpected changed lines %v", lines)
+	}
+}
+
+func TestParseBlamePorcelain(t *testing.T) {
+	blame := "abc 1 1 2\nauthor Ann\nauthor-mail <ann@example.com>\nsummary x\nfilename a.go\n\tpackage a\nabc 2 2\nauthor Ann\nauthor-mail <ann@example.com>\nfilename a.go\n\t\ndef 3 3 1\nauthor Bob\nauthor-mail <bob@example.com>\nfilename a.go\n\tfunc A() {}\n"
+	if authors := parseBlamePorcelain(blame); !reflect.DeepEqual(authors, []string{"Ann <ann@example.com>", "Ann <ann@example.com>", "Bob <bob@example.com>"}) {
+		t.Errorf("Unexpected authors %v", authors)
+	}
+}
@@ -0,0 +1,217 @@
+package gocoverdir
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// buildVersion and buildCommit are set for releases with
+// go build -ldflags "-X github.com/cep21/gocoverdir.buildVersion=v1.2.3 -X github.com/cep21/gocoverdir.buildCommit=$(git rev-parse HEAD)" ./cmd/gocoverdir
+var (
+	buildVersion = ""
+	buildCommit  = ""
+)
+
+// toolVersion is gocoverdir's version and commit, for -version and every report.  Without ldflags it uses what
+// go install and go build record in the binary
+func toolVersion() string {
+	version, commit := buildVersion, buildCommit
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if version == "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && commit == "" {
+				commit = setting.Value
+			}
+		}
+	}
+	if version == "" {
+		version = "devel"
+	}
+	if len(commit) > 12 {
+		commit = commit[:12]
+	}
+	if commit == "" {
+		return version
+	}
+	return version + " (" + commit + ")"
+}
+
+// releaseRepository is where selfupdate looks for releases
+const releaseRepository = "cep21/gocoverdir"
+
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// releaseChecksums is the release asset with the SHA-256 of every binary, in the format of sha256sum.  selfupdate
+// refuses releases without it
+const releaseChecksums = "checksums.txt"
+
+// releaseBinary is the binary of a release for one platform
+type releaseBinary struct {
+	tag          string
+	name         string
+	url          string
+	checksumsURL string
+}
+
+// releaseAssetName is the name of the release binary for goos and goarch
+func releaseAssetName(goos string, goarch string) string {
+	name := fmt.Sprintf("gocoverdir_%s_%s", goos, goarch)
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// latestRelease finds the newest release and the download URLs of its binary for goos and goarch and of its checksums
+func latestRelease(g *githubClient, goos string, goarch string) (releaseBinary, error) {
+	var release githubRelease
+	if err := g.getJSON(fmt.Sprintf("%s/repos/%s/releases/latest", githubAPIURL(g.getenv), releaseRepository), &release); err != nil {
+		return releaseBinary{}, err
+	}
+	ret := releaseBinary{tag: release.TagName, name: releaseAssetName(goos, goarch)}
+	for _, asset := range release.Assets {
+		switch asset.Name {
+		case ret.name:
+			ret.url = asset.URL
+		case releaseChecksums:
+			ret.checksumsURL = asset.URL
+		}
+	}
+	if ret.url == "" {
+		return releaseBinary{}, fmt.Errorf("release %s has no binary %s", release.TagName, ret.name)
+	}
+	if ret.checksumsURL == "" {
+		return releaseBinary{}, fmt.Errorf("release %s has no %s to verify %s with", release.TagName, releaseChecksums, ret.name)
+	}
+	return ret, nil
+}
+
+// verifyChecksum checks contents against the SHA-256 checksums lists for name
+func verifyChecksum(checksums []byte, name string, contents []byte) error {
+	sum := sha256.Sum256(contents)
+	for _, line := range strings.Split(string(checksums), "\n") {
+		fields := strings.Fields(line)
+		// sha256sum marks files it read in binary mode with a *
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+		if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+			return fmt.Errorf("%s does not match %s: expected SHA-256 %s, downloaded %x", name, releaseChecksums, fields[0], sum)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s does not list %s", releaseChecksums, name)
+}
+
+// download reads url, which for release assets redirects to storage that rejects GitHub API headers
+func download(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s returned %s", url, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// replaceExecutable writes contents next to executable and renames it over executable, so a failed download never
+// leaves a broken binary
+func replaceExecutable(executable string, contents []byte, goos string) error {
+	info, err := os.Stat(executable)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(executable), ".gocoverdir-update")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), info.Mode()); err != nil {
+		return err
+	}
+	// Windows cannot rename over a running executable, but can move it out of the way.  The last update's is no longer
+	// running, so it can go
+	if goos == "windows" {
+		if err := os.Remove(executable + ".old"); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Rename(executable, executable+".old"); err != nil {
+			return err
+		}
+	}
+	return os.Rename(tmp.Name(), executable)
+}
+
+// runSelfUpdate replaces the running binary with the latest release
+func runSelfUpdate(args []string) error {
+	fs := flag.NewFlagSet("selfupdate", flag.ContinueOnError)
+	check := fs.Bool("check", false, "If true, only print the latest release")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	g := &githubClient{client: &http.Client{Timeout: 5 * time.Minute}, getenv: os.Getenv}
+	release, err := latestRelease(g, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return err
+	}
+	tag := release.tag
+	current := toolVersion()
+	if strings.HasPrefix(current, tag+" ") || current == tag {
+		fmt.Printf("gocoverdir %s is the latest release\n", current)
+		return nil
+	}
+	if *check {
+		fmt.Printf("gocoverdir %s is available, this is %s\n", tag, current)
+		return nil
+	}
+	contents, err := download(g.client, release.url)
+	if err != nil {
+		return err
+	}
+	checksums, err := download(g.client, release.checksumsURL)
+	if err != nil {
+		return err
+	}
+	if err := verifyChecksum(checksums, release.name, contents); err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if executable, err = filepath.EvalSymlinks(executable); err != nil {
+		return err
+	}
+	if err := replaceExecutable(executable, contents, runtime.GOOS); err != nil {
+		return err
+	}
+	fmt.Printf("Updated gocoverdir %s to %s\n", current, tag)
+	return nil
+}
@@ -0,0 +1,104 @@
+package gocoverdir
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestToolVersion(t *testing.T) {
+	defer func(version string, commit string) {
+		buildVersion, buildCommit = version, commit
+	}(buildVersion, buildCommit)
+	buildVersion, buildCommit = "v1.2.3", "0123456789abcdef"
+	if v := toolVersion(); v != "v1.2.3 (0123456789ab)" {
+		t.Errorf("Unexpected version %q", v)
+	}
+	buildVersion, buildCommit = "", ""
+	if v := toolVersion(); v == "" {
+		t.Errorf("Expected a version without ldflags")
+	}
+	if r := newReport(nil, "statements", nil, nil, nil, nil); r.ToolVersion != toolVersion() {
+		t.Errorf("Reports should record the version, saw %q", r.ToolVersion)
+	}
+}
+
+func TestLatestRelease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/cep21/gocoverdir/releases/latest" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"tag_name": "v2.0.0", "assets": [{"name": "gocoverdir_linux_amd64", "browser_download_url": "https://example.com/linux"}, {"name": "gocoverdir_windows_amd64.exe", "browser_download_url": "https://example.com/windows"}, {"name": "checksums.txt", "browser_download_url": "https://example.com/checksums"}]}`)
+	}))
+	defer server.Close()
+	g := &githubClient{client: server.Client(), getenv: func(key string) string {
+		if key == "GITHUB_API_URL" {
+			return server.URL
+		}
+		return ""
+	}}
+	release, err := latestRelease(g, "windows", "amd64")
+	noError(t, err)
+	expected := releaseBinary{tag: "v2.0.0", name: "gocoverdir_windows_amd64.exe", url: "https://example.com/windows", checksumsURL: "https://example.com/checksums"}
+	if release != expected {
+		t.Errorf("Unexpected release %+v", release)
+	}
+	if _, err := latestRelease(g, "plan9", "386"); err == nil || !strings.Contains(err.Error(), "gocoverdir_plan9_386") {
+		t.Errorf("Expected an error naming the missing binary, got %v", err)
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	checksums := []byte("0000  gocoverdir_linux_amd64\n" +
+		"11507a0e2f5e69d5dfa40a62a1bd7b6ee57e6bcd85c67c9b8431b36fff21c437 *gocoverdir_windows_amd64.exe\n")
+	noError(t, verifyChecksum(checksums, "gocoverdir_windows_amd64.exe", []byte("new")))
+	if err := verifyChecksum(checksums, "gocoverdir_linux_amd64", []byte("new")); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected a mismatched checksum to fail, got %v", err)
+	}
+	if err := verifyChecksum(checksums, "gocoverdir_darwin_arm64", []byte("new")); err == nil || !strings.Contains(err.Error(), "does not list") {
+		t.Errorf("Expected a binary missing from the checksums to fail, got %v", err)
+	}
+}
+
+func TestReplaceExecutable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestReplaceExecutable")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	executable := filepath.Join(dir, "gocoverdir")
+	noError(t, ioutil.WriteFile(executable, []byte("old"), 0755))
+	noError(t, replaceExecutable(executable, []byte("new"), "linux"))
+	contents, err := ioutil.ReadFile(executable)
+	noError(t, err)
+	info, err := os.Stat(executable)
+	noError(t, err)
+	if string(contents) != "new" || info.Mode().Perm() != 0755 {
+		t.Errorf("Unexpected executable %q with mode %s", contents, info.Mode())
+	}
+	files, err := ioutil.ReadDir(dir)
+	noError(t, err)
+	if len(files) != 1 {
+		t.Errorf("Expected the temporary file to be renamed, saw %d files", len(files))
+	}
+}
+
+func TestReplaceExecutableWindows(t *testing.T) {
+	dir := t.TempDir()
+	executable := filepath.Join(dir, "gocoverdir.exe")
+	noError(t, ioutil.WriteFile(executable, []byte("v1"), 0755))
+	// The second update finds the first one's .old still there
+	noError(t, replaceExecutable(executable, []byte("v2"), "windows"))
+	noError(t, replaceExecutable(executable, []byte("v3"), "windows"))
+	contents, err := ioutil.ReadFile(executable)
+	noError(t, err)
+	old, err := ioutil.ReadFile(executable + ".old")
+	noError(t, err)
+	if string(contents) != "v3" || string(old) != "v2" {
+		t.Errorf("Unexpected executable %q and old %q", contents, old)
+	}
+}
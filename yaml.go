@@ -0,0 +1,47 @@
+package gocoverdir
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlToJSON converts YAML to JSON, so YAML files decode into the same structs, just as strictly, as JSON ones
+func yamlToJSON(contents []byte) ([]byte, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(contents, &value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonValue(value))
+}
+
+// jsonValue converts what YAML decodes into what JSON can encode: YAML mappings can have keys that are not strings,
+// like 2030: or true:, which JSON objects cannot.  Timestamps go back to text, with dates like 2030-01-01 unchanged
+func jsonValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case time.Time:
+		if value.Equal(value.Truncate(24*time.Hour)) && value.Location() == time.UTC {
+			return value.Format("2006-01-02")
+		}
+		return value.Format(time.RFC3339Nano)
+	case map[string]interface{}:
+		for key, v := range value {
+			value[key] = jsonValue(v)
+		}
+		return value
+	case map[interface{}]interface{}:
+		ret := make(map[string]interface{}, len(value))
+		for key, v := range value {
+			ret[fmt.Sprint(key)] = jsonValue(v)
+		}
+		return ret
+	case []interface{}:
+		for i, v := range value {
+			value[i] = jsonValue(v)
+		}
+		return value
+	}
+	return value
+}
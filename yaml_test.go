@@ -0,0 +1,64 @@
+package gocoverdir
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestYAMLConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gocoverdirtest")
+	noError(t, err)
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "gocoverdir.yaml")
+	noError(t, ioutil.WriteFile(filename, []byte(`# Coverage gates
+requiredcoverage: 80
+minstmts: 5
+packages:
+  ./...: 60
+  "internal/payments/...": 90 # audited
+internal: [ "cmd/..." ]
+goals:
+  pkg/parser:
+    target: 75
+    by: 2030-01-01
+exclude:
+- return-err
+- 'panic'
+sourcemap:
+  - generated: (.*)\.pb\.go$
+    source: proto/$1.proto
+`), 0644))
+	cfg, err := loadConfig(filename)
+	noError(t, err)
+	expected := &config{
+		RequiredCoverage: 80,
+		MinStatements:    5,
+		Packages:         map[string]float64{"./...": 60, "internal/payments/...": 90},
+		Internal:         []string{"cmd/..."},
+		Goals:            map[string]goal{"pkg/parser": {Target: 75, By: "2030-01-01"}},
+		Exclude:          []string{"return-err", "panic"},
+		SourceMap:        []sourceMapRule{{Generated: `(.*)\.pb\.go$`, Source: "proto/$1.proto"}},
+	}
+	cfg.SourceMap[0].generated = nil
+	expected.failOutput = cfg.failOutput
+	if !reflect.DeepEqual(cfg, expected) {
+		t.Errorf("Expected %+v, saw %+v", expected, cfg)
+	}
+
+	// Anchors and flow collections that are not JSON
+	noError(t, ioutil.WriteFile(filename, []byte("requiredcoverage: &gate 90\npackages: {a/...: *gate, b/...: 80}\nexclude: [return-err, panic]\n"), 0644))
+	cfg, err = loadConfig(filename)
+	noError(t, err)
+	if cfg.RequiredCoverage != 90 || !reflect.DeepEqual(cfg.Packages, map[string]float64{"a/...": 90, "b/...": 80}) || !reflect.DeepEqual(cfg.Exclude, []string{"return-err", "panic"}) {
+		t.Errorf("Unexpected config %+v", cfg)
+	}
+	for _, invalid := range []string{"requiredcoverage 80\n", "packages:\n  a: 1\n    b: 2\n", "a: 1\na: 2\n", "\tminstmts: 5\n", "unknown: 1\n"} {
+		noError(t, ioutil.WriteFile(filename, []byte(invalid), 0644))
+		if _, err := loadConfig(filename); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}